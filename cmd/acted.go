@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/autobrr/tqm/pkg/config"
+)
+
+// actions recorded in actedTorrents
+const (
+	actionRetag   = "retag"
+	actionRelabel = "relabel"
+	actionPause   = "pause"
+	actionClean   = "clean"
+)
+
+// defaultRecentlyActedTTL is how long a torrent counts as recently acted on when recently_acted_ttl is not set
+const defaultRecentlyActedTTL = time.Hour
+
+// actedEntry is the action that changed a torrent and when
+type actedEntry struct {
+	Action string    `json:"action"`
+	At     time.Time `json:"at"`
+}
+
+// actedSet holds the hashes of the torrents acted on recently with the action that changed them
+type actedSet struct {
+	mu     sync.Mutex
+	hashes map[string]actedEntry
+	// recorded are the entries of this process, the ones to write back to recently_acted_file
+	recorded map[string]actedEntry
+}
+
+func newActedSet() *actedSet {
+	return &actedSet{
+		hashes:   make(map[string]actedEntry),
+		recorded: make(map[string]actedEntry),
+	}
+}
+
+// actedTorrents is shared by the action loops, and persisted to recently_acted_file when it is set, so actions run by
+// separate invocations skip the torrents an earlier action changed, e.g. a torrent whose files are still moving after
+// a relabel is not removed by clean
+var actedTorrents = newActedSet()
+
+// record marks hash as acted on by action, a dry-run records the torrents it would change so it skips the same ones
+func (s *actedSet) record(hash string, action string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := actedEntry{Action: action, At: now()}
+	s.hashes[hash] = e
+	s.recorded[hash] = e
+}
+
+// actedBy returns the action hash was acted on by
+func (s *actedSet) actedBy(hash string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.hashes[hash]
+	return e.Action, ok
+}
+
+// load adds the entries of path younger than ttl, a missing file adds none
+func (s *actedSet) load(path string, ttl time.Duration) error {
+	entries, err := readActed(path)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := now().Add(-ttl)
+	for h, e := range entries {
+		if e.At.After(cutoff) {
+			s.hashes[h] = e
+		}
+	}
+
+	return nil
+}
+
+// save merges the entries recorded by this process into path, dropping the ones older than ttl
+func (s *actedSet) save(path string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.recorded) == 0 {
+		return nil
+	}
+
+	// another invocation may have written the file since it was loaded
+	entries, err := readActed(path)
+	if err != nil {
+		return err
+	}
+	if entries == nil {
+		entries = make(map[string]actedEntry)
+	}
+
+	for h, e := range s.recorded {
+		entries[h] = e
+	}
+
+	cutoff := now().Add(-ttl)
+	for h, e := range entries {
+		if !e.At.After(cutoff) {
+			delete(entries, h)
+		}
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal recently acted torrents: %w", err)
+	}
+
+	if err := writeFileAtomic(path, append(data, '\n')); err != nil {
+		return err
+	}
+
+	s.recorded = make(map[string]actedEntry)
+	return nil
+}
+
+// readActed reads the entries stored in path, nil when there is no file
+func readActed(path string) (map[string]actedEntry, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read recently acted file: %w", err)
+	}
+
+	var entries map[string]actedEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("unmarshal recently acted file: %w", err)
+	}
+
+	return entries, nil
+}
+
+// recentlyActedTTL returns how long a torrent counts as recently acted on
+func recentlyActedTTL() time.Duration {
+	if config.Config != nil && config.Config.RecentlyActedTTL != nil {
+		return *config.Config.RecentlyActedTTL
+	}
+
+	return defaultRecentlyActedTTL
+}
+
+// loadActedTorrents reads the torrents acted on by earlier invocations when exclude_recently_acted persists them
+func loadActedTorrents() error {
+	if !config.Config.ExcludeRecentlyActed || config.Config.RecentlyActedFile == "" {
+		return nil
+	}
+
+	return actedTorrents.load(config.Config.RecentlyActedFile, recentlyActedTTL())
+}
+
+// saveActedTorrents persists the torrents acted on by this invocation, a dry-run changed nothing so it saves none
+func saveActedTorrents() {
+	if config.Config == nil || !config.Config.ExcludeRecentlyActed || config.Config.RecentlyActedFile == "" ||
+		flagDryRun {
+		return
+	}
+
+	if err := actedTorrents.save(config.Config.RecentlyActedFile, recentlyActedTTL()); err != nil {
+		log.WithError(err).Error("Failed writing recently acted file")
+	}
+}
+
+// skipRecentlyActed reports whether t is skipped by action because exclude_recently_acted is enabled and another
+// action changed it recently, logging the skip
+func skipRecentlyActed(log *logrus.Entry, t *config.Torrent, action string) bool {
+	if config.Config == nil || !config.Config.ExcludeRecentlyActed {
+		return false
+	}
+
+	by, ok := actedTorrents.actedBy(t.Hash)
+	if !ok || by == action {
+		return false
+	}
+
+	log.Debugf("Skipping torrent recently acted on by %s: %q", by, t.Name)
+	return true
+}
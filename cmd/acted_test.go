@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/logger"
+	"github.com/autobrr/tqm/pkg/torrentfilemap"
+)
+
+func TestExcludeRecentlyActed(t *testing.T) {
+	setupTestConfig()
+	removalDelay, relabelDelay = 0, 0
+	t.Cleanup(func() {
+		removalDelay, relabelDelay = time.Second, 5*time.Second
+		config.Config.ExcludeRecentlyActed = false
+		actedTorrents = newActedSet()
+	})
+
+	filter := &config.FilterConfiguration{Remove: []string{`Ratio > 1`}}
+	filter.Label = append(filter.Label, struct {
+		Name     string
+		Priority int
+		Update   []string
+	}{Name: "archive", Update: []string{`Label == "tv"`}})
+
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Label: "tv", Ratio: 2, Files: []string{"/data/a"}},
+		"b": {Hash: "b", Name: "b", Label: "movies", Ratio: 2, Files: []string{"/data/b"}},
+	}
+
+	for _, exclude := range []bool{false, true} {
+		t.Run(fmt.Sprintf("exclude_recently_acted_%t", exclude), func(t *testing.T) {
+			config.Config.ExcludeRecentlyActed = exclude
+			actedTorrents = newActedSet()
+
+			// relabel then clean in the same process, as chained commands would
+			c := newMockClient(t, filter, 0, torrents)
+			working, err := c.GetTorrents(context.Background())
+			require.NoError(t, err)
+
+			err = relabelEligibleTorrents(context.Background(), logger.GetLogger("test"), c, working,
+				torrentfilemap.New(working), &recordingSender{}, "test", time.Now())
+			require.NoError(t, err)
+			assert.Equal(t, map[string]string{"a": "archive"}, c.Labels)
+
+			expected := []string{"a", "b"}
+			if exclude {
+				expected = []string{"b"}
+			}
+			assert.Equal(t, expected, runRemove(t, false, filter, 0, torrents))
+
+			// a repeated relabel is not skipped because of its own earlier run
+			assert.False(t, skipRecentlyActed(logger.GetLogger("test"), &config.Torrent{Hash: "a"}, actionRelabel))
+		})
+	}
+}
+
+func TestActedSet_Persisted(t *testing.T) {
+	setupTestConfig()
+	path := filepath.Join(t.TempDir(), "acted.json")
+	ttl := time.Hour
+
+	current := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+	t.Cleanup(func() {
+		now = time.Now
+		config.Config.ExcludeRecentlyActed = false
+		actedTorrents = newActedSet()
+	})
+	config.Config.ExcludeRecentlyActed = true
+
+	// relabel records a torrent in one invocation
+	relabel := newActedSet()
+	relabel.record("a", actionRelabel)
+	require.NoError(t, relabel.save(path, ttl))
+
+	// a later clean invocation skips it
+	current = current.Add(30 * time.Minute)
+	actedTorrents = newActedSet()
+	require.NoError(t, actedTorrents.load(path, ttl))
+	assert.True(t, skipRecentlyActed(logger.GetLogger("test"), &config.Torrent{Hash: "a"}, actionClean))
+	assert.False(t, skipRecentlyActed(logger.GetLogger("test"), &config.Torrent{Hash: "a"}, actionRelabel))
+
+	// another invocation records a torrent, the entry of the first one is kept
+	current = current.Add(20 * time.Minute)
+	retag := newActedSet()
+	retag.record("b", actionRetag)
+	require.NoError(t, retag.save(path, ttl))
+
+	entries, err := readActed(path)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	// after the ttl the first entry no longer counts and is pruned on the next save
+	current = current.Add(20 * time.Minute)
+	actedTorrents = newActedSet()
+	require.NoError(t, actedTorrents.load(path, ttl))
+	assert.False(t, skipRecentlyActed(logger.GetLogger("test"), &config.Torrent{Hash: "a"}, actionClean))
+	assert.True(t, skipRecentlyActed(logger.GetLogger("test"), &config.Torrent{Hash: "b"}, actionClean))
+
+	actedTorrents.record("c", actionPause)
+	require.NoError(t, actedTorrents.save(path, ttl))
+
+	entries, err = readActed(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "c"}, slices.Sorted(maps.Keys(entries)))
+}
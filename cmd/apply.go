@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/autobrr/tqm/pkg/client"
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/expression"
+	"github.com/autobrr/tqm/pkg/hardlinkfilemap"
+	"github.com/autobrr/tqm/pkg/logger"
+	"github.com/autobrr/tqm/pkg/pathmapping"
+	"github.com/autobrr/tqm/pkg/torrentfilemap"
+	"github.com/autobrr/tqm/pkg/tracker"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply [PLAN]",
+	Short: "Execute the actions of a plan written by a dry-run",
+	Long: `This command executes exactly the actions of a plan written by clean --dry-run --plan, without evaluating the
+filters again. The plan must be signed with the configured plan_signing_key. Before acting, every planned torrent is
+checked against its state when it was planned, and nothing is done when any of them changed or is gone. The data of a
+planned torrent whose files are now shared with a torrent outside the plan is kept.`,
+
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		// init core
+		if !initialized {
+			initCore(true)
+			initialized = true
+		}
+
+		// set log
+		log := logger.GetLogger("apply")
+
+		if err := checkConfirmationToken(); err != nil {
+			log.WithError(err).Fatal("Failed confirming destructive command")
+		}
+
+		key, err := planSigningKey()
+		if err != nil {
+			log.WithError(err).Fatal("Failed verifying plan")
+		}
+
+		p, err := readPlan(args[0], key)
+		if err != nil {
+			log.WithError(err).Fatalf("Failed loading plan: %s", args[0])
+		}
+
+		log.Infof("Loaded plan of %d action(s) created at %s", len(p.Actions), p.CreatedAt.Local().Format(time.RFC3339))
+
+		// connect to every client of the plan before acting on any
+		clients := make(map[string]*planClient)
+		torrents := make(map[string]map[string]config.Torrent)
+		for _, clientName := range planClients(p) {
+			clients[clientName] = connectPlanClient(ctx, log, clientName)
+			torrents[clientName] = clients[clientName].torrents
+		}
+
+		if drifts := planDrifts(p, torrents); len(drifts) > 0 {
+			for _, drift := range drifts {
+				log.Errorf("Changed since planned: %s", drift)
+			}
+			log.Fatalf("Aborting, %d planned torrent(s) changed since the plan was made", len(drifts))
+		}
+
+		// cross-seeds added since the plan was made still use the data
+		if n := keepSharedData(log, p, clients); n > 0 {
+			log.Warnf("Keeping the data of %d planned torrent(s) now sharing files with other torrents", n)
+		}
+
+		removed, failed := executePlan(ctx, log, clients, p)
+		log.Info("-----")
+		log.Infof("Applied plan, removed %d torrent(s), %d failure(s)", removed, failed)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+}
+
+// planClient is a client of a plan, with its current torrents and the maps of their files
+type planClient struct {
+	c        client.Interface
+	filter   *config.FilterConfiguration
+	torrents map[string]config.Torrent
+	tfm      *torrentfilemap.TorrentFileMap
+	hfm      hardlinkfilemap.HardlinkFileMapI
+	mapping  *pathmapping.Mapping
+}
+
+// connectPlanClient connects to a client of a plan, retrieves its torrents and maps their files
+func connectPlanClient(ctx context.Context, log *logrus.Entry, clientName string) *planClient {
+	// retrieve client object
+	clientConfig, ok := config.Config.Clients[clientName]
+	if !ok {
+		log.Fatalf("No client configuration found for: %q", clientName)
+	}
+
+	// validate client is enabled
+	if err := validateClientEnabled(clientConfig); err != nil {
+		log.WithError(err).Fatal("Failed validating client is enabled")
+	}
+
+	// retrieve client type
+	clientType, err := getClientConfigString("type", clientConfig)
+	if err != nil {
+		log.WithError(err).Fatal("Failed determining client type")
+	}
+
+	// download path mapping
+	clientDownloadPathMapping, err := getClientDownloadPathMapping(clientConfig)
+	if err != nil {
+		log.WithError(err).Fatal("Failed loading client download path mappings")
+	}
+
+	// retrieve client filters
+	clientFilter, err := getClientFilter(clientName, clientConfig)
+	if err != nil {
+		log.WithError(err).Fatal("Failed retrieving client filter")
+	}
+
+	// compile client filters
+	exp, err := expression.Compile(clientFilter)
+	if err != nil {
+		log.WithError(err).Fatal("Failed compiling client filters")
+	}
+
+	// load client object
+	c, err := client.NewClient(*clientType, clientName, exp)
+	if err != nil {
+		log.WithError(err).Fatalf("Failed initializing client: %q", clientName)
+	}
+
+	log.Infof("Initialized client %q, type: %s (%d trackers)", clientName, c.Type(), tracker.Loaded())
+
+	// connect to client
+	if err := c.Connect(ctx); err != nil {
+		log.WithError(err).Fatal("Failed connecting")
+	} else {
+		log.Debugf("Connected to client")
+	}
+
+	// retrieve torrents
+	torrents, err := c.GetTorrents(ctx)
+	if err != nil {
+		log.WithError(err).Fatal("Failed retrieving torrents")
+	} else {
+		log.Infof("Retrieved %d torrents", len(torrents))
+	}
+
+	return &planClient{
+		c:        c,
+		filter:   clientFilter,
+		torrents: torrents,
+		tfm:      newTorrentFileMap(log, torrents, clientFilter, clientConfig),
+		hfm:      newHardlinkFileMap(log, clientName, torrents, clientFilter, clientConfig),
+		mapping:  clientDownloadPathMapping,
+	}
+}
+
+// keepSharedData keeps the data of the planned removals whose files are shared with a torrent outside the plan, it
+// returns the number of removals changed
+func keepSharedData(log *logrus.Entry, p *removalPlan, clients map[string]*planClient) int {
+	// imagine every planned torrent removed, the files still mapped belong to other torrents
+	for _, a := range p.Actions {
+		pc := clients[a.Client]
+		if t, ok := pc.torrents[a.Hash]; ok {
+			pc.tfm.Remove(t)
+			pc.hfm.RemoveByTorrent(t)
+		}
+	}
+
+	kept := 0
+	for i, a := range p.Actions {
+		if !a.DeleteData {
+			continue
+		}
+
+		pc := clients[a.Client]
+		t := pc.torrents[a.Hash]
+		if pc.tfm.NoInstances(t) && pc.hfm.NoInstances(t) {
+			continue
+		}
+
+		log.Warnf("Keeping data of %s: %q, its files are shared with a torrent outside the plan", a.Client, a.Name)
+		p.Actions[i].DeleteData = false
+		p.Actions[i].Archiving = false
+		kept++
+	}
+
+	return kept
+}
+
+// executePlan executes the actions of a plan in order, with the recheck, archive and verify steps of the client
+// filters, returning the number of torrents removed and of failures
+func executePlan(ctx context.Context, log *logrus.Entry, clients map[string]*planClient, p *removalPlan) (int, int) {
+	removed, failed := 0, 0
+
+	for _, a := range p.Actions {
+		pc := clients[a.Client]
+		t := pc.torrents[a.Hash]
+
+		log.Info("-----")
+		log.Infof("Removing from %s: %q - %s", a.Client, a.Name, a.Reason)
+
+		if flagDryRun {
+			log.Warnf("Dry-run enabled, skipping remove (would delete data: %t)...", a.DeleteData)
+			continue
+		}
+
+		// deleting the data without the planned archive would lose it
+		if a.Archiving && (pc.filter == nil || pc.filter.ArchivePath == "") {
+			log.Errorf("Failed removing torrent, it was planned to be archived but no archive_path is configured: %q",
+				a.Name)
+			failed++
+			continue
+		}
+
+		r := &pendingRemoval{
+			h:          a.Hash,
+			t:          &t,
+			reason:     a.Reason,
+			deleteData: a.DeleteData,
+			archiving:  a.Archiving,
+		}
+		executeRemoval(ctx, log, pc.c, pc.filter, pc.mapping, removalDelay, r)
+		if r.failed {
+			failed++
+			continue
+		}
+
+		removed++
+	}
+
+	return removed, failed
+}
@@ -1,31 +1,34 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"slices"
 	"strings"
 	"time"
 
 	"github.com/dustin/go-humanize"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
 	"github.com/autobrr/tqm/pkg/client"
 	"github.com/autobrr/tqm/pkg/config"
-	"github.com/autobrr/tqm/pkg/evaluate"
+	"github.com/autobrr/tqm/pkg/diskspace"
 	"github.com/autobrr/tqm/pkg/expression"
-	"github.com/autobrr/tqm/pkg/hardlinkfilemap"
 	"github.com/autobrr/tqm/pkg/logger"
 	"github.com/autobrr/tqm/pkg/notification"
-	"github.com/autobrr/tqm/pkg/torrentfilemap"
+	"github.com/autobrr/tqm/pkg/pathmapping"
 	"github.com/autobrr/tqm/pkg/tracker"
 )
 
 var cleanCmd = &cobra.Command{
-	Use:   "clean [CLIENT]",
+	Use:   "clean [CLIENT]...",
 	Short: "Check torrent client for torrents to remove",
 	Long:  `This command can be used to check a torrent clients queue for torrents to remove based on its configured filters.`,
 
-	Args: cobra.ExactArgs(1),
+	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := cmd.Context()
 		startTime := time.Now()
@@ -39,143 +42,375 @@ var cleanCmd = &cobra.Command{
 		// set log
 		log := logger.GetLogger("clean")
 
-		noti := notification.NewDiscordSender(log, config.Config.Notifications)
+		// validate report flags
+		if flagReport != "" {
+			if !flagDryRun {
+				log.Fatal("--report requires --dry-run")
+			}
+			if flagReport != reportFormatTable && flagReport != reportFormatJSON {
+				log.Fatalf("Unsupported report format: %q (table or json)", flagReport)
+			}
+		}
 
-		// retrieve client object
-		clientName := args[0]
-		clientConfig, ok := config.Config.Clients[clientName]
-		if !ok {
-			log.Fatalf("No client configuration found for: %q", clientName)
+		// interactive triage is a dry-run
+		if flagDryRunInteractive != "" {
+			flagDryRun = true
 		}
 
-		// validate client is enabled
-		if err := validateClientEnabled(clientConfig); err != nil {
-			log.WithError(err).Fatal("Failed validating client is enabled")
+		// collect the removals of the dry-run into a plan for the apply command
+		if flagPlan != "" {
+			if !flagDryRun {
+				log.Fatal("--plan requires --dry-run")
+			}
+			if _, err := planSigningKey(); err != nil {
+				log.WithError(err).Fatal("Failed signing plan")
+			}
+			cleanPlan = newRemovalPlan()
 		}
 
-		// retrieve client type
-		clientType, err := getClientConfigString("type", clientConfig)
-		if err != nil {
-			log.WithError(err).Fatal("Failed determining client type")
+		if err := checkConfirmationToken(); err != nil {
+			log.WithError(err).Fatal("Failed confirming destructive command")
 		}
 
-		// retrieve client free space path
-		clientFreeSpacePath, _ := getClientConfigString("free_space_path", clientConfig)
+		noti := newNotificationSender(log)
 
-		// retrieve client filters
-		clientFilter, err := getClientFilter(clientConfig)
-		if err != nil {
-			log.WithError(err).Fatal("Failed retrieving client filter")
-		}
+		// warm the tracker API caches, so evaluating the torrents does not wait on bulk fetches
+		prefetchTrackers(ctx, log)
 
-		if flagFilterName != "" {
-			clientFilter, err = getFilter(flagFilterName)
-			if err != nil {
-				log.WithError(err).Fatal("Failed retrieving specified filter")
+		if len(args) == 1 {
+			if err := cleanClient(ctx, log, noti, args[0], nil); err != nil {
+				log.WithError(err).Fatalf("Failed cleaning client: %q", args[0])
 			}
+			reportTrackerBudget(log)
+			writeCleanPlan(log)
+			writeMetrics(log)
+			return
 		}
 
-		// compile client filters
-		exp, err := expression.Compile(clientFilter)
-		if err != nil {
-			log.WithError(err).Fatal("Failed compiling client filters")
+		// send a single rollup notification instead of one per client
+		summary := notification.NewSummary()
+		cleanClients(ctx, log, noti, args, summary, cleanClient)
+
+		reportTrackerBudget(log)
+		writeCleanPlan(log)
+		writeMetrics(log)
+
+		count, reclaimed := summary.Totals()
+		log.Info("========================================")
+		log.WithField("reclaimed_space", humanize.IBytes(uint64(reclaimed))).
+			Infof("Removed torrents across %d clients: %d", len(args), count)
+
+		if !noti.CanSend() {
+			log.Debug("Notifications disabled, skipping...")
+		} else if err := summary.Send(noti, "Torrent Cleanup", time.Since(startTime), flagDryRun); err != nil {
+			log.WithError(err).Error("Failed sending notification")
+		}
+
+		if failed := summary.Failed(); failed > 0 {
+			log.Fatalf("Failed cleaning %d of %d clients", failed, len(args))
+		}
+	},
+}
+
+// writeCleanPlan signs and stores the plan collected by the dry-run, when --plan is set
+func writeCleanPlan(log *logrus.Entry) {
+	if cleanPlan == nil {
+		return
+	}
+
+	key, err := planSigningKey()
+	if err != nil {
+		log.WithError(err).Fatal("Failed signing plan")
+	}
+
+	if err := writePlan(flagPlan, cleanPlan, key); err != nil {
+		log.WithError(err).Fatal("Failed writing plan")
+	}
+
+	log.Infof("Wrote plan of %d action(s) to: %q, run apply to execute it", len(cleanPlan.Actions), flagPlan)
+}
+
+// reportTrackerBudget warns about the torrents left unchecked once the tracker request budget ran out
+func reportTrackerBudget(log *logrus.Entry) {
+	if skipped := tracker.BudgetSkipped(); skipped > 0 {
+		log.Warnf("Tracker request budget of %d exhausted, %d torrent(s) were not checked with their tracker API "+
+			"and were treated as not unregistered", tracker.BudgetLimit(), skipped)
+	}
+}
+
+// prefetchTrackers fetches the bulk data of the trackers that have any, logging progress as each one finishes
+func prefetchTrackers(ctx context.Context, log *logrus.Entry) {
+	total := tracker.Prefetchers()
+	if total == 0 {
+		return
+	}
+
+	start := time.Now()
+	log.Infof("Prefetching %d tracker(s)", total)
+
+	tracker.PrefetchAll(ctx, func(r tracker.PrefetchResult, done, total int) {
+		if r.Err != nil {
+			log.WithError(r.Err).Errorf("Failed prefetching tracker %s (%d/%d)", r.Tracker, done, total)
+			return
+		}
+
+		log.Infof("Prefetched tracker %s (%d/%d)", r.Tracker, done, total)
+	})
+
+	log.Debugf("Prefetched trackers in %s", time.Since(start))
+}
+
+// cleanClients cleans each client with clean, a client that fails is recorded in summary and the next one is still
+// cleaned
+func cleanClients(ctx context.Context, log *logrus.Entry, noti notification.Sender, clientNames []string, summary *notification.Summary,
+	clean func(ctx context.Context, log *logrus.Entry, noti notification.Sender, clientName string, summary *notification.Summary) error) {
+	for _, clientName := range clientNames {
+		log.Infof("========== %s ==========", clientName)
+		if err := clean(ctx, log, noti, clientName, summary); err != nil {
+			log.WithError(err).Errorf("Failed cleaning client: %q", clientName)
+			summary.Fail(clientName, err)
 		}
+	}
+}
+
+// cleanClient removes eligible torrents from a single client, recording its results in summary when set
+func cleanClient(ctx context.Context, log *logrus.Entry, noti notification.Sender, clientName string, summary *notification.Summary) error {
+	startTime := time.Now()
+
+	// retrieve client object
+	clientConfig, ok := config.Config.Clients[clientName]
+	if !ok {
+		return fmt.Errorf("no client configuration found for: %q", clientName)
+	}
+
+	// validate client is enabled
+	if err := validateClientEnabled(clientConfig); err != nil {
+		return fmt.Errorf("validate client is enabled: %w", err)
+	}
+
+	// retrieve client type
+	clientType, err := getClientConfigString("type", clientConfig)
+	if err != nil {
+		return fmt.Errorf("determine client type: %w", err)
+	}
 
-		// load client object
-		c, err := client.NewClient(*clientType, clientName, exp)
+	// retrieve client free space path
+	clientFreeSpacePath, _ := getClientConfigString("free_space_path", clientConfig)
+
+	// retrieve client filters
+	clientFilter, err := getClientFilter(clientName, clientConfig)
+	if err != nil {
+		return fmt.Errorf("retrieve client filter: %w", err)
+	}
+
+	if flagFilterName != "" {
+		clientFilter, err = getFilter(flagFilterName)
 		if err != nil {
-			log.WithError(err).Fatalf("Failed initializing client: %q", clientName)
+			return fmt.Errorf("retrieve specified filter: %w", err)
 		}
+	}
+
+	// compile client filters
+	exp, err := expression.Compile(clientFilter)
+	if err != nil {
+		return fmt.Errorf("compile client filters: %w", err)
+	}
+
+	// load client object
+	c, err := client.NewClient(*clientType, clientName, exp)
+	if err != nil {
+		return fmt.Errorf("initialize client: %w", err)
+	}
 
-		log.Infof("Initialized client %q, type: %s (%d trackers)", clientName, c.Type(), tracker.Loaded())
+	log.Infof("Initialized client %q, type: %s (%d trackers)", clientName, c.Type(), tracker.Loaded())
 
-		// connect to client
-		if err := c.Connect(ctx); err != nil {
-			log.WithError(err).Fatal("Failed connecting")
+	// connect to client
+	if err := c.Connect(ctx); err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	log.Debugf("Connected to client")
+
+	// get free disk space (can/will be used by filters)
+	freeSpaceSet := false
+	switch *clientType {
+	case "qbittorrent":
+		// For qBittorrent, we can get free space without a path
+		space, err := c.GetCurrentFreeSpace(ctx, "")
+		if err != nil {
+			log.WithError(err).Error("Failed retrieving free-space")
 		} else {
-			log.Debugf("Connected to client")
+			freeSpaceSet = true
+			log.Infof("Retrieved free-space: %v (%.2f GB)",
+				humanize.IBytes(uint64(space)), c.GetFreeSpace())
 		}
 
-		// get free disk space (can/will be used by filters)
-		switch *clientType {
-		case "qbittorrent":
-			// For qBittorrent, we can get free space without a path
-			space, err := c.GetCurrentFreeSpace(ctx, "")
+	case "deluge":
+		if clientFreeSpacePath != nil {
+			space, err := c.GetCurrentFreeSpace(ctx, *clientFreeSpacePath)
 			if err != nil {
-				log.WithError(err).Error("Failed retrieving free-space")
-			} else {
-				log.Infof("Retrieved free-space: %v (%.2f GB)",
-					humanize.IBytes(uint64(space)), c.GetFreeSpace())
+				return fmt.Errorf("retrieve free-space for %q: %w", *clientFreeSpacePath, err)
 			}
 
-		case "deluge":
-			if clientFreeSpacePath != nil {
-				space, err := c.GetCurrentFreeSpace(ctx, *clientFreeSpacePath)
-				if err != nil {
-					log.WithError(err).Errorf("Failed retrieving free-space for: %q", *clientFreeSpacePath)
-					os.Exit(1)
-				} else {
-					log.Infof("Retrieved free-space for %q: %v (%.2f GB)", *clientFreeSpacePath,
-						humanize.IBytes(uint64(space)), c.GetFreeSpace())
-				}
-			} else {
-				if filterUsesFreeSpace(clientFilter) {
-					log.Error("Deluge requires free_space_path to be configured in order to retrieve free space information")
-					os.Exit(1)
-				}
-			}
+			freeSpaceSet = true
+			log.Infof("Retrieved free-space for %q: %v (%.2f GB)", *clientFreeSpacePath,
+				humanize.IBytes(uint64(space)), c.GetFreeSpace())
+		} else if filterUsesFreeSpace(clientFilter) || len(clientFilter.FreeSpaceFilters) > 0 {
+			return errors.New("deluge requires free_space_path to be configured in order to retrieve free space information")
 		}
+	}
 
-		// retrieve torrents
-		torrents, err := c.GetTorrents(ctx)
+	// switch to the filter of the free space tier that applies, the client evaluates the expressions it was
+	// initialized with, so they are replaced in place
+	if selected, err := selectFreeSpaceFilter(log, clientFilter, c.GetFreeSpace(), freeSpaceSet); err != nil {
+		return fmt.Errorf("select free space filter: %w", err)
+	} else if selected != clientFilter {
+		selectedExp, err := expression.Compile(selected)
 		if err != nil {
-			log.WithError(err).Fatal("Failed retrieving torrents")
-		} else {
-			log.Infof("Retrieved %d torrents", len(torrents))
+			return fmt.Errorf("compile free space filter: %w", err)
 		}
 
-		// create map of files associated to torrents (via hash)
-		tfm := torrentfilemap.New(torrents)
-		log.Infof("Mapped torrents to %d unique torrent files", tfm.Length())
+		clientFilter = selected
+		*exp = *selectedExp
+	}
 
-		var hfm hardlinkfilemap.HardlinkFileMapI
-		if evaluate.StringSliceContains(clientFilter.MapHardlinksFor, "clean", true) {
-			// download path mapping
-			clientDownloadPathMapping, err := getClientDownloadPathMapping(clientConfig)
-			if err != nil {
-				log.WithError(err).Fatal("Failed loading client download path mappings")
-			} else if clientDownloadPathMapping != nil {
-				log.Debugf("Loaded %d client download path mappings: %#v", len(clientDownloadPathMapping),
-					clientDownloadPathMapping)
-			}
+	if flagForceRecheckBeforeRemove {
+		clientFilter.RecheckBeforeRemove = true
+	}
+
+	if clientFilter.RecheckBeforeRemove && *clientType != "qbittorrent" {
+		return errors.New("rechecking before removal is currently only supported for qbittorrent")
+	}
+
+	// retrieve torrents
+	torrents, err := c.GetTorrents(ctx)
+	if err != nil {
+		return fmt.Errorf("retrieve torrents: %w", err)
+	}
+	log.Infof("Retrieved %d torrents", len(torrents))
+
+	if err := checkTorrentCount(clientName, len(torrents)); err != nil {
+		return fmt.Errorf("refusing to act on the retrieved torrents: %w", err)
+	}
 
-			// create map of paths associated to underlying file ids
-			start := time.Now()
-			hfm = hardlinkfilemap.New(torrents, clientDownloadPathMapping)
-			log.Infof("Mapped all torrent file paths to %d unique underlying file IDs in %s", hfm.Length(), time.Since(start))
+	// warn about trackers that appear to be down across many torrents
+	checkTrackerHealth(log, noti, clientName, torrents, startTime)
 
-			// add HardlinkedOutsideClient field to torrents
-			for h, t := range torrents {
-				t.HardlinkedOutsideClient = hfm.HardlinkedOutsideClient(t)
-				torrents[h] = t
+	// notify about watched torrents that are unregistered or whose tracker is down
+	checkWatchlist(ctx, log, noti, clientName, torrents, startTime)
+
+	// evaluate time based fields as of the requested time
+	if offset, err := applyAsOf(torrents); err != nil {
+		return fmt.Errorf("apply --as-of time: %w", err)
+	} else if offset != 0 {
+		log.Warnf("Evaluating filters as of %s (%s from now)", now().Add(offset).Format(time.RFC3339), offset.Round(time.Second))
+	}
+
+	// only torrents on the filesystem of free_space_path add to the free space when removed
+	if clientFreeSpacePath != nil {
+		clientDownloadPathMapping, err := getClientDownloadPathMapping(clientConfig)
+		if err != nil {
+			return fmt.Errorf("load client download path mappings: %w", err)
+		}
+
+		if n := markFreeSpaceElsewhere(log, torrents, *clientFreeSpacePath, clientDownloadPathMapping); n > 0 {
+			log.Infof("%d torrent(s) are on another filesystem than %q, removing them does not add to free space", n,
+				*clientFreeSpacePath)
+		}
+	}
+
+	// create map of files associated to torrents (via hash)
+	tfm := newTorrentFileMap(log, torrents, clientFilter, clientConfig)
+	setSharingFiles(torrents, tfm)
+
+	hfm := newHardlinkFileMap(log, clientName, torrents, clientFilter, clientConfig)
+
+	// look for the replacements of upgraded torrents among all torrents, before the pre-filters drop any
+	if clientFilter.RequireReplacement {
+		setReplacements(torrents)
+	}
+
+	// apply tracker, label/tag and hash pre-filters
+	applyPreFilters(log, torrents)
+
+	// triage the torrents meeting the remove filters by hand, for a later live run with --decisions
+	if flagDryRunInteractive != "" {
+		if isTerminal(os.Stdin) {
+			decisions, err := triageClient(ctx, c, torrents, flagDryRunInteractive)
+			if err != nil {
+				return fmt.Errorf("triage torrents: %w", err)
 			}
-		} else {
-			log.Warnf("Not mapping hardlinks for client %q", clientName)
-			log.Warnf("If your setup involves multiple torrents sharing the same underlying file using hardlinks, or you are using the 'HardlinkedOutsideClient' field in your filters, you should add 'clean' to the 'MapHardlinksFor' field in your filter configuration")
-			hfm = hardlinkfilemap.NewNoopHardlinkFileMap()
+
+			log.Infof("Wrote %d keep and %d remove decisions to: %q", len(decisions.Keep), len(decisions.Remove),
+				flagDryRunInteractive)
+			return nil
 		}
 
-		// remove torrents that are not ignored and match remove criteria
-		if err := removeEligibleTorrents(ctx, log, c, torrents, tfm, hfm, clientFilter, noti, clientName, startTime); err != nil {
-			log.WithError(err).Fatal("Failed removing eligible torrents...")
+		log.Warn("--dry-run-interactive needs a terminal, running a normal dry-run")
+	}
+
+	// only remove the torrents decided for removal during a triage
+	if flagDecisions != "" {
+		decisions, err := readTriageDecisions(flagDecisions)
+		if err != nil {
+			return fmt.Errorf("load decisions: %w", err)
 		}
-	},
+
+		n := applyTriageDecisions(torrents, decisions)
+		log.Infof("Excluded %d torrents not decided for removal in %q, %d remaining", n, flagDecisions, len(torrents))
+	}
+
+	// remove torrents that are not ignored and match remove criteria
+	if err := removeEligibleTorrents(ctx, log, c, torrents, tfm, hfm, clientFilter, noti, clientName, startTime, summary); err != nil {
+		return fmt.Errorf("remove eligible torrents: %w", err)
+	}
+
+	return nil
 }
 
 func init() {
 	rootCmd.AddCommand(cleanCmd)
 
 	cleanCmd.Flags().StringVar(&flagFilterName, "filter", "", "Filter to use instead of client")
+	cleanCmd.Flags().BoolVar(&flagForceRecheckBeforeRemove, "force-recheck-before-remove", false, "Force recheck torrents and verify they are complete before deleting their data (only qbit)")
+	cleanCmd.Flags().StringVar(&flagReport, "report", "", "Print the ordered removals of a dry-run with the space reclaimed at each step (table or json)")
+	cleanCmd.Flags().Float64Var(&flagFreeSpaceTarget, "free-space-target", 0, "Free space in GB the report marks as reached")
+	cleanCmd.Flags().StringVar(&flagDryRunInteractive, "dry-run-interactive", "", "Dry run prompting to keep, remove or skip each torrent to remove, writing the decisions to this file")
+	cleanCmd.Flags().StringVar(&flagPlan, "plan", "", "Write the removals of a dry-run to this signed plan file, executed later by apply")
+	cleanCmd.Flags().StringVar(&flagDecisions, "decisions", "", "Only remove the torrents decided for removal in this --dry-run-interactive file")
+	addPreFilterFlags(cleanCmd)
+}
+
+// selectFreeSpaceFilter returns the filter of the free_space_filters tier of filter that applies at freeGB, the tier
+// with the lowest below_gb that free space is under, or filter itself when free space is above every tier or unknown
+func selectFreeSpaceFilter(log *logrus.Entry, filter *config.FilterConfiguration, freeGB float64, freeSpaceSet bool) (*config.FilterConfiguration, error) {
+	if len(filter.FreeSpaceFilters) == 0 {
+		return filter, nil
+	}
+
+	if !freeSpaceSet {
+		log.Warn("Free space is unknown, using the base filter instead of free_space_filters")
+		return filter, nil
+	}
+
+	var tier *config.FreeSpaceFilter
+	for i, t := range filter.FreeSpaceFilters {
+		if freeGB < t.BelowGB && (tier == nil || t.BelowGB < tier.BelowGB) {
+			tier = &filter.FreeSpaceFilters[i]
+		}
+	}
+
+	if tier == nil {
+		log.Infof("Using the base filter, free space %.2f GB is above every free_space_filters tier", freeGB)
+		return filter, nil
+	}
+
+	selected, err := getFilter(tier.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Warnf("Using filter %q, free space %.2f GB is below %.2f GB", tier.Filter, freeGB, tier.BelowGB)
+	return selected, nil
 }
 
 // filterUsesFreeSpace checks if any filter conditions use FreeSpaceGB or FreeSpaceSet
@@ -207,5 +442,47 @@ func filterUsesFreeSpace(filter *config.FilterConfiguration) bool {
 		}
 	}
 
+	// Check milestone expressions
+	for _, milestone := range filter.Milestone {
+		if checkExpression(milestone.Value) {
+			return true
+		}
+	}
+
 	return false
 }
+
+// markFreeSpaceElsewhere flags the torrents saved on another filesystem than freeSpacePath, removing them does not
+// add to the tracked free space. Both paths are client paths, mapped with the download path mapping to where tqm sees
+// them. Torrents whose filesystem can't be determined are assumed to be on the tracked one. It returns the number of
+// torrents flagged
+func markFreeSpaceElsewhere(log *logrus.Entry, torrents map[string]config.Torrent, freeSpacePath string,
+	mapping *pathmapping.Mapping) int {
+	monitored, _ := mapping.Map(freeSpacePath)
+	if _, err := diskspace.Device(monitored); err != nil {
+		log.WithError(err).Warn("Failed determining the filesystem of free_space_path, counting all torrents towards free space")
+		return 0
+	}
+
+	flagged := 0
+	for h, t := range torrents {
+		if !t.FreeSpaceSet || t.Path == "" {
+			continue
+		}
+
+		path, _ := mapping.Map(t.Path)
+		same, err := diskspace.SameFilesystem(monitored, path)
+		if err != nil {
+			log.WithError(err).Debugf("Failed determining the filesystem of torrent: %q", t.Name)
+			continue
+		} else if same {
+			continue
+		}
+
+		t.FreeSpaceElsewhere = true
+		torrents[h] = t
+		flagged++
+	}
+
+	return flagged
+}
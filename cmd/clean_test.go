@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/diskspace"
+	"github.com/autobrr/tqm/pkg/hardlinkfilemap"
+	"github.com/autobrr/tqm/pkg/logger"
+	"github.com/autobrr/tqm/pkg/notification"
+	"github.com/autobrr/tqm/pkg/pathmapping"
+	"github.com/autobrr/tqm/pkg/torrentfilemap"
+)
+
+func TestMarkFreeSpaceElsewhere(t *testing.T) {
+	// /proc is a filesystem of its own on linux
+	dir := t.TempDir()
+	if same, err := diskspace.SameFilesystem(dir, "/proc"); err != nil || same {
+		t.Skip("no second filesystem available")
+	}
+
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "movies"), 0o755))
+	mapping, err := pathmapping.New(map[string]string{"/downloads": dir})
+	require.NoError(t, err)
+
+	torrents := map[string]config.Torrent{
+		"same":    {Name: "same", Path: "/downloads/movies", FreeSpaceSet: true},
+		"other":   {Name: "other", Path: "/proc", FreeSpaceSet: true},
+		"unknown": {Name: "unknown", Path: "/downloads/missing", FreeSpaceSet: true},
+		"unset":   {Name: "unset", Path: "/proc"},
+	}
+
+	assert.Equal(t, 1, markFreeSpaceElsewhere(logger.GetLogger("test"), torrents, "/downloads", mapping))
+	for h, torrent := range torrents {
+		assert.Equal(t, h == "other", torrent.FreeSpaceElsewhere, h)
+	}
+
+	// a free_space_path tqm can't see counts every torrent
+	torrents["other"] = config.Torrent{Name: "other", Path: "/proc", FreeSpaceSet: true}
+	assert.Zero(t, markFreeSpaceElsewhere(logger.GetLogger("test"), torrents, "/missing", nil))
+}
+
+func TestRemoveEligibleTorrents_FreeSpaceElsewhere(t *testing.T) {
+	removalDelay = 0
+	t.Cleanup(func() { removalDelay = time.Second })
+
+	filter := &config.FilterConfiguration{Remove: []string{`Label == "remove"`}}
+	c := newMockClient(t, filter, 10, map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Label: "remove", DownloadedBytes: 2 * humanize.GiByte, Files: []string{"/data/a"}},
+		"b": {Hash: "b", Name: "b", Label: "remove", DownloadedBytes: 3 * humanize.GiByte, Files: []string{"/other/b"},
+			FreeSpaceElsewhere: true},
+	})
+
+	working, err := c.GetTorrents(context.Background())
+	require.NoError(t, err)
+
+	err = removeEligibleTorrents(context.Background(), logger.GetLogger("test"), c, working, torrentfilemap.New(working),
+		hardlinkfilemap.NewNoopHardlinkFileMap(), filter, &recordingSender{}, "test", time.Now(), nil)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"a", "b"}, c.Removed)
+	assert.InDelta(t, 12, c.GetFreeSpace(), 0.001)
+}
+
+func TestSelectFreeSpaceFilter(t *testing.T) {
+	config.Config.Filters = map[string]config.FilterConfiguration{
+		"aggressive": {Remove: []string{`Ratio > 1`}},
+		"emergency":  {Remove: []string{`Ratio > 0.5`}},
+	}
+	t.Cleanup(func() { config.Config.Filters = nil })
+
+	base := &config.FilterConfiguration{
+		Remove: []string{`Ratio > 2`},
+		FreeSpaceFilters: []config.FreeSpaceFilter{
+			{BelowGB: 100, Filter: "aggressive"},
+			{BelowGB: 20, Filter: "emergency"},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		freeGB       float64
+		freeSpaceSet bool
+		wantRemove   []string
+	}{
+		{name: "above_every_tier", freeGB: 500, freeSpaceSet: true, wantRemove: []string{`Ratio > 2`}},
+		{name: "below_one_tier", freeGB: 50, freeSpaceSet: true, wantRemove: []string{`Ratio > 1`}},
+		{name: "lowest_tier_wins", freeGB: 10, freeSpaceSet: true, wantRemove: []string{`Ratio > 0.5`}},
+		{name: "at_threshold_is_not_below", freeGB: 100, freeSpaceSet: true, wantRemove: []string{`Ratio > 2`}},
+		{name: "unknown_free_space", freeGB: 0, freeSpaceSet: false, wantRemove: []string{`Ratio > 2`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, err := selectFreeSpaceFilter(logger.GetLogger("test"), base, tt.freeGB, tt.freeSpaceSet)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRemove, selected.Remove)
+		})
+	}
+
+	// a tier naming a missing filter fails
+	base.FreeSpaceFilters = append(base.FreeSpaceFilters, config.FreeSpaceFilter{BelowGB: 5, Filter: "missing"})
+	_, err := selectFreeSpaceFilter(logger.GetLogger("test"), base, 1, true)
+	require.Error(t, err)
+}
+
+func TestCleanClients_ContinuesAfterFailure(t *testing.T) {
+	orig := config.Config.Clients
+	config.Config.Clients = map[string]map[string]any{"disabled": {"enabled": false}}
+	t.Cleanup(func() { config.Config.Clients = orig })
+
+	// the clients that can't be cleaned fail without exiting, the ones after them are still cleaned
+	var cleaned []string
+	clean := func(ctx context.Context, log *logrus.Entry, noti notification.Sender, clientName string, summary *notification.Summary) error {
+		cleaned = append(cleaned, clientName)
+		if clientName == "qbt" {
+			summary.Add(clientName, 1, 1024)
+			return nil
+		}
+		return cleanClient(ctx, log, noti, clientName, summary)
+	}
+
+	summary := notification.NewSummary()
+	cleanClients(context.Background(), logger.GetLogger("test"), &recordingSender{},
+		[]string{"missing", "disabled", "qbt"}, summary, clean)
+
+	assert.Equal(t, []string{"missing", "disabled", "qbt"}, cleaned)
+	assert.Equal(t, 2, summary.Failed())
+
+	clients := summary.Clients()
+	require.Len(t, clients, 3)
+	assert.ErrorContains(t, clients[0].Err, "no client configuration found")
+	assert.ErrorContains(t, clients[1].Err, "client is not enabled")
+	assert.NoError(t, clients[2].Err)
+	assert.Equal(t, 1, clients[2].Count)
+}
@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/expression"
+	"github.com/autobrr/tqm/pkg/logger"
+	"github.com/autobrr/tqm/pkg/schedule"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run the commands of the schedule config on their cron schedules",
+	Long: `This command keeps running and runs each job of the schedule config when its cron expression is due, as an
+alternative to cron. Jobs run one at a time, each as a separate tqm process with the same config, so they log, notify
+and honor --dry-run like a command run by hand. An interrupt or terminate signal stops scheduling new jobs and waits
+for the running job to finish. A hangup signal reloads the config once the running job finished, keeping the previous
+config when the new one is invalid.`,
+
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		// init core
+		if !initialized {
+			initCore(true)
+			initialized = true
+		}
+
+		// set log
+		log := logger.GetLogger("daemon")
+
+		if len(config.Config.Schedule) == 0 {
+			log.Fatal("No jobs configured under schedule")
+		}
+
+		// fail now rather than with every destructive job
+		if err := checkConfirmationToken(); err != nil {
+			log.WithError(err).Fatal("Failed confirming destructive command")
+		}
+
+		executable, err := os.Executable()
+		if err != nil {
+			log.WithError(err).Fatal("Failed locating the tqm executable")
+		}
+
+		jobs, err := scheduledJobs()
+		if err != nil {
+			log.WithError(err).Fatal("Failed loading schedule")
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		defer signal.Stop(hup)
+
+		runDaemon(ctx, log, hup, jobs, func(job config.ScheduleJob) error {
+			return runScheduleJob(executable, job)
+		}, reloadSchedule)
+
+		log.Info("Stopped")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+}
+
+// scheduledJob is a job of the schedule config with its parsed cron expression
+type scheduledJob struct {
+	job      config.ScheduleJob
+	schedule *schedule.Schedule
+}
+
+// scheduledJobs parses the cron expression of every job of the schedule config, validating the filters of their clients
+func scheduledJobs() ([]*scheduledJob, error) {
+	if len(config.Config.Schedule) == 0 {
+		return nil, errors.New("no jobs configured under schedule")
+	}
+
+	jobs := make([]*scheduledJob, 0, len(config.Config.Schedule))
+	for _, job := range config.Config.Schedule {
+		s, err := schedule.Parse(job.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("job %q: %w", scheduleJobName(job), err)
+		}
+
+		if err := validateScheduleFilters(job); err != nil {
+			return nil, fmt.Errorf("job %q: %w", scheduleJobName(job), err)
+		}
+
+		jobs = append(jobs, &scheduledJob{job: job, schedule: s})
+	}
+
+	return jobs, nil
+}
+
+// validateScheduleFilters compiles the filters of the clients of a job, so a broken filter is reported by the daemon
+// rather than by every run of the job
+func validateScheduleFilters(job config.ScheduleJob) error {
+	for _, clientName := range job.Clients {
+		clientConfig, ok := config.Config.Clients[clientName]
+		if !ok {
+			return fmt.Errorf("no client configuration found for: %q", clientName)
+		}
+
+		clientFilter, err := getClientFilter(clientName, clientConfig)
+		if err != nil {
+			return fmt.Errorf("client %q: %w", clientName, err)
+		}
+
+		if _, err := expression.Compile(clientFilter); err != nil {
+			return fmt.Errorf("client %q: compile filters: %w", clientName, err)
+		}
+	}
+
+	return nil
+}
+
+// reloadSchedule reloads the config and returns its jobs, the previous config is kept when either fails
+func reloadSchedule() ([]*scheduledJob, error) {
+	previousK, previousConfig := config.K, config.Config
+	if err := config.Reload(); err != nil {
+		return nil, err
+	}
+
+	jobs, err := scheduledJobs()
+	if err != nil {
+		config.K, config.Config = previousK, previousConfig
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// scheduleJobName returns the name of a job, its command and clients when it has none
+func scheduleJobName(job config.ScheduleJob) string {
+	if job.Name != "" {
+		return job.Name
+	}
+
+	return strings.Join(append([]string{job.Command}, job.Clients...), " ")
+}
+
+// scheduleJobArgs returns the arguments of the tqm process running a job, the global flags of the daemon are passed on
+func scheduleJobArgs(job config.ScheduleJob) []string {
+	args := append([]string{job.Command}, job.Clients...)
+	args = append(args, job.Args...)
+
+	args = append(args, "--config", flagConfigFile, "--log", flagLogFile)
+	if flagProfile != "" {
+		args = append(args, "--profile", flagProfile)
+	}
+	if flagLogLevel > 0 {
+		args = append(args, "-"+strings.Repeat("v", flagLogLevel))
+	}
+	if flagDryRun || job.DryRun {
+		args = append(args, "--dry-run")
+	}
+
+	return args
+}
+
+// scheduleJobEnv returns the environment of the tqm process running a job, the confirmation token is passed in it
+// rather than in the arguments, which every local user can read
+func scheduleJobEnv() []string {
+	env := os.Environ()
+	if token := confirmationToken(); token != "" {
+		env = append(env, confirmTokenEnv+"="+token)
+	}
+
+	return env
+}
+
+// runScheduleJob runs a job as a tqm process, its output goes to the output of the daemon
+func runScheduleJob(executable string, job config.ScheduleJob) error {
+	c := exec.Command(executable, scheduleJobArgs(job)...)
+	c.Env = scheduleJobEnv()
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	detachScheduleJob(c)
+
+	if err := c.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return fmt.Errorf("exit code %d", exitErr.ExitCode())
+		}
+		return err
+	}
+
+	return nil
+}
+
+// runScheduler runs each job with run when it is due until ctx is done, then waits for the running job. Jobs run one
+// at a time, a job due while another runs starts once it finished
+func runScheduler(ctx context.Context, log *logrus.Entry, jobs []*scheduledJob, run func(job config.ScheduleJob) error) {
+	var (
+		wg      sync.WaitGroup
+		running sync.Mutex
+	)
+
+	for _, j := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			name := scheduleJobName(j.job)
+			for {
+				next, err := j.schedule.Next(now())
+				if err != nil {
+					log.WithError(err).Errorf("Not scheduling job: %q", name)
+					return
+				}
+				log.Infof("Next run of %q: %s", name, next.Format(time.RFC3339))
+
+				timer := time.NewTimer(time.Until(next))
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+
+				running.Lock()
+				if ctx.Err() != nil {
+					running.Unlock()
+					return
+				}
+
+				log.Info("-----")
+				log.Infof("Running job: %q", name)
+				start := time.Now()
+				if err := run(j.job); err != nil {
+					log.WithError(err).Errorf("Job failed: %q", name)
+				} else {
+					log.Infof("Finished job %q in %s", name, time.Since(start).Round(time.Second))
+				}
+				running.Unlock()
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	log.Info("Waiting for the running job to finish...")
+	wg.Wait()
+}
+
+// runDaemon runs the scheduler until ctx is done. A signal on hup stops the scheduler once the running job finished
+// and restarts it with the jobs returned by reload, the current jobs keep running when reload fails
+func runDaemon(ctx context.Context, log *logrus.Entry, hup <-chan os.Signal, jobs []*scheduledJob,
+	run func(job config.ScheduleJob) error, reload func() ([]*scheduledJob, error)) {
+	for {
+		schedulerCtx, cancel := context.WithCancel(ctx)
+		reloading := false
+
+		stopped := make(chan struct{})
+		go func() {
+			defer close(stopped)
+			runScheduler(schedulerCtx, log, jobs, run)
+		}()
+
+		select {
+		case <-ctx.Done():
+		case <-hup:
+			log.Info("Received hangup signal, reloading config...")
+			reloading = true
+		}
+
+		cancel()
+		<-stopped
+
+		if !reloading || ctx.Err() != nil {
+			return
+		}
+
+		reloaded, err := reload()
+		if err != nil {
+			log.WithError(err).Error("Failed reloading config, keeping the previous config")
+			continue
+		}
+
+		jobs = reloaded
+		log.Infof("Reloaded config (%d jobs)", len(jobs))
+	}
+}
@@ -0,0 +1,7 @@
+//go:build !unix
+
+package cmd
+
+import "os/exec"
+
+func detachScheduleJob(_ *exec.Cmd) {}
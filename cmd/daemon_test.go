@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/logger"
+	"github.com/autobrr/tqm/pkg/schedule"
+)
+
+func TestScheduleJobArgs(t *testing.T) {
+	flagConfigFile, flagLogFile, flagLogLevel = "/config/config.yaml", "/config/activity.log", 2
+	t.Cleanup(func() {
+		flagConfigFile, flagLogFile, flagLogLevel = "config.yaml", "activity.log", 0
+	})
+
+	job := config.ScheduleJob{Command: "clean", Clients: []string{"qbt"}, Args: []string{"--filter", "nightly"}, DryRun: true}
+	assert.Equal(t, []string{"clean", "qbt", "--filter", "nightly", "--config", "/config/config.yaml", "--log",
+		"/config/activity.log", "-vv", "--dry-run"}, scheduleJobArgs(job))
+
+	// the confirmation token is passed in the environment, not the arguments
+	flagConfirmToken = "secret"
+	t.Cleanup(func() { flagConfirmToken = "" })
+	job.DryRun = false
+	assert.Equal(t, []string{"clean", "qbt", "--filter", "nightly", "--config", "/config/config.yaml", "--log",
+		"/config/activity.log", "-vv"}, scheduleJobArgs(job))
+	assert.Contains(t, scheduleJobEnv(), "TQM_CONFIRM_TOKEN=secret")
+
+	assert.Equal(t, "clean qbt", scheduleJobName(job))
+	job.Name = "nightly clean"
+	assert.Equal(t, "nightly clean", scheduleJobName(job))
+}
+
+func TestRunScheduler(t *testing.T) {
+	// every job is due as soon as it is scheduled
+	now = func() time.Time { return time.Now().Add(-time.Minute) }
+	t.Cleanup(func() { now = time.Now })
+
+	s, err := schedule.Parse("* * * * *")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var ran []string
+	jobs := []*scheduledJob{{job: config.ScheduleJob{Command: "clean"}, schedule: s}}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runScheduler(ctx, logger.GetLogger("test"), jobs, func(job config.ScheduleJob) error {
+			ran = append(ran, job.Command)
+			// a shutdown while a job runs waits for it
+			cancel()
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("scheduler did not stop")
+	}
+
+	assert.Equal(t, []string{"clean"}, ran)
+}
+
+func TestRunDaemon_Reload(t *testing.T) {
+	s, err := schedule.Parse("0 0 1 1 *")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hup := make(chan os.Signal, 1)
+	jobs := []*scheduledJob{{job: config.ScheduleJob{Command: "clean"}, schedule: s}}
+
+	reloads := make(chan error, 2)
+	attempts := 0
+	reload := func() ([]*scheduledJob, error) {
+		attempts++
+		if attempts == 1 {
+			reloads <- errors.New("invalid config")
+			return nil, errors.New("invalid config")
+		}
+		reloads <- nil
+		return jobs, nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runDaemon(ctx, logger.GetLogger("test"), hup, jobs, func(config.ScheduleJob) error { return nil }, reload)
+	}()
+
+	// a failed reload keeps the daemon running with the previous jobs
+	for _, wantErr := range []bool{true, false} {
+		hup <- syscall.SIGHUP
+		select {
+		case err := <-reloads:
+			assert.Equal(t, wantErr, err != nil)
+		case <-time.After(5 * time.Second):
+			t.Fatal("config was not reloaded")
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("daemon did not stop")
+	}
+}
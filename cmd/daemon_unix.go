@@ -0,0 +1,14 @@
+//go:build unix
+
+package cmd
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachScheduleJob starts the job in its own process group, so the signals sent to the group of the daemon, e.g. by
+// Ctrl-C in a terminal, only reach the daemon, which lets the running job finish
+func detachScheduleJob(c *exec.Cmd) {
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
@@ -0,0 +1,30 @@
+//go:build unix
+
+package cmd
+
+import (
+	"os/exec"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetachScheduleJob(t *testing.T) {
+	c := exec.Command("sleep", "10")
+	detachScheduleJob(c)
+	if err := c.Start(); err != nil {
+		t.Skipf("sleep not available: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = c.Process.Kill()
+		_ = c.Wait()
+	})
+
+	// the job leads its own process group, signals to the group of the daemon don't reach it
+	pgid, err := syscall.Getpgid(c.Process.Pid)
+	require.NoError(t, err)
+	assert.Equal(t, c.Process.Pid, pgid)
+	assert.NotEqual(t, syscall.Getpgrp(), pgid)
+}
@@ -0,0 +1,377 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+
+	"github.com/autobrr/tqm/pkg/client"
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/expression"
+	"github.com/autobrr/tqm/pkg/hardlinkfilemap"
+	"github.com/autobrr/tqm/pkg/logger"
+	"github.com/autobrr/tqm/pkg/tracker"
+)
+
+const (
+	explainFormatText = "text"
+	explainFormatJSON = "json"
+)
+
+var flagExplainFormat string
+
+var explainCmd = &cobra.Command{
+	Use:   "explain [CLIENT]",
+	Short: "Explain how the filters treat each torrent",
+	Long: `This command evaluates every ignore, remove, pause, label and tag expression of a client's filter against each
+torrent and prints the result of each expression with the resulting decision. Nothing is changed on the client.`,
+
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		// init core
+		if !initialized {
+			initCore(true)
+			initialized = true
+		}
+
+		// set log
+		log := logger.GetLogger("explain")
+
+		if flagExplainFormat != explainFormatText && flagExplainFormat != explainFormatJSON {
+			log.Fatalf("Unsupported format: %q (text or json)", flagExplainFormat)
+		}
+
+		// retrieve client object
+		clientName := args[0]
+		clientConfig, ok := config.Config.Clients[clientName]
+		if !ok {
+			log.Fatalf("No client configuration found for: %q", clientName)
+		}
+
+		// validate client is enabled
+		if err := validateClientEnabled(clientConfig); err != nil {
+			log.WithError(err).Fatal("Failed validating client is enabled")
+		}
+
+		// retrieve client type
+		clientType, err := getClientConfigString("type", clientConfig)
+		if err != nil {
+			log.WithError(err).Fatal("Failed determining client type")
+		}
+
+		// retrieve client free space path (needed for Deluge free space check)
+		clientFreeSpacePath, _ := getClientConfigString("free_space_path", clientConfig)
+
+		// retrieve client filters
+		clientFilter, err := getClientFilter(clientName, clientConfig)
+		if err != nil {
+			log.WithError(err).Fatal("Failed retrieving client filter")
+		}
+
+		if flagFilterName != "" {
+			clientFilter, err = getFilter(flagFilterName)
+			if err != nil {
+				log.WithError(err).Fatal("Failed retrieving specified filter")
+			}
+		}
+
+		// compile client filters
+		exp, err := expression.Compile(clientFilter)
+		if err != nil {
+			log.WithError(err).Fatal("Failed compiling client filters")
+		}
+
+		// load client object
+		c, err := client.NewClient(*clientType, clientName, exp)
+		if err != nil {
+			log.WithError(err).Fatalf("Failed initializing client: %q", clientName)
+		}
+
+		log.Infof("Initialized client %q, type: %s (%d trackers)", clientName, c.Type(), tracker.Loaded())
+
+		// connect to client
+		if err := c.Connect(ctx); err != nil {
+			log.WithError(err).Fatal("Failed connecting")
+		} else {
+			log.Debugf("Connected to client")
+		}
+
+		// get free disk space (can/will be used by filters)
+		switch *clientType {
+		case "qbittorrent":
+			if _, err := c.GetCurrentFreeSpace(ctx, ""); err != nil {
+				log.WithError(err).Error("Failed retrieving free-space")
+			}
+
+		case "deluge":
+			if clientFreeSpacePath != nil {
+				if _, err := c.GetCurrentFreeSpace(ctx, *clientFreeSpacePath); err != nil {
+					log.WithError(err).Errorf("Failed retrieving free-space for: %q", *clientFreeSpacePath)
+					os.Exit(1)
+				}
+			} else if filterUsesFreeSpace(clientFilter) {
+				log.Error("Deluge requires free_space_path to be configured in order to retrieve free space information")
+				os.Exit(1)
+			}
+		}
+
+		// retrieve torrents
+		torrents, err := c.GetTorrents(ctx)
+		if err != nil {
+			log.WithError(err).Fatal("Failed retrieving torrents")
+		} else {
+			log.Infof("Retrieved %d torrents", len(torrents))
+		}
+
+		// evaluate time based fields as of the requested time
+		if offset, err := applyAsOf(torrents); err != nil {
+			log.WithError(err).Fatal("Failed applying --as-of time")
+		} else if offset != 0 {
+			log.Warnf("Evaluating filters as of %s (%s from now)", now().Add(offset).Format(time.RFC3339), offset.Round(time.Second))
+		}
+
+		// map hardlinks when the filter does, so HardlinkedOutsideClient is evaluated as in the other commands
+		if len(clientFilter.MapHardlinksFor) > 0 {
+			clientDownloadPathMapping, err := getClientDownloadPathMapping(clientConfig)
+			if err != nil {
+				log.WithError(err).Fatal("Failed loading client download path mappings")
+			}
+
+			hfm := hardlinkfilemap.New(torrents, clientDownloadPathMapping, clientFilter.ResolveSymlinks, clientFilter.HardlinkFailClosed)
+			for h, t := range torrents {
+				t.HardlinkedOutsideClient = hfm.HardlinkedOutsideClient(t)
+				torrents[h] = t
+			}
+		}
+
+		// apply tracker, label/tag and hash pre-filters
+		applyPreFilters(log, torrents)
+
+		explanations, err := explainTorrents(ctx, c, exp, torrents)
+		if err != nil {
+			log.WithError(err).Fatal("Failed explaining torrents")
+		}
+
+		if err := writeExplanations(os.Stdout, explanations, flagExplainFormat); err != nil {
+			log.WithError(err).Fatal("Failed writing explanations")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+
+	explainCmd.Flags().StringVar(&flagFilterName, "filter", "", "Filter to use instead of client")
+	explainCmd.Flags().StringVar(&flagExplainFormat, "format", explainFormatText, "Output format (text or json)")
+	addPreFilterFlags(explainCmd)
+}
+
+type ruleExplanation struct {
+	Name        string                  `json:"name"`
+	Mode        string                  `json:"mode,omitempty"`
+	Matched     bool                    `json:"matched"`
+	Evaluations []expression.Evaluation `json:"evaluations"`
+}
+
+// torrentExplanation holds the result of every filter expression for a torrent and the resulting decisions
+type torrentExplanation struct {
+	Name    string                  `json:"name"`
+	Hash    string                  `json:"hash"`
+	Tracker string                  `json:"tracker"`
+	Label   string                  `json:"label"`
+	Size    int64                   `json:"size"`
+	Ignore  []expression.Evaluation `json:"ignore"`
+	Remove  []expression.Evaluation `json:"remove"`
+	Pause   []expression.Evaluation `json:"pause"`
+	Labels  []ruleExplanation       `json:"labels"`
+	Tags    []ruleExplanation       `json:"tags"`
+
+	Ignored    bool     `json:"ignored"`
+	Removed    bool     `json:"removed"`
+	Paused     bool     `json:"paused"`
+	Relabel    string   `json:"relabel,omitempty"`
+	AddTags    []string `json:"add_tags,omitempty"`
+	RemoveTags []string `json:"remove_tags,omitempty"`
+	Decision   string   `json:"decision"`
+}
+
+// decision summarizes what the filters decide for the torrent
+func (e *torrentExplanation) decision() string {
+	var actions []string
+	if e.Ignored {
+		actions = append(actions, "ignored")
+	}
+	if e.Removed {
+		actions = append(actions, "remove")
+	}
+	if e.Paused {
+		actions = append(actions, "pause")
+	}
+	if e.Relabel != "" {
+		actions = append(actions, "relabel to "+e.Relabel)
+	}
+	if len(e.AddTags) > 0 {
+		actions = append(actions, "add tags "+strings.Join(e.AddTags, ", "))
+	}
+	if len(e.RemoveTags) > 0 {
+		actions = append(actions, "remove tags "+strings.Join(e.RemoveTags, ", "))
+	}
+
+	if len(actions) == 0 {
+		return "keep"
+	}
+
+	return strings.Join(actions, ", ")
+}
+
+// anyMatched reports whether an expression matched, or failed when failed counts as a match
+func anyMatched(evaluations []expression.Evaluation, failed bool) bool {
+	return slices.ContainsFunc(evaluations, func(e expression.Evaluation) bool {
+		return e.Matched || (failed && e.Error != "")
+	})
+}
+
+// anyFailed reports whether an expression failed
+func anyFailed(evaluations []expression.Evaluation) bool {
+	return slices.ContainsFunc(evaluations, func(e expression.Evaluation) bool {
+		return e.Error != ""
+	})
+}
+
+// allMatched reports whether every expression matched
+func allMatched(evaluations []expression.Evaluation) bool {
+	return !slices.ContainsFunc(evaluations, func(e expression.Evaluation) bool {
+		return !e.Matched
+	})
+}
+
+// explainTorrent evaluates every expression against the torrent, the decisions follow the commands: ignores only
+// apply to removing and pausing, an ignore expression that fails skips the torrent and other failing expressions
+// don't match
+func explainTorrent(ctx context.Context, c client.Interface, exp *expression.Expressions, t *config.Torrent) (torrentExplanation, error) {
+	e := torrentExplanation{
+		Name:    t.Name,
+		Hash:    t.Hash,
+		Tracker: t.TrackerName,
+		Label:   t.Label,
+		Size:    t.TotalBytes,
+		Ignore:  expression.EvaluateEach(ctx, t, exp.Ignores),
+		Remove:  expression.EvaluateEach(ctx, t, exp.Removes),
+		Pause:   expression.EvaluateEach(ctx, t, exp.Pauses),
+		Labels:  []ruleExplanation{},
+		Tags:    []ruleExplanation{},
+	}
+
+	for _, label := range exp.Labels {
+		evaluations := expression.EvaluateEach(ctx, t, label.Updates)
+		matched := allMatched(evaluations)
+		e.Labels = append(e.Labels, ruleExplanation{Name: label.Name, Matched: matched, Evaluations: evaluations})
+
+		// the first matching label is applied
+		if matched && e.Relabel == "" && label.Name != t.Label {
+			e.Relabel = label.Name
+		}
+	}
+
+	for _, tag := range exp.Tags {
+		evaluations := expression.EvaluateEach(ctx, t, tag.Updates)
+		e.Tags = append(e.Tags, ruleExplanation{Name: tag.Name, Mode: tag.Mode, Matched: allMatched(evaluations), Evaluations: evaluations})
+	}
+
+	e.Ignored = anyMatched(e.Ignore, true)
+	bypassIgnore := e.Ignored && !anyFailed(e.Ignore) && t.BypassesIgnore(ctx)
+	e.Removed = (!e.Ignored || bypassIgnore) && anyMatched(e.Remove, false)
+	e.Paused = !e.Ignored && anyMatched(e.Pause, false)
+
+	// tag changes are decided by the client, respecting rule priority and modes
+	if tc, ok := c.(client.TagInterface); ok && len(exp.Tags)+len(exp.Milestones) > 0 {
+		info, err := tc.ShouldRetag(ctx, t)
+		if err != nil {
+			return torrentExplanation{}, fmt.Errorf("retag: %w", err)
+		}
+
+		e.AddTags = slices.Sorted(maps.Keys(info.Add))
+		e.RemoveTags = slices.Sorted(maps.Keys(info.Remove))
+	}
+
+	e.Decision = e.decision()
+	return e, nil
+}
+
+// explainTorrents explains every torrent, sorted by name
+func explainTorrents(ctx context.Context, c client.Interface, exp *expression.Expressions, torrents map[string]config.Torrent) ([]torrentExplanation, error) {
+	explanations := make([]torrentExplanation, 0, len(torrents))
+	for _, t := range torrents {
+		e, err := explainTorrent(ctx, c, exp, &t)
+		if err != nil {
+			return nil, fmt.Errorf("explain %q: %w", t.Name, err)
+		}
+
+		explanations = append(explanations, e)
+	}
+
+	slices.SortFunc(explanations, func(a, b torrentExplanation) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return explanations, nil
+}
+
+func writeExplanations(w io.Writer, explanations []torrentExplanation, format string) error {
+	if format == explainFormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(explanations); err != nil {
+			return fmt.Errorf("encode explanations: %w", err)
+		}
+		return nil
+	}
+
+	for _, e := range explanations {
+		fmt.Fprintf(w, "%s (%s)\n", e.Name, e.Hash)
+		fmt.Fprintf(w, "  tracker: %s, label: %s, size: %s\n", e.Tracker, e.Label, humanize.IBytes(uint64(e.Size)))
+
+		writeEvaluations(w, "ignore", e.Ignore)
+		writeEvaluations(w, "remove", e.Remove)
+		writeEvaluations(w, "pause", e.Pause)
+		for _, l := range e.Labels {
+			writeEvaluations(w, "label "+l.Name, l.Evaluations)
+		}
+		for _, t := range e.Tags {
+			writeEvaluations(w, fmt.Sprintf("tag %s (%s)", t.Name, t.Mode), t.Evaluations)
+		}
+
+		fmt.Fprintf(w, "  decision: %s\n\n", e.Decision)
+	}
+
+	return nil
+}
+
+func writeEvaluations(w io.Writer, section string, evaluations []expression.Evaluation) {
+	if len(evaluations) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "  %s:\n", section)
+	for _, e := range evaluations {
+		switch {
+		case e.Error != "":
+			fmt.Fprintf(w, "    [!] %s (error: %s)\n", e.Expression, e.Error)
+		case e.Matched:
+			fmt.Fprintf(w, "    [x] %s\n", e.Expression)
+		default:
+			fmt.Fprintf(w, "    [ ] %s\n", e.Expression)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/expression"
+)
+
+func explainFilter() *config.FilterConfiguration {
+	filter := &config.FilterConfiguration{
+		Ignore: []string{`HasAnyTag("keep")`, `Label == "broken" && FreeSpaceAt("/nonexistent/tqm") > 0`},
+		Remove: []string{`Ratio > 2`, `SeedingDays > 30`},
+		Pause:  []string{`Ratio > 1`},
+	}
+	filter.Label = append(filter.Label, struct {
+		Name     string
+		Priority int
+		Update   []string
+	}{Name: "archive", Update: []string{`SeedingDays > 10`, `Label == "tv"`}})
+	filter.Tag = append(filter.Tag, struct {
+		Name     string
+		Mode     string
+		UploadKb *int `mapstructure:"uploadKb"`
+		Priority int
+		Update   []string
+	}{Name: "high-ratio", Mode: "full", Update: []string{`Ratio > 2`}})
+
+	return filter
+}
+
+func TestExplainTorrents(t *testing.T) {
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Label: "tv", Ratio: 3, SeedingDays: 20},
+		"b": {Hash: "b", Name: "b", Label: "tv", Ratio: 3, SeedingDays: 20, Tags: []string{"keep"}},
+		"c": {Hash: "c", Name: "c", Label: "movies", Ratio: 0.5, SeedingDays: 1, Tags: []string{"high-ratio"}},
+		"d": {Hash: "d", Name: "d", Label: "broken", Ratio: 3},
+	}
+
+	filter := explainFilter()
+	c := newMockClient(t, filter, 100, torrents)
+	exp, err := expression.Compile(filter)
+	require.NoError(t, err)
+
+	explanations, err := explainTorrents(context.Background(), c, exp, torrents)
+	require.NoError(t, err)
+	require.Len(t, explanations, 4)
+
+	byHash := make(map[string]torrentExplanation)
+	for _, e := range explanations {
+		byHash[e.Hash] = e
+	}
+
+	a := byHash["a"]
+	assert.Equal(t, []expression.Evaluation{
+		{Expression: `Ratio > 2`, Matched: true},
+		{Expression: `SeedingDays > 30`, Matched: false},
+	}, a.Remove, "every expression is evaluated")
+	assert.True(t, a.Removed)
+	assert.True(t, a.Paused)
+	assert.Equal(t, "archive", a.Relabel)
+	assert.Equal(t, []string{"high-ratio"}, a.AddTags)
+	assert.Equal(t, "remove, pause, relabel to archive, add tags high-ratio", a.Decision)
+
+	b := byHash["b"]
+	assert.True(t, b.Ignored)
+	assert.False(t, b.Removed)
+	assert.False(t, b.Paused)
+	assert.Equal(t, "ignored, relabel to archive, add tags high-ratio", b.Decision, "ignores only apply to removing and pausing")
+
+	c2 := byHash["c"]
+	assert.Equal(t, []string{"high-ratio"}, c2.RemoveTags)
+	assert.Equal(t, "remove tags high-ratio", c2.Decision)
+	require.Len(t, c2.Labels, 1)
+	assert.False(t, c2.Labels[0].Matched)
+
+	d := byHash["d"]
+	assert.NotEmpty(t, d.Ignore[1].Error)
+	assert.True(t, d.Ignored, "a failing ignore expression skips the torrent")
+	assert.False(t, d.Removed)
+}
+
+func TestWriteExplanations(t *testing.T) {
+	explanations := []torrentExplanation{{
+		Name:     "a",
+		Hash:     "abc",
+		Ignore:   []expression.Evaluation{{Expression: `HasAnyTag("keep")`}},
+		Remove:   []expression.Evaluation{{Expression: `Ratio > 2`, Matched: true}, {Expression: `FreeSpaceAt("/x") > 1`, Error: "no such file"}},
+		Labels:   []ruleExplanation{},
+		Tags:     []ruleExplanation{{Name: "t", Mode: "add", Evaluations: []expression.Evaluation{{Expression: `Ratio > 5`}}}},
+		Removed:  true,
+		Decision: "remove",
+	}}
+
+	t.Run("text", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, writeExplanations(&out, explanations, explainFormatText))
+		assert.Contains(t, out.String(), "a (abc)")
+		assert.Contains(t, out.String(), `[ ] HasAnyTag("keep")`)
+		assert.Contains(t, out.String(), `[x] Ratio > 2`)
+		assert.Contains(t, out.String(), `[!] FreeSpaceAt("/x") > 1 (error: no such file)`)
+		assert.Contains(t, out.String(), "tag t (add):")
+		assert.Contains(t, out.String(), "decision: remove")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, writeExplanations(&out, explanations, explainFormatJSON))
+
+		var decoded []torrentExplanation
+		require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+		assert.Equal(t, explanations, decoded)
+	})
+}
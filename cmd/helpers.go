@@ -4,6 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -15,10 +19,29 @@ import (
 	"github.com/autobrr/tqm/pkg/config"
 	"github.com/autobrr/tqm/pkg/hardlinkfilemap"
 	"github.com/autobrr/tqm/pkg/notification"
+	"github.com/autobrr/tqm/pkg/pathmapping"
 	"github.com/autobrr/tqm/pkg/torrentfilemap"
 )
 
+var (
+	// removalDelay is the pause after each successful removal, giving the client time to process it
+	removalDelay = 1 * time.Second
+	// verifyRemovalDelay is the extra wait before checking the files of a removed torrent are gone, as clients
+	// delete the data in the background
+	verifyRemovalDelay = 5 * time.Second
+	// relabelDelay is the pause after each successful relabel, giving the client time to move the files
+	relabelDelay = 5 * time.Second
+	// recheckTimeout is how long to wait for a recheck to finish before giving up
+	recheckTimeout = 1 * time.Hour
+	// removalRetries is the number of times failed removals are retried at the end of a clean run
+	removalRetries = 2
+	// removalRetryBackoff is the wait before the first retry of failed removals, doubled before each further retry
+	removalRetryBackoff = 5 * time.Second
+)
+
 func removeSlice(slice []string, remove []string) []string {
+	// work on a copy so the caller's slice (e.g. the torrent's current tags) is left untouched
+	slice = slices.Clone(slice)
 	for _, item := range remove {
 		for i, v := range slice {
 			if v == item {
@@ -29,6 +52,173 @@ func removeSlice(slice []string, remove []string) []string {
 	return slice
 }
 
+type tagEditor interface {
+	AddTags(ctx context.Context, hash string, tags []string) error
+	RemoveTags(ctx context.Context, hash string, tags []string) error
+}
+
+// applyTagsIndividually adds and then removes tags using separate calls, for clients that do not support SetTags.
+// If removing fails after tags were added, the added tags are rolled back unless retag_partial_failure is "keep".
+func applyTagsIndividually(ctx context.Context, log *logrus.Entry, c tagEditor, t *config.Torrent, addTags []string, removeTags []string) (bool, error) {
+	added := false
+	if len(addTags) > 0 {
+		if err := c.AddTags(ctx, t.Hash, addTags); err != nil {
+			return false, fmt.Errorf("add tags %v: %w", addTags, err)
+		}
+
+		log.Debugf("Added tags: %v", addTags)
+		added = true
+	}
+
+	if len(removeTags) == 0 {
+		return added, nil
+	}
+
+	removeErr := c.RemoveTags(ctx, t.Hash, removeTags)
+	if removeErr == nil {
+		log.Debugf("Removed tags: %v", removeTags)
+		return true, nil
+	}
+
+	if !added {
+		return false, fmt.Errorf("remove tags %v: %w", removeTags, removeErr)
+	}
+
+	if config.Config != nil && strings.EqualFold(config.Config.RetagPartialFailure, config.RetagPartialFailureKeep) {
+		log.Warnf("Torrent left partially retagged, added %v but could not remove %v: %q", addTags, removeTags, t.Name)
+		return true, fmt.Errorf("remove tags %v: %w", removeTags, removeErr)
+	}
+
+	// roll back the added tags so the torrent is left as it was
+	if err := c.RemoveTags(ctx, t.Hash, addTags); err != nil {
+		log.WithError(err).Errorf("Failed rolling back added tags %v, torrent left with tags added but %v not removed: %q",
+			addTags, removeTags, t.Name)
+		return true, fmt.Errorf("remove tags %v: %w", removeTags, removeErr)
+	}
+
+	log.Warnf("Rolled back added tags %v after failing to remove %v: %q", addTags, removeTags, t.Name)
+	return false, fmt.Errorf("remove tags %v: %w", removeTags, removeErr)
+}
+
+// archiveTorrentFiles hardlinks the files of a torrent into archivePath, keeping their structure relative to the torrent path
+func archiveTorrentFiles(log *logrus.Entry, t *config.Torrent, archivePath string) error {
+	names := make([]string, 0, len(t.Files))
+	for _, f := range t.Files {
+		name, err := filepath.Rel(t.Path, f)
+		if err != nil || strings.HasPrefix(name, "..") {
+			return fmt.Errorf("file %q is not within torrent path %q", f, t.Path)
+		}
+		names = append(names, name)
+	}
+
+	return client.LinkFiles(log, t.Path, archivePath, names, true)
+}
+
+// freeSpaceChange describes the free space before and after a run for a notification
+func freeSpaceChange(before float64, after float64) string {
+	return fmt.Sprintf("Free space **%.2f GB** → **%.2f GB**", before, after)
+}
+
+// deleteDataForTorrent decides whether the data of t is deleted on removal, when delete_data_if_path is set only
+// torrents saved below one of its paths have their data deleted. A tracker policy keeping the data overrides both
+func deleteDataForTorrent(filter *config.FilterConfiguration, t *config.Torrent, deleteData bool) bool {
+	if t.TrackerKeepsData() {
+		return false
+	}
+
+	if filter == nil || len(filter.DeleteDataIfPath) == 0 {
+		return deleteData
+	}
+
+	for _, path := range filter.DeleteDataIfPath {
+		if path != "" && t.PathHasPrefix(path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// torrentContext bounds the filter evaluation of a single torrent by --timeout-per-torrent, so a tracker API call that
+// hangs is abandoned and the run moves on. The returned func releases the context, warning when the time ran out
+func torrentContext(ctx context.Context) (context.Context, func(log *logrus.Entry, t *config.Torrent)) {
+	if flagTimeoutPerTorrent <= 0 {
+		return ctx, func(*logrus.Entry, *config.Torrent) {}
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, flagTimeoutPerTorrent)
+	return tctx, func(log *logrus.Entry, t *config.Torrent) {
+		if errors.Is(tctx.Err(), context.DeadlineExceeded) {
+			log.Warnf("Tracker checks of %q timed out after %s, its tracker status is treated as unknown", t.Name,
+				flagTimeoutPerTorrent)
+		}
+		cancel()
+	}
+}
+
+// relabelSettings returns the pause after each relabel, relabel_delay when set
+func relabelSettings() time.Duration {
+	if config.Config != nil && config.Config.RelabelDelay != nil {
+		return *config.Config.RelabelDelay
+	}
+
+	return relabelDelay
+}
+
+// skipMoving reports whether t is skipped because skip_moving is enabled and the client is still moving its files,
+// e.g. after a relabel with automatic torrent management
+func skipMoving(log *logrus.Entry, t *config.Torrent) bool {
+	if config.Config == nil || !config.Config.SkipMoving || !t.IsMoving() {
+		return false
+	}
+
+	log.Debugf("Skipping torrent being moved: %q", t.Name)
+	return true
+}
+
+// verifyDataRemoved waits for the client to delete the data of a removed torrent and returns the files still on disk,
+// removing them directly when removeLeftovers is set. The files are looked up through the download_path_mapping of the
+// client, the paths it reports may not exist on this host
+func verifyDataRemoved(log *logrus.Entry, t *config.Torrent, mapping *pathmapping.Mapping, removeLeftovers bool) []string {
+	time.Sleep(verifyRemovalDelay)
+
+	var leftovers []string
+	for _, f := range t.Files {
+		f, _ = mapping.Map(f)
+		if _, err := os.Lstat(f); os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			log.WithError(err).Warnf("Failed checking file was removed: %q", f)
+		}
+		leftovers = append(leftovers, f)
+	}
+
+	if len(leftovers) == 0 {
+		log.Debug("Verified data was removed")
+		return nil
+	}
+
+	log.Warnf("%d file(s) remain on disk after removal: %q", len(leftovers), t.Name)
+	if !removeLeftovers {
+		for _, f := range leftovers {
+			log.Warnf("Leftover file: %q", f)
+		}
+		return leftovers
+	}
+
+	var remaining []string
+	for _, f := range leftovers {
+		if err := os.RemoveAll(f); err != nil {
+			log.WithError(err).Errorf("Failed removing leftover file: %q", f)
+			remaining = append(remaining, f)
+			continue
+		}
+		log.Infof("Removed leftover file: %q", f)
+	}
+
+	return remaining
+}
+
 // retag torrent that meet required filters
 func retagEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.TagInterface, torrents map[string]config.Torrent, noti notification.Sender, client string, startTime time.Time) error {
 	// vars
@@ -42,8 +232,15 @@ func retagEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.TagI
 
 	// iterate torrents
 	for h, t := range torrents {
+		if skipMoving(log, &t) || skipRecentlyActed(log, &t, actionRetag) {
+			ignoredTorrents++
+			continue
+		}
+
 		// should we retag torrent and/or apply speed limit?
-		retagInfo, err := c.ShouldRetag(ctx, &t)
+		tctx, done := torrentContext(ctx)
+		retagInfo, err := c.ShouldRetag(tctx, &t)
+		done(log, &t)
 		if err != nil {
 			// error while determining whether to evaluate tag rules
 			log.WithError(err).Errorf("Failed evaluating tag rules for: %+v", t)
@@ -109,24 +306,17 @@ func retagEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.TagI
 			} else if errors.Is(err, qbittorrent.ErrUnsupportedVersion) {
 				log.Debug("Unsupported qBittorrent version, using AddTags and RemoveTags instead")
 
-				if len(addTags) > 0 {
-					if err := c.AddTags(ctx, t.Hash, addTags); err != nil {
-						log.WithError(err).Errorf("Failed adding tags %v to torrent: %+v", addTags, t)
-						actionFailed = true
-					} else {
-						log.Debugf("Added tags: %v", addTags)
-						actionTaken = true
-					}
-				}
-				if len(removeTags) > 0 && !actionFailed {
-					if err := c.RemoveTags(ctx, t.Hash, removeTags); err != nil {
-						log.WithError(err).Errorf("Failed removing tags %v from torrent: %+v", removeTags, t)
-						actionFailed = true
-					} else {
-						log.Debugf("Removed tags: %v", removeTags)
-						actionTaken = true
+				taken, err := applyTagsIndividually(ctx, log, c, &t, addTags, removeTags)
+				if err != nil {
+					log.WithError(err).Errorf("Failed applying tags to torrent: %+v", t)
+					actionFailed = true
+
+					if taken {
+						// tags were added but not removed, report the state the torrent was left in
+						finalTags = append(slices.Clone(t.Tags), addTags...)
 					}
 				}
+				actionTaken = taken
 			} else {
 				log.WithError(err).Errorf("Failed setting tags %v for torrent: %+v", finalTags, t)
 				actionFailed = true
@@ -159,6 +349,7 @@ func retagEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.TagI
 
 		// don't check for shouldTakeAction again as it can't be false
 		if actionTaken || flagDryRun {
+			actedTorrents.record(t.Hash, actionRetag)
 			fields = append(fields, noti.BuildField(notification.ActionRetag, notification.BuildOptions{
 				Torrent:    t,
 				NewTags:    finalTags,
@@ -172,6 +363,7 @@ func retagEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.TagI
 	log.Info("-----")
 	log.Infof("Ignored torrents: %d", ignoredTorrents)
 	log.Infof("Retagged torrents: %d, %d failures", retaggedTorrents, errorRetaggedTorrents)
+	lastRun.record(client, "retagged", retaggedTorrents, errorRetaggedTorrents, 0)
 
 	if !noti.CanSend() {
 		log.Debug("Notifications disabled, skipping...")
@@ -207,8 +399,15 @@ func relabelEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.In
 
 	// iterate torrents
 	for h, t := range torrents {
+		if skipMoving(log, &t) || skipRecentlyActed(log, &t, actionRelabel) {
+			ignoredTorrents++
+			continue
+		}
+
 		// should we relabel torrent?
-		label, relabel, err := c.ShouldRelabel(ctx, &t)
+		tctx, done := torrentContext(ctx)
+		label, relabel, err := c.ShouldRelabel(tctx, &t)
+		done(log, &t)
 		if err != nil {
 			// error while determining whether to relabel torrent
 			log.WithError(err).Errorf("Failed determining whether to relabel: %+v", t)
@@ -252,6 +451,12 @@ func relabelEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.In
 			"Tracker Status: %q", t.Ratio, t.SeedingDays, t.Seeds, t.Label, strings.Join(t.Tags, ", "), t.TrackerName, t.TrackerStatus)
 
 		if !flagDryRun {
+			if err := checkSafeMode(); err != nil {
+				log.WithError(err).Errorf("Failed relabeling torrent: %+v", t)
+				errorRelabelTorrents++
+				continue
+			}
+
 			if err := c.SetTorrentLabel(ctx, t.Hash, label, hardlink); err != nil {
 				log.WithError(err).Fatalf("Failed relabeling torrent: %+v", t)
 				errorRelabelTorrents++
@@ -259,11 +464,12 @@ func relabelEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.In
 			}
 
 			log.Info("Relabeled")
-			time.Sleep(5 * time.Second)
+			time.Sleep(relabelSettings())
 		} else {
 			log.Warn("Dry-run enabled, skipping relabel...")
 		}
 
+		actedTorrents.record(t.Hash, actionRelabel)
 		fields = append(fields, noti.BuildField(notification.ActionRelabel, notification.BuildOptions{
 			Torrent:  t,
 			NewLabel: label,
@@ -278,6 +484,7 @@ func relabelEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.In
 		log.Infof("Non-unique torrents: %d", nonUniqueTorrents)
 	}
 	log.Infof("Relabeled torrents: %d, %d failures", relabeledTorrents, errorRelabelTorrents)
+	lastRun.record(client, "relabeled", relabeledTorrents, errorRelabelTorrents, 0)
 
 	if !noti.CanSend() {
 		log.Debug("Notifications disabled, skipping...")
@@ -299,8 +506,290 @@ func relabelEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.In
 	return nil
 }
 
+// pause torrents that meet pause filters
+func pauseEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.Interface, torrents map[string]config.Torrent, noti notification.Sender, client string, startTime time.Time) error {
+	var (
+		pauseList []string
+		fields    []notification.Field
+	)
+
+	// iterate through torrents
+	for _, t := range torrents {
+		if skipMoving(log, &t) || skipRecentlyActed(log, &t, actionPause) {
+			continue
+		}
+
+		// check if torrent should be ignored, then if it should be paused
+		tctx, done := torrentContext(ctx)
+		ignored, err := shouldIgnore(tctx, c, &t)
+		paused := false
+		if err == nil && !ignored {
+			paused, err = c.CheckTorrentPause(tctx, &t)
+			if err != nil {
+				err = fmt.Errorf("pause filters: %w", err)
+			}
+		}
+		done(log, &t)
+
+		if err != nil {
+			log.WithError(err).Errorf("Failed checking filters for torrent: %q", t.Name)
+			continue
+		} else if ignored {
+			log.Debugf("Ignoring torrent: %q", t.Name)
+			continue
+		}
+
+		if paused {
+			log.Infof("Adding torrent to pause list: %q", t.Name)
+			pauseList = append(pauseList, t.Hash)
+			fields = append(fields, noti.BuildField(notification.ActionPause, notification.BuildOptions{
+				Torrent: t,
+			}))
+		}
+	}
+
+	// pause torrents if not dry run
+	if !flagDryRun {
+		if len(pauseList) > 0 {
+			log.Infof("Pausing %d torrent(s)...", len(pauseList))
+			if err := c.PauseTorrents(ctx, pauseList); err != nil {
+				return fmt.Errorf("pause torrents: %w", err)
+			}
+			log.Infof("Successfully paused %d torrent(s)", len(pauseList))
+			for _, hash := range pauseList {
+				actedTorrents.record(hash, actionPause)
+			}
+		} else {
+			log.Info("No torrents to pause")
+		}
+	} else {
+		if len(pauseList) > 0 {
+			log.Infof("[DRY-RUN] Would pause %d torrent(s)", len(pauseList))
+			for _, hash := range pauseList {
+				actedTorrents.record(hash, actionPause)
+			}
+		} else {
+			log.Info("[DRY-RUN] No torrents would be paused")
+		}
+	}
+	lastRun.record(client, "paused", len(pauseList), 0, 0)
+
+	if !noti.CanSend() {
+		log.Debug("Notifications disabled, skipping...")
+		return nil
+	}
+
+	sendErr := noti.Send(
+		"Torrent Pause",
+		fmt.Sprintf("Paused **%d** torrent(s)", len(pauseList)),
+		client,
+		time.Since(startTime),
+		fields,
+		flagDryRun,
+	)
+	if sendErr != nil {
+		log.WithError(sendErr).Error("Failed sending notification")
+	}
+
+	return nil
+}
+
+// recheck and resume torrents the client reports in an error state
+func recoverErroredTorrents(ctx context.Context, log *logrus.Entry, c client.Interface, torrents map[string]config.Torrent, noti notification.Sender, client string, startTime time.Time) error {
+	var (
+		errored   int
+		recovered int
+		failed    int
+		fields    []notification.Field
+	)
+
+	for _, t := range torrents {
+		if !t.IsError() {
+			continue
+		}
+
+		// check if torrent should be ignored
+		tctx, done := torrentContext(ctx)
+		ignored, err := shouldIgnore(tctx, c, &t)
+		done(log, &t)
+		if err != nil {
+			log.WithError(err).Errorf("Failed checking ignore filters for torrent: %q", t.Name)
+			continue
+		} else if ignored {
+			log.Debugf("Ignoring errored torrent: %q", t.Name)
+			continue
+		}
+
+		errored++
+		log.Infof("Errored torrent: %q - state: %s - save path: %s", t.Name, t.State, t.Path)
+
+		if flagDryRun {
+			log.Info("[DRY-RUN] Would recheck and resume")
+			fields = append(fields, noti.BuildField(notification.ActionRecover, notification.BuildOptions{
+				Torrent: t,
+			}))
+			continue
+		}
+
+		complete, err := c.RecheckAndWait(ctx, t.Hash, recheckTimeout)
+		if err != nil {
+			log.WithError(err).Errorf("Failed rechecking torrent: %q", t.Name)
+			failed++
+			continue
+		} else if !complete {
+			log.Warnf("Torrent is incomplete after recheck, resuming to download the missing data: %q", t.Name)
+		}
+
+		if err := c.ResumeTorrents(ctx, []string{t.Hash}); err != nil {
+			log.WithError(err).Errorf("Failed resuming torrent: %q", t.Name)
+			failed++
+			continue
+		}
+
+		log.Info("Rechecked and resumed")
+		recovered++
+		fields = append(fields, noti.BuildField(notification.ActionRecover, notification.BuildOptions{
+			Torrent: t,
+		}))
+	}
+
+	if flagDryRun {
+		log.Infof("[DRY-RUN] Would recover %d errored torrent(s)", errored)
+	} else {
+		log.Infof("Recovered %d of %d errored torrent(s), %d failed", recovered, errored, failed)
+	}
+	lastRun.record(client, "recovered", recovered, failed, 0)
+
+	if !noti.CanSend() {
+		log.Debug("Notifications disabled, skipping...")
+	} else if len(fields) > 0 {
+		sendErr := noti.Send(
+			"Torrent Recover",
+			fmt.Sprintf("Recovered **%d** errored torrent(s)", len(fields)),
+			client,
+			time.Since(startTime),
+			fields,
+			flagDryRun,
+		)
+		if sendErr != nil {
+			log.WithError(sendErr).Error("Failed sending notification")
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("recover %d torrent(s) failed", failed)
+	}
+
+	return nil
+}
+
+// pendingRemoval is a torrent being removed, its client calls can run on a worker while the bookkeeping of
+// removeEligibleTorrents stays on its goroutine
+type pendingRemoval struct {
+	h          string
+	t          *config.Torrent
+	reason     string
+	deleteData bool
+	archiving  bool
+	failed     bool
+	// retryable is set when the client failed the removal itself, which is often transient e.g. a locked file
+	retryable bool
+	// retrying is set on a retry, the recheck and archive steps already succeeded
+	retrying bool
+
+	isHardlinked            bool
+	isUnique                bool
+	isNotUniqueUnregistered bool
+}
+
+// removalPathMapping returns the download_path_mapping of the named client, nil when it has none
+func removalPathMapping(clientName string) (*pathmapping.Mapping, error) {
+	if config.Config == nil {
+		return nil, nil
+	}
+
+	clientConfig, ok := config.Config.Clients[clientName]
+	if !ok {
+		return nil, nil
+	}
+
+	return getClientDownloadPathMapping(clientConfig)
+}
+
+// executeRemoval makes the client calls of a live removal with the steps of filter, it only touches the removal itself
+// so it can run on a worker. mapping is the download_path_mapping of the client
+func executeRemoval(ctx context.Context, log *logrus.Entry, c client.Interface, filter *config.FilterConfiguration, mapping *pathmapping.Mapping, delay time.Duration, r *pendingRemoval) {
+	t := r.t
+
+	// verify the data on disk belongs to the torrent before deleting it
+	if r.deleteData && filter != nil && filter.RecheckBeforeRemove && !r.retrying {
+		log.Info("Rechecking before removal...")
+		complete, err := c.RecheckAndWait(ctx, t.Hash, recheckTimeout)
+		if err != nil || !complete {
+			if err == nil {
+				err = errors.New("torrent is not complete after recheck")
+			}
+			log.WithError(err).Errorf("Aborting removal, recheck failed: %q", t.Name)
+			r.failed = true
+			return
+		}
+	}
+
+	// keep the files in the archive through hardlinks before the data is deleted
+	if r.archiving && !r.retrying {
+		if err := archiveTorrentFiles(log, t, filter.ArchivePath); err != nil {
+			log.WithError(err).Errorf("Aborting removal, failed archiving files: %q", t.Name)
+			r.failed = true
+			return
+		}
+		log.Infof("Archived files to: %q", filter.ArchivePath)
+	}
+
+	// Do remove
+	removed, err := false, checkSafeMode()
+	if err == nil {
+		removed, err = c.RemoveTorrent(ctx, t, r.deleteData)
+
+		// the session may have expired during a long run, log in again once
+		if errors.Is(err, client.ErrAuth) {
+			log.WithError(err).Warn("Client refused the request, logging in again")
+			if err = c.Connect(ctx); err == nil {
+				removed, err = c.RemoveTorrent(ctx, t, r.deleteData)
+			}
+		}
+	}
+	if err != nil {
+		log.WithError(err).Errorf("Failed removing torrent: %+v", t)
+		r.failed = true
+		// refused credentials fail every retry the same way, and a torrent not found is already gone
+		r.retryable = !errors.Is(err, errSafeMode) && !errors.Is(err, client.ErrAuth) &&
+			!errors.Is(err, client.ErrNotFound)
+		return
+	} else if !removed {
+		// the client refused the removal, e.g. while the tracker is down, a retry would be refused the same way
+		log.Error("Failed removing torrent...")
+		r.failed = true
+		return
+	}
+
+	if r.deleteData {
+		log.Info("Removed with data")
+	} else {
+		log.Info("Removed (kept data on disk)")
+	}
+
+	time.Sleep(delay)
+
+	// the client reports success before its background deletion finishes, or even when it fails
+	if r.deleteData && filter != nil && filter.VerifyRemoval {
+		if leftovers := verifyDataRemoved(log, t, mapping, filter.RemoveLeftovers); len(leftovers) > 0 {
+			r.reason = fmt.Sprintf("%s (%d file(s) left on disk)", r.reason, len(leftovers))
+		}
+	}
+}
+
 // remove torrents that meet remove filters
-func removeEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.Interface, torrents map[string]config.Torrent, tfm *torrentfilemap.TorrentFileMap, hfm hardlinkfilemap.HardlinkFileMapI, filter *config.FilterConfiguration, noti notification.Sender, client string, startTime time.Time) error {
+func removeEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.Interface, torrents map[string]config.Torrent, tfm *torrentfilemap.TorrentFileMap, hfm hardlinkfilemap.HardlinkFileMapI, filter *config.FilterConfiguration, noti notification.Sender, client string, startTime time.Time, summary *notification.Summary) error {
 	// vars
 	var (
 		ignoredTorrents     int
@@ -314,10 +803,53 @@ func removeEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.Int
 		deleteData = *filter.DeleteData
 	}
 
+	// with a quarantine, torrents are tagged and paused before a later run removes them
+	quarantineClient, quarantineTag, quarantinePeriod, err := quarantineSettings(c, filter)
+	if err != nil {
+		return err
+	}
+	var (
+		quarantinedTorrents     int
+		errorQuarantineTorrents int
+	)
+
+	// torrents skipped despite meeting the remove filters are optionally tagged with why
+	skipTags, err := newSkipTagger(c, filter)
+	if err != nil {
+		return err
+	}
+
+	// the removed files are verified at their path on this host
+	pathMapping, err := removalPathMapping(client)
+	if err != nil {
+		return err
+	}
+
 	var fields []notification.Field
 
-	// helper function to remove torrent
-	removeTorrent := func(ctx context.Context, h string, t *config.Torrent, reason string, isHardlinked bool, isUnique bool, isNotUniqueUnregistered bool) bool {
+	// the free space tracked by the client, reported before and after the removals when it was retrieved
+	freeSpaceSet := false
+	for _, t := range torrents {
+		freeSpaceSet = t.FreeSpaceSet
+		break
+	}
+	freeSpaceBefore := c.GetFreeSpace()
+
+	// list the removals of a dry-run in order, with the space they would reclaim
+	var report *removalReport
+	if flagDryRun && flagReport != "" {
+		report = newRemovalReport(client, flagFreeSpaceTarget)
+	}
+
+	// removals are paced by the configured delay, and run on a pool of workers when they are concurrent
+	delay, concurrency := removalSettings(log, filter)
+	var pool *workerPool[*pendingRemoval]
+	if concurrency > 1 && !flagDryRun {
+		pool = newWorkerPool[*pendingRemoval](concurrency)
+	}
+
+	// prepareRemoval logs the removal and decides whether the data is deleted
+	prepareRemoval := func(h string, t *config.Torrent, reason string, isHardlinked bool, isUnique bool, isNotUniqueUnregistered bool) *pendingRemoval {
 		// Log removal details
 		if !t.APIDividerPrinted {
 			log.Info("-----")
@@ -347,55 +879,89 @@ func removeEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.Int
 		log.Infof("Ratio: %.3f / Seed days: %.3f / Seeds: %d / Label: %s / Tags: %s / Tracker: %s / "+
 			"Tracker Status: %q", t.Ratio, t.SeedingDays, t.Seeds, t.Label, strings.Join(t.Tags, ", "), t.TrackerName, t.TrackerStatus)
 
-		// update the hardlink map before removing the torrent
+		// update the hardlink map before removing the torrent, while its files can still be inspected
 		hfm.RemoveByTorrent(*t)
 
 		// Determine whether to delete data
-		localDeleteData := deleteData
+		localDeleteData := deleteDataForTorrent(filter, t, deleteData)
+		if deleteData && t.TrackerKeepsData() {
+			log.Infof("Keeping data of torrent, per the policy of tracker: %s", t.TrackerName)
+		}
 
 		// For non-unique torrents with file overlap (not hardlinked), always keep the data
 		if !isUnique && !isHardlinked {
 			localDeleteData = false
 		}
 
-		if !flagDryRun {
-			// Do remove
-			removed, err := c.RemoveTorrent(ctx, t, localDeleteData)
-			if err != nil {
-				log.WithError(err).Errorf("Failed removing torrent: %+v", t)
-				// don't remove from torrents file map, but prevent further operations on this torrent
-				delete(torrents, h)
-				errorRemoveTorrents++
-				return false
-			} else if !removed {
-				log.Error("Failed removing torrent...")
-				// don't remove from torrents file map, but prevent further operations on this torrent
-				delete(torrents, h)
-				errorRemoveTorrents++
+		return &pendingRemoval{
+			h:                       h,
+			t:                       t,
+			reason:                  reason,
+			deleteData:              localDeleteData,
+			archiving:               localDeleteData && filter != nil && filter.ArchivePath != "",
+			isHardlinked:            isHardlinked,
+			isUnique:                isUnique,
+			isNotUniqueUnregistered: isNotUniqueUnregistered,
+		}
+	}
+
+	// removals the client failed, retried once the other removals are done
+	var failedRemovals []*pendingRemoval
+
+	// finishRemoval updates the counters, maps and free space with the outcome of a removal
+	finishRemoval := func(r *pendingRemoval) bool {
+		t := r.t
+
+		if r.failed {
+			// don't remove from torrents file map, but prevent further operations on this torrent
+			hfm.AddByTorrent(*t)
+			delete(torrents, r.h)
+			if r.retryable {
+				failedRemovals = append(failedRemovals, r)
 				return false
-			} else {
-				if localDeleteData {
-					log.Info("Removed with data")
-				} else {
-					log.Info("Removed (kept data on disk)")
-				}
+			}
 
-				// increase free space if we removed data
-				if localDeleteData && t.FreeSpaceSet {
-					log.Tracef("Increasing free space by: %s", humanize.IBytes(uint64(t.DownloadedBytes)))
-					c.AddFreeSpace(t.DownloadedBytes)
-					log.Tracef("New free space: %.2f GB", c.GetFreeSpace())
-				}
+			errorRemoveTorrents++
+			return false
+		}
 
-				time.Sleep(1 * time.Second)
+		if !flagDryRun {
+			// increase free space if we removed data (archived files remain on disk through their hardlinks)
+			if r.deleteData && !r.archiving && t.FreeSpaceSet && !t.FreeSpaceElsewhere {
+				log.Tracef("Increasing free space by: %s", humanize.IBytes(uint64(t.DownloadedBytes)))
+				c.AddFreeSpace(t.DownloadedBytes)
+				log.Tracef("New free space: %.2f GB", c.GetFreeSpace())
 			}
 		} else {
-			log.Warnf("Dry-run enabled, skipping remove (would delete data: %t)...", localDeleteData)
+			log.Warnf("Dry-run enabled, skipping remove (would delete data: %t)...", r.deleteData)
+			if r.archiving {
+				log.Warnf("Dry-run enabled, skipping archiving files to: %q", filter.ArchivePath)
+			}
+
+			// account for the space a live run would reclaim, so filters using free space evaluate the same
+			var reclaimed int64
+			if r.deleteData && !r.archiving {
+				reclaimed = t.DownloadedBytes
+			}
+
+			if reclaimed > 0 && t.FreeSpaceSet && !t.FreeSpaceElsewhere {
+				log.Tracef("Increasing free space by: %s", humanize.IBytes(uint64(t.DownloadedBytes)))
+				c.AddFreeSpace(t.DownloadedBytes)
+				log.Tracef("New free space: %.2f GB", c.GetFreeSpace())
+			}
+
+			if report != nil {
+				report.add(t, r.reason, r.deleteData, reclaimed, c.GetFreeSpace(), t.FreeSpaceSet)
+			}
+
+			if cleanPlan != nil {
+				cleanPlan.addRemoval(client, t, r.reason, r.deleteData, r.archiving)
+			}
 		}
 
 		fields = append(fields, noti.BuildField(notification.ActionClean, notification.BuildOptions{
 			Torrent:       *t,
-			RemovalReason: reason,
+			RemovalReason: r.reason,
 		}))
 
 		// increased hard removed counters
@@ -404,23 +970,86 @@ func removeEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.Int
 
 		// remove the torrent from the torrent maps
 		tfm.Remove(*t)
-		delete(torrents, h)
+		delete(torrents, r.h)
 		return true
 	}
 
+	// helper function to remove torrent
+	removeTorrent := func(ctx context.Context, h string, t *config.Torrent, reason string, isHardlinked bool, isUnique bool, isNotUniqueUnregistered bool) bool {
+		r := prepareRemoval(h, t, reason, isHardlinked, isUnique, isNotUniqueUnregistered)
+		if !flagDryRun {
+			executeRemoval(ctx, log, c, filter, pathMapping, delay, r)
+		}
+
+		return finishRemoval(r)
+	}
+
+	// removeUniqueTorrent removes a torrent sharing no files with other torrents on a worker when removals are
+	// concurrent, as its outcome can't change whether other torrents are unique
+	removeUniqueTorrent := func(ctx context.Context, h string, t *config.Torrent, reason string) {
+		if pool == nil {
+			removeTorrent(ctx, h, t, reason, false, true, false)
+			return
+		}
+
+		r := prepareRemoval(h, t, reason, false, true, false)
+		pool.submit(r, func(r *pendingRemoval) {
+			executeRemoval(ctx, log.WithField("torrent", r.t.Name), c, filter, pathMapping, delay, r)
+		})
+
+		for _, done := range pool.finished() {
+			finishRemoval(done)
+		}
+	}
+
 	// iterate torrents
 	hardlinkedCandidates := make(map[string]config.Torrent)
 	fileOverlapCandidates := make(map[string]config.Torrent)
 	candidateReasons := make(map[string]string)
-	for h, t := range torrents {
-		// should we ignore this torrent?
-		ignore, err := c.ShouldIgnore(ctx, &t)
+	// evaluate in a stable order, so a dry-run removes the same torrents in the same order as a live run
+	for _, h := range slices.Sorted(maps.Keys(torrents)) {
+		t := torrents[h]
+
+		// a torrent whose files are being moved, or that an earlier action changed, is kept like an ignored one
+		if skipMoving(log, &t) || skipRecentlyActed(log, &t, actionClean) {
+			delete(torrents, h)
+			ignoredTorrents++
+			continue
+		}
+
+		// should we ignore this torrent? if not, should we remove it?
+		tctx, done := torrentContext(ctx)
+		ignore, err := shouldIgnore(tctx, c, &t)
+		if err == nil && ignore && !ignoreListed(&t) && t.BypassesIgnore(tctx) {
+			// the precedence lets the remove filters decide
+			log.Debugf("Ignore filters matched %s but precedence is %s, evaluating remove filters",
+				t.Name, config.Config.EffectivePrecedence())
+			ignore = false
+		}
+		ignore = err == nil && ignore
+		var remove bool
+		var reason string
+		var removeErr error
+		if err == nil && !ignore {
+			if quarantineClient != nil {
+				removeErr = quarantineRefresh(tctx, &t, quarantineTag)
+			}
+			if removeErr == nil {
+				remove, reason, removeErr = c.ShouldRemoveWithReason(tctx, &t)
+			}
+			if removeErr == nil && quarantineClient != nil {
+				remove, reason = quarantineDecision(&t, quarantineTag, filter, remove, reason)
+			}
+		}
+		done(log, &t)
+		runMetrics.observe(&t)
+
 		if err != nil {
 			// error while determining whether to ignore torrent
 			log.WithError(err).Errorf("Failed determining whether to ignore: %+v", t)
 			delete(torrents, h)
 			continue
-		} else if ignore && !(config.Config.BypassIgnoreIfUnregistered && t.IsUnregistered(ctx)) {
+		} else if ignore {
 			// torrent met ignore filter
 			log.Tracef("Ignoring torrent %s: %s", h, t.Name)
 			delete(torrents, h)
@@ -429,8 +1058,7 @@ func removeEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.Int
 		}
 
 		// should we remove this torrent?
-		remove, reason, err := c.ShouldRemoveWithReason(ctx, &t)
-		if err != nil {
+		if err := removeErr; err != nil {
 			log.WithError(err).Errorf("Failed determining whether to remove: %+v", t)
 			// dont do any further operations on this torrent, but keep in the torrent file map
 			delete(torrents, h)
@@ -438,11 +1066,52 @@ func removeEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.Int
 		} else if !remove {
 			// torrent did not meet the remove filters
 			log.Tracef("Not removing %s: %s", h, t.Name)
+			if quarantineClient != nil {
+				if err := releaseQuarantine(ctx, log, quarantineClient, &t, quarantineTag); err != nil {
+					log.WithError(err).Errorf("Failed releasing torrent from quarantine: %q", t.Name)
+				}
+			}
 			continue
 		}
 
 		// torrent meets the remove filters
 
+		// only remove torrents that have already been paused
+		if filter != nil && filter.RequirePaused && t.NormalizedState() != config.StatePaused {
+			log.Debugf("Not removing %s: %s (not paused, state: %s)", h, t.Name, t.State)
+			skipTags.tag(ctx, log, &t, skipNotPaused)
+			delete(torrents, h)
+			continue
+		}
+
+		// only remove trumped or upgraded torrents once the newer version is in the client
+		if filter != nil && filter.RequireReplacement && t.IsUpgraded() && !t.HasReplacement {
+			log.Debugf("Not removing %s: %s (upgraded, no replacement found)", h, t.Name)
+			skipTags.tag(ctx, log, &t, skipNoReplacement)
+			delete(torrents, h)
+			continue
+		}
+
+		// clients may refuse to remove torrents while their tracker is down, e.g. qBittorrent, so they are tagged
+		// before the removal is attempted and dry-runs show the tag too
+		if t.IsTrackerDown() {
+			skipTags.tag(ctx, log, &t, skipTrackerDown)
+		}
+
+		// quarantined torrents are kept until they carried the quarantine tag for the quarantine period
+		if quarantineClient != nil && !quarantineExpired(&t, quarantineTag, quarantinePeriod, now()) {
+			if _, quarantined := quarantinedAt(&t, quarantineTag); quarantined {
+				log.Debugf("Not removing %s: %s (quarantined)", h, t.Name)
+			} else if err := quarantineTorrent(ctx, log, quarantineClient, &t, quarantineTag, reason); err != nil {
+				log.WithError(err).Errorf("Failed quarantining torrent: %q", t.Name)
+				errorQuarantineTorrents++
+			} else {
+				quarantinedTorrents++
+			}
+			delete(torrents, h)
+			continue
+		}
+
 		// Check if the torrent is not unique (either through file mapping or hardlinks)
 		isUnique := true
 		isHardlinked := false
@@ -461,7 +1130,10 @@ func removeEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.Int
 
 		if !isUnique {
 			// Check if torrent is unregistered (can bypass uniqueness checks)
-			if t.IsUnregistered(ctx) {
+			tctx, done := torrentContext(ctx)
+			unregistered := t.IsUnregistered(tctx)
+			done(log, &t)
+			if unregistered {
 				// For unregistered torrents, override safety checks and remove immediately
 				removeTorrent(ctx, h, &t, reason, isHardlinked, isUnique, true)
 				continue
@@ -485,13 +1157,27 @@ func removeEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.Int
 		}
 
 		// Remove unique torrents
-		removeTorrent(ctx, h, &t, reason, false, isUnique, false)
+		removeUniqueTorrent(ctx, h, &t, reason)
+	}
+
+	// finish removing the unique torrents before the candidates are processed
+	if pool != nil {
+		for _, done := range pool.wait() {
+			finishRemoval(done)
+		}
 	}
 
 	log.Info("========================================")
 	log.Infof("Finished initial check, %d hardlinked candidates and %d file overlap candidates for removal", len(hardlinkedCandidates), len(fileOverlapCandidates))
 	log.Info("========================================")
 
+	// keep min_copies seeding members of each group of cross-seeds, decided while tfm and hfm still hold every candidate
+	minCopies := 0
+	if filter != nil {
+		minCopies = filter.MinCopies
+	}
+	kept := keepCopies(tfm, hfm, mergeCandidates(fileOverlapCandidates, hardlinkedCandidates), minCopies)
+
 	// imagine we removed all candidates,
 	// now lets check if the candidates still have more versions
 	// or can be safely removed
@@ -505,11 +1191,27 @@ func removeEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.Int
 		hfm.RemoveByTorrent(t)
 	}
 
+	// the kept copies are skipped, the other members of their groups are still removed
+	keptCandidates := 0
+	for _, candidates := range []map[string]config.Torrent{fileOverlapCandidates, hardlinkedCandidates} {
+		for _, h := range slices.Sorted(maps.Keys(candidates)) {
+			if !kept.isKept(h) {
+				continue
+			}
+
+			log.Infof("Keeping %s: %s (seeding copy, min_copies: %d)", h, candidates[h].Name, minCopies)
+			delete(candidates, h)
+			delete(torrents, h)
+			keptCandidates++
+		}
+	}
+
 	// Process file overlap candidates - these can be removed without data deletion
 	removedCandidates := 0
 	removedFileOverlapCandidates := 0
 	removedHardlinkedCandidates := 0
-	for h, t := range fileOverlapCandidates {
+	for _, h := range slices.Sorted(maps.Keys(fileOverlapCandidates)) {
+		t := fileOverlapCandidates[h]
 		noInstances := tfm.NoInstances(t) && hfm.NoInstances(t)
 
 		if !noInstances {
@@ -525,7 +1227,8 @@ func removeEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.Int
 	}
 
 	// Process hardlinked candidates - these can be removed with data deletion
-	for h, t := range hardlinkedCandidates {
+	for _, h := range slices.Sorted(maps.Keys(hardlinkedCandidates)) {
+		t := hardlinkedCandidates[h]
 		noInstances := tfm.NoInstances(t) && hfm.NoInstances(t)
 
 		if !noInstances {
@@ -533,13 +1236,65 @@ func removeEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.Int
 			continue
 		}
 
+		// the data of a candidate sharing files with a kept copy stays on disk, like a file overlap
 		reason := candidateReasons[h]
-		if removeTorrent(ctx, h, &t, reason, true, false, false) {
+		if removeTorrent(ctx, h, &t, reason, !kept.sharesWithKept(h), false, false) {
 			removedCandidates++
 			removedHardlinkedCandidates++
 		}
 	}
 
+	// the candidates left in torrents are still not unique
+	for _, candidates := range []map[string]config.Torrent{fileOverlapCandidates, hardlinkedCandidates} {
+		for _, h := range slices.Sorted(maps.Keys(candidates)) {
+			if t, ok := torrents[h]; ok {
+				skipTags.tag(ctx, log, &t, skipNonUnique)
+			}
+		}
+	}
+
+	// retry the removals the client failed, with a growing wait in between
+	backoff := removalRetryBackoff
+	for attempt := 1; attempt <= removalRetries && len(failedRemovals) > 0; attempt++ {
+		retrying := failedRemovals
+		failedRemovals = nil
+
+		log.Info("-----")
+		log.Infof("Retrying %d failed removal(s) in %s (attempt %d/%d)", len(retrying), backoff, attempt, removalRetries)
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		if ctx.Err() != nil {
+			log.WithError(ctx.Err()).Warn("Stopped retrying failed removals")
+			failedRemovals = retrying
+			break
+		}
+		backoff *= 2
+
+		for _, r := range retrying {
+			log.Infof("Retrying removal: %q", r.t.Name)
+			r.failed, r.retryable, r.retrying = false, false, true
+			hfm.RemoveByTorrent(*r.t)
+			executeRemoval(ctx, log, c, filter, pathMapping, delay, r)
+
+			if finishRemoval(r) && !r.isUnique && !r.isNotUniqueUnregistered {
+				removedCandidates++
+				if r.isHardlinked {
+					removedHardlinkedCandidates++
+				} else {
+					removedFileOverlapCandidates++
+				}
+			}
+		}
+	}
+
+	// report the removals that still failed
+	for _, r := range failedRemovals {
+		log.Errorf("Failed removing torrent after %d retries: %q", removalRetries, r.t.Name)
+		errorRemoveTorrents++
+	}
+
 	reclaimedSpace := humanize.IBytes(uint64(removedTorrentBytes))
 
 	// show result
@@ -556,6 +1311,9 @@ func removeEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.Int
 	if totalSkipped > 0 {
 		log.Infof("Skipped: %d hardlinked, %d file overlap", skippedHardlinked, skippedFileOverlap)
 	}
+	if keptCandidates > 0 {
+		log.Infof("Kept: %d seeding copies (min_copies: %d)", keptCandidates, minCopies)
+	}
 
 	// Show removed torrents summary
 	log.WithField("reclaimed_space", reclaimedSpace).
@@ -566,6 +1324,33 @@ func removeEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.Int
 		log.Infof("Failures: %d torrents failed to remove", errorRemoveTorrents)
 	}
 
+	description := fmt.Sprintf("Removed **%d** torrent(s) | Total reclaimed **%s**", hardRemoveTorrents, reclaimedSpace)
+	if freeSpaceSet {
+		log.Infof("Free space: %.2f GB -> %.2f GB", freeSpaceBefore, c.GetFreeSpace())
+		description += " | " + freeSpaceChange(freeSpaceBefore, c.GetFreeSpace())
+	}
+
+	if quarantineClient != nil {
+		log.Infof("Quarantined torrents: %d, %d failures", quarantinedTorrents, errorQuarantineTorrents)
+		lastRun.record(client, "quarantined", quarantinedTorrents, errorQuarantineTorrents, 0)
+	}
+	lastRun.record(client, "removed", hardRemoveTorrents, errorRemoveTorrents, removedTorrentBytes)
+	if !flagDryRun {
+		lastRun.dropTorrents(client, hardRemoveTorrents)
+	}
+
+	if report != nil {
+		if err := report.write(reportOutput, flagReport); err != nil {
+			log.WithError(err).Error("Failed writing removal report")
+		}
+	}
+
+	// multi-client runs send a single rollup notification instead
+	if summary != nil {
+		summary.Add(client, hardRemoveTorrents, removedTorrentBytes)
+		return nil
+	}
+
 	if !noti.CanSend() {
 		log.Debug("Notifications disabled, skipping...")
 		return nil
@@ -573,7 +1358,7 @@ func removeEligibleTorrents(ctx context.Context, log *logrus.Entry, c client.Int
 
 	sendErr := noti.Send(
 		"Torrent Cleanup",
-		fmt.Sprintf("Removed **%d** torrent(s) | Total reclaimed **%s**", hardRemoveTorrents, reclaimedSpace),
+		description,
 		client,
 		time.Since(startTime),
 		fields,
@@ -0,0 +1,1046 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/client"
+	"github.com/autobrr/tqm/pkg/client/clienttest"
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/expression"
+	"github.com/autobrr/tqm/pkg/hardlinkfilemap"
+	"github.com/autobrr/tqm/pkg/logger"
+	"github.com/autobrr/tqm/pkg/notification"
+	"github.com/autobrr/tqm/pkg/pathmapping"
+	"github.com/autobrr/tqm/pkg/torrentfilemap"
+)
+
+type fakeTagEditor struct {
+	addErr    error
+	removeErr map[string]error
+
+	added   [][]string
+	removed [][]string
+}
+
+func (f *fakeTagEditor) AddTags(_ context.Context, _ string, tags []string) error {
+	if f.addErr != nil {
+		return f.addErr
+	}
+	f.added = append(f.added, tags)
+	return nil
+}
+
+func (f *fakeTagEditor) RemoveTags(_ context.Context, _ string, tags []string) error {
+	if err, ok := f.removeErr[tags[0]]; ok {
+		return err
+	}
+	f.removed = append(f.removed, tags)
+	return nil
+}
+
+func TestApplyTagsIndividually(t *testing.T) {
+	log := logger.GetLogger("test")
+	errRemove := errors.New("remove failed")
+
+	tests := []struct {
+		name          string
+		partialMode   string
+		editor        *fakeTagEditor
+		expectTaken   bool
+		expectErr     bool
+		expectRemoved [][]string
+	}{
+		{
+			name:          "success",
+			editor:        &fakeTagEditor{},
+			expectTaken:   true,
+			expectRemoved: [][]string{{"old"}},
+		},
+		{
+			name:        "add_fails",
+			editor:      &fakeTagEditor{addErr: errors.New("add failed")},
+			expectTaken: false,
+			expectErr:   true,
+		},
+		{
+			name:          "remove_fails_rolls_back",
+			editor:        &fakeTagEditor{removeErr: map[string]error{"old": errRemove}},
+			expectTaken:   false,
+			expectErr:     true,
+			expectRemoved: [][]string{{"new"}},
+		},
+		{
+			name:        "remove_fails_keep_partial",
+			partialMode: config.RetagPartialFailureKeep,
+			editor:      &fakeTagEditor{removeErr: map[string]error{"old": errRemove}},
+			expectTaken: true,
+			expectErr:   true,
+		},
+		{
+			name: "remove_and_rollback_fail",
+			editor: &fakeTagEditor{removeErr: map[string]error{
+				"old": errRemove,
+				"new": errors.New("rollback failed"),
+			}},
+			expectTaken: true,
+			expectErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Config.RetagPartialFailure = tt.partialMode
+			t.Cleanup(func() { config.Config.RetagPartialFailure = "" })
+
+			torrent := &config.Torrent{Hash: "hash1", Name: "torrent1", Tags: []string{"old"}}
+			taken, err := applyTagsIndividually(context.Background(), log, tt.editor, torrent, []string{"new"}, []string{"old"})
+
+			assert.Equal(t, tt.expectTaken, taken)
+			if tt.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectRemoved, tt.editor.removed)
+		})
+	}
+}
+
+func TestRemoveSlice_DoesNotModifyInput(t *testing.T) {
+	tags := []string{"a", "b", "c"}
+	result := removeSlice(tags, []string{"b"})
+
+	assert.Equal(t, []string{"a", "c"}, result)
+	assert.Equal(t, []string{"a", "b", "c"}, tags)
+}
+
+func newMockClient(t *testing.T, filter *config.FilterConfiguration, freeSpaceGB float64, torrents map[string]config.Torrent) *clienttest.MockClient {
+	t.Helper()
+	exp, err := expression.Compile(filter)
+	require.NoError(t, err)
+
+	c := clienttest.NewMockClient(exp, torrents)
+	c.FreeSpaceGB = freeSpaceGB
+	c.FreeSpaceSet = true
+	return c
+}
+
+// recordingSender records the torrents notification fields were built for
+type recordingSender struct {
+	hashes  []string
+	reasons []string
+}
+
+func (s *recordingSender) CanSend() bool { return false }
+func (s *recordingSender) Name() string  { return "recording" }
+
+func (s *recordingSender) Send(string, string, string, time.Duration, []notification.Field, bool) error {
+	return nil
+}
+
+func (s *recordingSender) BuildField(_ notification.Action, opt notification.BuildOptions) notification.Field {
+	s.hashes = append(s.hashes, opt.Torrent.Hash)
+	s.reasons = append(s.reasons, opt.RemovalReason)
+	return notification.Field{}
+}
+
+// descriptionSender records the description of the notifications sent
+type descriptionSender struct {
+	recordingSender
+	descriptions []string
+}
+
+func (s *descriptionSender) CanSend() bool { return true }
+
+func (s *descriptionSender) Send(_ string, description string, _ string, _ time.Duration, _ []notification.Field, _ bool) error {
+	s.descriptions = append(s.descriptions, description)
+	return nil
+}
+
+// runRemove runs removeEligibleTorrents against a fresh copy of torrents and returns the hashes it removed
+func runRemove(t *testing.T, dryRun bool, filter *config.FilterConfiguration, freeSpaceGB float64, torrents map[string]config.Torrent) []string {
+	t.Helper()
+
+	flagDryRun = dryRun
+	t.Cleanup(func() { flagDryRun = false })
+
+	c := newMockClient(t, filter, freeSpaceGB, torrents)
+	working, err := c.GetTorrents(context.Background())
+	require.NoError(t, err)
+
+	noti := &recordingSender{}
+	err = removeEligibleTorrents(context.Background(), logger.GetLogger("test"), c, working, torrentfilemap.New(working),
+		hardlinkfilemap.NewNoopHardlinkFileMap(), filter, noti, "test", time.Now(), nil)
+	require.NoError(t, err)
+
+	if !dryRun {
+		assert.ElementsMatch(t, c.Removed, noti.hashes, "live run should only report torrents the client removed")
+	}
+
+	sort.Strings(noti.hashes)
+	return noti.hashes
+}
+
+func TestRemoveEligibleTorrents_DryRunMatchesLive(t *testing.T) {
+	removalDelay = 0
+	t.Cleanup(func() { removalDelay = time.Second })
+
+	tests := []struct {
+		name      string
+		filter    *config.FilterConfiguration
+		freeSpace float64
+		torrents  map[string]config.Torrent
+		expected  []string
+	}{
+		{
+			name: "cross_seed_candidates",
+			filter: &config.FilterConfiguration{
+				Ignore: []string{`Label == "keep"`},
+				Remove: []string{`Label == "remove"`},
+			},
+			torrents: map[string]config.Torrent{
+				// a and b share a file, both removable
+				"a": {Hash: "a", Name: "a", Label: "remove", Downloaded: true, Files: []string{"/data/x"}},
+				"b": {Hash: "b", Name: "b", Label: "remove", Downloaded: true, Files: []string{"/data/x"}},
+				// c is unique
+				"c": {Hash: "c", Name: "c", Label: "remove", Downloaded: true, Files: []string{"/data/y"}},
+				// d shares a file with the ignored e, so it must be kept
+				"d": {Hash: "d", Name: "d", Label: "remove", Downloaded: true, Files: []string{"/data/z"}},
+				"e": {Hash: "e", Name: "e", Label: "keep", Downloaded: true, Files: []string{"/data/z"}},
+			},
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name: "free_space_target",
+			filter: &config.FilterConfiguration{
+				Remove: []string{`FreeSpaceGB() < 10`},
+			},
+			freeSpace: 9,
+			torrents: map[string]config.Torrent{
+				"a": {Hash: "a", Name: "a", Downloaded: true, DownloadedBytes: 2 * humanize.GiByte, Files: []string{"/data/a"}},
+				"b": {Hash: "b", Name: "b", Downloaded: true, DownloadedBytes: 2 * humanize.GiByte, Files: []string{"/data/b"}},
+				"c": {Hash: "c", Name: "c", Downloaded: true, DownloadedBytes: 2 * humanize.GiByte, Files: []string{"/data/c"}},
+			},
+			expected: []string{"a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			live := runRemove(t, false, tt.filter, tt.freeSpace, tt.torrents)
+			dry := runRemove(t, true, tt.filter, tt.freeSpace, tt.torrents)
+
+			assert.Equal(t, tt.expected, live)
+			assert.Equal(t, live, dry, "dry-run should remove the same torrents as a live run")
+		})
+	}
+}
+
+func TestRemoveEligibleTorrents_BypassIgnoreExemptions(t *testing.T) {
+	removalDelay = 0
+	t.Cleanup(func() { removalDelay = time.Second })
+
+	config.InitializeTrackerStatuses(config.TrackerErrorsConfig{})
+	config.Config.BypassIgnoreIfUnregistered = true
+	config.Config.BypassIgnoreExemptions = config.BypassIgnoreExemptions{Trackers: []string{"keep.tracker"}}
+	t.Cleanup(func() {
+		config.Config.BypassIgnoreIfUnregistered = false
+		config.Config.BypassIgnoreExemptions = config.BypassIgnoreExemptions{}
+	})
+
+	filter := &config.FilterConfiguration{
+		Ignore: []string{`Label == "permaseed"`},
+		Remove: []string{`IsUnregistered()`},
+	}
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Label: "permaseed", TrackerName: "other.tracker", TrackerStatus: "Unregistered torrent",
+			Downloaded: true, Files: []string{"/data/a"}},
+		"b": {Hash: "b", Name: "b", Label: "permaseed", TrackerName: "keep.tracker", TrackerStatus: "Unregistered torrent",
+			Downloaded: true, Files: []string{"/data/b"}},
+	}
+
+	assert.Equal(t, []string{"a"}, runRemove(t, false, filter, 0, torrents))
+}
+
+func TestRemoveEligibleTorrents_Precedence(t *testing.T) {
+	removalDelay = 0
+	t.Cleanup(func() { removalDelay = time.Second })
+
+	config.InitializeTrackerStatuses(config.TrackerErrorsConfig{})
+	t.Cleanup(func() {
+		config.Config.Precedence = ""
+		config.Config.BypassIgnoreIfUnregistered = false
+	})
+
+	// every torrent matches both the ignore and the remove filters
+	filter := &config.FilterConfiguration{
+		Ignore: []string{`Label == "permaseed"`},
+		Remove: []string{`Ratio > 2`},
+	}
+	torrents := map[string]config.Torrent{
+		"registered": {Hash: "registered", Name: "registered", Label: "permaseed", Ratio: 3,
+			TrackerName: "tracker.com", TrackerStatus: "Working", Downloaded: true, Files: []string{"/data/registered"}},
+		"unregistered": {Hash: "unregistered", Name: "unregistered", Label: "permaseed", Ratio: 3,
+			TrackerName: "tracker.com", TrackerStatus: "Unregistered torrent", Downloaded: true,
+			Files: []string{"/data/unregistered"}},
+	}
+
+	tests := []struct {
+		name       string
+		precedence string
+		bypass     bool
+		expected   []string
+	}{
+		{name: "default", expected: nil},
+		{name: "legacy_bypass", bypass: true, expected: []string{"unregistered"}},
+		{name: "ignore_wins", precedence: config.PrecedenceIgnoreWins, bypass: true, expected: nil},
+		{name: "remove_wins", precedence: config.PrecedenceRemoveWins, expected: []string{"registered", "unregistered"}},
+		{name: "bypass_if_unregistered", precedence: config.PrecedenceBypassIfUnregistered, expected: []string{"unregistered"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Config.Precedence = tt.precedence
+			config.Config.BypassIgnoreIfUnregistered = tt.bypass
+
+			removed := runRemove(t, false, filter, 0, maps.Clone(torrents))
+			if tt.expected == nil {
+				assert.Empty(t, removed)
+			} else {
+				assert.ElementsMatch(t, tt.expected, removed)
+			}
+		})
+	}
+}
+
+func TestRemoveEligibleTorrents_SafeMode(t *testing.T) {
+	removalDelay = 0
+	t.Cleanup(func() { removalDelay = time.Second })
+
+	// the removal is refused even if dry-run was not applied
+	config.Config.SafeMode = true
+	t.Cleanup(func() { config.Config.SafeMode = false })
+
+	filter := &config.FilterConfiguration{Remove: []string{`Label == "remove"`}}
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Label: "remove", Downloaded: true, Files: []string{"/data/a"}},
+	}
+
+	assert.Empty(t, runRemove(t, false, filter, 0, torrents))
+}
+
+func TestRemoveEligibleTorrents_RequirePaused(t *testing.T) {
+	removalDelay = 0
+	t.Cleanup(func() { removalDelay = time.Second })
+
+	filter := &config.FilterConfiguration{
+		Remove:        []string{`Label == "remove"`},
+		RequirePaused: true,
+	}
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Label: "remove", State: "pausedUP", Downloaded: true, Files: []string{"/data/a"}},
+		"b": {Hash: "b", Name: "b", Label: "remove", State: "stalledUP", Downloaded: true, Files: []string{"/data/b"}},
+		"c": {Hash: "c", Name: "c", Label: "remove", State: "Paused", Downloaded: true, Files: []string{"/data/c"}},
+	}
+
+	assert.Equal(t, []string{"a", "c"}, runRemove(t, false, filter, 0, torrents))
+}
+
+func TestRemoveEligibleTorrents_RecheckBeforeRemove(t *testing.T) {
+	removalDelay = 0
+	t.Cleanup(func() { removalDelay = time.Second })
+
+	filter := &config.FilterConfiguration{
+		Remove:              []string{`Label == "remove"`},
+		RecheckBeforeRemove: true,
+	}
+
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Label: "remove", Downloaded: true, Files: []string{"/data/a"}},
+		"b": {Hash: "b", Name: "b", Label: "remove", Downloaded: true, Files: []string{"/data/b"}},
+	}
+
+	c := newMockClient(t, filter, 0, torrents)
+	c.Incomplete = map[string]bool{"b": true}
+
+	err := removeEligibleTorrents(context.Background(), logger.GetLogger("test"), c, torrents, torrentfilemap.New(torrents),
+		hardlinkfilemap.NewNoopHardlinkFileMap(), filter, &recordingSender{}, "test", time.Now(), nil)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"a", "b"}, c.Rechecked)
+	assert.Equal(t, []string{"a"}, c.Removed, "incomplete torrent should not be removed")
+}
+
+func TestRemoveEligibleTorrents_RetryFailedRemovals(t *testing.T) {
+	removalDelay, removalRetryBackoff = 0, 0
+	t.Cleanup(func() {
+		removalDelay = time.Second
+		removalRetryBackoff = 5 * time.Second
+	})
+
+	filter := &config.FilterConfiguration{Remove: []string{`Label == "remove"`}}
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Label: "remove", Downloaded: true, Files: []string{"/data/a"}},
+		"b": {Hash: "b", Name: "b", Label: "remove", Downloaded: true, Files: []string{"/data/b"}},
+		"c": {Hash: "c", Name: "c", Label: "remove", Downloaded: true, Files: []string{"/data/c"}},
+	}
+
+	c := newMockClient(t, filter, 0, torrents)
+	// a fails once and is removed by the retry, b keeps failing
+	c.RemoveFailures = map[string]int{"a": 1, "b": removalRetries + 1}
+
+	sender := &recordingSender{}
+	err := removeEligibleTorrents(context.Background(), logger.GetLogger("test"), c, torrents, torrentfilemap.New(torrents),
+		hardlinkfilemap.NewNoopHardlinkFileMap(), filter, sender, "test", time.Now(), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"c", "a"}, c.Removed)
+	assert.Equal(t, []string{"c", "a"}, sender.hashes)
+	assert.Equal(t, 0, c.RemoveFailures["b"], "b should have been retried %d times", removalRetries)
+}
+
+func TestRemoveEligibleTorrents_RefusedRemovalNotRetried(t *testing.T) {
+	removalDelay, removalRetryBackoff = 0, time.Hour
+	t.Cleanup(func() {
+		removalDelay = time.Second
+		removalRetryBackoff = 5 * time.Second
+	})
+
+	filter := &config.FilterConfiguration{Remove: []string{`Label == "remove"`}}
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Label: "remove", Downloaded: true, Files: []string{"/data/a"}},
+		"b": {Hash: "b", Name: "b", Label: "remove", Downloaded: true, Files: []string{"/data/b"}},
+	}
+
+	c := newMockClient(t, filter, 0, torrents)
+	// a is refused like a torrent whose tracker is down, b keeps failing until the run is canceled
+	c.RefuseRemoval = map[string]bool{"a": true}
+	c.RemoveFailures = map[string]int{"b": removalRetries + 1}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := removeEligibleTorrents(ctx, logger.GetLogger("test"), c, torrents, torrentfilemap.New(torrents),
+		hardlinkfilemap.NewNoopHardlinkFileMap(), filter, &recordingSender{}, "test", time.Now(), nil)
+	require.NoError(t, err)
+
+	assert.Less(t, time.Since(start), time.Minute, "the retry wait should end with the context")
+	assert.Empty(t, c.Removed)
+	assert.Equal(t, map[string]int{"a": 1, "b": 1}, c.RemoveAttempts)
+}
+
+func TestRemoveEligibleTorrents_ClientErrorKinds(t *testing.T) {
+	removalDelay, removalRetryBackoff = 0, 0
+	t.Cleanup(func() {
+		removalDelay = time.Second
+		removalRetryBackoff = 5 * time.Second
+	})
+
+	filter := &config.FilterConfiguration{Remove: []string{`Label == "remove"`}}
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Label: "remove", Downloaded: true, Files: []string{"/data/a"}},
+		"b": {Hash: "b", Name: "b", Label: "remove", Downloaded: true, Files: []string{"/data/b"}},
+		"c": {Hash: "c", Name: "c", Label: "remove", Downloaded: true, Files: []string{"/data/c"}},
+	}
+
+	c := newMockClient(t, filter, 0, torrents)
+	// a is removed once logged in again, b is refused even after logging in again, c is already gone
+	c.SessionExpired = true
+	c.RemoveErrors = map[string]error{
+		"b": &client.RequestError{Kind: client.ErrAuth, Err: errors.New("banned")},
+		"c": client.ErrTorrentNotFound,
+	}
+
+	err := removeEligibleTorrents(context.Background(), logger.GetLogger("test"), c, torrents, torrentfilemap.New(torrents),
+		hardlinkfilemap.NewNoopHardlinkFileMap(), filter, &recordingSender{}, "test", time.Now(), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"a"}, c.Removed)
+	assert.Equal(t, map[string]int{"a": 2, "b": 2, "c": 1}, c.RemoveAttempts, "auth and not found errors are not retried")
+	assert.Equal(t, 2, c.Connects)
+}
+
+func TestRemoveEligibleTorrents_ArchivePath(t *testing.T) {
+	removalDelay = 0
+	t.Cleanup(func() { removalDelay = time.Second })
+
+	downloadPath := t.TempDir()
+	archivePath := t.TempDir()
+
+	source := filepath.Join(downloadPath, "show", "episode.mkv")
+	require.NoError(t, os.MkdirAll(filepath.Dir(source), 0755))
+	require.NoError(t, os.WriteFile(source, []byte("data"), 0644))
+
+	filter := &config.FilterConfiguration{
+		Remove:      []string{`Label == "remove"`},
+		ArchivePath: archivePath,
+	}
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Label: "remove", Downloaded: true, Path: downloadPath, Files: []string{source}},
+	}
+	archived := filepath.Join(archivePath, "show", "episode.mkv")
+
+	// dry-run leaves the archive untouched
+	assert.Equal(t, []string{"a"}, runRemove(t, true, filter, 0, torrents))
+	assert.NoFileExists(t, archived)
+
+	assert.Equal(t, []string{"a"}, runRemove(t, false, filter, 0, torrents))
+
+	sourceInfo, err := os.Stat(source)
+	require.NoError(t, err)
+	archivedInfo, err := os.Stat(archived)
+	require.NoError(t, err)
+	assert.True(t, os.SameFile(sourceInfo, archivedInfo), "archived file should be a hardlink of the source")
+}
+
+func TestRelabelEligibleTorrents(t *testing.T) {
+	relabelDelay = 0
+	t.Cleanup(func() { relabelDelay = 5 * time.Second })
+
+	filter := &config.FilterConfiguration{}
+	filter.Label = make([]struct {
+		Name     string
+		Priority int
+		Update   []string
+	}, 1)
+	filter.Label[0].Name = "archive"
+	filter.Label[0].Update = []string{`Label == "tv"`}
+
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Label: "tv", Files: []string{"/data/a"}},
+		// b and c are cross-seeds of each other, so relabeling either would move files the other depends on
+		"b": {Hash: "b", Name: "b", Label: "tv", Files: []string{"/data/x"}},
+		"c": {Hash: "c", Name: "c", Label: "movies", Files: []string{"/data/x"}},
+		"d": {Hash: "d", Name: "d", Label: "archive", Files: []string{"/data/d"}},
+	}
+
+	for _, dryRun := range []bool{false, true} {
+		flagDryRun = dryRun
+		t.Cleanup(func() { flagDryRun = false })
+
+		c := newMockClient(t, filter, 0, torrents)
+		noti := &recordingSender{}
+		err := relabelEligibleTorrents(context.Background(), logger.GetLogger("test"), c, torrents, torrentfilemap.New(torrents),
+			noti, "test", time.Now())
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"a"}, noti.hashes)
+		if dryRun {
+			assert.Empty(t, c.Labels, "dry-run should not relabel torrents")
+		} else {
+			assert.Equal(t, map[string]string{"a": "archive"}, c.Labels)
+		}
+	}
+}
+
+func TestRetagEligibleTorrents(t *testing.T) {
+	uploadKb := 100
+	filter := &config.FilterConfiguration{}
+	filter.Tag = make([]struct {
+		Name     string
+		Mode     string
+		UploadKb *int `mapstructure:"uploadKb"`
+		Priority int
+		Update   []string
+	}, 1)
+	filter.Tag[0].Name = "old"
+	filter.Tag[0].Mode = "full"
+	filter.Tag[0].UploadKb = &uploadKb
+	filter.Tag[0].Update = []string{`SeedingDays > 10`}
+
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", SeedingDays: 20, Tags: []string{"keep"}},
+		"b": {Hash: "b", Name: "b", SeedingDays: 1, Tags: []string{"old", "keep"}},
+		"c": {Hash: "c", Name: "c", SeedingDays: 1},
+	}
+
+	for _, dryRun := range []bool{false, true} {
+		flagDryRun = dryRun
+		t.Cleanup(func() { flagDryRun = false })
+
+		c := newMockClient(t, filter, 0, torrents)
+		noti := &recordingSender{}
+		err := retagEligibleTorrents(context.Background(), logger.GetLogger("test"), c, torrents, noti, "test", time.Now())
+		require.NoError(t, err)
+
+		sort.Strings(noti.hashes)
+		assert.Equal(t, []string{"a", "b"}, noti.hashes)
+
+		a, _ := c.Torrent("a")
+		b, _ := c.Torrent("b")
+		if dryRun {
+			assert.Equal(t, []string{"keep"}, a.Tags, "dry-run should not retag torrents")
+			assert.Equal(t, []string{"old", "keep"}, b.Tags, "dry-run should not retag torrents")
+			assert.Empty(t, c.UploadLimits)
+		} else {
+			assert.Equal(t, []string{"keep", "old"}, a.Tags)
+			assert.Equal(t, []string{"keep"}, b.Tags)
+			assert.Equal(t, map[string]int64{"a": 100 * 1024}, c.UploadLimits)
+		}
+	}
+}
+
+func TestPauseEligibleTorrents(t *testing.T) {
+	filter := &config.FilterConfiguration{
+		Ignore: []string{`Label == "keep"`},
+		Pause:  []string{`Ratio > 2`},
+	}
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Ratio: 3},
+		"b": {Hash: "b", Name: "b", Ratio: 3, Label: "keep"},
+		"c": {Hash: "c", Name: "c", Ratio: 1},
+	}
+
+	for _, dryRun := range []bool{false, true} {
+		flagDryRun = dryRun
+		t.Cleanup(func() { flagDryRun = false })
+
+		c := newMockClient(t, filter, 0, torrents)
+		noti := &recordingSender{}
+		err := pauseEligibleTorrents(context.Background(), logger.GetLogger("test"), c, torrents, noti, "test", time.Now())
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"a"}, noti.hashes)
+		if dryRun {
+			assert.Empty(t, c.Paused, "dry-run should not pause torrents")
+		} else {
+			assert.Equal(t, []string{"a"}, c.Paused)
+		}
+	}
+}
+
+func TestTorrentContext(t *testing.T) {
+	ctx := context.Background()
+	torrent := &config.Torrent{Name: "slow"}
+
+	// disabled by default
+	tctx, done := torrentContext(ctx)
+	_, hasDeadline := tctx.Deadline()
+	assert.False(t, hasDeadline)
+	done(logger.GetLogger("test"), torrent)
+
+	flagTimeoutPerTorrent = 10 * time.Millisecond
+	t.Cleanup(func() { flagTimeoutPerTorrent = 0 })
+
+	// a hung tracker call is abandoned once the timeout passes
+	tctx, done = torrentContext(ctx)
+	_, hasDeadline = tctx.Deadline()
+	assert.True(t, hasDeadline)
+
+	select {
+	case <-tctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("torrent context did not time out")
+	}
+	assert.ErrorIs(t, tctx.Err(), context.DeadlineExceeded)
+	done(logger.GetLogger("test"), torrent)
+
+	// releasing the context cancels it
+	tctx, done = torrentContext(ctx)
+	done(logger.GetLogger("test"), torrent)
+	assert.ErrorIs(t, tctx.Err(), context.Canceled)
+	assert.NoError(t, ctx.Err())
+}
+
+func TestSkipMoving(t *testing.T) {
+	removalDelay = 0
+	t.Cleanup(func() {
+		removalDelay = time.Second
+		config.Config.SkipMoving = false
+	})
+
+	filter := &config.FilterConfiguration{
+		Remove: []string{`Ratio > 1`},
+		Pause:  []string{`Ratio > 1`},
+	}
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Ratio: 2, State: "stalledUP", Files: []string{"/data/a"}},
+		"b": {Hash: "b", Name: "b", Ratio: 2, State: "moving", Files: []string{"/data/b"}},
+	}
+
+	for _, skip := range []bool{false, true} {
+		t.Run(fmt.Sprintf("skip_moving_%t", skip), func(t *testing.T) {
+			config.Config.SkipMoving = skip
+			expected := []string{"a", "b"}
+			if skip {
+				expected = []string{"a"}
+			}
+
+			assert.Equal(t, expected, runRemove(t, false, filter, 0, torrents))
+
+			c := newMockClient(t, filter, 0, torrents)
+			noti := &recordingSender{}
+			err := pauseEligibleTorrents(context.Background(), logger.GetLogger("test"), c, torrents, noti, "test", time.Now())
+			require.NoError(t, err)
+			assert.ElementsMatch(t, expected, c.Paused)
+		})
+	}
+}
+
+func TestRemoveEligibleTorrents_VerifyRemoval(t *testing.T) {
+	removalDelay, verifyRemovalDelay = 0, 0
+	t.Cleanup(func() { removalDelay, verifyRemovalDelay = time.Second, 5*time.Second })
+
+	for _, removeLeftovers := range []bool{false, true} {
+		t.Run(fmt.Sprintf("remove_leftovers_%t", removeLeftovers), func(t *testing.T) {
+			dir := t.TempDir()
+			file := filepath.Join(dir, "a", "a.mkv")
+			require.NoError(t, os.MkdirAll(filepath.Dir(file), 0755))
+			require.NoError(t, os.WriteFile(file, []byte("a"), 0644))
+
+			filter := &config.FilterConfiguration{
+				Remove:          []string{`Ratio > 1`},
+				VerifyRemoval:   true,
+				RemoveLeftovers: removeLeftovers,
+			}
+			// the mock client never deletes data, so every file is left over
+			torrents := map[string]config.Torrent{
+				"a": {Hash: "a", Name: "a", Ratio: 2, Path: dir, Files: []string{file, filepath.Join(dir, "a", "gone.nfo")}},
+			}
+
+			c := newMockClient(t, filter, 0, torrents)
+			noti := &recordingSender{}
+			err := removeEligibleTorrents(context.Background(), logger.GetLogger("test"), c, torrents, torrentfilemap.New(torrents),
+				hardlinkfilemap.NewNoopHardlinkFileMap(), filter, noti, "test", time.Now(), nil)
+			require.NoError(t, err)
+
+			assert.Equal(t, []string{"a"}, c.Removed)
+			require.Len(t, noti.reasons, 1)
+			if removeLeftovers {
+				assert.NoFileExists(t, file)
+				assert.NotContains(t, noti.reasons[0], "left on disk")
+			} else {
+				assert.FileExists(t, file)
+				assert.Contains(t, noti.reasons[0], "(1 file(s) left on disk)")
+			}
+		})
+	}
+}
+
+func TestVerifyDataRemoved_PathMapping(t *testing.T) {
+	verifyRemovalDelay = 0
+	t.Cleanup(func() { verifyRemovalDelay = 5 * time.Second })
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.mkv")
+	require.NoError(t, os.WriteFile(file, []byte("a"), 0644))
+
+	// the client reports its own path, the file is on this host below dir
+	mapping, err := pathmapping.New(map[string]string{"/downloads": dir})
+	require.NoError(t, err)
+	torrent := &config.Torrent{Name: "a", Files: []string{"/downloads/a.mkv"}}
+
+	assert.Equal(t, []string{file}, verifyDataRemoved(logger.GetLogger("test"), torrent, mapping, false))
+	assert.FileExists(t, file)
+
+	assert.Empty(t, verifyDataRemoved(logger.GetLogger("test"), torrent, mapping, true))
+	assert.NoFileExists(t, file)
+}
+
+func TestRemoveEligibleTorrents_Concurrency(t *testing.T) {
+	removalDelay = 0
+	t.Cleanup(func() { removalDelay = time.Second })
+
+	filter := &config.FilterConfiguration{
+		Ignore: []string{`Label == "keep"`},
+		Remove: []string{`Label == "remove"`},
+	}
+	torrents := map[string]config.Torrent{
+		// a and b share a file, both removable, so they are removed as candidates keeping the data
+		"a": {Hash: "a", Name: "a", Label: "remove", Downloaded: true, Files: []string{"/data/x"}},
+		"b": {Hash: "b", Name: "b", Label: "remove", Downloaded: true, Files: []string{"/data/x"}},
+		// d shares a file with the ignored e, so it must be kept
+		"d": {Hash: "d", Name: "d", Label: "remove", Downloaded: true, Files: []string{"/data/z"}},
+		"e": {Hash: "e", Name: "e", Label: "keep", Downloaded: true, Files: []string{"/data/z"}},
+	}
+	for i := range 8 {
+		h := fmt.Sprintf("u%d", i)
+		torrents[h] = config.Torrent{Hash: h, Name: h, Label: "remove", Downloaded: true, Files: []string{"/data/" + h}}
+	}
+
+	for _, concurrency := range []int{1, 4} {
+		t.Run(fmt.Sprintf("concurrency_%d", concurrency), func(t *testing.T) {
+			config.Config.Removal.Concurrency = concurrency
+			t.Cleanup(func() { config.Config.Removal.Concurrency = 0 })
+
+			c := newMockClient(t, filter, 0, torrents)
+			working, err := c.GetTorrents(context.Background())
+			require.NoError(t, err)
+
+			noti := &recordingSender{}
+			err = removeEligibleTorrents(context.Background(), logger.GetLogger("test"), c, working, torrentfilemap.New(working),
+				hardlinkfilemap.NewNoopHardlinkFileMap(), filter, noti, "test", time.Now(), nil)
+			require.NoError(t, err)
+
+			expected := []string{"a", "b", "u0", "u1", "u2", "u3", "u4", "u5", "u6", "u7"}
+			assert.ElementsMatch(t, expected, c.Removed)
+			assert.ElementsMatch(t, expected, noti.hashes)
+			for _, h := range expected {
+				assert.Equal(t, h[0] == 'u', c.RemovedData[h], "only unique torrents should be removed with data: %s", h)
+			}
+			assert.Equal(t, []string{"d"}, slices.Collect(maps.Keys(working)), "only the kept torrent should be left for later steps")
+		})
+	}
+}
+
+func TestDeleteDataForTorrent(t *testing.T) {
+	deleteFalse := false
+	backup := &config.FilterConfiguration{DeleteDataIfPath: []string{"/mnt/backup", `D:\Backup`}}
+
+	tests := []struct {
+		name       string
+		filter     *config.FilterConfiguration
+		path       string
+		deleteData bool
+		expected   bool
+	}{
+		{name: "no_filter", path: "/mnt/primary", deleteData: true, expected: true},
+		{name: "no_paths_uses_default", filter: &config.FilterConfiguration{DeleteData: &deleteFalse}, path: "/mnt/backup", expected: false},
+		{name: "below_path", filter: backup, path: "/mnt/backup/movies", deleteData: false, expected: true},
+		{name: "exact_path", filter: backup, path: "/mnt/backup", deleteData: false, expected: true},
+		{name: "windows_path", filter: backup, path: `d:\backup\tv`, deleteData: false, expected: true},
+		{name: "other_path", filter: backup, path: "/mnt/primary/movies", deleteData: true, expected: false},
+		{name: "sibling_prefix", filter: backup, path: "/mnt/backup2/movies", deleteData: true, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			torrent := &config.Torrent{Path: tt.path}
+			assert.Equal(t, tt.expected, deleteDataForTorrent(tt.filter, torrent, tt.deleteData))
+		})
+	}
+}
+
+func TestDeleteDataForTorrent_TrackerPolicy(t *testing.T) {
+	setupTestConfig()
+	deleteFalse, deleteTrue := false, true
+	config.Config.TrackerPolicies = map[string]config.TrackerPolicy{
+		"keep.tracker":  {DeleteData: &deleteFalse},
+		"other.tracker": {DeleteData: &deleteTrue},
+	}
+	t.Cleanup(func() { config.Config.TrackerPolicies = nil })
+
+	backup := &config.FilterConfiguration{DeleteDataIfPath: []string{"/mnt/backup"}}
+
+	tests := []struct {
+		name       string
+		filter     *config.FilterConfiguration
+		tracker    string
+		deleteData bool
+		expected   bool
+	}{
+		{name: "keeps_over_filter", tracker: "keep.tracker", deleteData: true, expected: false},
+		{name: "keeps_over_path", filter: backup, tracker: "keep.tracker", deleteData: true, expected: false},
+		{name: "true_defers_to_filter", tracker: "other.tracker", deleteData: false, expected: false},
+		{name: "true_defers_to_path", filter: backup, tracker: "other.tracker", deleteData: true, expected: true},
+		{name: "no_policy", tracker: "some.tracker", deleteData: true, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			torrent := &config.Torrent{Path: "/mnt/backup/movies", TrackerName: tt.tracker}
+			assert.Equal(t, tt.expected, deleteDataForTorrent(tt.filter, torrent, tt.deleteData))
+		})
+	}
+}
+
+func TestRemoveEligibleTorrents_TrackerPolicy(t *testing.T) {
+	removalDelay = 0
+	setupTestConfig()
+	deleteFalse := false
+	config.Config.TrackerPolicies = map[string]config.TrackerPolicy{"keep.tracker": {DeleteData: &deleteFalse}}
+	t.Cleanup(func() {
+		removalDelay = time.Second
+		config.Config.TrackerPolicies = nil
+	})
+
+	filter := &config.FilterConfiguration{Remove: []string{`Label == "remove"`}}
+	torrents := map[string]config.Torrent{
+		"keep":  {Hash: "keep", Name: "keep", Label: "remove", TrackerName: "keep.tracker", Files: []string{"/data/keep"}},
+		"other": {Hash: "other", Name: "other", Label: "remove", TrackerName: "other.tracker", Files: []string{"/data/other"}},
+	}
+
+	c := newMockClient(t, filter, 0, torrents)
+	working, err := c.GetTorrents(context.Background())
+	require.NoError(t, err)
+
+	err = removeEligibleTorrents(context.Background(), logger.GetLogger("test"), c, working, torrentfilemap.New(working),
+		hardlinkfilemap.NewNoopHardlinkFileMap(), filter, &recordingSender{}, "test", time.Now(), nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]bool{"keep": false, "other": true}, c.RemovedData)
+}
+
+func TestRemoveEligibleTorrents_DeleteDataIfPath(t *testing.T) {
+	removalDelay = 0
+	t.Cleanup(func() { removalDelay = time.Second })
+
+	filter := &config.FilterConfiguration{
+		Remove:           []string{`Label == "remove"`},
+		DeleteDataIfPath: []string{"/mnt/backup"},
+	}
+	torrents := map[string]config.Torrent{
+		"backup":  {Hash: "backup", Name: "backup", Label: "remove", Downloaded: true, Path: "/mnt/backup", Files: []string{"/mnt/backup/a"}},
+		"primary": {Hash: "primary", Name: "primary", Label: "remove", Downloaded: true, Path: "/mnt/primary", Files: []string{"/mnt/primary/b"}},
+		// overlapping torrents keep their data wherever they are saved
+		"x1": {Hash: "x1", Name: "x1", Label: "remove", Downloaded: true, Path: "/mnt/backup", Files: []string{"/mnt/backup/x"}},
+		"x2": {Hash: "x2", Name: "x2", Label: "remove", Downloaded: true, Path: "/mnt/backup", Files: []string{"/mnt/backup/x"}},
+	}
+
+	c := newMockClient(t, filter, 0, torrents)
+	working, err := c.GetTorrents(context.Background())
+	require.NoError(t, err)
+
+	err = removeEligibleTorrents(context.Background(), logger.GetLogger("test"), c, working, torrentfilemap.New(working),
+		hardlinkfilemap.NewNoopHardlinkFileMap(), filter, &recordingSender{}, "test", time.Now(), nil)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"backup", "primary", "x1", "x2"}, c.Removed)
+	assert.Equal(t, map[string]bool{"backup": true, "primary": false, "x1": false, "x2": false}, c.RemovedData)
+}
+
+func TestRemoveEligibleTorrents_FreeSpaceDescription(t *testing.T) {
+	removalDelay = 0
+	t.Cleanup(func() { removalDelay = time.Second })
+
+	filter := &config.FilterConfiguration{Remove: []string{`Label == "remove"`}}
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Label: "remove", DownloadedBytes: 2 * humanize.GiByte, Files: []string{"/data/a"}},
+		"b": {Hash: "b", Name: "b", Label: "keep", DownloadedBytes: humanize.GiByte, Files: []string{"/data/b"}},
+	}
+
+	c := newMockClient(t, filter, 10, torrents)
+	working, err := c.GetTorrents(context.Background())
+	require.NoError(t, err)
+
+	noti := &descriptionSender{}
+	err = removeEligibleTorrents(context.Background(), logger.GetLogger("test"), c, working, torrentfilemap.New(working),
+		hardlinkfilemap.NewNoopHardlinkFileMap(), filter, noti, "test", time.Now(), nil)
+	require.NoError(t, err)
+
+	require.Len(t, noti.descriptions, 1)
+	assert.Equal(t, "Removed **1** torrent(s) | Total reclaimed **2.0 GiB** | Free space **10.00 GB** → **12.00 GB**",
+		noti.descriptions[0])
+}
+
+func TestRemovalSettings(t *testing.T) {
+	delay := 2 * time.Second
+	config.Config.Removal = config.RemovalConfig{Concurrency: 4, Delay: &delay}
+	t.Cleanup(func() { config.Config.Removal = config.RemovalConfig{} })
+
+	gotDelay, gotConcurrency := removalSettings(logger.GetLogger("test"), &config.FilterConfiguration{Remove: []string{`Ratio > 2`}})
+	assert.Equal(t, delay, gotDelay)
+	assert.Equal(t, 4, gotConcurrency)
+
+	_, gotConcurrency = removalSettings(logger.GetLogger("test"), &config.FilterConfiguration{Remove: []string{`FreeSpaceGB() < 10`}})
+	assert.Equal(t, 1, gotConcurrency, "free space filters need the removals in order")
+
+	config.Config.Removal = config.RemovalConfig{}
+	gotDelay, gotConcurrency = removalSettings(logger.GetLogger("test"), nil)
+	assert.Equal(t, removalDelay, gotDelay)
+	assert.Equal(t, 1, gotConcurrency)
+}
+
+func TestRelabelSettings(t *testing.T) {
+	assert.Equal(t, relabelDelay, relabelSettings())
+
+	delay := time.Duration(0)
+	config.Config.RelabelDelay = &delay
+	t.Cleanup(func() { config.Config.RelabelDelay = nil })
+	assert.Equal(t, time.Duration(0), relabelSettings())
+}
+
+func TestRecoverErroredTorrents(t *testing.T) {
+	filter := &config.FilterConfiguration{
+		Ignore: []string{`Label == "keep"`},
+	}
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", State: "missingFiles"},
+		"b": {Hash: "b", Name: "b", State: "error", Label: "keep"},
+		"c": {Hash: "c", Name: "c", State: "stalledUP"},
+		"d": {Hash: "d", Name: "d", State: "error"},
+	}
+
+	for _, dryRun := range []bool{false, true} {
+		flagDryRun = dryRun
+		t.Cleanup(func() { flagDryRun = false })
+
+		c := newMockClient(t, filter, 0, torrents)
+		c.Incomplete = map[string]bool{"d": true}
+		noti := &recordingSender{}
+		err := recoverErroredTorrents(context.Background(), logger.GetLogger("test"), c, torrents, noti, "test", time.Now())
+		require.NoError(t, err)
+
+		sort.Strings(noti.hashes)
+		assert.Equal(t, []string{"a", "d"}, noti.hashes)
+		if dryRun {
+			assert.Empty(t, c.Rechecked, "dry-run should not recheck torrents")
+			assert.Empty(t, c.Resumed, "dry-run should not resume torrents")
+		} else {
+			sort.Strings(c.Rechecked)
+			sort.Strings(c.Resumed)
+			assert.Equal(t, []string{"a", "d"}, c.Rechecked)
+			assert.Equal(t, []string{"a", "d"}, c.Resumed, "incomplete torrents should resume to download the missing data")
+		}
+	}
+}
+
+func TestRemoveEligibleTorrents_Report(t *testing.T) {
+	removalDelay = 0
+	t.Cleanup(func() { removalDelay = time.Second })
+
+	var out bytes.Buffer
+	reportOutput = &out
+	flagReport = reportFormatJSON
+	flagFreeSpaceTarget = 13
+	t.Cleanup(func() {
+		reportOutput = os.Stdout
+		flagReport = ""
+		flagFreeSpaceTarget = 0
+	})
+
+	filter := &config.FilterConfiguration{
+		Remove: []string{`FreeSpaceGB() < 14`},
+	}
+	torrents := map[string]config.Torrent{
+		"c": {Hash: "c", Name: "c", Downloaded: true, DownloadedBytes: 2 * humanize.GiByte, Files: []string{"/data/c"}},
+		"a": {Hash: "a", Name: "a", Downloaded: true, DownloadedBytes: 2 * humanize.GiByte, Files: []string{"/data/a"}},
+		"b": {Hash: "b", Name: "b", Downloaded: true, DownloadedBytes: 2 * humanize.GiByte, Files: []string{"/data/b"}},
+		"d": {Hash: "d", Name: "d", Downloaded: true, DownloadedBytes: 2 * humanize.GiByte, Files: []string{"/data/d"}},
+	}
+
+	assert.Equal(t, []string{"a", "b", "c"}, runRemove(t, true, filter, 9, torrents))
+
+	var report removalReport
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+
+	require.Len(t, report.Steps, 3)
+	for i, step := range report.Steps {
+		assert.Equal(t, string(rune('a'+i)), step.Hash, "removals should be reported in evaluation order")
+		assert.Equal(t, int64(i+1)*2*humanize.GiByte, step.ReclaimedBytes)
+		require.NotNil(t, step.FreeSpaceGB)
+		assert.InDelta(t, 9+float64(i+1)*2, *step.FreeSpaceGB, 0.001)
+	}
+	assert.Equal(t, 2, report.TargetMetAt)
+
+	// the live run removes the same torrents without writing a report
+	out.Reset()
+	assert.Equal(t, []string{"a", "b", "c"}, runRemove(t, false, filter, 9, torrents))
+	assert.Empty(t, out.String())
+}
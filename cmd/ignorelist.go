@@ -0,0 +1,312 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/autobrr/tqm/pkg/client"
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/expression"
+	"github.com/autobrr/tqm/pkg/logger"
+	"github.com/autobrr/tqm/pkg/tracker"
+)
+
+var flagIgnoreListOutput string
+
+// ignoreList holds the hashes of ignore_list_file, torrents in it are always ignored
+var ignoreList map[string]string
+
+var ignoreListCmd = &cobra.Command{
+	Use:   "ignore-list",
+	Short: "Export and import lists of ignored torrent hashes",
+	Long: `These commands move curated ignore lists between tqm instances. Torrents whose hash is in the file set by
+ignore_list_file are always ignored, like torrents meeting the ignore filters.`,
+}
+
+var ignoreListExportCmd = &cobra.Command{
+	Use:   "export [CLIENT]",
+	Short: "Export the hashes of the torrents a client currently ignores",
+	Long: `This command writes the hash of every torrent meeting the client's ignore filters, or already in the ignore
+list, one per line followed by its name as a comment. Nothing is changed on the client.`,
+
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		// init core
+		if !initialized {
+			initCore(true)
+			initialized = true
+		}
+
+		// set log
+		log := logger.GetLogger("ignore-list")
+
+		// retrieve client object
+		clientName := args[0]
+		clientConfig, ok := config.Config.Clients[clientName]
+		if !ok {
+			log.Fatalf("No client configuration found for: %q", clientName)
+		}
+
+		// validate client is enabled
+		if err := validateClientEnabled(clientConfig); err != nil {
+			log.WithError(err).Fatal("Failed validating client is enabled")
+		}
+
+		// retrieve client type
+		clientType, err := getClientConfigString("type", clientConfig)
+		if err != nil {
+			log.WithError(err).Fatal("Failed determining client type")
+		}
+
+		// retrieve client filters
+		clientFilter, err := getClientFilter(clientName, clientConfig)
+		if err != nil {
+			log.WithError(err).Fatal("Failed retrieving client filter")
+		}
+
+		if flagFilterName != "" {
+			clientFilter, err = getFilter(flagFilterName)
+			if err != nil {
+				log.WithError(err).Fatal("Failed retrieving specified filter")
+			}
+		}
+
+		// compile client filters
+		exp, err := expression.Compile(clientFilter)
+		if err != nil {
+			log.WithError(err).Fatal("Failed compiling client filters")
+		}
+
+		// load client object
+		c, err := client.NewClient(*clientType, clientName, exp)
+		if err != nil {
+			log.WithError(err).Fatalf("Failed initializing client: %q", clientName)
+		}
+
+		log.Infof("Initialized client %q, type: %s (%d trackers)", clientName, c.Type(), tracker.Loaded())
+
+		// connect to client
+		if err := c.Connect(ctx); err != nil {
+			log.WithError(err).Fatal("Failed connecting")
+		} else {
+			log.Debugf("Connected to client")
+		}
+
+		// retrieve torrents
+		torrents, err := c.GetTorrents(ctx)
+		if err != nil {
+			log.WithError(err).Fatal("Failed retrieving torrents")
+		} else {
+			log.Infof("Retrieved %d torrents", len(torrents))
+		}
+
+		ignored, err := ignoredTorrents(ctx, c, torrents)
+		if err != nil {
+			log.WithError(err).Fatal("Failed evaluating ignore filters")
+		}
+
+		if flagIgnoreListOutput == "" {
+			if err := writeIgnoreList(os.Stdout, ignored); err != nil {
+				log.WithError(err).Fatal("Failed writing ignore list")
+			}
+			return
+		}
+
+		if err := saveIgnoreList(flagIgnoreListOutput, ignored); err != nil {
+			log.WithError(err).Fatal("Failed writing ignore list")
+		}
+
+		log.Infof("Exported %d ignored torrent(s) to %s", len(ignored), flagIgnoreListOutput)
+	},
+}
+
+var ignoreListImportCmd = &cobra.Command{
+	Use:   "import [FILE]",
+	Short: "Merge a list of hashes into the ignore list",
+	Long: `This command adds the hashes of FILE, e.g. written by ignore-list export on another instance, to the file set
+by ignore_list_file. Hashes already in the ignore list keep their comment.`,
+
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		// init core
+		if !initialized {
+			initCore(true)
+			initialized = true
+		}
+
+		// set log
+		log := logger.GetLogger("ignore-list")
+
+		if config.Config.IgnoreListFile == "" {
+			log.Fatal("No ignore_list_file is configured")
+		}
+
+		imported, err := loadIgnoreListFile(args[0])
+		if err != nil {
+			log.WithError(err).Fatalf("Failed reading: %s", args[0])
+		}
+
+		added := mergeIgnoreList(ignoreList, imported)
+		if flagDryRun {
+			log.Infof("[DRY-RUN] Would add %d of %d hash(es) to %s", added, len(imported), config.Config.IgnoreListFile)
+			return
+		}
+
+		if err := saveIgnoreList(config.Config.IgnoreListFile, ignoreList); err != nil {
+			log.WithError(err).Fatal("Failed writing ignore list")
+		}
+
+		log.Infof("Added %d of %d hash(es) to %s (%d total)", added, len(imported), config.Config.IgnoreListFile,
+			len(ignoreList))
+	},
+}
+
+func init() {
+	ignoreListExportCmd.Flags().StringVar(&flagFilterName, "filter", "", "Filter to use instead of client")
+	ignoreListExportCmd.Flags().StringVarP(&flagIgnoreListOutput, "output", "o", "", "Write the list to a file instead of stdout")
+
+	ignoreListCmd.AddCommand(ignoreListExportCmd)
+	ignoreListCmd.AddCommand(ignoreListImportCmd)
+	rootCmd.AddCommand(ignoreListCmd)
+}
+
+// initIgnoreList loads the configured ignore list, a missing file is an empty list
+func initIgnoreList(path string) error {
+	ignoreList = map[string]string{}
+	if path == "" {
+		return nil
+	}
+
+	list, err := loadIgnoreListFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	ignoreList = list
+	return nil
+}
+
+// ignoreListed reports whether t is in the ignore list
+func ignoreListed(t *config.Torrent) bool {
+	_, ok := ignoreList[strings.ToLower(t.Hash)]
+	return ok
+}
+
+// shouldIgnore reports whether t is in the ignore list or meets the ignore filters of c
+func shouldIgnore(ctx context.Context, c client.Interface, t *config.Torrent) (bool, error) {
+	if ignoreListed(t) {
+		return true, nil
+	}
+
+	return c.ShouldIgnore(ctx, t)
+}
+
+// ignoredTorrents returns the hash and name of every torrent that is ignored
+func ignoredTorrents(ctx context.Context, c client.Interface, torrents map[string]config.Torrent) (map[string]string, error) {
+	ignored := make(map[string]string)
+	for _, t := range torrents {
+		tctx, done := torrentContext(ctx)
+		ignore, err := shouldIgnore(tctx, c, &t)
+		done(log, &t)
+		if err != nil {
+			return nil, fmt.Errorf("ignore filters: %v: %w", t.Name, err)
+		} else if ignore {
+			ignored[strings.ToLower(t.Hash)] = t.Name
+		}
+	}
+
+	return ignored, nil
+}
+
+// loadIgnoreListFile reads the ignore list stored in path
+func loadIgnoreListFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open ignore list: %w", err)
+	}
+	defer f.Close()
+
+	return readIgnoreList(f)
+}
+
+// readIgnoreList parses an ignore list, a hash per line optionally followed by a comment. Blank lines and lines
+// starting with # are skipped
+func readIgnoreList(r io.Reader) (map[string]string, error) {
+	list := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		hash, comment, _ := strings.Cut(text, "#")
+		hash = strings.ToLower(strings.TrimSpace(hash))
+		if strings.ContainsAny(hash, " \t") {
+			return nil, fmt.Errorf("line %d: invalid hash: %q", line, hash)
+		}
+
+		list[hash] = strings.TrimSpace(comment)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read ignore list: %w", err)
+	}
+
+	return list, nil
+}
+
+// writeIgnoreList writes an ignore list sorted by hash, with the comment of each hash after it
+func writeIgnoreList(w io.Writer, list map[string]string) error {
+	for _, hash := range slices.Sorted(maps.Keys(list)) {
+		line := hash
+		if comment := list[hash]; comment != "" {
+			line += " # " + comment
+		}
+
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// saveIgnoreList replaces the ignore list stored in path
+func saveIgnoreList(path string, list map[string]string) error {
+	var b strings.Builder
+	if err := writeIgnoreList(&b, list); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, []byte(b.String()))
+}
+
+// mergeIgnoreList adds the hashes of src missing from dst, returning the number added
+func mergeIgnoreList(dst, src map[string]string) int {
+	added := 0
+	for hash, comment := range src {
+		if _, ok := dst[hash]; ok {
+			continue
+		}
+
+		dst[hash] = comment
+		added++
+	}
+
+	return added
+}
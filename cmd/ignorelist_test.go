@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/hardlinkfilemap"
+	"github.com/autobrr/tqm/pkg/logger"
+	"github.com/autobrr/tqm/pkg/torrentfilemap"
+)
+
+func TestReadIgnoreList(t *testing.T) {
+	list, err := readIgnoreList(strings.NewReader(`# exported from qbt
+
+ABCDEF # Some.Torrent
+  012345
+`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"abcdef": "Some.Torrent", "012345": ""}, list)
+
+	_, err = readIgnoreList(strings.NewReader("abc def\n"))
+	require.Error(t, err)
+}
+
+func TestSaveIgnoreList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ignore.txt")
+
+	require.NoError(t, saveIgnoreList(path, map[string]string{"b": "", "a": "A # name"}))
+
+	list, err := loadIgnoreListFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "A # name", "b": ""}, list)
+
+	// a missing ignore list is empty
+	require.NoError(t, initIgnoreList(filepath.Join(t.TempDir(), "missing.txt")))
+	assert.Empty(t, ignoreList)
+
+	require.NoError(t, initIgnoreList(path))
+	t.Cleanup(func() { ignoreList = nil })
+	assert.True(t, ignoreListed(&config.Torrent{Hash: "A"}))
+	assert.False(t, ignoreListed(&config.Torrent{Hash: "c"}))
+}
+
+func TestMergeIgnoreList(t *testing.T) {
+	dst := map[string]string{"a": "kept"}
+	added := mergeIgnoreList(dst, map[string]string{"a": "other", "b": "B"})
+
+	assert.Equal(t, 1, added)
+	assert.Equal(t, map[string]string{"a": "kept", "b": "B"}, dst)
+}
+
+func TestRemoveEligibleTorrents_IgnoreList(t *testing.T) {
+	removalDelay = 0
+	ignoreList = map[string]string{"a": ""}
+	t.Cleanup(func() {
+		removalDelay = time.Second
+		ignoreList = nil
+	})
+
+	filter := &config.FilterConfiguration{Remove: []string{`Label == "remove"`}}
+	c := newMockClient(t, filter, 0, map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Label: "remove", Files: []string{"/data/a"}},
+		"b": {Hash: "b", Name: "b", Label: "remove", Files: []string{"/data/b"}},
+	})
+
+	working, err := c.GetTorrents(context.Background())
+	require.NoError(t, err)
+
+	err = removeEligibleTorrents(context.Background(), logger.GetLogger("test"), c, working, torrentfilemap.New(working),
+		hardlinkfilemap.NewNoopHardlinkFileMap(), filter, &recordingSender{}, "test", time.Now(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, c.Removed)
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/autobrr/tqm/pkg/config"
+)
+
+// lastRunState is the summary of a run written to last_run_file, for external monitoring
+type lastRunState struct {
+	Command        string         `json:"command"`
+	Clients        []string       `json:"clients"`
+	StartedAt      time.Time      `json:"started_at"`
+	FinishedAt     time.Time      `json:"finished_at"`
+	DryRun         bool           `json:"dry_run"`
+	Success        bool           `json:"success"`
+	Counts         map[string]int `json:"counts"`
+	ReclaimedBytes int64          `json:"reclaimed_bytes"`
+	Errors         int            `json:"errors"`
+	// Torrents is the number of torrents retrieved from each client, kept from earlier runs for clients this run
+	// did not retrieve
+	Torrents map[string]int `json:"torrents,omitempty"`
+}
+
+// lastRunRecorder collects the results of the current run
+type lastRunRecorder struct {
+	mu      sync.Mutex
+	state   lastRunState
+	written bool
+}
+
+// lastRun records the results of this process
+var lastRun = &lastRunRecorder{}
+
+// lastRunCommands are the commands acting on torrents, only they write last_run_file so read-only commands such as
+// query or explain don't replace the summary of the last real run
+var lastRunCommands = map[string]bool{
+	"apply":      true,
+	"clean":      true,
+	"orphan":     true,
+	"pause":      true,
+	"recover":    true,
+	"relabel":    true,
+	"rename-tag": true,
+	"retag":      true,
+}
+
+// start resets the recorder for a new run of command
+func (r *lastRunRecorder) start(command string, startedAt time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.state = lastRunState{
+		Command:   command,
+		Clients:   []string{},
+		StartedAt: startedAt,
+		Counts:    map[string]int{},
+		Torrents:  map[string]int{},
+	}
+	r.written = false
+}
+
+// recordTorrents stores the number of torrents retrieved from client
+func (r *lastRunRecorder) recordTorrents(client string, count int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.state.Torrents == nil {
+		r.state.Torrents = map[string]int{}
+	}
+
+	r.state.Torrents[client] = count
+}
+
+// dropTorrents lowers the torrent count of client by the torrents this run removed, so the removals don't count as a
+// drop against max_drop on the next run
+func (r *lastRunRecorder) dropTorrents(client string, removed int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if count, ok := r.state.Torrents[client]; ok {
+		r.state.Torrents[client] = max(count-removed, 0)
+	}
+}
+
+// record adds the outcome of an action against a client
+func (r *lastRunRecorder) record(client, action string, count, failures int, reclaimed int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.state.Counts == nil {
+		r.state.Counts = map[string]int{}
+	}
+
+	if client != "" && !slices.Contains(r.state.Clients, client) {
+		r.state.Clients = append(r.state.Clients, client)
+	}
+
+	r.state.Counts[action] += count
+	r.state.Errors += failures
+	r.state.ReclaimedBytes += reclaimed
+}
+
+// write stores the run state in path, only the first call of a run of a command in lastRunCommands writes
+func (r *lastRunRecorder) write(path string, finishedAt time.Time, success bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.written || !lastRunCommands[r.state.Command] {
+		return nil
+	}
+	r.written = true
+
+	state := r.state
+	state.FinishedAt = finishedAt
+	state.DryRun = flagDryRun
+	state.Success = success
+
+	// keep the torrent counts of clients this run did not retrieve, they are the baseline of the next run
+	state.Torrents = map[string]int{}
+	if previous, err := readLastRun(path); err == nil && previous != nil {
+		maps.Copy(state.Torrents, previous.Torrents)
+	}
+	maps.Copy(state.Torrents, r.state.Torrents)
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal last run state: %w", err)
+	}
+
+	return writeFileAtomic(path, append(data, '\n'))
+}
+
+// readLastRun reads the state written to path by an earlier run, nil when there is none
+func readLastRun(path string) (*lastRunState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read last run file: %w", err)
+	}
+
+	var state lastRunState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("unmarshal last run file: %w", err)
+	}
+
+	return &state, nil
+}
+
+// writeLastRun writes the last run state when last_run_file is configured
+func writeLastRun(success bool) {
+	if config.Config == nil || config.Config.LastRunFile == "" {
+		return
+	}
+
+	if err := lastRun.write(config.Config.LastRunFile, now(), success); err != nil {
+		log.WithError(err).Error("Failed writing last run file")
+	}
+}
+
+// writeFileAtomic replaces path with data, so readers never see a partially written file
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("close temp file: %w", err)
+	}
+	if err := os.Chmod(f.Name(), 0o644); err != nil {
+		return fmt.Errorf("chmod temp file: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("rename temp file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLastRunRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "last-run.json")
+	startedAt := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	finishedAt := startedAt.Add(time.Minute)
+
+	r := &lastRunRecorder{}
+	r.start("clean", startedAt)
+	r.record("qbt", "removed", 2, 1, 1024)
+	r.record("deluge", "removed", 3, 0, 2048)
+	r.record("qbt", "relabeled", 1, 0, 0)
+
+	require.NoError(t, r.write(path, finishedAt, true))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var state lastRunState
+	require.NoError(t, json.Unmarshal(data, &state))
+
+	assert.Equal(t, "clean", state.Command)
+	assert.Equal(t, []string{"qbt", "deluge"}, state.Clients)
+	assert.True(t, state.StartedAt.Equal(startedAt))
+	assert.True(t, state.FinishedAt.Equal(finishedAt))
+	assert.True(t, state.Success)
+	assert.Equal(t, map[string]int{"removed": 5, "relabeled": 1}, state.Counts)
+	assert.Equal(t, int64(3072), state.ReclaimedBytes)
+	assert.Equal(t, 1, state.Errors)
+
+	// only the first write of a run is kept, a later fatal exit handler does not overwrite it
+	require.NoError(t, r.write(path, finishedAt, false))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &state))
+	assert.True(t, state.Success)
+
+	// no temp files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestLastRunRecorder_Failed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "last-run.json")
+
+	r := &lastRunRecorder{}
+	r.start("pause", time.Now())
+
+	require.NoError(t, r.write(path, time.Now(), false))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var state lastRunState
+	require.NoError(t, json.Unmarshal(data, &state))
+
+	assert.Equal(t, "pause", state.Command)
+	assert.False(t, state.Success)
+	assert.Empty(t, state.Clients)
+	assert.Empty(t, state.Counts)
+}
+
+func TestLastRunRecorder_ReadOnlyCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "last-run.json")
+
+	r := &lastRunRecorder{}
+	r.start("clean", time.Now())
+	require.NoError(t, r.write(path, time.Now(), true))
+
+	// a read-only command keeps the summary of the last run acting on torrents
+	r.start("query", time.Now())
+	require.NoError(t, r.write(path, time.Now(), false))
+
+	state, err := readLastRun(path)
+	require.NoError(t, err)
+	assert.Equal(t, "clean", state.Command)
+	assert.True(t, state.Success)
+}
+
+func TestLastRunRecorder_KeepsTorrentCounts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "last-run.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"torrents": {"qbt": 1000, "deluge": 50}}`), 0644))
+
+	r := &lastRunRecorder{}
+	r.start("clean", time.Now())
+	r.recordTorrents("qbt", 1010)
+	require.NoError(t, r.write(path, time.Now(), true))
+
+	state, err := readLastRun(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"qbt": 1010, "deluge": 50}, state.Torrents)
+}
+
+func TestLastRunRecorder_DropTorrents(t *testing.T) {
+	r := &lastRunRecorder{}
+	r.start("clean", time.Now())
+	r.recordTorrents("qbt", 1000)
+
+	// removals lower the baseline of the next run, clients not retrieved are left alone
+	r.dropTorrents("qbt", 600)
+	r.dropTorrents("deluge", 5)
+	assert.Equal(t, map[string]int{"qbt": 400}, r.state.Torrents)
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/tracker"
+)
+
+// tracker counters written to metrics_file, in the order they are written
+var trackerMetrics = []struct {
+	name string
+	help string
+}{
+	{name: "tqm_tracker_torrents_total", help: "Torrents evaluated by clean per tracker."},
+	{name: "tqm_tracker_unregistered_total", help: "Torrents found unregistered by clean per tracker."},
+	{name: "tqm_tracker_down_total", help: "Torrents whose tracker was down during clean per tracker."},
+	{name: "tqm_tracker_api_requests_total", help: "Tracker API requests per tracker."},
+	{name: "tqm_tracker_api_errors_total", help: "Failed tracker API requests per tracker."},
+}
+
+// metricLineRegex matches a sample written by writeMetrics
+var metricLineRegex = regexp.MustCompile(`^(\w+)\{tracker="((?:[^"\\]|\\.)*)"\} (\d+)$`)
+
+var (
+	labelEscaper   = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	labelUnescaper = strings.NewReplacer(`\\`, `\`, `\"`, `"`, `\n`, "\n")
+)
+
+// metricsRecorder collects the per tracker counters of the current run
+type metricsRecorder struct {
+	mu sync.Mutex
+	// counters maps a metric name to its value per tracker
+	counters map[string]map[string]int
+}
+
+// runMetrics records the counters of this process
+var runMetrics = newMetricsRecorder()
+
+func newMetricsRecorder() *metricsRecorder {
+	return &metricsRecorder{counters: make(map[string]map[string]int)}
+}
+
+func (m *metricsRecorder) add(name string, trackerName string, n int) {
+	if _, ok := m.counters[name]; !ok {
+		m.counters[name] = make(map[string]int)
+	}
+	m.counters[name][trackerName] += n
+}
+
+// observe counts an evaluated torrent under the same tracker label as its API requests
+func (m *metricsRecorder) observe(t *config.Torrent) {
+	if t.TrackerName == "" {
+		return
+	}
+
+	label := tracker.StatsKey(t.TrackerName)
+	unregistered := metricsUnregistered(t)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.add("tqm_tracker_torrents_total", label, 1)
+	if unregistered {
+		m.add("tqm_tracker_unregistered_total", label, 1)
+	}
+	if t.IsTrackerDown() {
+		m.add("tqm_tracker_down_total", label, 1)
+	}
+}
+
+// metricsUnregistered reports whether t is unregistered, as found by the filters when they checked it, or else by the
+// status messages of its trackers, so torrents no filter checked are counted without extra tracker API requests
+func metricsUnregistered(t *config.Torrent) bool {
+	switch t.RegistrationState {
+	case config.UnregisteredState:
+		return true
+	case config.RegisteredState, config.IntermediateState:
+		return false
+	}
+
+	return !t.IsTrackerDown() && !t.IsIntermediateStatus() && t.UnregisteredTrackerCount() > 0
+}
+
+// observeAPI counts the tracker API requests of the run
+func (m *metricsRecorder) observeAPI(stats map[string]tracker.APIStat) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for name, stat := range stats {
+		m.add("tqm_tracker_api_requests_total", name, stat.Requests)
+		m.add("tqm_tracker_api_errors_total", name, stat.Errors)
+	}
+}
+
+// write adds the counters of the run to those stored in path by earlier runs, and replaces path with the result in
+// the Prometheus text format
+func (m *metricsRecorder) write(path string) error {
+	previous, err := readMetrics(path)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for name, values := range m.counters {
+		if _, ok := previous[name]; !ok {
+			previous[name] = make(map[string]int)
+		}
+		for trackerName, n := range values {
+			previous[name][trackerName] += n
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	for _, metric := range trackerMetrics {
+		values := previous[metric.name]
+		if len(values) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(buf, "# HELP %s %s\n", metric.name, metric.help)
+		fmt.Fprintf(buf, "# TYPE %s counter\n", metric.name)
+		for _, trackerName := range slices.Sorted(maps.Keys(values)) {
+			fmt.Fprintf(buf, "%s{tracker=\"%s\"} %d\n", metric.name, labelEscaper.Replace(trackerName), values[trackerName])
+		}
+	}
+
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+// readMetrics reads the counters written to path by an earlier run, a missing file has none
+func readMetrics(path string) (map[string]map[string]int, error) {
+	counters := make(map[string]map[string]int)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return counters, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read metrics file: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		matches := metricLineRegex.FindStringSubmatch(scanner.Text())
+		if matches == nil {
+			continue
+		}
+
+		n, err := strconv.Atoi(matches[3])
+		if err != nil {
+			continue
+		}
+
+		if _, ok := counters[matches[1]]; !ok {
+			counters[matches[1]] = make(map[string]int)
+		}
+		counters[matches[1]][labelUnescaper.Replace(matches[2])] = n
+	}
+
+	return counters, scanner.Err()
+}
+
+// writeMetrics adds the counters of the clean run to metrics_file, when it is configured
+func writeMetrics(log *logrus.Entry) {
+	if config.Config == nil || config.Config.MetricsFile == "" {
+		return
+	}
+
+	runMetrics.observeAPI(tracker.APIStats())
+	if err := runMetrics.write(config.Config.MetricsFile); err != nil {
+		log.WithError(err).Error("Failed writing metrics file")
+	}
+}
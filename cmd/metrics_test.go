@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/tracker"
+)
+
+func TestMetricsRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tqm.prom")
+	config.InitializeTrackerStatuses(config.TrackerErrorsConfig{})
+
+	m := newMetricsRecorder()
+	m.observe(&config.Torrent{Hash: "A", TrackerName: "tracker.example"})
+	m.observe(&config.Torrent{Hash: "B", TrackerName: "tracker.example", RegistrationState: config.UnregisteredState})
+	m.observe(&config.Torrent{Hash: "C", TrackerName: `other"tracker`, TrackerStatus: "timed out"})
+	m.observe(&config.Torrent{Hash: "D"})
+	// not checked by a filter, its status message decides
+	m.observe(&config.Torrent{Hash: "E", TrackerName: "tracker.example", TrackerStatus: "Unregistered torrent"})
+	m.observe(&config.Torrent{Hash: "F", TrackerName: "tracker.example", TrackerStatus: "Unregistered torrent",
+		RegistrationState: config.RegisteredState})
+	m.observeAPI(map[string]tracker.APIStat{"BTN": {Requests: 3, Errors: 1}})
+
+	require.NoError(t, m.write(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `# HELP tqm_tracker_torrents_total Torrents evaluated by clean per tracker.
+# TYPE tqm_tracker_torrents_total counter
+tqm_tracker_torrents_total{tracker="other\"tracker"} 1
+tqm_tracker_torrents_total{tracker="tracker.example"} 4
+# HELP tqm_tracker_unregistered_total Torrents found unregistered by clean per tracker.
+# TYPE tqm_tracker_unregistered_total counter
+tqm_tracker_unregistered_total{tracker="tracker.example"} 2
+# HELP tqm_tracker_down_total Torrents whose tracker was down during clean per tracker.
+# TYPE tqm_tracker_down_total counter
+tqm_tracker_down_total{tracker="other\"tracker"} 1
+# HELP tqm_tracker_api_requests_total Tracker API requests per tracker.
+# TYPE tqm_tracker_api_requests_total counter
+tqm_tracker_api_requests_total{tracker="BTN"} 3
+# HELP tqm_tracker_api_errors_total Failed tracker API requests per tracker.
+# TYPE tqm_tracker_api_errors_total counter
+tqm_tracker_api_errors_total{tracker="BTN"} 1
+`, string(data))
+
+	t.Run("accumulates_across_runs", func(t *testing.T) {
+		next := newMetricsRecorder()
+		next.observe(&config.Torrent{Hash: "A", TrackerName: `other"tracker`})
+		next.observeAPI(map[string]tracker.APIStat{"BTN": {Requests: 2}})
+		require.NoError(t, next.write(path))
+
+		counters, err := readMetrics(path)
+		require.NoError(t, err)
+		assert.Equal(t, 2, counters["tqm_tracker_torrents_total"][`other"tracker`])
+		assert.Equal(t, 4, counters["tqm_tracker_torrents_total"]["tracker.example"])
+		assert.Equal(t, 5, counters["tqm_tracker_api_requests_total"]["BTN"])
+		assert.Equal(t, 1, counters["tqm_tracker_api_errors_total"]["BTN"])
+	})
+}
+
+func TestReadMetrics_MissingFile(t *testing.T) {
+	counters, err := readMetrics(filepath.Join(t.TempDir(), "missing.prom"))
+	require.NoError(t, err)
+	assert.Empty(t, counters)
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/hardlinkfilemap"
+	"github.com/autobrr/tqm/pkg/torrentfilemap"
+)
+
+// keptCopies are the candidates clean keeps so groups of cross-seeds keep min_copies seeding members
+type keptCopies struct {
+	// kept holds the hashes of the kept candidates
+	kept map[string]struct{}
+	// shared holds the hashes of the other candidates sharing files with a kept candidate, they are removed without
+	// their data as the kept candidate still uses it
+	shared map[string]struct{}
+}
+
+// keepCopies groups the torrents sharing files with the candidates of clean, by path in tfm or by hardlink in hfm, and
+// keeps seeding candidates of each group until the group has minCopies seeding members, or every seeding candidate of
+// groups with fewer. Seeding torrents that are not candidates count as copies. It must be called before the candidates
+// are removed from tfm and hfm
+func keepCopies(tfm *torrentfilemap.TorrentFileMap, hfm hardlinkfilemap.HardlinkFileMapI, candidates map[string]config.Torrent, minCopies int) *keptCopies {
+	k := &keptCopies{
+		kept:   make(map[string]struct{}),
+		shared: make(map[string]struct{}),
+	}
+	if minCopies <= 0 {
+		return k
+	}
+
+	// the torrents sharing files with each member of a group, candidate or not
+	sharing := make(map[string][]string)
+	torrents := maps.Clone(candidates)
+	sharingFiles := func(h string) []string {
+		if hashes, ok := sharing[h]; ok {
+			return hashes
+		}
+
+		hashes := []string{}
+		for _, other := range slices.Concat(tfm.GetTorrentsSharingFiles(torrents[h]), hfm.GetTorrentsSharingFiles(torrents[h])) {
+			if _, ok := torrents[other.Hash]; !ok {
+				torrents[other.Hash] = other
+			}
+			if !slices.Contains(hashes, other.Hash) {
+				hashes = append(hashes, other.Hash)
+			}
+		}
+		sharing[h] = hashes
+		return hashes
+	}
+
+	// walk each group in a stable order, so a dry-run keeps the same torrents as a live run
+	grouped := make(map[string]struct{}, len(candidates))
+	for _, h := range slices.Sorted(maps.Keys(candidates)) {
+		if _, ok := grouped[h]; ok || len(sharingFiles(h)) == 0 {
+			continue
+		}
+
+		var group []string
+		queue := []string{h}
+		grouped[h] = struct{}{}
+		for len(queue) > 0 {
+			cur := queue[0]
+			queue = queue[1:]
+			group = append(group, cur)
+
+			for _, other := range sharingFiles(cur) {
+				if _, ok := grouped[other]; !ok {
+					grouped[other] = struct{}{}
+					queue = append(queue, other)
+				}
+			}
+		}
+
+		// the seeding torrents clean does not remove are copies already, candidates are kept for the shortfall
+		copies := 0
+		for _, member := range group {
+			if _, ok := candidates[member]; !ok && torrents[member].Seeding {
+				copies++
+			}
+		}
+
+		slices.Sort(group)
+		for _, member := range group {
+			if _, ok := candidates[member]; ok && copies < minCopies && candidates[member].Seeding {
+				k.kept[member] = struct{}{}
+				copies++
+			}
+		}
+
+		for _, member := range group {
+			if _, ok := candidates[member]; !ok {
+				continue
+			}
+			if _, ok := k.kept[member]; ok {
+				continue
+			}
+
+			for _, other := range sharing[member] {
+				if _, ok := k.kept[other]; ok {
+					k.shared[member] = struct{}{}
+					break
+				}
+			}
+		}
+	}
+
+	return k
+}
+
+// isKept reports whether the candidate with hash is kept
+func (k *keptCopies) isKept(hash string) bool {
+	_, ok := k.kept[hash]
+	return ok
+}
+
+// sharesWithKept reports whether the candidate with hash shares files with a kept candidate
+func (k *keptCopies) sharesWithKept(hash string) bool {
+	_, ok := k.shared[hash]
+	return ok
+}
+
+// mergeCandidates returns the candidates of every map in one map
+func mergeCandidates(candidates ...map[string]config.Torrent) map[string]config.Torrent {
+	merged := make(map[string]config.Torrent)
+	for _, m := range candidates {
+		maps.Copy(merged, m)
+	}
+
+	return merged
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/hardlinkfilemap"
+	"github.com/autobrr/tqm/pkg/logger"
+	"github.com/autobrr/tqm/pkg/torrentfilemap"
+)
+
+func TestKeepCopies(t *testing.T) {
+	candidates := map[string]config.Torrent{
+		// a group of three seeding cross-seeds
+		"a": {Hash: "a", Seeding: true, Files: []string{"/data/x"}},
+		"b": {Hash: "b", Seeding: true, Files: []string{"/data/x"}},
+		"c": {Hash: "c", Seeding: true, Files: []string{"/data/x"}},
+		// a group of two where only one seeds
+		"d": {Hash: "d", Files: []string{"/data/y"}},
+		"e": {Hash: "e", Seeding: true, Files: []string{"/data/y"}},
+		// a chain, f and h only share files through g
+		"f": {Hash: "f", Seeding: true, Files: []string{"/data/z1"}},
+		"g": {Hash: "g", Seeding: true, Files: []string{"/data/z1", "/data/z2"}},
+		"h": {Hash: "h", Seeding: true, Files: []string{"/data/z2"}},
+		// a torrent sharing no files is not part of a group
+		"i": {Hash: "i", Seeding: true, Files: []string{"/data/unique"}},
+	}
+
+	tests := []struct {
+		name       string
+		minCopies  int
+		wantKept   []string
+		wantShared []string
+	}{
+		{name: "disabled", minCopies: 0},
+		{name: "one_copy", minCopies: 1, wantKept: []string{"a", "e", "f"}, wantShared: []string{"b", "c", "d", "g"}},
+		{name: "two_copies", minCopies: 2, wantKept: []string{"a", "b", "e", "f", "g"}, wantShared: []string{"c", "d", "h"}},
+		{name: "more_than_group", minCopies: 5, wantKept: []string{"a", "b", "c", "e", "f", "g", "h"}, wantShared: []string{"d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := keepCopies(torrentfilemap.New(candidates), hardlinkfilemap.NewNoopHardlinkFileMap(), candidates, tt.minCopies)
+			assert.ElementsMatch(t, tt.wantKept, slices.Collect(maps.Keys(k.kept)))
+			assert.ElementsMatch(t, tt.wantShared, slices.Collect(maps.Keys(k.shared)))
+		})
+	}
+}
+
+func TestKeepCopies_OtherTorrents(t *testing.T) {
+	candidates := map[string]config.Torrent{
+		"a": {Hash: "a", Seeding: true, Files: []string{"/data/x"}},
+		"b": {Hash: "b", Seeding: true, Files: []string{"/data/x"}},
+		"c": {Hash: "c", Seeding: true, Files: []string{"/data/y"}},
+		"d": {Hash: "d", Seeding: true, Files: []string{"/data/y"}},
+	}
+
+	// a seeding torrent clean keeps shares x, one that is not seeding shares y
+	torrents := maps.Clone(candidates)
+	torrents["keep-x"] = config.Torrent{Hash: "keep-x", Seeding: true, Files: []string{"/data/x"}}
+	torrents["keep-y"] = config.Torrent{Hash: "keep-y", Files: []string{"/data/y"}}
+
+	tests := []struct {
+		name      string
+		minCopies int
+		wantKept  []string
+	}{
+		{name: "one_copy", minCopies: 1, wantKept: []string{"c"}},
+		{name: "two_copies", minCopies: 2, wantKept: []string{"a", "c", "d"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := keepCopies(torrentfilemap.New(torrents), hardlinkfilemap.NewNoopHardlinkFileMap(), candidates, tt.minCopies)
+			assert.ElementsMatch(t, tt.wantKept, slices.Collect(maps.Keys(k.kept)))
+		})
+	}
+}
+
+func TestRemoveEligibleTorrents_MinCopies(t *testing.T) {
+	removalDelay = 0
+	t.Cleanup(func() { removalDelay = time.Second })
+
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Label: "remove", Seeding: true, Files: []string{"/data/x"}},
+		"b": {Hash: "b", Name: "b", Label: "remove", Seeding: true, Files: []string{"/data/x"}},
+		"c": {Hash: "c", Name: "c", Label: "remove", Seeding: true, Files: []string{"/data/x"}},
+		"d": {Hash: "d", Name: "d", Label: "remove", Seeding: true, Files: []string{"/data/y"}},
+		"e": {Hash: "e", Name: "e", Label: "remove", Seeding: true, Files: []string{"/data/y"}},
+		"f": {Hash: "f", Name: "f", Label: "remove", Seeding: true, Files: []string{"/data/unique"}},
+	}
+
+	tests := []struct {
+		name      string
+		minCopies int
+		expected  []string
+	}{
+		{name: "disabled", minCopies: 0, expected: []string{"a", "b", "c", "d", "e", "f"}},
+		{name: "one_copy", minCopies: 1, expected: []string{"b", "c", "e", "f"}},
+		{name: "two_copies", minCopies: 2, expected: []string{"c", "f"}},
+		{name: "three_copies", minCopies: 3, expected: []string{"f"}},
+	}
+
+	for _, tt := range tests {
+		for _, dryRun := range []bool{false, true} {
+			filter := &config.FilterConfiguration{Remove: []string{`Label == "remove"`}, MinCopies: tt.minCopies}
+			assert.Equal(t, tt.expected, runRemove(t, dryRun, filter, 0, maps.Clone(torrents)), "%s (dry-run: %t)", tt.name, dryRun)
+		}
+	}
+}
+
+func TestRemoveEligibleTorrents_MinCopiesHardlinked(t *testing.T) {
+	removalDelay = 0
+	t.Cleanup(func() { removalDelay = time.Second })
+
+	// two cross-seeds sharing their data only through a hardlink at a different path
+	root := t.TempDir()
+	source := filepath.Join(root, "movies", "movie.mkv")
+	linked := filepath.Join(root, "cross-seed", "movie.mkv")
+	require.NoError(t, os.MkdirAll(filepath.Dir(source), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Dir(linked), 0755))
+	require.NoError(t, os.WriteFile(source, []byte("data"), 0644))
+	if err := os.Link(source, linked); err != nil {
+		t.Skipf("hardlinks not supported: %v", err)
+	}
+
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Label: "remove", Seeding: true, Downloaded: true, Files: []string{source}},
+		"b": {Hash: "b", Name: "b", Label: "remove", Seeding: true, Downloaded: true, Files: []string{linked}},
+	}
+
+	filter := &config.FilterConfiguration{Remove: []string{`Label == "remove"`}, MinCopies: 1}
+	c := newMockClient(t, filter, 0, torrents)
+	working, err := c.GetTorrents(context.Background())
+	require.NoError(t, err)
+
+	err = removeEligibleTorrents(context.Background(), logger.GetLogger("test"), c, working, torrentfilemap.New(working),
+		hardlinkfilemap.New(working, nil, false, false), filter, &recordingSender{}, "test", time.Now(), nil)
+	require.NoError(t, err)
+
+	// a is kept, b is removed without the data a still uses
+	assert.Equal(t, []string{"b"}, c.Removed)
+	assert.False(t, c.RemovedData["b"])
+	assert.FileExists(t, source)
+}
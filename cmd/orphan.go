@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"cmp"
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -10,23 +14,37 @@ import (
 	"time"
 
 	"github.com/dustin/go-humanize"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
 	"github.com/autobrr/tqm/pkg/client"
 	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/diskspace"
 	"github.com/autobrr/tqm/pkg/logger"
 	"github.com/autobrr/tqm/pkg/notification"
+	"github.com/autobrr/tqm/pkg/pathmapping"
 	"github.com/autobrr/tqm/pkg/paths"
 	"github.com/autobrr/tqm/pkg/torrentfilemap"
 	"github.com/autobrr/tqm/pkg/tracker"
 )
 
+var (
+	// defaultIncompleteDirs are the folders below a download path commonly used for downloads in progress or
+	// unpacking, they are never scanned for orphans
+	defaultIncompleteDirs = []string{"incomplete", ".incomplete", ".unpacked"}
+
+	// inProgressSuffixes are the extensions of files a client is still writing, they are never removed as orphans
+	inProgressSuffixes = []string{".!qb", ".part", ".parts"}
+)
+
 var orphanCmd = &cobra.Command{
-	Use:   "orphan [CLIENT]",
+	Use:   "orphan [CLIENT]...",
 	Short: "Check download location for orphan files/folders not in torrent client",
-	Long:  `This command can be used to find files and folders in the download_location that are no longer in the torrent client.`,
+	Long: `This command can be used to find files and folders in the download_location that are no longer in the torrent client.
 
-	Args: cobra.ExactArgs(1),
+When multiple clients are given, their download locations are scanned once and a file is only an orphan when none of the clients has a torrent for it.`,
+
+	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := cmd.Context()
 		start := time.Now()
@@ -40,93 +58,84 @@ var orphanCmd = &cobra.Command{
 		// set log
 		log := logger.GetLogger("orphan")
 
-		noti := notification.NewDiscordSender(log, config.Config.Notifications)
-
-		// retrieve client object
-		clientName := args[0]
-		clientConfig, ok := config.Config.Clients[clientName]
-		if !ok {
-			log.Fatalf("No client configuration found for: %q", clientName)
-		}
-
-		// validate client is enabled
-		if err := validateClientEnabled(clientConfig); err != nil {
-			log.WithError(err).Fatal("Failed validating client is enabled")
-		}
-
-		// retrieve client type
-		clientType, err := getClientConfigString("type", clientConfig)
-		if err != nil {
-			log.WithError(err).Fatal("Failed determining client type")
-		}
-
-		// retrieve client download path
-		clientDownloadPath, err := getClientConfigString("download_path", clientConfig)
-		if err != nil {
-			log.WithError(err).Fatal("Failed determining client download path")
-		} else if clientDownloadPath == nil || *clientDownloadPath == "" {
-			log.Fatal("Client download path must be set...")
-		}
-
-		// retrieve client download path mapping
-		clientDownloadPathMapping, err := getClientDownloadPathMapping(clientConfig)
-		if err != nil {
-			log.WithError(err).Fatal("Failed loading client download path mappings")
-		} else if clientDownloadPathMapping != nil {
-			log.Debugf("Loaded %d client download path mappings: %#v", len(clientDownloadPathMapping),
-				clientDownloadPathMapping)
+		if err := validateOrphanOrder(flagOrphanOrderBy); err != nil {
+			log.WithError(err).Fatal("Invalid --order-by")
 		}
 
-		// load client object
-		c, err := client.NewClient(*clientType, clientName, nil)
-		if err != nil {
-			log.WithError(err).Fatalf("Failed initializing client: %q", clientName)
+		if err := checkConfirmationToken(); err != nil {
+			log.WithError(err).Fatal("Failed confirming destructive command")
 		}
 
-		log.Infof("Initialized client %q, type: %s (%d trackers)", clientName, c.Type(), tracker.Loaded())
+		noti := newNotificationSender(log)
 
-		// connect to client
-		if err := c.Connect(ctx); err != nil {
-			log.WithError(err).Fatal("Failed connecting")
-		} else {
-			log.Debugf("Connected to client")
+		// load every client, so files belonging to a torrent of any of them are not orphans
+		clients := make([]*orphanClient, 0, len(args))
+		clientNames := make([]string, 0, len(args))
+		for _, clientName := range args {
+			clients = append(clients, loadOrphanClient(ctx, log, clientName))
+			clientNames = append(clientNames, clientName)
 		}
+		clientName := strings.Join(clientNames, ", ")
 
-		// retrieve torrents
-		torrents, err := c.GetTorrents(ctx)
-		if err != nil {
-			log.WithError(err).Fatal("Failed retrieving torrents")
-		} else {
-			log.Infof("Retrieved %d torrents", len(torrents))
+		var (
+			scanRoots     []string
+			excludedRoots []string
+			downloadPaths []string
+			ignorePaths   []string
+			protectedExts []string
+			gracePeriod   time.Duration
+		)
+		for _, oc := range clients {
+			scanRoots = append(scanRoots, oc.scanRoots...)
+			excludedRoots = append(excludedRoots, oc.excludedRoots...)
+			downloadPaths = append(downloadPaths, oc.downloadPath)
+			ignorePaths = append(ignorePaths, oc.filter.Orphan.IgnorePaths...)
+			protectedExts = append(protectedExts, oc.filter.Orphan.ProtectedExtensions...)
+
+			// use the longest grace period of the clients
+			clientGracePeriod := 10 * time.Minute
+			if oc.filter.Orphan.GracePeriod > 0 {
+				clientGracePeriod = oc.filter.Orphan.GracePeriod
+			}
+			gracePeriod = max(gracePeriod, clientGracePeriod)
 		}
 
-		// create map of files associated with torrents (via hash)
-		tfm := torrentfilemap.New(torrents)
-		log.Infof("Mapped torrents to %d unique torrent files", tfm.Length())
-
-		// get all paths in client download location
-		localDownloadPaths, _ := paths.InFolder(*clientDownloadPath, true, true,
-			nil)
-		log.Tracef("Retrieved %d paths from: %q", len(localDownloadPaths), *clientDownloadPath)
+		// walk overlapping download paths only once
+		scanRoots = outermostPaths(scanRoots)
 
 		// sort paths into their respective maps
 		localFilePaths := make(map[string]int64)
 		localFolderPaths := make(map[string]int64)
 
-		for _, p := range localDownloadPaths {
-			if p.IsDir {
-				if strings.EqualFold(p.RealPath, *clientDownloadPath) {
-					// ignore root download path
+		for _, root := range scanRoots {
+			// get all paths in the scanned location
+			localDownloadPaths, _ := paths.InFolder(root, true, true,
+				nil)
+			log.Tracef("Retrieved %d paths from: %q", len(localDownloadPaths), root)
+
+			for _, p := range localDownloadPaths {
+				if slices.ContainsFunc(excludedRoots, func(excluded string) bool {
+					return isWithinPath(p.RealPath, excluded)
+				}) {
 					continue
 				}
 
-				localFolderPaths[p.RealPath] = p.Size
-			} else {
-				localFilePaths[p.RealPath] = p.Size
+				if p.IsDir {
+					if strings.EqualFold(p.RealPath, root) || slices.ContainsFunc(downloadPaths, func(downloadPath string) bool {
+						return strings.EqualFold(p.RealPath, downloadPath)
+					}) {
+						// ignore root download path
+						continue
+					}
+
+					localFolderPaths[p.RealPath] = p.Size
+				} else {
+					localFilePaths[p.RealPath] = p.Size
+				}
 			}
 		}
 
-		log.Infof("Retrieved paths from %q: %d files / %d folders", *clientDownloadPath, len(localFilePaths),
+		log.Infof("Retrieved paths from %q: %d files / %d folders", strings.Join(scanRoots, ", "), len(localFilePaths),
 			len(localFolderPaths))
 
 		const (
@@ -144,29 +153,33 @@ var orphanCmd = &cobra.Command{
 			fields                []notification.Field
 		)
 
-		filter, err := getClientFilter(clientConfig)
-		if err != nil {
-			log.WithError(err).Fatal("Failed to get client filter")
-		}
+		log.Debugf("Using grace period: %v", gracePeriod)
 
-		if filter == nil {
-			log.Fatal("Defined filter is empty")
+		// free space of the first download path, reported before and after the removals
+		freeSpaceBefore, freeSpaceErr := diskspace.FreeGB(downloadPaths[0])
+		if freeSpaceErr != nil {
+			log.WithError(freeSpaceErr).Debug("Failed retrieving free-space of the download path")
 		}
 
-		gracePeriod := 10 * time.Minute
-		if filter.Orphan.GracePeriod > 0 {
-			gracePeriod = filter.Orphan.GracePeriod
-		}
-		log.Debugf("Using grace period: %v", gracePeriod)
+		// orphans detected during the scan, written to --orphan-report
+		report := &orphanReport{}
 
-		processInBatches(localFilePaths, maxWorkers, batchSize, func(localPath string, localPathSize int64) {
+		processFile := func(localPath string, localPathSize int64) {
 			defer wg.Done()
 
-			if tfm.HasPath(localPath, clientDownloadPathMapping) {
+			if trackedByAnyClient(clients, localPath) {
+				return
+			}
+
+			if isInProgressFile(localPath) {
+				mu.Lock()
+				log.Debugf("File is an incomplete download, skipping removal: %q", localPath)
+				mu.Unlock()
+				ignoredLocalFiles.Add(1)
 				return
 			}
 
-			if paths.IsIgnored(localPath, filter.Orphan.IgnorePaths) {
+			if paths.IsIgnored(localPath, ignorePaths) {
 				mu.Lock()
 				log.Debugf("File matches a path in the ignore list, skipping removal: %q", localPath)
 				mu.Unlock()
@@ -174,6 +187,15 @@ var orphanCmd = &cobra.Command{
 				return
 			}
 
+			if hasProtectedExtension(localPath, protectedExts) {
+				mu.Lock()
+				log.Debugf("File has a protected extension, skipping removal: %q", localPath)
+				mu.Unlock()
+				ignoredLocalFiles.Add(1)
+				report.add(localPath, localPathSize, true, orphanStatusProtected)
+				return
+			}
+
 			// check file modification time for grace period
 			fileInfo, err := os.Stat(localPath)
 			if err != nil {
@@ -187,6 +209,7 @@ var orphanCmd = &cobra.Command{
 				mu.Lock()
 				log.Warnf("File is recently modified (within %v), skipping removal due to grace period: %q", gracePeriod, localPath)
 				mu.Unlock()
+				report.add(localPath, localPathSize, true, orphanStatusGracePeriod)
 				return
 			}
 
@@ -201,17 +224,20 @@ var orphanCmd = &cobra.Command{
 				mu.Lock()
 				log.Warn("Dry-run enabled, skipping remove...")
 				mu.Unlock()
+				report.add(localPath, localPathSize, true, orphanStatusDryRun)
 			} else {
-				if err := os.Remove(localPath); err != nil {
+				if err := removeOrphan(localPath); err != nil {
 					mu.Lock()
 					log.WithError(err).Errorf("Failed removing orphan...")
 					mu.Unlock()
 					removeFailures.Add(1)
 					removed = false
+					report.add(localPath, localPathSize, true, orphanStatusFailed)
 				} else {
 					mu.Lock()
 					log.Info("Removed")
 					mu.Unlock()
+					report.add(localPath, localPathSize, true, orphanStatusRemoved)
 				}
 			}
 
@@ -227,18 +253,43 @@ var orphanCmd = &cobra.Command{
 				}))
 				mu.Unlock()
 			}
-		}, &wg)
+		}
+
+		if len(flagOrphanOrderBy) == 0 && flagFreeSpaceTarget <= 0 {
+			processInBatches(localFilePaths, maxWorkers, batchSize, processFile, &wg)
+		} else {
+			// ordered and bounded runs remove one file at a time, so the target is checked before each removal
+			target := flagFreeSpaceTarget
+			if target > 0 && freeSpaceErr != nil {
+				log.Warn("Free space of the download path is unknown, ignoring --free-space-target")
+				target = 0
+			}
+
+			files := orderedOrphanFiles(localFilePaths, flagOrphanOrderBy)
+			for i, f := range files {
+				freeSpace := freeSpaceBefore + float64(removedLocalFilesSize.Load())/humanize.GiByte
+				if target > 0 && freeSpace >= target {
+					log.Info("-----")
+					log.Infof("Free space target reached (%.2f GB >= %.2f GB), skipping %d remaining file(s)",
+						freeSpace, target, len(files)-i)
+					break
+				}
+
+				wg.Add(1)
+				processFile(f.path, f.size)
+			}
+		}
 
 		wg.Wait()
 
 		var ignoredLocalFolders uint32
 		orphanFolderPaths := make([]string, 0, len(localFolderPaths))
 		for localPath := range localFolderPaths {
-			if tfm.HasPath(localPath, clientDownloadPathMapping) {
+			if trackedByAnyClient(clients, localPath) {
 				continue
 			}
 
-			if paths.IsIgnored(localPath, filter.Orphan.IgnorePaths) {
+			if paths.IsIgnored(localPath, ignorePaths) {
 				log.Debugf("Folder matches a path in the ignore list, skipping removal: %q", localPath)
 				ignoredLocalFolders++
 				continue
@@ -265,20 +316,25 @@ var orphanCmd = &cobra.Command{
 			empty, err := paths.IsDirEmpty(localPath)
 			if err != nil {
 				log.WithError(err).Warnf("Could not check if directory is empty, skipping removal: %q", localPath)
+				report.add(localPath, 0, false, orphanStatusFailed)
 			} else if !empty {
 				log.Warnf("Orphan directory is not empty, skipping removal: %q", localPath)
+				report.add(localPath, 0, false, orphanStatusNotEmpty)
 			} else {
 				log.Infof("Attempting to remove empty orphan directory: %q", localPath)
 				if flagDryRun {
 					log.Warn("Dry-run enabled, skipping remove...")
 					removed = true
+					report.add(localPath, 0, false, orphanStatusDryRun)
 				} else {
-					if err := os.Remove(localPath); err != nil {
+					if err := removeOrphan(localPath); err != nil {
 						log.WithError(err).Errorf("Failed removing empty orphan directory...")
 						removeFailures.Add(1)
+						report.add(localPath, 0, false, orphanStatusFailed)
 					} else {
 						log.Info("Removed empty orphan directory")
 						removed = true
+						report.add(localPath, 0, false, orphanStatusRemoved)
 					}
 				}
 			}
@@ -297,6 +353,24 @@ var orphanCmd = &cobra.Command{
 		log.WithField("reclaimed_space", humanize.IBytes(removedLocalFilesSize.Load())).
 			Infof("Removed orphans: %d files, %d folders and %d failures. Ignored %d files and %d folders",
 				removedLocalFiles.Load(), removedLocalFolders, removeFailures.Load(), ignoredLocalFiles.Load(), ignoredLocalFolders)
+		lastRun.record(clientName, "orphans_removed", int(removedLocalFiles.Load())+int(removedLocalFolders),
+			int(removeFailures.Load()), int64(removedLocalFilesSize.Load()))
+
+		description := fmt.Sprintf("Removed **%d** orphaned files and **%d** orphaned folders | Total reclaimed **%s**",
+			removedLocalFiles.Load(), removedLocalFolders, humanize.IBytes(removedLocalFilesSize.Load()))
+		if freeSpaceErr == nil {
+			freeSpaceAfter := freeSpaceBefore + float64(removedLocalFilesSize.Load())/humanize.GiByte
+			log.Infof("Free space of %q: %.2f GB -> %.2f GB", downloadPaths[0], freeSpaceBefore, freeSpaceAfter)
+			description += " | " + freeSpaceChange(freeSpaceBefore, freeSpaceAfter)
+		}
+
+		if flagOrphanReport != "" {
+			if err := report.writeFile(flagOrphanReport); err != nil {
+				log.WithError(err).Error("Failed writing orphan report")
+			} else {
+				log.Infof("Wrote %d orphans to: %q", report.len(), flagOrphanReport)
+			}
+		}
 
 		if !noti.CanSend() {
 			log.Debug("Notifications disabled, skipping...")
@@ -305,8 +379,7 @@ var orphanCmd = &cobra.Command{
 
 		sendErr := noti.Send(
 			"Orphans",
-			fmt.Sprintf("Removed **%d** orphaned files and **%d** orphaned folders | Total reclaimed **%s**",
-				removedLocalFiles.Load(), removedLocalFolders, humanize.IBytes(removedLocalFilesSize.Load())),
+			description,
 			clientName,
 			time.Since(start),
 			fields,
@@ -318,6 +391,300 @@ var orphanCmd = &cobra.Command{
 	},
 }
 
+type orphanClient struct {
+	downloadPath        string
+	downloadPathMapping *pathmapping.Mapping
+	filter              *config.FilterConfiguration
+	tfm                 *torrentfilemap.TorrentFileMap
+	scanRoots           []string
+	excludedRoots       []string
+}
+
+// loadOrphanClient connects to a client and maps the files of its torrents
+func loadOrphanClient(ctx context.Context, log *logrus.Entry, clientName string) *orphanClient {
+	// retrieve client object
+	clientConfig, ok := config.Config.Clients[clientName]
+	if !ok {
+		log.Fatalf("No client configuration found for: %q", clientName)
+	}
+
+	// validate client is enabled
+	if err := validateClientEnabled(clientConfig); err != nil {
+		log.WithError(err).Fatal("Failed validating client is enabled")
+	}
+
+	// retrieve client type
+	clientType, err := getClientConfigString("type", clientConfig)
+	if err != nil {
+		log.WithError(err).Fatal("Failed determining client type")
+	}
+
+	// retrieve client download path
+	clientDownloadPath, err := getClientConfigString("download_path", clientConfig)
+	if err != nil {
+		log.WithError(err).Fatal("Failed determining client download path")
+	} else if clientDownloadPath == nil || *clientDownloadPath == "" {
+		log.Fatal("Client download path must be set...")
+	}
+
+	// retrieve client download path mapping
+	clientDownloadPathMapping, err := getClientDownloadPathMapping(clientConfig)
+	if err != nil {
+		log.WithError(err).Fatal("Failed loading client download path mappings")
+	} else if clientDownloadPathMapping != nil {
+		log.Debugf("Loaded %d client download path mappings: %s", clientDownloadPathMapping.Len(),
+			clientDownloadPathMapping)
+	}
+
+	filter, err := getClientFilter(clientName, clientConfig)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to get client filter")
+	}
+
+	if filter == nil {
+		log.Fatal("Defined filter is empty")
+	}
+
+	// load client object
+	c, err := client.NewClient(*clientType, clientName, nil)
+	if err != nil {
+		log.WithError(err).Fatalf("Failed initializing client: %q", clientName)
+	}
+
+	log.Infof("Initialized client %q, type: %s (%d trackers)", clientName, c.Type(), tracker.Loaded())
+
+	// connect to client
+	if err := c.Connect(ctx); err != nil {
+		log.WithError(err).Fatal("Failed connecting")
+	} else {
+		log.Debugf("Connected to client")
+	}
+
+	// retrieve torrents
+	torrents, err := c.GetTorrents(ctx)
+	if err != nil {
+		log.WithError(err).Fatal("Failed retrieving torrents")
+	} else {
+		log.Infof("Retrieved %d torrents", len(torrents))
+	}
+
+	if err := checkTorrentCount(clientName, len(torrents)); err != nil {
+		log.WithError(err).Fatal("Refusing to act on the retrieved torrents")
+	}
+
+	// resolve the category folders to scan or skip
+	scanRoots := []string{*clientDownloadPath}
+	var excludedRoots []string
+	if len(flagIncludeCategories) > 0 || len(flagExcludeCategories) > 0 {
+		if err := c.LoadLabelPathMap(ctx); err != nil {
+			log.WithError(err).Fatal("Failed loading label path map")
+		}
+
+		scanRoots, excludedRoots, err = orphanScanRoots(*clientDownloadPath, c.LabelPathMap(),
+			clientDownloadPathMapping, flagIncludeCategories, flagExcludeCategories)
+		if err != nil {
+			log.WithError(err).Fatal("Failed resolving category paths")
+		}
+
+		log.Infof("Scanning %d folder(s), excluding %d category folder(s)", len(scanRoots), len(excludedRoots))
+		log.Debugf("Scan folders: %q, excluded folders: %q", scanRoots, excludedRoots)
+	}
+
+	// folders of downloads in progress are never orphans
+	incompleteDirs := incompleteRoots(*clientDownloadPath, filter.Orphan.IncompleteDirs)
+	excludedRoots = append(excludedRoots, incompleteDirs...)
+	log.Debugf("Excluded incomplete folders: %q", incompleteDirs)
+
+	// create map of files associated with torrents (via hash)
+	tfm := torrentfilemap.New(torrents)
+	log.Infof("Mapped torrents to %d unique torrent files", tfm.Length())
+
+	return &orphanClient{
+		downloadPath:        *clientDownloadPath,
+		downloadPathMapping: clientDownloadPathMapping,
+		filter:              filter,
+		tfm:                 tfm,
+		scanRoots:           scanRoots,
+		excludedRoots:       excludedRoots,
+	}
+}
+
+// removeOrphan removes an orphaned file or empty folder, unless safe_mode is enabled
+func removeOrphan(path string) error {
+	if err := checkSafeMode(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// trackedByAnyClient reports whether a local path belongs to a torrent of any of the clients
+func trackedByAnyClient(clients []*orphanClient, localPath string) bool {
+	return slices.ContainsFunc(clients, func(oc *orphanClient) bool {
+		return oc.tfm.HasPath(localPath, oc.downloadPathMapping)
+	})
+}
+
+// orphanScanRoots resolves category names to their local save paths, returning the folders to walk and the folders to skip
+func orphanScanRoots(downloadPath string, labelPathMap map[string]string, pathMapping *pathmapping.Mapping,
+	include []string, exclude []string) ([]string, []string, error) {
+
+	resolve := func(categories []string) ([]string, error) {
+		resolved := make([]string, 0, len(categories))
+		for _, category := range categories {
+			p, ok := labelPathMap[category]
+			if !ok || p == "" {
+				return nil, fmt.Errorf("no save path found for category: %q", category)
+			}
+
+			// category save paths are reported as the client sees them
+			p, _ = pathMapping.Map(p)
+
+			p = filepath.Clean(p)
+			if !isWithinPath(p, downloadPath) {
+				return nil, fmt.Errorf("save path of category %q is outside the download path: %q", category, p)
+			}
+
+			resolved = append(resolved, p)
+		}
+		return resolved, nil
+	}
+
+	excluded, err := resolve(exclude)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(include) == 0 {
+		return []string{downloadPath}, excluded, nil
+	}
+
+	included, err := resolve(include)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// walk nested category folders only once
+	return outermostPaths(included), excluded, nil
+}
+
+// incompleteRoots returns the incomplete folders of a download path, the defaults and dirs, relative dirs are resolved
+// against the download path
+func incompleteRoots(downloadPath string, dirs []string) []string {
+	roots := make([]string, 0, len(defaultIncompleteDirs)+len(dirs))
+	for _, dir := range slices.Concat(defaultIncompleteDirs, dirs) {
+		if dir == "" {
+			continue
+		}
+
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(downloadPath, dir)
+		}
+
+		dir = filepath.Clean(dir)
+		if !slices.Contains(roots, dir) {
+			roots = append(roots, dir)
+		}
+	}
+
+	return roots
+}
+
+// isInProgressFile reports whether path is a file a client is still writing, e.g. qBittorrent's .!qB extension
+func isInProgressFile(path string) bool {
+	path = strings.ToLower(path)
+	return slices.ContainsFunc(inProgressSuffixes, func(suffix string) bool {
+		return strings.HasSuffix(path, suffix)
+	})
+}
+
+// hasProtectedExtension reports whether the extension of path is one of extensions, which are matched case-insensitively
+// with or without their leading dot
+func hasProtectedExtension(path string, extensions []string) bool {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	if ext == "" {
+		return false
+	}
+
+	return slices.ContainsFunc(extensions, func(protected string) bool {
+		return strings.EqualFold(strings.TrimPrefix(protected, "."), ext)
+	})
+}
+
+// outermostPaths returns the sorted paths that are not inside another of the paths
+func outermostPaths(paths []string) []string {
+	sorted := slices.Clone(paths)
+	sort.Strings(sorted)
+
+	roots := make([]string, 0, len(sorted))
+	for _, p := range sorted {
+		if !slices.ContainsFunc(roots, func(root string) bool { return isWithinPath(p, root) }) {
+			roots = append(roots, p)
+		}
+	}
+	return roots
+}
+
+// isWithinPath reports whether path is root or inside it
+func isWithinPath(path string, root string) bool {
+	path = filepath.Clean(path)
+	root = filepath.Clean(root)
+	return path == root || strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// orphanOrderKeys are the keys orphan files can be ordered by, largest and oldest first
+var orphanOrderKeys = []string{"size", "mtime"}
+
+// validateOrphanOrder checks every key of --order-by is supported
+func validateOrphanOrder(keys []string) error {
+	for _, key := range keys {
+		if !slices.Contains(orphanOrderKeys, key) {
+			return fmt.Errorf("unsupported key: %q (%s)", key, strings.Join(orphanOrderKeys, ", "))
+		}
+	}
+	return nil
+}
+
+// orphanFile is a local file in the order it is processed
+type orphanFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// orderedOrphanFiles returns the files ordered by each key in turn, largest and oldest first, ties and files without
+// keys are ordered by path
+func orderedOrphanFiles(files map[string]int64, keys []string) []orphanFile {
+	ordered := make([]orphanFile, 0, len(files))
+	for p, size := range files {
+		f := orphanFile{path: p, size: size}
+		if slices.Contains(keys, "mtime") {
+			if info, err := os.Stat(p); err == nil {
+				f.modTime = info.ModTime()
+			}
+		}
+		ordered = append(ordered, f)
+	}
+
+	slices.SortFunc(ordered, func(a, b orphanFile) int {
+		for _, key := range keys {
+			var c int
+			switch key {
+			case "size":
+				c = cmp.Compare(b.size, a.size)
+			case "mtime":
+				c = a.modTime.Compare(b.modTime)
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return strings.Compare(a.path, b.path)
+	})
+
+	return ordered
+}
+
 // processInBatches processes a map in batches using a worker pool
 func processInBatches(items map[string]int64, maxWorkers int, batchSize int,
 	processFn func(string, int64), wg *sync.WaitGroup) {
@@ -360,4 +727,10 @@ func processInBatches(items map[string]int64, maxWorkers int, batchSize int,
 
 func init() {
 	rootCmd.AddCommand(orphanCmd)
+
+	orphanCmd.Flags().StringSliceVar(&flagIncludeCategories, "include-category", nil, "Only scan the save path of this category (can be repeated)")
+	orphanCmd.Flags().StringVar(&flagOrphanReport, "orphan-report", "", "Write the detected orphans with their size and outcome to this file")
+	orphanCmd.Flags().StringSliceVar(&flagExcludeCategories, "exclude-category", nil, "Skip the save path of this category (can be repeated)")
+	orphanCmd.Flags().StringSliceVar(&flagOrphanOrderBy, "order-by", nil, "Remove orphan files ordered by size (largest first) and/or mtime (oldest first)")
+	orphanCmd.Flags().Float64Var(&flagFreeSpaceTarget, "free-space-target", 0, "Stop removing orphan files once the download path has this much free space in GB")
 }
@@ -14,10 +14,18 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/pathmapping"
 	"github.com/autobrr/tqm/pkg/paths"
 	"github.com/autobrr/tqm/pkg/torrentfilemap"
 )
 
+func newPathMapping(t *testing.T, mappings map[string]string) *pathmapping.Mapping {
+	t.Helper()
+	mapping, err := pathmapping.New(mappings)
+	require.NoError(t, err)
+	return mapping
+}
+
 func createTempDir(t *testing.T, baseDir, subPath string) string {
 	t.Helper()
 	dir := filepath.Join(baseDir, subPath)
@@ -188,9 +196,9 @@ func TestOrphanIdentification(t *testing.T) {
 
 	mappedFileLocalPath := createTempFile(t, downloadDir, "file4.txt", "mapped_content")
 
-	pathMapping := map[string]string{
+	pathMapping := newPathMapping(t, map[string]string{
 		downloadDir: "/data/mapped",
-	}
+	})
 
 	localFilePaths := make(map[string]int64)
 	localFolderPaths := make(map[string]int64)
@@ -347,12 +355,307 @@ func TestOrphanFolderSorting(t *testing.T) {
 	assert.Equal(t, expectedOrder, paths, "Folder paths are not sorted correctly by depth (descending)")
 }
 
+func TestOrphanScanRoots(t *testing.T) {
+	labelPathMap := map[string]string{
+		"movies":     "/data/torrents/movies",
+		"movies-4k":  "/data/torrents/movies/4k",
+		"tv":         "/data/torrents/tv",
+		"tv-sonarr":  "/data/torrents/tv-sonarr",
+		"elsewhere":  "/mnt/other",
+		"no-path":    "",
+		"remote-map": "/downloads/music",
+	}
+	mapping := newPathMapping(t, map[string]string{"/downloads": "/data/torrents"})
+
+	tests := []struct {
+		name         string
+		include      []string
+		exclude      []string
+		wantRoots    []string
+		wantExcluded []string
+		wantErr      bool
+	}{
+		{
+			name:         "exclude",
+			exclude:      []string{"tv"},
+			wantRoots:    []string{"/data/torrents"},
+			wantExcluded: []string{"/data/torrents/tv"},
+		},
+		{
+			name:         "include",
+			include:      []string{"tv", "movies"},
+			wantRoots:    []string{"/data/torrents/movies", "/data/torrents/tv"},
+			wantExcluded: []string{},
+		},
+		{
+			name:         "include_nested",
+			include:      []string{"movies-4k", "movies"},
+			wantRoots:    []string{"/data/torrents/movies"},
+			wantExcluded: []string{},
+		},
+		{
+			name:         "include_and_exclude_nested",
+			include:      []string{"movies"},
+			exclude:      []string{"movies-4k"},
+			wantRoots:    []string{"/data/torrents/movies"},
+			wantExcluded: []string{"/data/torrents/movies/4k"},
+		},
+		{
+			name:         "path_mapping",
+			include:      []string{"remote-map"},
+			wantRoots:    []string{"/data/torrents/music"},
+			wantExcluded: []string{},
+		},
+		{
+			name:    "unknown_category",
+			exclude: []string{"missing"},
+			wantErr: true,
+		},
+		{
+			name:    "empty_save_path",
+			include: []string{"no-path"},
+			wantErr: true,
+		},
+		{
+			name:    "outside_download_path",
+			include: []string{"elsewhere"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roots, excluded, err := orphanScanRoots("/data/torrents", labelPathMap, mapping, tt.include, tt.exclude)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRoots, roots)
+			assert.Equal(t, tt.wantExcluded, excluded)
+		})
+	}
+}
+
+func TestIncompleteRoots(t *testing.T) {
+	assert.Equal(t, []string{
+		"/data/torrents/incomplete",
+		"/data/torrents/.incomplete",
+		"/data/torrents/.unpacked",
+		"/data/torrents/temp",
+		"/mnt/scratch",
+	}, incompleteRoots("/data/torrents", []string{"temp/", "/mnt/scratch", "incomplete", ""}))
+
+	assert.Len(t, incompleteRoots("/data/torrents", nil), len(defaultIncompleteDirs))
+}
+
+func TestIsInProgressFile(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{path: "/data/torrents/movie.mkv.!qB", expected: true},
+		{path: "/data/torrents/movie.mkv.!qb", expected: true},
+		{path: "/data/torrents/movie.mkv.part", expected: true},
+		{path: "/data/torrents/.abc123.parts", expected: true},
+		{path: "/data/torrents/movie.mkv", expected: false},
+		{path: "/data/torrents/movie.part1.rar", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isInProgressFile(tt.path))
+		})
+	}
+}
+
+func TestHasProtectedExtension(t *testing.T) {
+	protected := []string{".torrent", "NFO"}
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{path: "/data/torrents/movie.torrent", expected: true},
+		{path: "/data/torrents/Movie/movie.NFO", expected: true},
+		{path: "/data/torrents/Movie/movie.nfo", expected: true},
+		{path: "/data/torrents/Movie/movie.mkv", expected: false},
+		{path: "/data/torrents/Movie/nfo", expected: false},
+		{path: "/data/torrents/Movie/movie.nfo.bak", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.expected, hasProtectedExtension(tt.path, protected))
+		})
+	}
+
+	assert.False(t, hasProtectedExtension("/data/torrents/movie.torrent", nil))
+}
+
+func TestOrphanProtectedExtensions(t *testing.T) {
+	downloadDir := createTempDir(t, t.TempDir(), "downloads")
+
+	twoHoursAgo := time.Now().Add(-2 * time.Hour)
+	orphanFile := createTempFile(t, downloadDir, "orphan.txt", "orphan")
+	orphanNfo := createTempFile(t, downloadDir, "release.nfo", "nfo")
+	orphanTorrent := createTempFile(t, downloadDir, "release.torrent", "torrent")
+	for _, path := range []string{orphanFile, orphanNfo, orphanTorrent} {
+		require.NoError(t, os.Chtimes(path, twoHoursAgo, twoHoursAgo))
+	}
+
+	localFilePaths := map[string]int64{orphanFile: 6, orphanNfo: 3, orphanTorrent: 7}
+	protected := []string{".nfo", "torrent"}
+	gracePeriod := time.Hour
+
+	// files outside the grace period are only removed when their extension is not protected
+	var wg sync.WaitGroup
+	processFileFn := func(localPath string, localPathSize int64) {
+		defer wg.Done()
+		if hasProtectedExtension(localPath, protected) {
+			return
+		}
+		fileInfo, err := os.Stat(localPath)
+		if err != nil || time.Since(fileInfo.ModTime()) < gracePeriod {
+			return
+		}
+		assert.NoError(t, removeOrphan(localPath))
+	}
+	processInBatches(localFilePaths, 5, 10, processFileFn, &wg)
+	wg.Wait()
+
+	assert.NoFileExists(t, orphanFile)
+	assert.FileExists(t, orphanNfo)
+	assert.FileExists(t, orphanTorrent)
+}
+
+func TestTrackedByAnyClient(t *testing.T) {
+	clients := []*orphanClient{
+		{
+			tfm: torrentfilemap.New(map[string]config.Torrent{
+				"a": {Hash: "a", Files: []string{"/downloads/qbt/movie.mkv"}},
+			}),
+			downloadPathMapping: newPathMapping(t, map[string]string{"/downloads/qbt": "/mnt/storage/qbt"}),
+		},
+		{
+			tfm: torrentfilemap.New(map[string]config.Torrent{
+				"b": {Hash: "b", Files: []string{"/data/deluge/show/episode.mkv"}},
+			}),
+			downloadPathMapping: newPathMapping(t, map[string]string{"/data/deluge": "/mnt/storage/deluge"}),
+		},
+	}
+
+	assert.True(t, trackedByAnyClient(clients, "/mnt/storage/qbt/movie.mkv"))
+	assert.True(t, trackedByAnyClient(clients, "/mnt/storage/deluge/show/episode.mkv"))
+	assert.True(t, trackedByAnyClient(clients, "/mnt/storage/deluge/show"))
+	assert.False(t, trackedByAnyClient(clients, "/mnt/storage/qbt/orphan.mkv"))
+
+	// a single client would treat the other client's files as orphans
+	assert.False(t, trackedByAnyClient(clients[:1], "/mnt/storage/deluge/show/episode.mkv"))
+}
+
+func TestOutermostPaths(t *testing.T) {
+	assert.Equal(t, []string{"/mnt/a", "/mnt/b"},
+		outermostPaths([]string{"/mnt/b", "/mnt/a/qbt", "/mnt/a", "/mnt/b", "/mnt/a/deluge/tv"}))
+	assert.Equal(t, []string{"/mnt/tv", "/mnt/tv-sonarr"}, outermostPaths([]string{"/mnt/tv-sonarr", "/mnt/tv"}))
+}
+
+func TestOrphanReport(t *testing.T) {
+	report := &orphanReport{}
+
+	var wg sync.WaitGroup
+	for i, status := range []string{orphanStatusRemoved, orphanStatusGracePeriod, orphanStatusFailed} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.add(fmt.Sprintf("/data/file-%d.mkv", i), 2048, true, status)
+		}()
+	}
+	wg.Wait()
+	report.add("/data/folder", 0, false, orphanStatusNotEmpty)
+
+	out := filepath.Join(t.TempDir(), "orphans.txt")
+	require.NoError(t, report.writeFile(out))
+
+	data, err := os.ReadFile(out)
+	require.NoError(t, err)
+
+	expected := "TYPE    SIZE     STATUS        PATH\n" +
+		"file    2.0 KiB  removed       /data/file-0.mkv\n" +
+		"file    2.0 KiB  grace-period  /data/file-1.mkv\n" +
+		"file    2.0 KiB  failed        /data/file-2.mkv\n" +
+		"folder  -        not-empty     /data/folder\n"
+	assert.Equal(t, expected, string(data))
+	assert.Equal(t, 4, report.len())
+}
+
+func TestRemoveOrphan_SafeMode(t *testing.T) {
+	file := createTempFile(t, t.TempDir(), "orphan.mkv", "data")
+
+	config.Config.SafeMode = true
+	t.Cleanup(func() { config.Config.SafeMode = false })
+
+	assert.ErrorIs(t, removeOrphan(file), errSafeMode)
+	assert.FileExists(t, file)
+
+	config.Config.SafeMode = false
+	require.NoError(t, removeOrphan(file))
+	assert.NoFileExists(t, file)
+}
+
+func TestIsWithinPath(t *testing.T) {
+	assert.True(t, isWithinPath("/data/tv", "/data/tv"))
+	assert.True(t, isWithinPath("/data/tv/show/episode.mkv", "/data/tv"))
+	assert.True(t, isWithinPath("/data/tv/show", "/data/tv/"))
+	assert.False(t, isWithinPath("/data/tv-sonarr/show", "/data/tv"))
+	assert.False(t, isWithinPath("/data", "/data/tv"))
+}
+
 func setupTestConfig() {
 	if config.Config == nil {
 		config.Config = &config.Configuration{}
 	}
 }
 
+func TestOrderedOrphanFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	files := make(map[string]int64)
+	for name, f := range map[string]struct {
+		size int64
+		age  time.Duration
+	}{
+		"small-old": {size: 10, age: 48 * time.Hour},
+		"large-new": {size: 100, age: time.Hour},
+		"large-old": {size: 100, age: 72 * time.Hour},
+		"medium":    {size: 50, age: 24 * time.Hour},
+	} {
+		p := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(p, nil, 0o644))
+		require.NoError(t, os.Chtimes(p, now.Add(-f.age), now.Add(-f.age)))
+		files[p] = f.size
+	}
+
+	names := func(ordered []orphanFile) []string {
+		var n []string
+		for _, f := range ordered {
+			n = append(n, filepath.Base(f.path))
+		}
+		return n
+	}
+
+	assert.Equal(t, []string{"large-new", "large-old", "medium", "small-old"}, names(orderedOrphanFiles(files, nil)))
+	assert.Equal(t, []string{"large-old", "large-new", "medium", "small-old"},
+		names(orderedOrphanFiles(files, []string{"size", "mtime"})))
+	assert.Equal(t, []string{"large-old", "small-old", "medium", "large-new"},
+		names(orderedOrphanFiles(files, []string{"mtime"})))
+
+	require.NoError(t, validateOrphanOrder([]string{"size", "mtime"}))
+	require.Error(t, validateOrphanOrder([]string{"name"}))
+}
+
 func TestMain(m *testing.M) {
 	setupTestConfig()
 	exitCode := m.Run()
@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"fmt"
 	"os"
 	"time"
 
@@ -12,7 +11,6 @@ import (
 	"github.com/autobrr/tqm/pkg/config"
 	"github.com/autobrr/tqm/pkg/expression"
 	"github.com/autobrr/tqm/pkg/logger"
-	"github.com/autobrr/tqm/pkg/notification"
 	"github.com/autobrr/tqm/pkg/tracker"
 )
 
@@ -35,7 +33,7 @@ var pauseCmd = &cobra.Command{
 		// set log
 		log := logger.GetLogger("pause")
 
-		noti := notification.NewDiscordSender(log, config.Config.Notifications)
+		noti := newNotificationSender(log)
 
 		// retrieve client object
 		clientName := args[0]
@@ -59,7 +57,7 @@ var pauseCmd = &cobra.Command{
 		clientFreeSpacePath, _ := getClientConfigString("free_space_path", clientConfig)
 
 		// retrieve client filters
-		clientFilter, err := getClientFilter(clientConfig)
+		clientFilter, err := getClientFilter(clientName, clientConfig)
 		if err != nil {
 			log.WithError(err).Fatal("Failed retrieving client filter")
 		}
@@ -122,76 +120,33 @@ var pauseCmd = &cobra.Command{
 		}
 
 		// retrieve torrents
-		torrents, err := c.GetTorrents(ctx)
+		torrents, err := retrieveTorrents(ctx, c)
 		if err != nil {
 			log.WithError(err).Fatal("Failed retrieving torrents")
 		} else {
 			log.Infof("Retrieved %d torrents", len(torrents))
 		}
 
-		var (
-			pauseList []string
-			fields    []notification.Field
-		)
-
-		// iterate through torrents
-		for _, t := range torrents {
-			// check if torrent should be ignored
-			if ignored, err := c.ShouldIgnore(ctx, &t); err != nil {
-				log.WithError(err).Errorf("Failed checking ignore filters for torrent: %q", t.Name)
-				continue
-			} else if ignored {
-				log.Debugf("Ignoring torrent: %q", t.Name)
-				continue
-			}
-
-			// check if torrent should be paused
-			if paused, err := c.CheckTorrentPause(ctx, &t); err != nil {
-				log.WithError(err).Errorf("Failed checking pause filters for torrent: %q", t.Name)
-				continue
-			} else if paused {
-				log.Infof("Adding torrent to pause list: %q", t.Name)
-				pauseList = append(pauseList, t.Hash)
-				fields = append(fields, noti.BuildField(notification.ActionPause, notification.BuildOptions{
-					Torrent: t,
-				}))
+		// only the torrent selected by --hash was retrieved, which says nothing about the size of the library
+		if flagHash == "" {
+			if err := checkTorrentCount(clientName, len(torrents)); err != nil {
+				log.WithError(err).Fatal("Refusing to act on the retrieved torrents")
 			}
 		}
 
-		// pause torrents if not dry run
-		if !flagDryRun {
-			if len(pauseList) > 0 {
-				log.Infof("Pausing %d torrent(s)...", len(pauseList))
-				if err := c.PauseTorrents(ctx, pauseList); err != nil {
-					log.WithError(err).Fatalf("Failed pausing torrents: %v", err)
-				}
-				log.Infof("Successfully paused %d torrent(s)", len(pauseList))
-			} else {
-				log.Info("No torrents to pause")
-			}
-		} else {
-			if len(pauseList) > 0 {
-				log.Infof("[DRY-RUN] Would pause %d torrent(s)", len(pauseList))
-			} else {
-				log.Info("[DRY-RUN] No torrents would be paused")
-			}
+		// evaluate time based fields as of the requested time
+		if offset, err := applyAsOf(torrents); err != nil {
+			log.WithError(err).Fatal("Failed applying --as-of time")
+		} else if offset != 0 {
+			log.Warnf("Evaluating filters as of %s (%s from now)", now().Add(offset).Format(time.RFC3339), offset.Round(time.Second))
 		}
 
-		if !noti.CanSend() {
-			log.Debug("Notifications disabled, skipping...")
-			return
-		}
+		// apply tracker, label/tag and hash pre-filters
+		applyPreFilters(log, torrents)
 
-		sendErr := noti.Send(
-			"Torrent Pause",
-			fmt.Sprintf("Paused **%d** torrent(s)", len(pauseList)),
-			clientName,
-			time.Since(start),
-			fields,
-			flagDryRun,
-		)
-		if sendErr != nil {
-			log.WithError(sendErr).Error("Failed sending notification")
+		// pause torrents that are not ignored and match pause criteria
+		if err := pauseEligibleTorrents(ctx, log, c, torrents, noti, clientName, start); err != nil {
+			log.WithError(err).Fatal("Failed pausing eligible torrents...")
 		}
 	},
 }
@@ -200,4 +155,5 @@ func init() {
 	rootCmd.AddCommand(pauseCmd)
 
 	pauseCmd.Flags().StringVar(&flagFilterName, "filter", "", "Filter to use instead of client")
+	addPreFilterFlags(pauseCmd)
 }
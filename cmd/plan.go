@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/autobrr/tqm/pkg/config"
+)
+
+const (
+	planVersion = 2
+
+	planActionRemove = "remove"
+)
+
+var (
+	flagPlan string
+
+	// cleanPlan collects the removals of a clean dry-run run with --plan
+	cleanPlan *removalPlan
+
+	errPlanSignature = errors.New("plan signature does not match, it was changed or signed with another key")
+)
+
+// plannedState is the state of a torrent when it was planned, applying the plan refuses to act on it once it changed
+type plannedState struct {
+	Label      string   `json:"label"`
+	Tags       []string `json:"tags"`
+	Path       string   `json:"path"`
+	TotalBytes int64    `json:"total_bytes"`
+	Downloaded bool     `json:"downloaded"`
+}
+
+func newPlannedState(t *config.Torrent) plannedState {
+	tags := slices.Clone(t.Tags)
+	slices.Sort(tags)
+
+	return plannedState{
+		Label:      t.Label,
+		Tags:       tags,
+		Path:       t.Path,
+		TotalBytes: t.TotalBytes,
+		Downloaded: t.Downloaded,
+	}
+}
+
+type planAction struct {
+	Client     string       `json:"client"`
+	Action     string       `json:"action"`
+	Hash       string       `json:"hash"`
+	Name       string       `json:"name"`
+	Reason     string       `json:"reason"`
+	DeleteData bool         `json:"delete_data"`
+	Archiving  bool         `json:"archiving"`
+	State      plannedState `json:"state"`
+}
+
+// removalPlan lists the exact actions of a dry-run, applied later by the apply command. It is signed with
+// plan_signing_key so a changed plan is refused
+type removalPlan struct {
+	Version   int          `json:"version"`
+	CreatedAt time.Time    `json:"created_at"`
+	Actions   []planAction `json:"actions"`
+	Signature string       `json:"signature"`
+}
+
+func newRemovalPlan() *removalPlan {
+	return &removalPlan{
+		Version:   planVersion,
+		CreatedAt: now().UTC(),
+		Actions:   []planAction{},
+	}
+}
+
+// addRemoval records the removal of t from client, archiving is set when its files are archived before the data is
+// deleted
+func (p *removalPlan) addRemoval(client string, t *config.Torrent, reason string, deleteData bool, archiving bool) {
+	p.Actions = append(p.Actions, planAction{
+		Client:     client,
+		Action:     planActionRemove,
+		Hash:       t.Hash,
+		Name:       t.Name,
+		Reason:     reason,
+		DeleteData: deleteData,
+		Archiving:  archiving,
+		State:      newPlannedState(t),
+	})
+}
+
+// signature returns the HMAC-SHA256 of the plan without its signature
+func (p *removalPlan) signature(key string) (string, error) {
+	unsigned := *p
+	unsigned.Signature = ""
+
+	data, err := json.Marshal(unsigned)
+	if err != nil {
+		return "", fmt.Errorf("marshal plan: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (p *removalPlan) sign(key string) error {
+	signature, err := p.signature(key)
+	if err != nil {
+		return err
+	}
+
+	p.Signature = signature
+	return nil
+}
+
+func (p *removalPlan) verify(key string) error {
+	signature, err := p.signature(key)
+	if err != nil {
+		return err
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(p.Signature)) {
+		return errPlanSignature
+	}
+
+	return nil
+}
+
+// writePlan signs the plan and stores it in path
+func writePlan(path string, p *removalPlan, key string) error {
+	if err := p.sign(key); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal plan: %w", err)
+	}
+
+	return writeFileAtomic(path, append(data, '\n'))
+}
+
+// readPlan reads the plan stored in path, verifying its signature
+func readPlan(path string, key string) (*removalPlan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read plan: %w", err)
+	}
+
+	p := &removalPlan{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("unmarshal plan: %w", err)
+	}
+
+	if p.Version != planVersion {
+		return nil, fmt.Errorf("unsupported plan version: %d", p.Version)
+	}
+
+	if err := p.verify(key); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// planSigningKey returns the configured plan_signing_key
+func planSigningKey() (string, error) {
+	if config.Config == nil || config.Config.PlanSigningKey == "" {
+		return "", errors.New("no plan_signing_key is configured")
+	}
+
+	return config.Config.PlanSigningKey, nil
+}
+
+// planDrift describes how the torrent of a planned action changed since it was planned, an empty string when it did
+// not. ok is false when the torrent is gone
+func planDrift(a planAction, t config.Torrent, ok bool) string {
+	if !ok {
+		return "torrent no longer exists"
+	}
+
+	current := newPlannedState(&t)
+	var changes []string
+	if current.Label != a.State.Label {
+		changes = append(changes, fmt.Sprintf("label %q -> %q", a.State.Label, current.Label))
+	}
+	if !slices.Equal(current.Tags, a.State.Tags) {
+		changes = append(changes, fmt.Sprintf("tags [%s] -> [%s]", strings.Join(a.State.Tags, ", "),
+			strings.Join(current.Tags, ", ")))
+	}
+	if current.Path != a.State.Path {
+		changes = append(changes, fmt.Sprintf("path %q -> %q", a.State.Path, current.Path))
+	}
+	if current.TotalBytes != a.State.TotalBytes {
+		changes = append(changes, fmt.Sprintf("size %d -> %d", a.State.TotalBytes, current.TotalBytes))
+	}
+	if current.Downloaded != a.State.Downloaded {
+		changes = append(changes, fmt.Sprintf("downloaded %t -> %t", a.State.Downloaded, current.Downloaded))
+	}
+
+	return strings.Join(changes, ", ")
+}
+
+// planDrifts returns the drift of every planned action whose torrent changed, torrents holds the current torrents of
+// each client of the plan
+func planDrifts(p *removalPlan, torrents map[string]map[string]config.Torrent) []string {
+	var drifts []string
+	for _, a := range p.Actions {
+		t, ok := torrents[a.Client][a.Hash]
+		if drift := planDrift(a, t, ok); drift != "" {
+			drifts = append(drifts, fmt.Sprintf("%s: %q: %s", a.Client, a.Name, drift))
+		}
+	}
+
+	return drifts
+}
+
+// planClients returns the clients of the plan in the order of their first action
+func planClients(p *removalPlan) []string {
+	var clients []string
+	for _, a := range p.Actions {
+		if !slices.Contains(clients, a.Client) {
+			clients = append(clients, a.Client)
+		}
+	}
+
+	return clients
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/hardlinkfilemap"
+	"github.com/autobrr/tqm/pkg/logger"
+	"github.com/autobrr/tqm/pkg/torrentfilemap"
+)
+
+func TestPlanSignature(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+
+	p := newRemovalPlan()
+	p.addRemoval("qbt", &config.Torrent{Hash: "a", Name: "a", Label: "tv", Tags: []string{"b", "a"}}, "unregistered", true, false)
+	require.NoError(t, writePlan(path, p, "secret"))
+
+	read, err := readPlan(path, "secret")
+	require.NoError(t, err)
+	require.Len(t, read.Actions, 1)
+	assert.Equal(t, []string{"a", "b"}, read.Actions[0].State.Tags)
+
+	_, err = readPlan(path, "other")
+	require.ErrorIs(t, err, errPlanSignature)
+
+	// a plan changed after it was signed is refused
+	read.Actions[0].DeleteData = false
+	require.ErrorIs(t, read.verify("secret"), errPlanSignature)
+}
+
+func TestPlanDrifts(t *testing.T) {
+	p := newRemovalPlan()
+	p.addRemoval("qbt", &config.Torrent{Hash: "a", Name: "a", Label: "tv", Tags: []string{"x"}, TotalBytes: 10}, "", true, false)
+	p.addRemoval("qbt", &config.Torrent{Hash: "b", Name: "b", Label: "tv", TotalBytes: 20}, "", true, false)
+	p.addRemoval("deluge", &config.Torrent{Hash: "c", Name: "c", Path: "/data"}, "", false, false)
+
+	torrents := map[string]map[string]config.Torrent{
+		"qbt": {
+			// seeding progress is not drift
+			"a": {Hash: "a", Label: "tv", Tags: []string{"x"}, TotalBytes: 10, Ratio: 2, SeedingDays: 3},
+			"b": {Hash: "b", Label: "keep", Tags: []string{"new"}, TotalBytes: 20},
+		},
+		"deluge": {},
+	}
+
+	assert.Equal(t, []string{
+		`qbt: "b": label "tv" -> "keep", tags [] -> [new]`,
+		`deluge: "c": torrent no longer exists`,
+	}, planDrifts(p, torrents))
+	assert.Equal(t, []string{"qbt", "deluge"}, planClients(p))
+}
+
+func TestPlan_CleanAndApply(t *testing.T) {
+	removalDelay = 0
+	flagDryRun = true
+	cleanPlan = newRemovalPlan()
+	t.Cleanup(func() {
+		removalDelay = time.Second
+		flagDryRun = false
+		cleanPlan = nil
+	})
+
+	filter := &config.FilterConfiguration{Remove: []string{`Label == "remove"`}}
+	c := newMockClient(t, filter, 0, map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Label: "remove", Files: []string{"/data/a"}},
+		"b": {Hash: "b", Name: "b", Label: "keep", Files: []string{"/data/b"}},
+	})
+
+	working, err := c.GetTorrents(context.Background())
+	require.NoError(t, err)
+
+	err = removeEligibleTorrents(context.Background(), logger.GetLogger("test"), c, working, torrentfilemap.New(working),
+		hardlinkfilemap.NewNoopHardlinkFileMap(), filter, &recordingSender{}, "qbt", time.Now(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, c.Removed)
+
+	require.Len(t, cleanPlan.Actions, 1)
+	assert.Equal(t, "a", cleanPlan.Actions[0].Hash)
+	assert.True(t, cleanPlan.Actions[0].DeleteData)
+
+	// applying the plan removes exactly the planned torrents
+	flagDryRun = false
+	current, err := c.GetTorrents(context.Background())
+	require.NoError(t, err)
+
+	torrents := map[string]map[string]config.Torrent{"qbt": current}
+	require.Empty(t, planDrifts(cleanPlan, torrents))
+
+	clients := map[string]*planClient{"qbt": {
+		c:        c,
+		filter:   filter,
+		torrents: current,
+		tfm:      torrentfilemap.New(current),
+		hfm:      hardlinkfilemap.NewNoopHardlinkFileMap(),
+	}}
+	require.Zero(t, keepSharedData(logger.GetLogger("test"), cleanPlan, clients))
+
+	removed, failed := executePlan(context.Background(), logger.GetLogger("test"), clients, cleanPlan)
+	assert.Equal(t, 1, removed)
+	assert.Zero(t, failed)
+	assert.Equal(t, []string{"a"}, c.Removed)
+	assert.Equal(t, map[string]bool{"a": true}, c.RemovedData)
+}
+
+func TestKeepSharedData(t *testing.T) {
+	p := newRemovalPlan()
+	p.addRemoval("qbt", &config.Torrent{Hash: "a", Name: "a", Files: []string{"/data/a.mkv"}}, "", true, true)
+	p.addRemoval("qbt", &config.Torrent{Hash: "b", Name: "b", Files: []string{"/data/b.mkv"}}, "", true, false)
+	p.addRemoval("qbt", &config.Torrent{Hash: "c", Name: "c", Files: []string{"/data/b.mkv"}}, "", true, false)
+
+	// a cross-seed of a was added since the plan was made, b and c only share files with each other
+	current := map[string]config.Torrent{
+		"a":     {Hash: "a", Name: "a", Files: []string{"/data/a.mkv"}},
+		"b":     {Hash: "b", Name: "b", Files: []string{"/data/b.mkv"}},
+		"c":     {Hash: "c", Name: "c", Files: []string{"/data/b.mkv"}},
+		"cross": {Hash: "cross", Name: "cross", Files: []string{"/data/a.mkv"}},
+	}
+	clients := map[string]*planClient{"qbt": {
+		torrents: current,
+		tfm:      torrentfilemap.New(current),
+		hfm:      hardlinkfilemap.NewNoopHardlinkFileMap(),
+	}}
+
+	assert.Equal(t, 1, keepSharedData(logger.GetLogger("test"), p, clients))
+	assert.False(t, p.Actions[0].DeleteData)
+	assert.False(t, p.Actions[0].Archiving)
+	assert.True(t, p.Actions[1].DeleteData)
+	assert.True(t, p.Actions[2].DeleteData)
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/autobrr/tqm/pkg/config"
+)
+
+// workerPool runs work on a bounded number of goroutines, and hands the finished items back to the caller so their
+// bookkeeping can stay on a single goroutine
+type workerPool[T any] struct {
+	sem chan struct{}
+	wg  sync.WaitGroup
+
+	mu   sync.Mutex
+	done []T
+}
+
+func newWorkerPool[T any](workers int) *workerPool[T] {
+	return &workerPool[T]{sem: make(chan struct{}, workers)}
+}
+
+// submit runs fn with item on a worker, blocking while all workers are busy
+func (p *workerPool[T]) submit(item T, fn func(T)) {
+	p.sem <- struct{}{}
+	p.wg.Add(1)
+
+	go func() {
+		defer p.wg.Done()
+		defer func() { <-p.sem }()
+
+		fn(item)
+
+		p.mu.Lock()
+		p.done = append(p.done, item)
+		p.mu.Unlock()
+	}()
+}
+
+// finished returns the items finished since the last call
+func (p *workerPool[T]) finished() []T {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	done := p.done
+	p.done = nil
+	return done
+}
+
+// wait waits for all submitted items and returns the ones not yet returned by finished
+func (p *workerPool[T]) wait() []T {
+	p.wg.Wait()
+	return p.finished()
+}
+
+// removalSettings returns the pause after each removal and how many torrents are removed at once
+func removalSettings(log *logrus.Entry, filter *config.FilterConfiguration) (time.Duration, int) {
+	delay, concurrency := removalDelay, 1
+	if config.Config == nil {
+		return delay, concurrency
+	}
+
+	if d := config.Config.Removal.Delay; d != nil {
+		delay = *d
+	}
+	concurrency = max(concurrency, config.Config.Removal.Concurrency)
+
+	// free space filters are evaluated against the space reclaimed so far, which needs the removals in order
+	if concurrency > 1 && filter != nil && filterUsesFreeSpace(filter) {
+		log.Warn("Removing torrents one at a time, as the filter uses free space")
+		concurrency = 1
+	}
+
+	return delay, concurrency
+}
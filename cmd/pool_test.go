@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerPool(t *testing.T) {
+	const workers = 3
+
+	pool := newWorkerPool[int](workers)
+
+	var running, peak atomic.Int32
+	var done []int
+	for i := range 12 {
+		pool.submit(i, func(int) {
+			n := running.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+		})
+		done = append(done, pool.finished()...)
+	}
+	done = append(done, pool.wait()...)
+
+	assert.ElementsMatch(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}, done, "every item should be returned once")
+	assert.LessOrEqual(t, peak.Load(), int32(workers))
+	assert.Greater(t, peak.Load(), int32(1), "items should run concurrently")
+	assert.Empty(t, pool.finished())
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/autobrr/tqm/pkg/client"
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/tracker"
+)
+
+// defaultQuarantineTag is the tag of quarantined torrents when the filter does not set one
+const defaultQuarantineTag = "quarantine"
+
+// quarantineSettings returns the client to tag quarantined torrents with and the quarantine tag and period of a
+// filter, the client is nil when quarantine is disabled
+func quarantineSettings(c client.Interface, filter *config.FilterConfiguration) (client.TagInterface, string, time.Duration, error) {
+	if filter == nil || filter.Quarantine.Period <= 0 {
+		return nil, "", 0, nil
+	}
+
+	tc, ok := c.(client.TagInterface)
+	if !ok {
+		return nil, "", 0, fmt.Errorf("quarantine: client does not support tags: %s", c.Type())
+	}
+
+	tag := filter.Quarantine.Tag
+	if tag == "" {
+		tag = defaultQuarantineTag
+	}
+
+	return tc, tag, filter.Quarantine.Period, nil
+}
+
+// quarantineTimestampTag is the machine tag recording when a torrent was quarantined, e.g. "quarantine:1700000000"
+func quarantineTimestampTag(tag string, at time.Time) string {
+	return fmt.Sprintf("%s:%d", tag, at.Unix())
+}
+
+// quarantinedAt returns when t was quarantined, read from its timestamp tag
+func quarantinedAt(t *config.Torrent, tag string) (time.Time, bool) {
+	value := t.TagValue(tag + ":")
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(seconds, 0), true
+}
+
+// quarantineExpired reports whether t has been quarantined for at least period at the time at
+func quarantineExpired(t *config.Torrent, tag string, period time.Duration, at time.Time) bool {
+	since, ok := quarantinedAt(t, tag)
+	return ok && at.Sub(since) >= period
+}
+
+// quarantineRuleTag is the machine tag recording the remove filter a torrent was quarantined by, as a hash of the
+// expression since tags can't hold every character of one, e.g. "quarantine-rule:9f3c21ab"
+func quarantineRuleTag(tag string, reason string) string {
+	return fmt.Sprintf("%s-rule:%s", tag, quarantineRuleHash(reason))
+}
+
+func quarantineRuleHash(reason string) string {
+	h := fnv.New32a()
+	h.Write([]byte(reason))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// trackerMessageFields are the fields and functions of filters that depend on the tracker messages
+var trackerMessageFields = regexp.MustCompile(`(?i)\b(TrackerStatus|AllTrackerStatuses|IsUnregistered|IsTrackerDown|` +
+	`IsIntermediateStatus|IsUpgraded|UnregisteredTrackerCount|UnregisteredOnAllTrackers)\b`)
+
+// quarantineLatches reports whether the remove filter expression that quarantined t keeps deciding its removal.
+// Quarantined torrents are paused, which clears the tracker messages, so a filter depending on them can't be
+// re-evaluated unless the tracker API of t can tell whether it is still unregistered
+func quarantineLatches(t *config.Torrent, expression string) bool {
+	return trackerMessageFields.MatchString(expression) && tracker.Get(t.TrackerName) == nil
+}
+
+// quarantineRefresh decides the registration of a quarantined torrent with its tracker API, since its tracker messages
+// were cleared by the pause. Torrents that are not quarantined or whose tracker has no API are left as they are
+func quarantineRefresh(ctx context.Context, t *config.Torrent, tag string) error {
+	if _, quarantined := quarantinedAt(t, tag); !quarantined || tracker.Get(t.TrackerName) == nil {
+		return nil
+	}
+
+	if !t.CheckRegistrationByAPI(ctx) {
+		return fmt.Errorf("quarantine: check registration of %s with the tracker api", t.TrackerName)
+	}
+
+	return nil
+}
+
+// quarantineDecision returns whether a quarantined torrent is still removed and why. The remove filter recorded when
+// it was quarantined decides instead of the evaluation of the paused torrent while it is configured and latches (see
+// quarantineLatches), every other quarantined torrent keeps the evaluation and is released once it no longer matches
+func quarantineDecision(t *config.Torrent, tag string, filter *config.FilterConfiguration, remove bool, reason string) (bool, string) {
+	rule := t.TagValue(tag + "-rule:")
+	if rule == "" {
+		return remove, reason
+	}
+
+	for _, expression := range filter.Remove {
+		if quarantineRuleHash(expression) == rule {
+			if !quarantineLatches(t, expression) {
+				return remove, reason
+			}
+			return true, expression
+		}
+	}
+
+	return false, ""
+}
+
+// quarantineTags returns the quarantine tags t carries
+func quarantineTags(t *config.Torrent, tag string) []string {
+	var tags []string
+	for _, v := range t.Tags {
+		if strings.EqualFold(v, tag) || hasPrefixFold(v, tag+":") || hasPrefixFold(v, tag+"-rule:") {
+			tags = append(tags, v)
+		}
+	}
+
+	return tags
+}
+
+func hasPrefixFold(s string, prefix string) bool {
+	return len(s) > len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+// quarantineTorrent tags t with the quarantine tags and pauses it, a later run removes it once the period has passed
+// while it still meets the remove filters, or while reason is configured when it latches
+func quarantineTorrent(ctx context.Context, log *logrus.Entry, c client.TagInterface, t *config.Torrent, tag string, reason string) error {
+	tags := []string{tag, quarantineTimestampTag(tag, now())}
+	if quarantineLatches(t, reason) {
+		tags = append(tags, quarantineRuleTag(tag, reason))
+	}
+	if flagDryRun {
+		log.Infof("[DRY-RUN] Would quarantine: %q (tags: %s)", t.Name, strings.Join(tags, ", "))
+		return nil
+	}
+
+	if err := c.AddTags(ctx, t.Hash, tags); err != nil {
+		return fmt.Errorf("add quarantine tags: %w", err)
+	}
+
+	if err := c.PauseTorrents(ctx, []string{t.Hash}); err != nil {
+		return fmt.Errorf("pause: %w", err)
+	}
+
+	log.Infof("Quarantined: %q", t.Name)
+	return nil
+}
+
+// releaseQuarantine removes the quarantine tags of a torrent that no longer meets the remove filters and resumes it
+func releaseQuarantine(ctx context.Context, log *logrus.Entry, c client.TagInterface, t *config.Torrent, tag string) error {
+	tags := quarantineTags(t, tag)
+	if len(tags) == 0 {
+		return nil
+	}
+
+	if flagDryRun {
+		log.Infof("[DRY-RUN] Would release from quarantine: %q", t.Name)
+		return nil
+	}
+
+	if err := c.RemoveTags(ctx, t.Hash, tags); err != nil {
+		return fmt.Errorf("remove quarantine tags: %w", err)
+	}
+
+	if err := c.ResumeTorrents(ctx, []string{t.Hash}); err != nil {
+		return fmt.Errorf("resume: %w", err)
+	}
+
+	log.Infof("Released from quarantine: %q", t.Name)
+	return nil
+}
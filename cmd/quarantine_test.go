@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/hardlinkfilemap"
+	"github.com/autobrr/tqm/pkg/logger"
+	"github.com/autobrr/tqm/pkg/torrentfilemap"
+	"github.com/autobrr/tqm/pkg/tracker"
+)
+
+func TestQuarantineExpired(t *testing.T) {
+	quarantined := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	period := 72 * time.Hour
+
+	tests := []struct {
+		name     string
+		tags     []string
+		at       time.Time
+		expected bool
+	}{
+		{name: "not_quarantined", tags: []string{"tv"}, at: quarantined.Add(period), expected: false},
+		{name: "within_period", tags: []string{"quarantine", "quarantine:1704103200"}, at: quarantined.Add(period - time.Second), expected: false},
+		{name: "period_passed", tags: []string{"quarantine", "quarantine:1704103200"}, at: quarantined.Add(period), expected: true},
+		{name: "tag_case", tags: []string{"Quarantine:1704103200"}, at: quarantined.Add(2 * period), expected: true},
+		{name: "invalid_timestamp", tags: []string{"quarantine:soon"}, at: quarantined.Add(2 * period), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			torrent := &config.Torrent{Tags: tt.tags}
+			assert.Equal(t, tt.expected, quarantineExpired(torrent, "quarantine", period, tt.at))
+		})
+	}
+
+	assert.Equal(t, "quarantine:1704103200", quarantineTimestampTag("quarantine", quarantined))
+	assert.Equal(t, []string{"quarantine", "quarantine:1704103200", "quarantine-rule:0badf00d"},
+		quarantineTags(&config.Torrent{Tags: []string{"tv", "quarantine", "quarantine:1704103200", "quarantined",
+			"quarantine-rule:0badf00d"}}, "quarantine"))
+}
+
+func TestQuarantineLatches(t *testing.T) {
+	require.NoError(t, tracker.Init(tracker.Config{RED: tracker.REDConfig{Key: "key"}}))
+	t.Cleanup(func() { require.NoError(t, tracker.Init(tracker.Config{})) })
+
+	tests := []struct {
+		name       string
+		tracker    string
+		expression string
+		expected   bool
+	}{
+		{name: "tracker_message", tracker: "tracker.com", expression: `IsUnregistered()`, expected: true},
+		{name: "tracker_status", tracker: "tracker.com", expression: `TrackerStatus contains "gone"`, expected: true},
+		{name: "lowercase_function", tracker: "tracker.com", expression: `isUnregistered()`, expected: true},
+		{name: "tracker_api", tracker: "flacsfor.me", expression: `IsUnregistered()`, expected: false},
+		{name: "other_fields", tracker: "tracker.com", expression: `Label == "remove"`, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, quarantineLatches(&config.Torrent{TrackerName: tt.tracker}, tt.expression))
+		})
+	}
+}
+
+func TestRemoveEligibleTorrents_Quarantine(t *testing.T) {
+	removalDelay = 0
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	now = func() time.Time { return start }
+	t.Cleanup(func() {
+		removalDelay = time.Second
+		now = time.Now
+	})
+
+	filter := &config.FilterConfiguration{Remove: []string{`Label == "remove"`}}
+	filter.Quarantine.Period = 72 * time.Hour
+
+	c := newMockClient(t, filter, 0, map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Label: "remove", Files: []string{"/data/a"}},
+		"b": {Hash: "b", Name: "b", Label: "keep", Files: []string{"/data/b"}, Tags: []string{"quarantine", "quarantine:1"}},
+	})
+
+	run := func() {
+		t.Helper()
+		working, err := c.GetTorrents(context.Background())
+		require.NoError(t, err)
+
+		err = removeEligibleTorrents(context.Background(), logger.GetLogger("test"), c, working, torrentfilemap.New(working),
+			hardlinkfilemap.NewNoopHardlinkFileMap(), filter, &recordingSender{}, "test", time.Now(), nil)
+		require.NoError(t, err)
+	}
+
+	// the first run quarantines the torrent instead of removing it, and releases the torrent that no longer matches
+	run()
+	assert.Empty(t, c.Removed)
+	assert.Equal(t, []string{"a"}, c.Paused)
+	assert.Equal(t, []string{"b"}, c.Resumed)
+
+	torrents, err := c.GetTorrents(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"quarantine", "quarantine:1704103200"}, torrents["a"].Tags)
+	assert.Empty(t, torrents["b"].Tags)
+
+	// within the quarantine period the torrent is kept
+	now = func() time.Time { return start.Add(71 * time.Hour) }
+	run()
+	assert.Empty(t, c.Removed)
+
+	// once the period has passed it is removed
+	now = func() time.Time { return start.Add(72 * time.Hour) }
+	run()
+	assert.Equal(t, []string{"a"}, c.Removed)
+}
+
+func TestRemoveEligibleTorrents_QuarantineRecordedRule(t *testing.T) {
+	config.InitializeTrackerStatuses(config.TrackerErrorsConfig{})
+	removalDelay = 0
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	now = func() time.Time { return start }
+	t.Cleanup(func() {
+		removalDelay = time.Second
+		now = time.Now
+	})
+
+	filter := &config.FilterConfiguration{Remove: []string{`IsUnregistered()`, `Label == "remove"`}}
+	filter.Quarantine.Period = 72 * time.Hour
+
+	c := newMockClient(t, filter, 0, map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Files: []string{"/data/a"}, TrackerStatus: "Unregistered torrent"},
+		"b": {Hash: "b", Name: "b", Label: "remove", Files: []string{"/data/b"}},
+	})
+
+	run := func() {
+		t.Helper()
+		working, err := c.GetTorrents(context.Background())
+		require.NoError(t, err)
+
+		err = removeEligibleTorrents(context.Background(), logger.GetLogger("test"), c, working, torrentfilemap.New(working),
+			hardlinkfilemap.NewNoopHardlinkFileMap(), filter, &recordingSender{}, "test", time.Now(), nil)
+		require.NoError(t, err)
+	}
+
+	run()
+	assert.ElementsMatch(t, []string{"a", "b"}, c.Paused)
+
+	// only the filter depending on the tracker message of a is recorded
+	a, _ := c.Torrent("a")
+	b, _ := c.Torrent("b")
+	assert.Contains(t, a.Tags, quarantineRuleTag("quarantine", `IsUnregistered()`))
+	assert.Equal(t, []string{"quarantine", "quarantine:1704103200"}, b.Tags)
+
+	// pausing cleared the tracker message of a, the recorded filter still decides
+	require.False(t, a.IsUnregistered(context.Background()))
+
+	// b stops meeting the remove filters during the quarantine, it is re-evaluated and released instead of removed
+	require.NoError(t, c.SetTorrentLabel(context.Background(), "b", "keep", false))
+	now = func() time.Time { return start.Add(72 * time.Hour) }
+	run()
+	assert.Equal(t, []string{"a"}, c.Removed)
+	assert.Equal(t, []string{"b"}, c.Resumed)
+
+	b, _ = c.Torrent("b")
+	assert.Empty(t, b.Tags)
+}
+
+func TestRemoveEligibleTorrents_QuarantineRecordedRuleRemoved(t *testing.T) {
+	config.InitializeTrackerStatuses(config.TrackerErrorsConfig{})
+	removalDelay = 0
+	now = func() time.Time { return time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC) }
+	t.Cleanup(func() {
+		removalDelay = time.Second
+		now = time.Now
+	})
+
+	filter := &config.FilterConfiguration{Remove: []string{`IsUnregistered()`}}
+	filter.Quarantine.Period = 72 * time.Hour
+
+	// a was quarantined by a filter that is no longer configured
+	c := newMockClient(t, filter, 0, map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Files: []string{"/data/a"},
+			Tags: []string{"quarantine", "quarantine:1", quarantineRuleTag("quarantine", `TrackerStatus contains "gone"`)}},
+	})
+
+	working, err := c.GetTorrents(context.Background())
+	require.NoError(t, err)
+
+	err = removeEligibleTorrents(context.Background(), logger.GetLogger("test"), c, working, torrentfilemap.New(working),
+		hardlinkfilemap.NewNoopHardlinkFileMap(), filter, &recordingSender{}, "test", time.Now(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, c.Removed)
+	assert.Equal(t, []string{"a"}, c.Resumed)
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+
+	"github.com/autobrr/tqm/pkg/client"
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/expression"
+	"github.com/autobrr/tqm/pkg/logger"
+	"github.com/autobrr/tqm/pkg/tracker"
+)
+
+var flagQueryFormat string
+
+var queryCmd = &cobra.Command{
+	Use:   "query [CLIENT] [EXPRESSION]",
+	Short: "List torrents matching an expression",
+	Long: `This command evaluates a single filter expression against a torrent client's queue and prints the matching torrents,
+without a filter in the config file. Nothing is changed on the client.`,
+
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		// init core
+		if !initialized {
+			initCore(true)
+			initialized = true
+		}
+
+		// set log
+		log := logger.GetLogger("query")
+
+		if flagQueryFormat != reportFormatTable && flagQueryFormat != reportFormatJSON {
+			log.Fatalf("Unsupported format: %q (table or json)", flagQueryFormat)
+		}
+
+		// retrieve client object
+		clientName := args[0]
+		clientConfig, ok := config.Config.Clients[clientName]
+		if !ok {
+			log.Fatalf("No client configuration found for: %q", clientName)
+		}
+
+		// validate client is enabled
+		if err := validateClientEnabled(clientConfig); err != nil {
+			log.WithError(err).Fatal("Failed validating client is enabled")
+		}
+
+		// retrieve client type
+		clientType, err := getClientConfigString("type", clientConfig)
+		if err != nil {
+			log.WithError(err).Fatal("Failed determining client type")
+		}
+
+		// retrieve client free space path (needed for Deluge free space check)
+		clientFreeSpacePath, _ := getClientConfigString("free_space_path", clientConfig)
+
+		// compile the expression as a one-off filter
+		queryFilter := &config.FilterConfiguration{Remove: []string{args[1]}}
+
+		exp, err := expression.Compile(queryFilter)
+		if err != nil {
+			log.WithError(err).Fatal("Failed compiling expression")
+		}
+
+		// load client object
+		c, err := client.NewClient(*clientType, clientName, exp)
+		if err != nil {
+			log.WithError(err).Fatalf("Failed initializing client: %q", clientName)
+		}
+
+		log.Infof("Initialized client %q, type: %s (%d trackers)", clientName, c.Type(), tracker.Loaded())
+
+		// connect to client
+		if err := c.Connect(ctx); err != nil {
+			log.WithError(err).Fatal("Failed connecting")
+		} else {
+			log.Debugf("Connected to client")
+		}
+
+		// get free disk space (can/will be used by the expression)
+		switch *clientType {
+		case "qbittorrent":
+			if _, err := c.GetCurrentFreeSpace(ctx, ""); err != nil {
+				log.WithError(err).Error("Failed retrieving free-space")
+			}
+
+		case "deluge":
+			if clientFreeSpacePath != nil {
+				if _, err := c.GetCurrentFreeSpace(ctx, *clientFreeSpacePath); err != nil {
+					log.WithError(err).Errorf("Failed retrieving free-space for: %q", *clientFreeSpacePath)
+					os.Exit(1)
+				}
+			} else if filterUsesFreeSpace(queryFilter) {
+				log.Error("Deluge requires free_space_path to be configured in order to retrieve free space information")
+				os.Exit(1)
+			}
+		}
+
+		// retrieve torrents
+		torrents, err := retrieveTorrents(ctx, c)
+		if err != nil {
+			log.WithError(err).Fatal("Failed retrieving torrents")
+		} else {
+			log.Infof("Retrieved %d torrents", len(torrents))
+		}
+
+		// evaluate time based fields as of the requested time
+		if offset, err := applyAsOf(torrents); err != nil {
+			log.WithError(err).Fatal("Failed applying --as-of time")
+		} else if offset != 0 {
+			log.Warnf("Evaluating filters as of %s (%s from now)", now().Add(offset).Format(time.RFC3339), offset.Round(time.Second))
+		}
+
+		// apply tracker, label/tag and hash pre-filters
+		applyPreFilters(log, torrents)
+
+		matches, err := queryTorrents(ctx, torrents, exp.Removes)
+		if err != nil {
+			log.WithError(err).Fatal("Failed evaluating expression")
+		}
+
+		log.Infof("%d of %d torrents match", len(matches), len(torrents))
+
+		if err := writeQueryResults(os.Stdout, matches, flagQueryFormat); err != nil {
+			log.WithError(err).Fatal("Failed writing matching torrents")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(queryCmd)
+
+	queryCmd.Flags().StringVar(&flagQueryFormat, "format", reportFormatTable, "Output format (table or json)")
+	addPreFilterFlags(queryCmd)
+}
+
+// queryTorrents returns the torrents matching any of the expressions, sorted by name
+func queryTorrents(ctx context.Context, torrents map[string]config.Torrent, expressions []expression.CompiledExpression) ([]config.Torrent, error) {
+	var matches []config.Torrent
+	for _, t := range torrents {
+		match, err := expression.CheckTorrentSingleMatch(ctx, &t, expressions)
+		if err != nil {
+			return nil, fmt.Errorf("evaluate %q: %w", t.Name, err)
+		}
+
+		if match {
+			matches = append(matches, t)
+		}
+	}
+
+	slices.SortFunc(matches, func(a, b config.Torrent) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return matches, nil
+}
+
+type queryResult struct {
+	Name        string  `json:"name"`
+	Hash        string  `json:"hash"`
+	Tracker     string  `json:"tracker"`
+	Label       string  `json:"label"`
+	State       string  `json:"state"`
+	Bytes       int64   `json:"bytes"`
+	Ratio       float64 `json:"ratio"`
+	SeedingDays float32 `json:"seeding_days"`
+}
+
+// writeQueryResults lists the matching torrents as a table or json
+func writeQueryResults(w io.Writer, torrents []config.Torrent, format string) error {
+	results := make([]queryResult, 0, len(torrents))
+	for _, t := range torrents {
+		results = append(results, queryResult{
+			Name:        t.Name,
+			Hash:        t.Hash,
+			Tracker:     t.TrackerName,
+			Label:       t.Label,
+			State:       t.State,
+			Bytes:       t.TotalBytes,
+			Ratio:       t.Ratio,
+			SeedingDays: t.SeedingDays,
+		})
+	}
+
+	if format == reportFormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(results); err != nil {
+			return fmt.Errorf("encode results: %w", err)
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tTRACKER\tLABEL\tSTATE\tSIZE\tRATIO\tSEED DAYS\tHASH")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%.2f\t%.1f\t%s\n", r.Name, r.Tracker, r.Label, r.State,
+			humanize.IBytes(uint64(r.Bytes)), r.Ratio, r.SeedingDays, r.Hash)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("write results: %w", err)
+	}
+
+	return nil
+}
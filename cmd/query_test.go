@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/expression"
+)
+
+func TestQueryTorrents(t *testing.T) {
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Name: "Show.S02", TrackerName: "tracker.com", Ratio: 6},
+		"b": {Hash: "b", Name: "Show.S01", TrackerName: "tracker.com", Ratio: 8},
+		"c": {Hash: "c", Name: "Movie", TrackerName: "tracker.com", Ratio: 1},
+		"d": {Hash: "d", Name: "Other", TrackerName: "other.net", Ratio: 9},
+	}
+
+	exp, err := expression.Compile(&config.FilterConfiguration{
+		Remove: []string{`Ratio > 5 && TrackerName == "tracker.com"`},
+	})
+	require.NoError(t, err)
+
+	matches, err := queryTorrents(context.Background(), torrents, exp.Removes)
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(matches))
+	for _, m := range matches {
+		names = append(names, m.Name)
+	}
+	assert.Equal(t, []string{"Show.S01", "Show.S02"}, names)
+}
+
+func TestWriteQueryResults(t *testing.T) {
+	torrents := []config.Torrent{
+		{Hash: "abc", Name: "Show.S01", TrackerName: "tracker.com", Label: "tv", State: "stalledUP", TotalBytes: 2048, Ratio: 2.5, SeedingDays: 10},
+	}
+
+	t.Run("table", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, writeQueryResults(&out, torrents, reportFormatTable))
+		assert.Contains(t, out.String(), "NAME")
+		assert.Contains(t, out.String(), "Show.S01")
+		assert.Contains(t, out.String(), "2.0 KiB")
+		assert.Contains(t, out.String(), "2.50")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, writeQueryResults(&out, torrents, reportFormatJSON))
+
+		var results []queryResult
+		require.NoError(t, json.Unmarshal(out.Bytes(), &results))
+		require.Len(t, results, 1)
+		assert.Equal(t, "abc", results[0].Hash)
+		assert.Equal(t, "tv", results[0].Label)
+		assert.Equal(t, int64(2048), results[0].Bytes)
+	})
+
+	t.Run("json_empty", func(t *testing.T) {
+		var out bytes.Buffer
+		require.NoError(t, writeQueryResults(&out, nil, reportFormatJSON))
+		assert.JSONEq(t, "[]", out.String())
+	})
+}
@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/autobrr/tqm/pkg/client"
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/expression"
+	"github.com/autobrr/tqm/pkg/logger"
+	"github.com/autobrr/tqm/pkg/tracker"
+)
+
+var flagCreateCategories bool
+
+// labelMismatch is a torrent whose label differs from the one its label rules select
+type labelMismatch struct {
+	Hash   string
+	Name   string
+	Label  string
+	Target string
+	// Missing is set when the target label is not a category of the client
+	Missing bool
+}
+
+var reconcileLabelsCmd = &cobra.Command{
+	Use:   "reconcile-labels [CLIENT]",
+	Short: "Report torrents whose label differs from their label rules",
+	Long: `This command compares the label of every torrent with the label its filter's label rules select, and prints a
+table of the torrents that relabel would change. Label rules naming a category that does not exist in the client are
+reported, and with --create-categories created. Torrents are never relabeled.`,
+
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		// init core
+		if !initialized {
+			initCore(true)
+			initialized = true
+		}
+
+		// set log
+		log := logger.GetLogger("reconcile-labels")
+
+		// retrieve client object
+		clientName := args[0]
+		clientConfig, ok := config.Config.Clients[clientName]
+		if !ok {
+			log.Fatalf("No client configuration found for: %q", clientName)
+		}
+
+		// validate client is enabled
+		if err := validateClientEnabled(clientConfig); err != nil {
+			log.WithError(err).Fatal("Failed validating client is enabled")
+		}
+
+		// retrieve client type
+		clientType, err := getClientConfigString("type", clientConfig)
+		if err != nil {
+			log.WithError(err).Fatal("Failed determining client type")
+		}
+
+		// retrieve client filters
+		clientFilter, err := getClientFilter(clientName, clientConfig)
+		if err != nil {
+			log.WithError(err).Fatal("Failed retrieving client filter")
+		}
+
+		if flagFilterName != "" {
+			clientFilter, err = getFilter(flagFilterName)
+			if err != nil {
+				log.WithError(err).Fatal("Failed retrieving specified filter")
+			}
+		}
+
+		// compile client filters
+		exp, err := expression.Compile(clientFilter)
+		if err != nil {
+			log.WithError(err).Fatal("Failed compiling client filters")
+		}
+
+		// load client object
+		c, err := client.NewClient(*clientType, clientName, exp)
+		if err != nil {
+			log.WithError(err).Fatalf("Failed initializing client: %q", clientName)
+		}
+
+		log.Infof("Initialized client %q, type: %s (%d trackers)", clientName, c.Type(), tracker.Loaded())
+
+		// connect to client
+		if err := c.Connect(ctx); err != nil {
+			log.WithError(err).Fatal("Failed connecting")
+		} else {
+			log.Debugf("Connected to client")
+		}
+
+		// load client label path map
+		if err := c.LoadLabelPathMap(ctx); err != nil {
+			log.WithError(err).Fatal("Failed loading label path map")
+		}
+
+		// categories named by label rules that the client does not have
+		missing := missingCategories(clientFilter, c.LabelPathMap())
+		for _, name := range missing {
+			log.Warnf("Label rule names a category that does not exist: %q", name)
+		}
+
+		if flagCreateCategories && len(missing) > 0 {
+			created, err := createCategories(ctx, c, missing)
+			if err != nil {
+				log.WithError(err).Fatal("Failed creating categories")
+			}
+			if flagDryRun {
+				log.Infof("[DRY-RUN] Would create %d category(s): %s", len(missing), strings.Join(missing, ", "))
+			} else {
+				log.Infof("Created %d category(s): %s", len(created), strings.Join(created, ", "))
+				if err := c.LoadLabelPathMap(ctx); err != nil {
+					log.WithError(err).Fatal("Failed reloading label path map")
+				}
+			}
+		}
+
+		// retrieve torrents
+		torrents, err := c.GetTorrents(ctx)
+		if err != nil {
+			log.WithError(err).Fatal("Failed retrieving torrents")
+		} else {
+			log.Infof("Retrieved %d torrents", len(torrents))
+		}
+
+		// apply tracker, label/tag and hash pre-filters
+		applyPreFilters(log, torrents)
+
+		mismatches, err := labelMismatches(ctx, c, torrents)
+		if err != nil {
+			log.WithError(err).Fatal("Failed evaluating label rules")
+		}
+
+		if err := writeLabelMismatches(os.Stdout, mismatches); err != nil {
+			log.WithError(err).Fatal("Failed writing mismatches")
+		}
+
+		log.Infof("Found %d torrent(s) with a label differing from their label rules", len(mismatches))
+	},
+}
+
+func init() {
+	reconcileLabelsCmd.Flags().StringVar(&flagFilterName, "filter", "", "Filter to use instead of client")
+	reconcileLabelsCmd.Flags().BoolVar(&flagCreateCategories, "create-categories", false, "Create the categories named by label rules that do not exist")
+	addPreFilterFlags(reconcileLabelsCmd)
+
+	rootCmd.AddCommand(reconcileLabelsCmd)
+}
+
+// missingCategories returns the labels named by the label rules of filter that are not in labelPaths, sorted
+func missingCategories(filter *config.FilterConfiguration, labelPaths map[string]string) []string {
+	var missing []string
+	for _, label := range filter.Label {
+		if _, ok := labelPaths[label.Name]; !ok && label.Name != "" && !slices.Contains(missing, label.Name) {
+			missing = append(missing, label.Name)
+		}
+	}
+
+	slices.Sort(missing)
+	return missing
+}
+
+// createCategories creates the missing categories in a client supporting them, returning the ones created. A dry-run
+// creates none
+func createCategories(ctx context.Context, c client.Interface, names []string) ([]string, error) {
+	cc, ok := c.(client.CategoryInterface)
+	if !ok {
+		return nil, fmt.Errorf("client does not support categories: %s", c.Type())
+	}
+
+	if flagDryRun {
+		return nil, nil
+	}
+
+	if err := checkSafeMode(); err != nil {
+		return nil, err
+	}
+
+	var created []string
+	for _, name := range names {
+		if err := cc.CreateCategory(ctx, name); err != nil {
+			return created, err
+		}
+		created = append(created, name)
+	}
+
+	return created, nil
+}
+
+// labelMismatches returns the torrents whose label rules select a label other than their own, sorted by name
+func labelMismatches(ctx context.Context, c client.Interface, torrents map[string]config.Torrent) ([]labelMismatch, error) {
+	labelPaths := c.LabelPathMap()
+
+	var mismatches []labelMismatch
+	for _, h := range slices.Sorted(maps.Keys(torrents)) {
+		t := torrents[h]
+
+		tctx, done := torrentContext(ctx)
+		label, relabel, err := c.ShouldRelabel(tctx, &t)
+		done(log, &t)
+		if err != nil {
+			return nil, fmt.Errorf("label rules: %v: %w", t.Name, err)
+		} else if !relabel || label == t.Label {
+			continue
+		}
+
+		_, exists := labelPaths[label]
+		mismatches = append(mismatches, labelMismatch{
+			Hash:    t.Hash,
+			Name:    t.Name,
+			Label:   t.Label,
+			Target:  label,
+			Missing: labelPaths != nil && !exists,
+		})
+	}
+
+	slices.SortStableFunc(mismatches, func(a, b labelMismatch) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	return mismatches, nil
+}
+
+// writeLabelMismatches writes a table of the mismatches
+func writeLabelMismatches(w io.Writer, mismatches []labelMismatch) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tLABEL\tTARGET\tCATEGORY\tHASH")
+	for _, m := range mismatches {
+		category := "exists"
+		if m.Missing {
+			category = "missing"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", m.Name, m.Label, m.Target, category, m.Hash)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("write mismatches: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+)
+
+// addLabelRule adds a label rule to filter
+func addLabelRule(filter *config.FilterConfiguration, name string, update string) {
+	filter.Label = append(filter.Label, struct {
+		Name     string
+		Priority int
+		Update   []string
+	}{Name: name, Update: []string{update}})
+}
+
+func TestLabelMismatches(t *testing.T) {
+	filter := &config.FilterConfiguration{}
+	addLabelRule(filter, "movies-done", `Label == "movies" && Ratio >= 1.0`)
+	addLabelRule(filter, "tv-done", `Label == "tv" && Ratio >= 1.0`)
+
+	c := newMockClient(t, filter, 0, map[string]config.Torrent{
+		"a": {Hash: "a", Name: "Movie", Label: "movies", Ratio: 2},
+		"b": {Hash: "b", Name: "Another Movie", Label: "movies", Ratio: 0.5},
+		"c": {Hash: "c", Name: "Show", Label: "tv", Ratio: 1},
+	})
+	c.LabelPaths = map[string]string{"movies": "/data/movies", "tv": "/data/tv", "movies-done": "/data/movies-done"}
+
+	torrents, err := c.GetTorrents(context.Background())
+	require.NoError(t, err)
+
+	mismatches, err := labelMismatches(context.Background(), c, torrents)
+	require.NoError(t, err)
+	assert.Equal(t, []labelMismatch{
+		{Hash: "a", Name: "Movie", Label: "movies", Target: "movies-done"},
+		{Hash: "c", Name: "Show", Label: "tv", Target: "tv-done", Missing: true},
+	}, mismatches)
+
+	var b bytes.Buffer
+	require.NoError(t, writeLabelMismatches(&b, mismatches))
+	assert.Equal(t, `NAME   LABEL   TARGET       CATEGORY  HASH
+Movie  movies  movies-done  exists    a
+Show   tv      tv-done      missing   c
+`, b.String())
+
+	assert.Equal(t, []string{"tv-done"}, missingCategories(filter, c.LabelPathMap()))
+}
+
+func TestCreateCategories(t *testing.T) {
+	t.Cleanup(func() { flagDryRun = false })
+
+	filter := &config.FilterConfiguration{}
+	addLabelRule(filter, "tv-done", "true")
+	c := newMockClient(t, filter, 0, nil)
+
+	flagDryRun = true
+	created, err := createCategories(context.Background(), c, []string{"tv-done"})
+	require.NoError(t, err)
+	assert.Empty(t, created)
+	assert.Empty(t, c.CreatedCategories)
+
+	flagDryRun = false
+	created, err = createCategories(context.Background(), c, []string{"tv-done"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tv-done"}, created)
+	assert.Empty(t, missingCategories(filter, c.LabelPathMap()))
+}
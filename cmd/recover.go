@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+
+	"github.com/autobrr/tqm/pkg/client"
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/expression"
+	"github.com/autobrr/tqm/pkg/logger"
+	"github.com/autobrr/tqm/pkg/tracker"
+)
+
+var recoverCmd = &cobra.Command{
+	Use:   "recover [CLIENT]",
+	Short: "Recheck and resume torrents in an error state",
+	Long:  `This command can be used to recheck and resume torrents a torrent client reports in an error state (e.g. missing files or a full disk), skipping torrents matched by its ignore filters.`,
+
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		start := time.Now()
+
+		// init core
+		if !initialized {
+			initCore(true)
+			initialized = true
+		}
+
+		// set log
+		log := logger.GetLogger("recover")
+
+		noti := newNotificationSender(log)
+
+		// retrieve client object
+		clientName := args[0]
+		clientConfig, ok := config.Config.Clients[clientName]
+		if !ok {
+			log.Fatalf("No client configuration found for: %q", clientName)
+		}
+
+		// validate client is enabled
+		if err := validateClientEnabled(clientConfig); err != nil {
+			log.WithError(err).Fatal("Failed validating client is enabled")
+		}
+
+		// retrieve client type
+		clientType, err := getClientConfigString("type", clientConfig)
+		if err != nil {
+			log.WithError(err).Fatal("Failed determining client type")
+		}
+
+		// retrieve client free space path (needed for Deluge free space check)
+		clientFreeSpacePath, _ := getClientConfigString("free_space_path", clientConfig)
+
+		// retrieve client filters
+		clientFilter, err := getClientFilter(clientName, clientConfig)
+		if err != nil {
+			log.WithError(err).Fatal("Failed retrieving client filter")
+		}
+
+		if flagFilterName != "" {
+			clientFilter, err = getFilter(flagFilterName)
+			if err != nil {
+				log.WithError(err).Fatal("Failed retrieving specified filter")
+			}
+		}
+
+		// compile client filters
+		exp, err := expression.Compile(clientFilter)
+		if err != nil {
+			log.WithError(err).Fatal("Failed compiling client filters")
+		}
+
+		// load client object
+		c, err := client.NewClient(*clientType, clientName, exp)
+		if err != nil {
+			log.WithError(err).Fatalf("Failed initializing client: %q", clientName)
+		}
+
+		log.Infof("Initialized client %q, type: %s (%d trackers)", clientName, c.Type(), tracker.Loaded())
+
+		// connect to client
+		if err := c.Connect(ctx); err != nil {
+			log.WithError(err).Fatal("Failed connecting")
+		} else {
+			log.Debugf("Connected to client")
+		}
+
+		// get free disk space (can/will be used by filters)
+		switch *clientType {
+		case "qbittorrent":
+			space, err := c.GetCurrentFreeSpace(ctx, "")
+			if err != nil {
+				log.WithError(err).Error("Failed retrieving free-space")
+			} else {
+				log.Infof("Retrieved free-space: %v (%.2f GB)",
+					humanize.IBytes(uint64(space)), c.GetFreeSpace())
+			}
+
+		case "deluge":
+			if clientFreeSpacePath != nil {
+				space, err := c.GetCurrentFreeSpace(ctx, *clientFreeSpacePath)
+				if err != nil {
+					log.WithError(err).Errorf("Failed retrieving free-space for: %q", *clientFreeSpacePath)
+					os.Exit(1)
+				} else {
+					log.Infof("Retrieved free-space for %q: %v (%.2f GB)", *clientFreeSpacePath,
+						humanize.IBytes(uint64(space)), c.GetFreeSpace())
+				}
+			} else {
+				if filterUsesFreeSpace(clientFilter) {
+					log.Error("Deluge requires free_space_path to be configured in order to retrieve free space information")
+					os.Exit(1)
+				}
+			}
+		}
+
+		// retrieve torrents
+		torrents, err := retrieveTorrents(ctx, c)
+		if err != nil {
+			log.WithError(err).Fatal("Failed retrieving torrents")
+		} else {
+			log.Infof("Retrieved %d torrents", len(torrents))
+		}
+
+		// only the torrent selected by --hash was retrieved, which says nothing about the size of the library
+		if flagHash == "" {
+			if err := checkTorrentCount(clientName, len(torrents)); err != nil {
+				log.WithError(err).Fatal("Refusing to act on the retrieved torrents")
+			}
+		}
+
+		// evaluate time based fields as of the requested time
+		if offset, err := applyAsOf(torrents); err != nil {
+			log.WithError(err).Fatal("Failed applying --as-of time")
+		} else if offset != 0 {
+			log.Warnf("Evaluating filters as of %s (%s from now)", now().Add(offset).Format(time.RFC3339), offset.Round(time.Second))
+		}
+
+		// apply tracker, label/tag and hash pre-filters
+		applyPreFilters(log, torrents)
+
+		// recheck and resume errored torrents that are not ignored
+		if err := recoverErroredTorrents(ctx, log, c, torrents, noti, clientName, start); err != nil {
+			log.WithError(err).Fatal("Failed recovering errored torrents...")
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(recoverCmd)
+
+	recoverCmd.Flags().StringVar(&flagFilterName, "filter", "", "Filter to use instead of client")
+	addPreFilterFlags(recoverCmd)
+}
@@ -12,8 +12,6 @@ import (
 	"github.com/autobrr/tqm/pkg/expression"
 	"github.com/autobrr/tqm/pkg/hardlinkfilemap"
 	"github.com/autobrr/tqm/pkg/logger"
-	"github.com/autobrr/tqm/pkg/notification"
-	"github.com/autobrr/tqm/pkg/torrentfilemap"
 	"github.com/autobrr/tqm/pkg/tracker"
 )
 
@@ -36,7 +34,7 @@ var relabelCmd = &cobra.Command{
 		// set log
 		log := logger.GetLogger("relabel")
 
-		noti := notification.NewDiscordSender(log, config.Config.Notifications)
+		noti := newNotificationSender(log)
 
 		// retrieve client object
 		clientName := args[0]
@@ -60,7 +58,7 @@ var relabelCmd = &cobra.Command{
 		clientFreeSpacePath, _ := getClientConfigString("free_space_path", clientConfig)
 
 		// retrieve client filters
-		clientFilter, err := getClientFilter(clientConfig)
+		clientFilter, err := getClientFilter(clientName, clientConfig)
 		if err != nil {
 			log.WithError(err).Fatal("Failed retrieving client filter")
 		}
@@ -126,9 +124,19 @@ var relabelCmd = &cobra.Command{
 			log.Infof("Retrieved %d torrents", len(torrents))
 		}
 
+		if err := checkTorrentCount(clientName, len(torrents)); err != nil {
+			log.WithError(err).Fatal("Refusing to act on the retrieved torrents")
+		}
+
+		// evaluate time based fields as of the requested time
+		if offset, err := applyAsOf(torrents); err != nil {
+			log.WithError(err).Fatal("Failed applying --as-of time")
+		} else if offset != 0 {
+			log.Warnf("Evaluating filters as of %s (%s from now)", now().Add(offset).Format(time.RFC3339), offset.Round(time.Second))
+		}
+
 		// create map of files associated to torrents (via hash)
-		tfm := torrentfilemap.New(torrents)
-		log.Infof("Mapped torrents to %d unique torrent files", tfm.Length())
+		tfm := newTorrentFileMap(log, torrents, clientFilter, clientConfig)
 
 		if evaluate.StringSliceContains(clientFilter.MapHardlinksFor, "relabel", true) {
 			// download path mapping
@@ -136,13 +144,13 @@ var relabelCmd = &cobra.Command{
 			if err != nil {
 				log.WithError(err).Fatal("Failed loading client download path mappings")
 			} else if clientDownloadPathMapping != nil {
-				log.Debugf("Loaded %d client download path mappings: %#v", len(clientDownloadPathMapping),
+				log.Debugf("Loaded %d client download path mappings: %s", clientDownloadPathMapping.Len(),
 					clientDownloadPathMapping)
 			}
 
 			// create map of paths associated to underlying file ids
 			start := time.Now()
-			hfm := hardlinkfilemap.New(torrents, clientDownloadPathMapping)
+			hfm := hardlinkfilemap.New(torrents, clientDownloadPathMapping, clientFilter.ResolveSymlinks, clientFilter.HardlinkFailClosed)
 			log.Infof("Mapped all torrent file paths to %d unique underlying file IDs in %s", hfm.Length(), time.Since(start))
 
 			// add HardlinkedOutsideClient field to torrents
@@ -155,6 +163,9 @@ var relabelCmd = &cobra.Command{
 			log.Warnf("If your setup involves multiple torrents sharing the same underlying file using hardlinks, or you are using the 'HardlinkedOutsideClient' field in your filters, you should add 'relabel' to the 'MapHardlinksFor' field in your filter configuration")
 		}
 
+		// apply tracker, label/tag and hash pre-filters
+		applyPreFilters(log, torrents)
+
 		// relabel torrents that meet the filter criteria
 		if err := relabelEligibleTorrents(ctx, log, c, torrents, tfm, noti, clientName, startTime); err != nil {
 			log.WithError(err).Fatal("Failed relabeling eligible torrents...")
@@ -166,4 +177,5 @@ func init() {
 	rootCmd.AddCommand(relabelCmd)
 
 	relabelCmd.Flags().StringVar(&flagFilterName, "filter", "", "Filter to use instead of client")
+	addPreFilterFlags(relabelCmd)
 }
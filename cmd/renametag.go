@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/autobrr/tqm/pkg/client"
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/logger"
+)
+
+var renameTagCmd = &cobra.Command{
+	Use:   "rename-tag [CLIENT] [OLD_TAG] [NEW_TAG]",
+	Short: "Rename a tag on client (only qbit)",
+	Long:  `This command can be used to rename a tag, moving every torrent with the old tag to the new tag and deleting the old tag.`,
+
+	Args: cobra.ExactArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		// init core
+		if !initialized {
+			initCore(true)
+			initialized = true
+		}
+
+		// set log
+		log := logger.GetLogger("rename-tag")
+
+		oldTag := strings.TrimSpace(args[1])
+		newTag := strings.TrimSpace(args[2])
+		if oldTag == "" || newTag == "" {
+			log.Fatal("Old and new tag must not be empty")
+		} else if oldTag == newTag {
+			log.Fatalf("Old and new tag are the same: %q", oldTag)
+		}
+
+		// retrieve client object
+		clientName := args[0]
+		clientConfig, ok := config.Config.Clients[clientName]
+		if !ok {
+			log.Fatalf("No client configuration found for: %q", clientName)
+		}
+
+		// validate client is enabled
+		if err := validateClientEnabled(clientConfig); err != nil {
+			log.WithError(err).Fatal("Failed validating client is enabled")
+		}
+
+		// retrieve client type
+		clientType, err := getClientConfigString("type", clientConfig)
+		if err != nil {
+			log.WithError(err).Fatal("Failed determining client type")
+		}
+
+		if *clientType != "qbittorrent" {
+			log.Fatalf("Renaming tags is currently only supported for qbittorrent")
+		}
+
+		// load client object
+		c, err := client.NewClient(*clientType, clientName, nil)
+		if err != nil {
+			log.WithError(err).Fatalf("Failed initializing client: %q", clientName)
+		}
+
+		ct, ok := c.(client.TagInterface)
+		if !ok {
+			log.Fatalf("Renaming tags is currently only supported for qbittorrent")
+		}
+
+		log.Infof("Initialized client %q, type: %s", clientName, ct.Type())
+
+		// connect to client
+		if err := ct.Connect(ctx); err != nil {
+			log.WithError(err).Fatal("Failed connecting")
+		} else {
+			log.Debugf("Connected to client")
+		}
+
+		if !flagDryRun {
+			if err := checkSafeMode(); err != nil {
+				log.WithError(err).Fatalf("Failed renaming tag %q to %q", oldTag, newTag)
+			}
+		}
+
+		moved, err := renameTag(ctx, log, ct, oldTag, newTag)
+		if err != nil {
+			log.WithError(err).Fatalf("Failed renaming tag %q to %q after moving %d torrents", oldTag, newTag, moved)
+		}
+
+		if flagDryRun {
+			log.Infof("Would move %d torrents from tag %q to %q", moved, oldTag, newTag)
+		} else {
+			log.Infof("Renamed tag %q to %q, moved %d torrents", oldTag, newTag, moved)
+		}
+	},
+}
+
+// renameTag moves the torrents of oldTag to newTag and returns how many were moved, a dry-run only counts them
+func renameTag(ctx context.Context, log *logrus.Entry, c client.TagInterface, oldTag string, newTag string) (int, error) {
+	if !flagDryRun {
+		return c.RenameTag(ctx, oldTag, newTag)
+	}
+
+	torrents, err := c.GetTorrents(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("get torrents: %w", err)
+	}
+
+	var tagged int
+	for _, t := range torrents {
+		if t.HasAnyTag(oldTag) {
+			tagged++
+		}
+	}
+
+	log.Warn("Dry-run enabled, skipping rename...")
+	return tagged, nil
+}
+
+func init() {
+	rootCmd.AddCommand(renameTagCmd)
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/logger"
+)
+
+func TestRenameTag(t *testing.T) {
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Tags: []string{"old"}},
+		"b": {Hash: "b", Name: "b", Tags: []string{"old", "keep"}},
+		"c": {Hash: "c", Name: "c", Tags: []string{"keep"}},
+	}
+
+	for _, dryRun := range []bool{false, true} {
+		name := "rename"
+		if dryRun {
+			name = "dry_run"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			flagDryRun = dryRun
+			t.Cleanup(func() { flagDryRun = false })
+
+			c := newMockClient(t, &config.FilterConfiguration{}, 0, torrents)
+
+			moved, err := renameTag(context.Background(), logger.GetLogger("test"), c, "old", "new")
+			require.NoError(t, err)
+			assert.Equal(t, 2, moved)
+
+			a, _ := c.Torrent("a")
+			b, _ := c.Torrent("b")
+			if dryRun {
+				assert.Equal(t, []string{"old"}, a.Tags)
+				assert.Empty(t, c.CreatedTags)
+				assert.Empty(t, c.DeletedTags)
+				return
+			}
+
+			assert.Equal(t, []string{"new"}, a.Tags)
+			assert.ElementsMatch(t, []string{"keep", "new"}, b.Tags)
+			assert.Equal(t, []string{"new"}, c.CreatedTags)
+			assert.Equal(t, []string{"old"}, c.DeletedTags)
+		})
+	}
+}
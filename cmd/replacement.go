@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/autobrr/tqm/pkg/config"
+)
+
+// releaseTokens end the title part of a release name, the quality, source, codec and edition tags that differ between
+// versions of the same release
+var releaseTokens = map[string]struct{}{
+	"480p": {}, "576p": {}, "720p": {}, "1080i": {}, "1080p": {}, "2160p": {}, "4k": {}, "uhd": {},
+	"bluray": {}, "bdrip": {}, "brrip": {}, "remux": {}, "web": {}, "webdl": {}, "webrip": {}, "hdtv": {},
+	"dvd": {}, "dvdrip": {}, "x264": {}, "x265": {}, "h264": {}, "h265": {}, "hevc": {}, "avc": {}, "xvid": {},
+	"hdr": {}, "proper": {}, "repack": {}, "internal": {}, "flac": {}, "mp3": {}, "aac": {}, "320": {}, "v0": {},
+}
+
+// mediaExtensions are stripped from single file release names
+var mediaExtensions = map[string]struct{}{
+	".mkv": {}, ".mp4": {}, ".avi": {}, ".m4v": {}, ".ts": {}, ".flac": {}, ".mp3": {}, ".zip": {}, ".rar": {},
+}
+
+// normalizeReleaseName reduces a release name to its title, e.g. "Movie.2020.1080p.BluRay.x264-GRP" and
+// "Movie (2020) 2160p WEB-DL" both become "movie 2020". The title ends after a year or season/episode tag, or at
+// the first quality, source or codec tag
+func normalizeReleaseName(name string) string {
+	name = strings.ToLower(name)
+	if _, ok := mediaExtensions[filepath.Ext(name)]; ok {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+
+	tokens := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var title []string
+	for _, token := range tokens {
+		if _, ok := releaseTokens[token]; ok && len(title) > 0 {
+			break
+		}
+
+		title = append(title, token)
+		if len(title) > 1 && (isYearToken(token) || isEpisodeToken(token)) {
+			break
+		}
+	}
+
+	return strings.Join(title, " ")
+}
+
+// isYearToken reports whether token is a release year, e.g. "2020"
+func isYearToken(token string) bool {
+	year, err := strconv.Atoi(token)
+	return err == nil && len(token) == 4 && year >= 1900 && year < 2100
+}
+
+// isEpisodeToken reports whether token is a season or episode tag, e.g. "s01" or "s01e02"
+func isEpisodeToken(token string) bool {
+	if len(token) < 3 || token[0] != 's' {
+		return false
+	}
+
+	season, episode, _ := strings.Cut(token[1:], "e")
+	if _, err := strconv.Atoi(season); err != nil {
+		return false
+	}
+	if _, err := strconv.Atoi(episode); episode != "" && err != nil {
+		return false
+	}
+
+	return true
+}
+
+// setReplacements marks the trumped or upgraded torrents that have a replacement, another torrent with the same
+// normalized name and a larger size that was not upgraded itself
+func setReplacements(torrents map[string]config.Torrent) {
+	byName := make(map[string][]config.Torrent)
+	for _, t := range torrents {
+		if name := normalizeReleaseName(t.Name); name != "" {
+			byName[name] = append(byName[name], t)
+		}
+	}
+
+	for h, t := range torrents {
+		if !t.IsUpgraded() {
+			continue
+		}
+
+		for _, other := range byName[normalizeReleaseName(t.Name)] {
+			if other.Hash != t.Hash && other.TotalBytes > t.TotalBytes && !other.IsUpgraded() {
+				t.HasReplacement = true
+				torrents[h] = t
+				break
+			}
+		}
+	}
+}
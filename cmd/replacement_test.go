@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/hardlinkfilemap"
+	"github.com/autobrr/tqm/pkg/logger"
+	"github.com/autobrr/tqm/pkg/torrentfilemap"
+)
+
+func TestNormalizeReleaseName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{name: "Movie.2020.1080p.BluRay.x264-GRP", expected: "movie 2020"},
+		{name: "Movie (2020) 2160p WEB-DL DV HDR", expected: "movie 2020"},
+		{name: "Movie.2020.720p.WEB.mkv", expected: "movie 2020"},
+		{name: "Show.S01E02.720p.HDTV.x264-GRP", expected: "show s01e02"},
+		{name: "Show S01 1080p WEB-DL", expected: "show s01"},
+		{name: "Artist - Album [FLAC]", expected: "artist album"},
+		{name: "2012.2009.1080p.BluRay", expected: "2012 2009"},
+		{name: "Some.Show.1080p.WEB", expected: "some show"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, normalizeReleaseName(tt.name))
+		})
+	}
+}
+
+func TestSetReplacements(t *testing.T) {
+	torrents := map[string]config.Torrent{
+		"old":     {Hash: "old", Name: "Movie.2020.720p.BluRay-GRP", TotalBytes: 5, TrackerStatus: "Trumped by a better release"},
+		"new":     {Hash: "new", Name: "Movie 2020 1080p BluRay-OTHER", TotalBytes: 10},
+		"smaller": {Hash: "smaller", Name: "Other.2021.1080p.WEB-GRP", TotalBytes: 10, TrackerStatus: "upgraded"},
+		"small":   {Hash: "small", Name: "Other.2021.720p.WEB-GRP", TotalBytes: 5},
+		"missing": {Hash: "missing", Name: "Third.2022.720p.WEB-GRP", TotalBytes: 5, TrackerStatus: "trumped"},
+	}
+
+	setReplacements(torrents)
+	assert.True(t, torrents["old"].HasReplacement)
+	assert.False(t, torrents["smaller"].HasReplacement, "a smaller torrent is not a replacement")
+	assert.False(t, torrents["missing"].HasReplacement)
+	assert.False(t, torrents["new"].HasReplacement)
+}
+
+func TestRemoveEligibleTorrents_RequireReplacement(t *testing.T) {
+	removalDelay = 0
+	t.Cleanup(func() { removalDelay = time.Second })
+
+	filter := &config.FilterConfiguration{Remove: []string{`Label == "remove"`}, RequireReplacement: true}
+	c := newMockClient(t, filter, 0, map[string]config.Torrent{
+		"a": {Hash: "a", Name: "Movie.2020.720p", Label: "remove", TrackerStatus: "trumped", TotalBytes: 5, Files: []string{"/data/a"}},
+		"b": {Hash: "b", Name: "Movie.2020.1080p", TotalBytes: 10, Files: []string{"/data/b"}},
+		"c": {Hash: "c", Name: "Show.S01E01.720p", Label: "remove", TrackerStatus: "upgraded", TotalBytes: 5, Files: []string{"/data/c"}},
+		"d": {Hash: "d", Name: "Other.2021.720p", Label: "remove", TrackerStatus: "unregistered", TotalBytes: 5, Files: []string{"/data/d"}},
+	})
+
+	working, err := c.GetTorrents(context.Background())
+	require.NoError(t, err)
+	setReplacements(working)
+
+	err = removeEligibleTorrents(context.Background(), logger.GetLogger("test"), c, working, torrentfilemap.New(working),
+		hardlinkfilemap.NewNoopHardlinkFileMap(), filter, &recordingSender{}, "test", time.Now(), nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "d"}, c.Removed)
+}
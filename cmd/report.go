@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/autobrr/tqm/pkg/config"
+)
+
+const (
+	reportFormatTable = "table"
+	reportFormatJSON  = "json"
+)
+
+// reportOutput is where dry-run removal reports are written, replaceable in tests
+var reportOutput io.Writer = os.Stdout
+
+type removalStep struct {
+	Step           int      `json:"step"`
+	Name           string   `json:"name"`
+	Hash           string   `json:"hash"`
+	Tracker        string   `json:"tracker"`
+	Reason         string   `json:"reason"`
+	DeleteData     bool     `json:"delete_data"`
+	Bytes          int64    `json:"bytes"`
+	ReclaimedBytes int64    `json:"reclaimed_bytes"`
+	FreeSpaceGB    *float64 `json:"free_space_gb,omitempty"`
+}
+
+// removalReport lists the torrents a dry-run would remove in order, with the space reclaimed after each removal
+type removalReport struct {
+	Client      string        `json:"client"`
+	TargetGB    float64       `json:"target_gb,omitempty"`
+	TargetMetAt int           `json:"target_met_at,omitempty"`
+	Steps       []removalStep `json:"steps"`
+}
+
+func newRemovalReport(client string, targetGB float64) *removalReport {
+	return &removalReport{
+		Client:   client,
+		TargetGB: targetGB,
+		Steps:    []removalStep{},
+	}
+}
+
+// add records a removal, reclaimed is the space it frees and freeSpaceGB the free space after it (when known)
+func (r *removalReport) add(t *config.Torrent, reason string, deleteData bool, reclaimed int64, freeSpaceGB float64, freeSpaceSet bool) {
+	step := removalStep{
+		Step:           len(r.Steps) + 1,
+		Name:           t.Name,
+		Hash:           t.Hash,
+		Tracker:        t.TrackerName,
+		Reason:         reason,
+		DeleteData:     deleteData,
+		Bytes:          t.DownloadedBytes,
+		ReclaimedBytes: reclaimed,
+	}
+
+	if len(r.Steps) > 0 {
+		step.ReclaimedBytes += r.Steps[len(r.Steps)-1].ReclaimedBytes
+	}
+
+	if freeSpaceSet {
+		step.FreeSpaceGB = &freeSpaceGB
+
+		if r.TargetGB > 0 && r.TargetMetAt == 0 && freeSpaceGB >= r.TargetGB {
+			r.TargetMetAt = step.Step
+		}
+	}
+
+	r.Steps = append(r.Steps, step)
+}
+
+func (r *removalReport) write(w io.Writer, format string) error {
+	if format == reportFormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("encode report: %w", err)
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STEP\tNAME\tTRACKER\tSIZE\tRECLAIMED\tFREE SPACE\tREASON")
+	for _, s := range r.Steps {
+		freeSpace := "-"
+		if s.FreeSpaceGB != nil {
+			freeSpace = fmt.Sprintf("%.2f GB", *s.FreeSpaceGB)
+		}
+
+		size := humanize.IBytes(uint64(s.Bytes))
+		if !s.DeleteData {
+			size += " (kept)"
+		}
+
+		marker := ""
+		if s.Step == r.TargetMetAt {
+			marker = " <- target met"
+		}
+
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s%s\n", s.Step, s.Name, s.Tracker, size,
+			humanize.IBytes(uint64(s.ReclaimedBytes)), freeSpace, s.Reason, marker)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+
+	if r.TargetGB > 0 {
+		if r.TargetMetAt > 0 {
+			fmt.Fprintf(w, "Free space target of %.2f GB met after %d torrent(s)\n", r.TargetGB, r.TargetMetAt)
+		} else {
+			fmt.Fprintf(w, "Free space target of %.2f GB not met\n", r.TargetGB)
+		}
+	}
+
+	return nil
+}
+
+const (
+	orphanStatusRemoved     = "removed"
+	orphanStatusDryRun      = "dry-run"
+	orphanStatusFailed      = "failed"
+	orphanStatusGracePeriod = "grace-period"
+	orphanStatusNotEmpty    = "not-empty"
+	orphanStatusProtected   = "protected"
+)
+
+type orphanEntry struct {
+	Path   string
+	Size   int64
+	IsFile bool
+	Status string
+}
+
+// orphanReport lists the orphans detected by a scan and what happened to them, safe for concurrent use
+type orphanReport struct {
+	mu      sync.Mutex
+	entries []orphanEntry
+}
+
+func (r *orphanReport) add(path string, size int64, isFile bool, status string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.entries = append(r.entries, orphanEntry{Path: path, Size: size, IsFile: isFile, Status: status})
+}
+
+func (r *orphanReport) len() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.entries)
+}
+
+// write lists the orphans sorted by path, one per line with their type, size, status and path
+func (r *orphanReport) write(w io.Writer) error {
+	r.mu.Lock()
+	entries := slices.Clone(r.entries)
+	r.mu.Unlock()
+
+	slices.SortFunc(entries, func(a, b orphanEntry) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tSIZE\tSTATUS\tPATH")
+	for _, e := range entries {
+		kind, size := "folder", "-"
+		if e.IsFile {
+			kind, size = "file", humanize.IBytes(uint64(e.Size))
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", kind, size, e.Status, e.Path)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+
+	return nil
+}
+
+func (r *orphanReport) writeFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create report: %w", err)
+	}
+
+	if err := r.write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
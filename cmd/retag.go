@@ -12,7 +12,6 @@ import (
 	"github.com/autobrr/tqm/pkg/expression"
 	"github.com/autobrr/tqm/pkg/hardlinkfilemap"
 	"github.com/autobrr/tqm/pkg/logger"
-	"github.com/autobrr/tqm/pkg/notification"
 	"github.com/autobrr/tqm/pkg/tracker"
 )
 
@@ -35,7 +34,7 @@ var retagCmd = &cobra.Command{
 		// set log
 		log := logger.GetLogger("retag")
 
-		noti := notification.NewDiscordSender(log, config.Config.Notifications)
+		noti := newNotificationSender(log)
 
 		// retrieve client object
 		clientName := args[0]
@@ -63,7 +62,7 @@ var retagCmd = &cobra.Command{
 		clientFreeSpacePath, _ := getClientConfigString("free_space_path", clientConfig)
 
 		// retrieve client filters
-		clientFilter, err := getClientFilter(clientConfig)
+		clientFilter, err := getClientFilter(clientName, clientConfig)
 		if err != nil {
 			log.WithError(err).Fatal("Failed retrieving client filter")
 		}
@@ -130,19 +129,30 @@ var retagCmd = &cobra.Command{
 			log.Infof("Retrieved %d torrents", len(torrents))
 		}
 
+		if err := checkTorrentCount(clientName, len(torrents)); err != nil {
+			log.WithError(err).Fatal("Refusing to act on the retrieved torrents")
+		}
+
+		// evaluate time based fields as of the requested time
+		if offset, err := applyAsOf(torrents); err != nil {
+			log.WithError(err).Fatal("Failed applying --as-of time")
+		} else if offset != 0 {
+			log.Warnf("Evaluating filters as of %s (%s from now)", now().Add(offset).Format(time.RFC3339), offset.Round(time.Second))
+		}
+
 		if evaluate.StringSliceContains(clientFilter.MapHardlinksFor, "retag", true) {
 			// download path mapping
 			clientDownloadPathMapping, err := getClientDownloadPathMapping(clientConfig)
 			if err != nil {
 				log.WithError(err).Fatal("Failed loading client download path mappings")
 			} else if clientDownloadPathMapping != nil {
-				log.Debugf("Loaded %d client download path mappings: %#v", len(clientDownloadPathMapping),
+				log.Debugf("Loaded %d client download path mappings: %s", clientDownloadPathMapping.Len(),
 					clientDownloadPathMapping)
 			}
 
 			// create map of paths associated to underlying file ids
 			start := time.Now()
-			hfm := hardlinkfilemap.New(torrents, clientDownloadPathMapping)
+			hfm := hardlinkfilemap.New(torrents, clientDownloadPathMapping, clientFilter.ResolveSymlinks, clientFilter.HardlinkFailClosed)
 			log.Infof("Mapped all torrent file paths to %d unique underlying file IDs in %s", hfm.Length(), time.Since(start))
 
 			// add HardlinkedOutsideClient field to torrents
@@ -155,12 +165,20 @@ var retagCmd = &cobra.Command{
 			log.Warnf("If your setup involves multiple torrents sharing the same underlying file using hardlinks, or you are using the 'HardlinkedOutsideClient' field in your filters, you should add 'retag' to the 'MapHardlinksFor' field in your filter configuration")
 		}
 
+		// apply tracker, label/tag and hash pre-filters
+		applyPreFilters(log, torrents)
+
 		// Verify tags exist on client if configured to create upfront
 		if qbtClient, ok := ct.(*client.QBittorrent); ok && qbtClient.CreateTagsUpfront {
 			var tagList []string
 			for _, v := range exp.Tags {
 				tagList = append(tagList, v.Name)
 			}
+			for _, m := range exp.Milestones {
+				for _, b := range m.Buckets {
+					tagList = append(tagList, b.Tag)
+				}
+			}
 			if err := ct.CreateTags(ctx, tagList); err != nil {
 				log.WithError(err).Fatal("Failed to create tags on client")
 			} else {
@@ -179,4 +197,5 @@ func init() {
 	rootCmd.AddCommand(retagCmd)
 
 	retagCmd.Flags().StringVar(&flagFilterName, "filter", "", "Filter to use instead of client")
+	addPreFilterFlags(retagCmd)
 }
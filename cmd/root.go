@@ -1,18 +1,28 @@
 package cmd
 
 import (
+	"context"
+	"crypto/subtle"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
+	"github.com/autobrr/tqm/pkg/client"
 	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/evaluate"
 	"github.com/autobrr/tqm/pkg/formatting"
+	"github.com/autobrr/tqm/pkg/hardlinkfilemap"
 	"github.com/autobrr/tqm/pkg/logger"
+	"github.com/autobrr/tqm/pkg/notification"
+	"github.com/autobrr/tqm/pkg/pathmapping"
 	"github.com/autobrr/tqm/pkg/runtime"
+	"github.com/autobrr/tqm/pkg/torrentfilemap"
 	"github.com/autobrr/tqm/pkg/tracker"
 )
 
@@ -20,16 +30,40 @@ var (
 	// Global flags
 	flagLogLevel     = 0
 	flagConfigFile   = "config.yaml"
+	flagProfile      string
 	flagConfigFolder = config.GetDefaultConfigDirectory("tqm", flagConfigFile)
 	flagLogFile      = "activity.log"
 
 	flagFilterName                       string
 	flagDryRun                           bool
 	flagExperimentalRelabelForCrossSeeds bool
+	flagOnlyTrackers                     []string
+	flagExcludeTrackers                  []string
+	flagLabels                           []string
+	flagTags                             []string
+	flagHash                             string
+	flagAsOf                             string
+	flagTimeoutPerTorrent                time.Duration
+	flagForceRecheckBeforeRemove         bool
+	flagReport                           string
+	flagFreeSpaceTarget                  float64
+	flagIncludeCategories                []string
+	flagExcludeCategories                []string
+	flagOrphanReport                     string
+	flagOrphanOrderBy                    []string
+	flagDryRunInteractive                string
+	flagDecisions                        string
+	flagConfirmToken                     string
+
+	// now is the clock time based filters are evaluated against, replaceable in tests
+	now = time.Now
 
 	// Global vars
 	log         *logrus.Entry
 	initialized bool
+
+	// notificationBatch buffers the notifications of this process when batching is enabled
+	notificationBatch *notification.Batch
 )
 
 var rootCmd = &cobra.Command{
@@ -37,6 +71,17 @@ var rootCmd = &cobra.Command{
 	Short: "A CLI torrent queue manager",
 	Long: `A CLI application that can be used to manage your torrent clients.
 `,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		lastRun.start(cmd.Name(), now())
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		flushNotifications()
+		saveActedTorrents()
+		if err := tracker.SaveIDCache(); err != nil {
+			log.WithError(err).Error("Failed writing tracker id cache")
+		}
+		writeLastRun(true)
+	},
 }
 
 func Execute() {
@@ -51,9 +96,13 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&flagConfigFolder, "config-dir", flagConfigFolder, "Config folder")
 	rootCmd.PersistentFlags().StringVarP(&flagConfigFile, "config", "c", flagConfigFile, "Config file")
 	rootCmd.PersistentFlags().StringVarP(&flagLogFile, "log", "l", flagLogFile, "Log file")
+	rootCmd.PersistentFlags().StringVar(&flagProfile, "profile", "", "Config profile to merge over the base config")
 	rootCmd.PersistentFlags().CountVarP(&flagLogLevel, "verbose", "v", "Verbose level")
 
 	rootCmd.PersistentFlags().BoolVar(&flagDryRun, "dry-run", false, "Dry run mode")
+	rootCmd.PersistentFlags().StringVar(&flagConfirmToken, "confirm-token", "", "Token matching require_confirmation_token, required by destructive commands when it is set")
+	rootCmd.PersistentFlags().DurationVar(&flagTimeoutPerTorrent, "timeout-per-torrent", 0, "Abandon the tracker checks of a torrent after this long, treating it as registered (e.g. 30s, 0 disables)")
+	rootCmd.PersistentFlags().StringVar(&flagAsOf, "as-of", "", "Evaluate time based filters as if run at this time (RFC3339, YYYY-MM-DD or a relative duration like +24h)")
 	rootCmd.PersistentFlags().BoolVar(&flagExperimentalRelabelForCrossSeeds, "experimental-relabel", false, "Enable experimental relabeling for cross-seeded torrents, using hardlinks (only qbit for now")
 
 	// Register commands (pauseCmd added here)
@@ -69,7 +118,11 @@ func initCore(showAppInfo bool) {
 		flagLogFile = filepath.Join(flagConfigFolder, flagLogFile)
 	}
 
-	// Init Logging
+	// Init Logging, targeting a single torrent is for debugging so log its evaluation in full
+	if flagHash != "" && flagLogLevel < 2 {
+		flagLogLevel = 2
+	}
+
 	if err := logger.Init(flagLogLevel, flagLogFile); err != nil {
 		log.WithError(err).Fatal("Failed to initialize logging")
 	}
@@ -82,14 +135,142 @@ func initCore(showAppInfo bool) {
 	}
 
 	// Init Config
-	if err := config.Init(flagConfigFile); err != nil {
+	if err := config.Init(flagConfigFile, flagProfile); err != nil {
 		log.WithError(err).Fatal("Failed to initialize config")
 	}
 
+	// a fatal error still sends the batched notifications and leaves a last run file behind, marked as failed
+	logrus.RegisterExitHandler(func() {
+		flushNotifications()
+		saveActedTorrents()
+		writeLastRun(false)
+	})
+
 	// Init Trackers
 	if err := tracker.Init(config.Config.Trackers); err != nil {
 		log.WithError(err).Fatal("Failed to initialize trackers")
 	}
+
+	// Init Ignore List
+	if err := initIgnoreList(config.Config.IgnoreListFile); err != nil {
+		log.WithError(err).Fatal("Failed to initialize ignore list")
+	}
+
+	// Load the torrents acted on by earlier invocations
+	if err := loadActedTorrents(); err != nil {
+		log.WithError(err).Fatal("Failed to load recently acted torrents")
+	}
+
+	applySafeMode(log)
+}
+
+// errSafeMode is returned for destructive operations while safe_mode is enabled
+var errSafeMode = errors.New("refused, safe_mode is enabled")
+
+// applySafeMode forces every command into dry-run mode when safe_mode is enabled
+func applySafeMode(log *logrus.Entry) {
+	if !config.Config.SafeMode {
+		return
+	}
+
+	flagDryRun = true
+	log.Warn("****************************************************************")
+	log.Warn("SAFE MODE: safe_mode is enabled in the config, running as dry-run")
+	log.Warn("No torrents, files, labels or tags will be changed")
+	log.Warn("****************************************************************")
+}
+
+// checkTorrentCount refuses the torrents retrieved from a client when there are fewer than torrent_safety allows,
+// accepted counts are recorded in the last run file as the baseline of the next run
+func checkTorrentCount(clientName string, count int) error {
+	cfg := config.Config.TorrentSafety
+	if cfg.MinTorrents > 0 && count < cfg.MinTorrents {
+		return fmt.Errorf("retrieved %d torrents, fewer than min_torrents (%d)", count, cfg.MinTorrents)
+	}
+
+	if cfg.MaxDrop > 0 && config.Config.LastRunFile != "" {
+		previous, err := readLastRun(config.Config.LastRunFile)
+		if err != nil {
+			return fmt.Errorf("max_drop: %w", err)
+		}
+
+		if previous != nil && previous.Torrents[clientName] > 0 {
+			last := previous.Torrents[clientName]
+			if drop := float64(last-count) / float64(last); drop > cfg.MaxDrop {
+				return fmt.Errorf("retrieved %d torrents, %.0f%% fewer than the %d of the last run (max_drop: %.0f%%)",
+					count, drop*100, last, cfg.MaxDrop*100)
+			}
+		}
+	}
+
+	lastRun.recordTorrents(clientName, count)
+	return nil
+}
+
+// checkSafeMode refuses a destructive operation while safe_mode is enabled, guarding against a missed dry-run check
+func checkSafeMode() error {
+	if config.Config != nil && config.Config.SafeMode {
+		return errSafeMode
+	}
+
+	return nil
+}
+
+// errConfirmationToken is returned for destructive commands run without the require_confirmation_token of the config
+var errConfirmationToken = errors.New("refused, --confirm-token does not match require_confirmation_token")
+
+// confirmTokenEnv is the environment variable the confirmation token is read from when --confirm-token is not passed,
+// unlike arguments it is not visible to other users in the process list
+const confirmTokenEnv = "TQM_CONFIRM_TOKEN"
+
+// confirmationToken returns the token passed with --confirm-token, or else with TQM_CONFIRM_TOKEN
+func confirmationToken() string {
+	if flagConfirmToken != "" {
+		return flagConfirmToken
+	}
+
+	return os.Getenv(confirmTokenEnv)
+}
+
+// checkConfirmationToken refuses a destructive command unless its confirmation token matches
+// require_confirmation_token, so a powerful config can't be run by the wrong person or script. A dry-run changes
+// nothing and needs no token
+func checkConfirmationToken() error {
+	if config.Config == nil || config.Config.RequireConfirmationToken == "" || flagDryRun {
+		return nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(confirmationToken()), []byte(config.Config.RequireConfirmationToken)) != 1 {
+		return errConfirmationToken
+	}
+
+	return nil
+}
+
+// newNotificationSender returns the notification sender for a command, shared by the whole process when batching
+func newNotificationSender(log *logrus.Entry) notification.Sender {
+	noti := notification.NewSender(log, config.Config.Notifications)
+	if !config.Config.Notifications.Batch {
+		return noti
+	}
+
+	if notificationBatch == nil {
+		notificationBatch = notification.NewBatch(noti, config.Config.Notifications.BatchDir,
+			config.Config.Notifications.BatchWindow)
+	}
+
+	return notificationBatch
+}
+
+// flushNotifications sends the notifications buffered during this process as a single message
+func flushNotifications() {
+	if notificationBatch == nil {
+		return
+	}
+
+	if err := notificationBatch.Flush(); err != nil {
+		log.WithError(err).Error("Failed sending batched notifications")
+	}
 }
 
 func showUsing() {
@@ -129,7 +310,7 @@ func getClientConfigString(setting string, clientConfig map[string]any) (*string
 	return &value, nil
 }
 
-func getClientDownloadPathMapping(clientConfig map[string]any) (map[string]string, error) {
+func getClientDownloadPathMapping(clientConfig map[string]any) (*pathmapping.Mapping, error) {
 	v, ok := clientConfig["download_path_mapping"]
 	if !ok {
 		return nil, nil
@@ -141,34 +322,126 @@ func getClientDownloadPathMapping(clientConfig map[string]any) (map[string]strin
 	}
 
 	clientDownloadPathMapping := make(map[string]string)
-	for k, v := range tmp {
-		if vv, ok := v.(string); ok {
-			clientDownloadPathMapping[k] = vv
-		} else {
-			return nil, fmt.Errorf("failed type-asserting download_path_mapping of client for %q: %#v", k, v)
+	if err := flattenPathMapping(clientDownloadPathMapping, "", tmp); err != nil {
+		return nil, err
+	}
+
+	mapping, err := pathmapping.New(clientDownloadPathMapping)
+	if err != nil {
+		return nil, fmt.Errorf("download_path_mapping of client: %w", err)
+	}
+
+	return mapping, nil
+}
+
+// flattenPathMapping joins keys the config loader split on its delimiter back together, paths and regex mappings
+// containing dots are loaded as nested maps
+func flattenPathMapping(dst map[string]string, prefix string, src map[string]any) error {
+	for k, v := range src {
+		k = prefix + k
+		switch vv := v.(type) {
+		case string:
+			dst[k] = vv
+		case map[string]any:
+			if err := flattenPathMapping(dst, k+config.Delimiter, vv); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("failed type-asserting download_path_mapping of client for %q: %#v", k, v)
 		}
 	}
 
-	return clientDownloadPathMapping, nil
+	return nil
 }
 
-func getClientFilter(clientConfig map[string]any) (*config.FilterConfiguration, error) {
-	v, ok := clientConfig["filter"]
-	if !ok {
-		return nil, fmt.Errorf("no filter setting found in client configuration: %+v", clientConfig)
+// newTorrentFileMap maps the files of torrents, keyed on their content instead of path when the filter enables it
+func newTorrentFileMap(log *logrus.Entry, torrents map[string]config.Torrent, filter *config.FilterConfiguration, clientConfig map[string]any) *torrentfilemap.TorrentFileMap {
+	if !filter.ContentCrossSeeds {
+		tfm := torrentfilemap.New(torrents)
+		log.Infof("Mapped torrents to %d unique torrent files", tfm.Length())
+		return tfm
 	}
 
-	clientFilterName, ok := v.(string)
-	if !ok {
-		return nil, fmt.Errorf("failed type-asserting filter of client: %#v", v)
+	clientDownloadPathMapping, err := getClientDownloadPathMapping(clientConfig)
+	if err != nil {
+		log.WithError(err).Fatal("Failed loading client download path mappings")
 	}
 
-	clientFilter, ok := config.Config.Filters[clientFilterName]
-	if !ok {
-		return nil, fmt.Errorf("failed finding configuration of filter: %+v", clientFilterName)
+	start := time.Now()
+	tfm := torrentfilemap.NewByContent(torrents, clientDownloadPathMapping)
+	log.Infof("Mapped torrents to %d unique torrent files by content in %s", tfm.Length(), time.Since(start))
+	return tfm
+}
+
+// newHardlinkFileMap maps the files of torrents to their underlying file ids when the filter maps hardlinks for clean,
+// setting the HardlinkedOutsideClient field of torrents
+func newHardlinkFileMap(log *logrus.Entry, clientName string, torrents map[string]config.Torrent, filter *config.FilterConfiguration, clientConfig map[string]any) hardlinkfilemap.HardlinkFileMapI {
+	if !evaluate.StringSliceContains(filter.MapHardlinksFor, "clean", true) {
+		log.Warnf("Not mapping hardlinks for client %q", clientName)
+		log.Warnf("If your setup involves multiple torrents sharing the same underlying file using hardlinks, or you are using the 'HardlinkedOutsideClient' field in your filters, you should add 'clean' to the 'MapHardlinksFor' field in your filter configuration")
+		return hardlinkfilemap.NewNoopHardlinkFileMap()
 	}
 
-	return &clientFilter, nil
+	// download path mapping
+	clientDownloadPathMapping, err := getClientDownloadPathMapping(clientConfig)
+	if err != nil {
+		log.WithError(err).Fatal("Failed loading client download path mappings")
+	} else if clientDownloadPathMapping != nil {
+		log.Debugf("Loaded %d client download path mappings: %s", clientDownloadPathMapping.Len(),
+			clientDownloadPathMapping)
+	}
+
+	// create map of paths associated to underlying file ids
+	start := time.Now()
+	hfm := hardlinkfilemap.New(torrents, clientDownloadPathMapping, filter.ResolveSymlinks, filter.HardlinkFailClosed)
+	log.Infof("Mapped all torrent file paths to %d unique underlying file IDs in %s", hfm.Length(), time.Since(start))
+
+	// add HardlinkedOutsideClient field to torrents
+	for h, t := range torrents {
+		t.HardlinkedOutsideClient = hfm.HardlinkedOutsideClient(t)
+		torrents[h] = t
+	}
+
+	return hfm
+}
+
+// setSharingFiles lets the torrents look up the torrents sharing files with them in tfm, for
+// SharesFilesWithRegistered. Torrents later removed from tfm are no longer returned
+func setSharingFiles(torrents map[string]config.Torrent, tfm *torrentfilemap.TorrentFileMap) {
+	for h, t := range torrents {
+		t.SharingFiles = func() []config.Torrent {
+			return tfm.GetTorrentsSharingFiles(t)
+		}
+		torrents[h] = t
+	}
+}
+
+// getClientFilter resolves the filter of a client, either defined inline, referenced by name
+// or falling back to the global default_filter
+func getClientFilter(clientName string, clientConfig map[string]any) (*config.FilterConfiguration, error) {
+	v, ok := clientConfig["filter"]
+	if !ok || v == "" {
+		if config.Config.DefaultFilter == "" {
+			return nil, fmt.Errorf("no filter setting found in client configuration and no default_filter set: %+v", clientConfig)
+		}
+
+		return getFilter(config.Config.DefaultFilter)
+	}
+
+	switch clientFilter := v.(type) {
+	case string:
+		return getFilter(clientFilter)
+	case map[string]any:
+		// filter defined inline within the client configuration
+		var inlineFilter config.FilterConfiguration
+		if err := config.K.Unmarshal(fmt.Sprintf("clients%s%s%sfilter", config.Delimiter, clientName, config.Delimiter), &inlineFilter); err != nil {
+			return nil, fmt.Errorf("failed unmarshalling inline filter of client: %w", err)
+		}
+
+		return &inlineFilter, nil
+	default:
+		return nil, fmt.Errorf("failed type-asserting filter of client: %#v", v)
+	}
 }
 
 func getFilter(filterName string) (*config.FilterConfiguration, error) {
@@ -179,3 +452,152 @@ func getFilter(filterName string) (*config.FilterConfiguration, error) {
 
 	return &clientFilter, nil
 }
+
+func filterTorrentsByTracker(torrents map[string]config.Torrent, onlyTrackers []string, excludeTrackers []string) int {
+	if len(onlyTrackers) == 0 && len(excludeTrackers) == 0 {
+		return 0
+	}
+
+	removed := 0
+	for h, t := range torrents {
+		if len(onlyTrackers) > 0 && !evaluate.StringSliceContains(onlyTrackers, t.TrackerName, true) {
+			delete(torrents, h)
+			removed++
+			continue
+		}
+
+		if evaluate.StringSliceContains(excludeTrackers, t.TrackerName, true) {
+			delete(torrents, h)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// filterTorrentsByLabelOrTag keeps only torrents with one of the labels and one of the tags, when set
+func filterTorrentsByLabelOrTag(torrents map[string]config.Torrent, labels []string, tags []string) int {
+	if len(labels) == 0 && len(tags) == 0 {
+		return 0
+	}
+
+	removed := 0
+	for h, t := range torrents {
+		if len(labels) > 0 && !evaluate.StringSliceContains(labels, t.Label, true) {
+			delete(torrents, h)
+			removed++
+			continue
+		}
+
+		if len(tags) > 0 && !t.HasAnyTag(tags...) {
+			delete(torrents, h)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+func addPreFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&flagOnlyTrackers, "only-tracker", nil, "Only process torrents from this tracker (can be repeated)")
+	cmd.Flags().StringSliceVar(&flagExcludeTrackers, "exclude-tracker", nil, "Skip torrents from this tracker (can be repeated)")
+	cmd.Flags().StringSliceVar(&flagLabels, "label", nil, "Only process torrents with this label (can be repeated)")
+	cmd.Flags().StringSliceVar(&flagTags, "tag", nil, "Only process torrents with this tag (can be repeated)")
+	cmd.Flags().StringVar(&flagHash, "hash", "", "Only process the torrent with this hash, logging its evaluation verbosely")
+}
+
+// filterTorrentsByHash keeps only the torrent with the hash, when set
+func filterTorrentsByHash(torrents map[string]config.Torrent, hash string) int {
+	if hash == "" {
+		return 0
+	}
+
+	removed := 0
+	for h := range torrents {
+		if !strings.EqualFold(h, hash) {
+			delete(torrents, h)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+// retrieveTorrents retrieves the torrents of the client, or only the torrent selected by --hash. only for commands
+// that don't need the rest of the library for cross-seed or hardlink detection
+func retrieveTorrents(ctx context.Context, c client.Interface) (map[string]config.Torrent, error) {
+	if flagHash == "" {
+		return c.GetTorrents(ctx)
+	}
+
+	t, err := c.GetTorrent(ctx, flagHash)
+	if errors.Is(err, client.ErrTorrentNotFound) {
+		return map[string]config.Torrent{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return map[string]config.Torrent{t.Hash: t}, nil
+}
+
+// applyPreFilters restricts torrents to those selected by the tracker, label/tag and hash flags
+func applyPreFilters(log *logrus.Entry, torrents map[string]config.Torrent) {
+	if n := filterTorrentsByTracker(torrents, flagOnlyTrackers, flagExcludeTrackers); n > 0 {
+		log.Infof("Excluded %d torrents by tracker flags, %d remaining", n, len(torrents))
+	}
+
+	if n := filterTorrentsByLabelOrTag(torrents, flagLabels, flagTags); n > 0 {
+		log.Infof("Excluded %d torrents by label/tag flags, %d remaining", n, len(torrents))
+	}
+
+	if flagHash != "" {
+		filterTorrentsByHash(torrents, flagHash)
+		if len(torrents) == 0 {
+			log.Warnf("No torrent found with hash: %q", flagHash)
+		} else {
+			log.Infof("Only processing torrent with hash: %q", flagHash)
+		}
+	}
+}
+
+// parseAsOf parses an absolute time or a duration relative to now
+func parseAsOf(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if strings.HasPrefix(value, "+") || strings.HasPrefix(value, "-") {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("parse relative duration: %w", err)
+		}
+
+		return now().Add(d), nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04", time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unsupported time format: %q", value)
+}
+
+// applyAsOf shifts the time based fields of torrents to the --as-of time, returning the applied offset
+func applyAsOf(torrents map[string]config.Torrent) (time.Duration, error) {
+	if flagAsOf == "" {
+		return 0, nil
+	}
+
+	asOf, err := parseAsOf(flagAsOf)
+	if err != nil {
+		return 0, err
+	}
+
+	offset := asOf.Sub(now())
+	for h, t := range torrents {
+		t.ShiftClock(offset)
+		torrents[h] = t
+	}
+
+	return offset, nil
+}
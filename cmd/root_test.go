@@ -0,0 +1,355 @@
+package cmd
+
+import (
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/hardlinkfilemap"
+	"github.com/autobrr/tqm/pkg/logger"
+	"github.com/autobrr/tqm/pkg/torrentfilemap"
+)
+
+func TestGetClientFilter(t *testing.T) {
+	config.Config.Filters = map[string]config.FilterConfiguration{
+		"default": {Remove: []string{"default"}},
+		"named":   {Remove: []string{"named"}},
+	}
+	t.Cleanup(func() {
+		config.Config.Filters = nil
+		config.Config.DefaultFilter = ""
+	})
+
+	tests := []struct {
+		name          string
+		defaultFilter string
+		clientConfig  map[string]any
+		expected      string
+		expectErr     bool
+	}{
+		{name: "named", clientConfig: map[string]any{"filter": "named"}, expected: "named"},
+		{name: "named_over_default", defaultFilter: "default", clientConfig: map[string]any{"filter": "named"}, expected: "named"},
+		{name: "falls_back_to_default", defaultFilter: "default", clientConfig: map[string]any{}, expected: "default"},
+		{name: "empty_falls_back_to_default", defaultFilter: "default", clientConfig: map[string]any{"filter": ""}, expected: "default"},
+		{name: "no_filter_no_default", clientConfig: map[string]any{}, expectErr: true},
+		{name: "unknown_named", clientConfig: map[string]any{"filter": "missing"}, expectErr: true},
+		{name: "invalid_type", clientConfig: map[string]any{"filter": 1}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Config.DefaultFilter = tt.defaultFilter
+
+			filter, err := getClientFilter("client", tt.clientConfig)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, []string{tt.expected}, filter.Remove)
+		})
+	}
+}
+
+func TestGetClientDownloadPathMapping(t *testing.T) {
+	// keys containing the config delimiter are loaded as nested maps
+	mapping, err := getClientDownloadPathMapping(map[string]any{
+		"download_path_mapping": map[string]any{
+			"/downloads": "/data",
+			"re:^/mnt/user/(": map[string]any{
+				"*)": "/data/$1",
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, mapping.Len())
+
+	mapped, _ := mapping.Map("/mnt/user/torrents/a.mkv")
+	assert.Equal(t, "/data/torrents/a.mkv", mapped)
+
+	_, err = getClientDownloadPathMapping(map[string]any{
+		"download_path_mapping": map[string]any{"re:/mnt/(user": "/data"},
+	})
+	require.Error(t, err)
+
+	mapping, err = getClientDownloadPathMapping(map[string]any{})
+	require.NoError(t, err)
+	assert.Nil(t, mapping)
+}
+
+func TestApplySafeMode(t *testing.T) {
+	t.Cleanup(func() {
+		flagDryRun = false
+		config.Config.SafeMode = false
+	})
+
+	applySafeMode(logger.GetLogger("test"))
+	assert.False(t, flagDryRun)
+	assert.NoError(t, checkSafeMode())
+
+	config.Config.SafeMode = true
+	applySafeMode(logger.GetLogger("test"))
+	assert.True(t, flagDryRun, "safe_mode should force dry-run")
+	assert.ErrorIs(t, checkSafeMode(), errSafeMode)
+}
+
+func TestCheckConfirmationToken(t *testing.T) {
+	t.Cleanup(func() {
+		flagDryRun = false
+		flagConfirmToken = ""
+		config.Config.RequireConfirmationToken = ""
+	})
+
+	// no token configured
+	assert.NoError(t, checkConfirmationToken())
+
+	config.Config.RequireConfirmationToken = "secret"
+	assert.ErrorIs(t, checkConfirmationToken(), errConfirmationToken)
+
+	flagConfirmToken = "wrong"
+	assert.ErrorIs(t, checkConfirmationToken(), errConfirmationToken)
+
+	flagConfirmToken = "secret"
+	assert.NoError(t, checkConfirmationToken())
+
+	// the token can be passed in the environment instead
+	flagConfirmToken = ""
+	t.Setenv(confirmTokenEnv, "secret")
+	assert.NoError(t, checkConfirmationToken())
+	t.Setenv(confirmTokenEnv, "wrong")
+	assert.ErrorIs(t, checkConfirmationToken(), errConfirmationToken)
+	t.Setenv(confirmTokenEnv, "")
+
+	// a dry-run needs no token
+	flagConfirmToken = ""
+	flagDryRun = true
+	assert.NoError(t, checkConfirmationToken())
+}
+
+func TestApplyAsOf(t *testing.T) {
+	fixed := time.Date(2025, 1, 10, 12, 0, 0, 0, time.Local)
+	now = func() time.Time { return fixed }
+	t.Cleanup(func() {
+		now = time.Now
+		flagAsOf = ""
+	})
+
+	tests := []struct {
+		name         string
+		asOf         string
+		expectOffset time.Duration
+		expectErr    bool
+	}{
+		{name: "unset", asOf: ""},
+		{name: "relative", asOf: "+24h", expectOffset: 24 * time.Hour},
+		{name: "date_only", asOf: "2025-01-12", expectOffset: 36 * time.Hour},
+		{name: "rfc3339", asOf: fixed.Add(-time.Hour).Format(time.RFC3339), expectOffset: -time.Hour},
+		{name: "invalid", asOf: "tomorrow", expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flagAsOf = tt.asOf
+			torrents := map[string]config.Torrent{
+				"a": {Hash: "a", Seeding: true, AddedSeconds: 86400, SeedingSeconds: 86400, LastActivitySeconds: 3600},
+			}
+
+			offset, err := applyAsOf(torrents)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectOffset, offset)
+			assert.Equal(t, 86400+int64(tt.expectOffset.Seconds()), torrents["a"].SeedingSeconds)
+		})
+	}
+}
+
+func TestFilterTorrentsByHash(t *testing.T) {
+	newTorrents := func() map[string]config.Torrent {
+		return map[string]config.Torrent{
+			"abc123": {Hash: "abc123"},
+			"def456": {Hash: "def456"},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		hash     string
+		expected []string
+	}{
+		{name: "no_flag", expected: []string{"abc123", "def456"}},
+		{name: "hash", hash: "def456", expected: []string{"def456"}},
+		{name: "case_insensitive", hash: "ABC123", expected: []string{"abc123"}},
+		{name: "unknown_hash", hash: "missing", expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			torrents := newTorrents()
+			removed := filterTorrentsByHash(torrents, tt.hash)
+
+			assert.Equal(t, 2-len(tt.expected), removed)
+			assert.ElementsMatch(t, tt.expected, slices.Collect(maps.Keys(torrents)))
+		})
+	}
+}
+
+func TestFilterTorrentsByLabelOrTag(t *testing.T) {
+	newTorrents := func() map[string]config.Torrent {
+		return map[string]config.Torrent{
+			"a": {Hash: "a", Label: "tv", Tags: []string{"keep"}},
+			"b": {Hash: "b", Label: "tv"},
+			"c": {Hash: "c", Label: "movies", Tags: []string{"keep", "other"}},
+			"d": {Hash: "d", Label: "music"},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		labels   []string
+		tags     []string
+		expected []string
+	}{
+		{name: "no_flags", expected: []string{"a", "b", "c", "d"}},
+		{name: "label", labels: []string{"TV"}, expected: []string{"a", "b"}},
+		{name: "multiple_labels", labels: []string{"tv", "movies"}, expected: []string{"a", "b", "c"}},
+		{name: "tag", tags: []string{"keep"}, expected: []string{"a", "c"}},
+		{name: "label_and_tag", labels: []string{"tv"}, tags: []string{"keep"}, expected: []string{"a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			torrents := newTorrents()
+			removed := filterTorrentsByLabelOrTag(torrents, tt.labels, tt.tags)
+
+			assert.Equal(t, 4-len(tt.expected), removed)
+			assert.ElementsMatch(t, tt.expected, slices.Collect(maps.Keys(torrents)))
+		})
+	}
+}
+
+func TestRetrieveTorrents(t *testing.T) {
+	t.Cleanup(func() { flagHash = "" })
+
+	torrents := map[string]config.Torrent{
+		"abc": {Hash: "abc", Name: "one"},
+		"def": {Hash: "def", Name: "two"},
+	}
+
+	t.Run("all_without_hash", func(t *testing.T) {
+		flagHash = ""
+		c := newMockClient(t, &config.FilterConfiguration{}, 0, torrents)
+
+		got, err := retrieveTorrents(context.Background(), c)
+		require.NoError(t, err)
+		assert.Len(t, got, 2)
+		assert.Equal(t, 1, c.FullFetches)
+		assert.Equal(t, 0, c.SingleFetches)
+	})
+
+	t.Run("single_with_hash", func(t *testing.T) {
+		flagHash = "DEF"
+		c := newMockClient(t, &config.FilterConfiguration{}, 0, torrents)
+
+		got, err := retrieveTorrents(context.Background(), c)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"def"}, slices.Collect(maps.Keys(got)))
+		assert.Equal(t, 0, c.FullFetches)
+		assert.Equal(t, 1, c.SingleFetches)
+	})
+
+	t.Run("unknown_hash", func(t *testing.T) {
+		flagHash = "missing"
+		c := newMockClient(t, &config.FilterConfiguration{}, 0, torrents)
+
+		got, err := retrieveTorrents(context.Background(), c)
+		require.NoError(t, err)
+		assert.Empty(t, got)
+	})
+}
+
+func TestCheckTorrentCount(t *testing.T) {
+	lastRunFile := filepath.Join(t.TempDir(), "last-run.json")
+	require.NoError(t, os.WriteFile(lastRunFile, []byte(`{"torrents": {"qbt": 1000}}`), 0644))
+
+	t.Cleanup(func() {
+		config.Config.TorrentSafety = config.TorrentSafetyConfig{}
+		config.Config.LastRunFile = ""
+		flagHash = ""
+	})
+
+	tests := []struct {
+		name      string
+		cfg       config.TorrentSafetyConfig
+		client    string
+		hash      string
+		count     int
+		expectErr bool
+	}{
+		{name: "disabled", count: 0},
+		{name: "min_torrents_met", cfg: config.TorrentSafetyConfig{MinTorrents: 100}, client: "qbt", count: 100},
+		{name: "below_min_torrents", cfg: config.TorrentSafetyConfig{MinTorrents: 100}, client: "qbt", count: 99, expectErr: true},
+		{name: "hash_still_checked", cfg: config.TorrentSafetyConfig{MinTorrents: 100}, client: "qbt", hash: "abc", count: 1, expectErr: true},
+		{name: "small_drop", cfg: config.TorrentSafetyConfig{MaxDrop: 0.5}, client: "qbt", count: 600},
+		{name: "sharp_drop", cfg: config.TorrentSafetyConfig{MaxDrop: 0.5}, client: "qbt", count: 400, expectErr: true},
+		{name: "growth", cfg: config.TorrentSafetyConfig{MaxDrop: 0.5}, client: "qbt", count: 2000},
+		{name: "no_previous_count", cfg: config.TorrentSafetyConfig{MaxDrop: 0.5}, client: "deluge", count: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Config.TorrentSafety = tt.cfg
+			config.Config.LastRunFile = lastRunFile
+			flagHash = tt.hash
+			lastRun.start("test", time.Now())
+
+			err := checkTorrentCount(tt.client, tt.count)
+			if tt.expectErr {
+				require.Error(t, err)
+				assert.NotContains(t, lastRun.state.Torrents, tt.client, "a refused count should not become the next baseline")
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRemoveEligibleTorrents_SharesFilesWithRegistered(t *testing.T) {
+	removalDelay = 0
+	t.Cleanup(func() { removalDelay = time.Second })
+
+	filter := &config.FilterConfiguration{Remove: []string{`IsUnregistered() && SharesFilesWithRegistered()`}}
+	c := newMockClient(t, filter, 0, map[string]config.Torrent{
+		// cross-seed unregistered on a.com while b.com is fine
+		"a1": {Hash: "a1", Name: "a1", TrackerName: "a.com", RegistrationState: config.UnregisteredState, Files: []string{"/data/one"}},
+		"b1": {Hash: "b1", Name: "b1", TrackerName: "b.com", RegistrationState: config.RegisteredState, Files: []string{"/data/one"}},
+		// cross-seed unregistered on both trackers
+		"a2": {Hash: "a2", Name: "a2", TrackerName: "a.com", RegistrationState: config.UnregisteredState, Files: []string{"/data/two"}},
+		"b2": {Hash: "b2", Name: "b2", TrackerName: "b.com", RegistrationState: config.UnregisteredState, Files: []string{"/data/two"}},
+		// unregistered without a cross-seed
+		"a3": {Hash: "a3", Name: "a3", TrackerName: "a.com", RegistrationState: config.UnregisteredState, Files: []string{"/data/three"}},
+	})
+
+	working, err := c.GetTorrents(context.Background())
+	require.NoError(t, err)
+
+	tfm := torrentfilemap.New(working)
+	setSharingFiles(working, tfm)
+
+	err = removeEligibleTorrents(context.Background(), logger.GetLogger("test"), c, working, tfm,
+		hardlinkfilemap.NewNoopHardlinkFileMap(), filter, &recordingSender{}, "test", time.Now(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a1"}, c.Removed)
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/logger"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+var genSchemaCmd = &cobra.Command{
+	Use:   "gen-schema",
+	Short: "Print a JSON schema for the config file",
+	Long: `This command prints a JSON schema of the config file, derived from the configuration types, which editors
+can use to validate and complete config.yaml.`,
+
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		log := logger.GetLogger("gen-schema")
+
+		data, err := json.MarshalIndent(configSchema(), "", "  ")
+		if err != nil {
+			log.WithError(err).Fatal("Failed generating schema")
+		}
+
+		fmt.Println(string(data))
+	},
+	DisableFlagsInUseLine: true,
+}
+
+func init() {
+	rootCmd.AddCommand(genSchemaCmd)
+}
+
+// configSchema returns the JSON schema of the config file
+func configSchema() map[string]any {
+	schema := typeSchema(reflect.TypeOf(config.Configuration{}))
+	schema["$schema"] = jsonSchemaDraft
+	schema["title"] = "tqm configuration"
+
+	return schema
+}
+
+// typeSchema returns the JSON schema of a config type
+func typeSchema(t reflect.Type) map[string]any {
+	if t == durationType {
+		return map[string]any{
+			"type":        "string",
+			"description": "duration, e.g. 10m or 1h30m (valid units are ns, us, ms, s, m, h)",
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem())
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]any)
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+
+			name := schemaFieldName(f)
+			if name == "-" {
+				continue
+			}
+
+			properties[name] = typeSchema(f.Type)
+		}
+
+		return map[string]any{"type": "object", "properties": properties}
+	default:
+		// any value is accepted, e.g. the free-form client settings
+		return map[string]any{}
+	}
+}
+
+// schemaFieldName returns the config key of a struct field, untagged fields are matched case-insensitively by koanf
+// so they are listed in lowercase
+func schemaFieldName(f reflect.StructField) string {
+	for _, tag := range []string{"koanf", "mapstructure"} {
+		if name, _, _ := strings.Cut(f.Tag.Get(tag), ","); name != "" {
+			return name
+		}
+	}
+
+	return strings.ToLower(f.Name)
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigSchema(t *testing.T) {
+	data, err := json.Marshal(configSchema())
+	require.NoError(t, err)
+
+	// round trip through json so the schema is inspected as editors see it
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal(data, &schema))
+
+	lookup := func(path ...string) map[string]any {
+		t.Helper()
+
+		node := schema
+		for _, key := range path {
+			next, ok := node[key].(map[string]any)
+			require.True(t, ok, "missing schema key %q in %v", key, path)
+			node = next
+		}
+		return node
+	}
+
+	assert.Equal(t, jsonSchemaDraft, schema["$schema"])
+
+	filter := lookup("properties", "filters", "additionalProperties", "properties")
+	assert.Equal(t, "array", filter["ignore"].(map[string]any)["type"])
+	assert.Equal(t, "boolean", filter["deletedata"].(map[string]any)["type"], "pointers should use the schema of their element")
+	assert.Equal(t, "boolean", filter["require_paused"].(map[string]any)["type"])
+
+	orphan := lookup("properties", "filters", "additionalProperties", "properties", "orphan", "properties")
+	assert.Equal(t, "string", orphan["grace_period"].(map[string]any)["type"], "durations should be strings")
+
+	tag := lookup("properties", "filters", "additionalProperties", "properties", "tag", "items", "properties")
+	assert.Equal(t, "integer", tag["uploadKb"].(map[string]any)["type"])
+	assert.Equal(t, "integer", tag["priority"].(map[string]any)["type"])
+
+	unit3d := lookup("properties", "trackers", "properties", "unit3d", "additionalProperties", "properties")
+	assert.Contains(t, unit3d, "api_key")
+	assert.Contains(t, unit3d, "tls_skip_verify")
+
+	requirement := lookup("properties", "tracker_requirements", "additionalProperties", "properties")
+	assert.Equal(t, "number", requirement["min_ratio"].(map[string]any)["type"])
+
+	assert.Empty(t, lookup("properties", "clients", "additionalProperties", "additionalProperties"), "client settings are free-form")
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/autobrr/tqm/pkg/client"
+	"github.com/autobrr/tqm/pkg/config"
+)
+
+// reasons clean skips a torrent meeting the remove filters
+const (
+	skipNonUnique     = "nonunique"
+	skipTrackerDown   = "tracker-down"
+	skipNotPaused     = "not-paused"
+	skipNoReplacement = "no-replacement"
+)
+
+// skipTagger tags the torrents clean skipped with the reason they were skipped, for review in the client
+type skipTagger struct {
+	c    client.TagInterface
+	tags map[string]string
+}
+
+// newSkipTagger returns the skip tagger of a filter, it is nil when skip_tags is disabled
+func newSkipTagger(c client.Interface, filter *config.FilterConfiguration) (*skipTagger, error) {
+	if filter == nil || !filter.SkipTags.Enabled {
+		return nil, nil
+	}
+
+	tc, ok := c.(client.TagInterface)
+	if !ok {
+		return nil, fmt.Errorf("skip tags: client does not support tags: %s", c.Type())
+	}
+
+	tags := map[string]string{
+		skipNonUnique:     filter.SkipTags.NonUnique,
+		skipTrackerDown:   filter.SkipTags.TrackerDown,
+		skipNotPaused:     filter.SkipTags.NotPaused,
+		skipNoReplacement: filter.SkipTags.NoReplacement,
+	}
+	for reason, tag := range tags {
+		if tag == "" {
+			tags[reason] = "skip:" + reason
+		}
+	}
+
+	return &skipTagger{c: tc, tags: tags}, nil
+}
+
+// tag adds the tag of reason to t unless it already carries it, failures are logged as the torrent is kept either way
+func (s *skipTagger) tag(ctx context.Context, log *logrus.Entry, t *config.Torrent, reason string) {
+	if s == nil {
+		return
+	}
+
+	tag := s.tags[reason]
+	if slices.Contains(t.Tags, tag) {
+		return
+	}
+
+	if flagDryRun {
+		log.Infof("[DRY-RUN] Would tag skipped torrent: %q (tag: %s)", t.Name, tag)
+		return
+	}
+
+	if err := s.c.AddTags(ctx, t.Hash, []string{tag}); err != nil {
+		log.WithError(err).Errorf("Failed tagging skipped torrent: %q", t.Name)
+		return
+	}
+
+	log.Debugf("Tagged skipped torrent: %q (tag: %s)", t.Name, tag)
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/hardlinkfilemap"
+	"github.com/autobrr/tqm/pkg/logger"
+	"github.com/autobrr/tqm/pkg/torrentfilemap"
+)
+
+func TestRemoveEligibleTorrents_SkipTags(t *testing.T) {
+	removalDelay = 0
+	t.Cleanup(func() {
+		removalDelay = time.Second
+		flagDryRun = false
+	})
+
+	filter := &config.FilterConfiguration{Remove: []string{`Label == "remove"`}, RequirePaused: true}
+	filter.SkipTags.Enabled = true
+	filter.SkipTags.NotPaused = "review:active"
+
+	c := newMockClient(t, filter, 0, map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Label: "remove", State: "stalledUP", Files: []string{"/data/a"}},
+		"b": {Hash: "b", Name: "b", Label: "remove", State: "pausedUP", Files: []string{"/data/shared"}},
+		"c": {Hash: "c", Name: "c", Label: "keep", State: "pausedUP", Files: []string{"/data/shared"}},
+		"d": {Hash: "d", Name: "d", Label: "remove", State: "pausedUP", Files: []string{"/data/d"}},
+	})
+
+	run := func() map[string]config.Torrent {
+		t.Helper()
+		working, err := c.GetTorrents(context.Background())
+		require.NoError(t, err)
+
+		err = removeEligibleTorrents(context.Background(), logger.GetLogger("test"), c, working, torrentfilemap.New(working),
+			hardlinkfilemap.NewNoopHardlinkFileMap(), filter, &recordingSender{}, "test", time.Now(), nil)
+		require.NoError(t, err)
+
+		torrents, err := c.GetTorrents(context.Background())
+		require.NoError(t, err)
+		return torrents
+	}
+
+	// a dry-run only logs the tags
+	flagDryRun = true
+	torrents := run()
+	assert.Empty(t, torrents["a"].Tags)
+	assert.Empty(t, torrents["b"].Tags)
+
+	flagDryRun = false
+	torrents = run()
+	assert.Equal(t, []string{"d"}, c.Removed)
+	assert.Equal(t, []string{"review:active"}, torrents["a"].Tags)
+	assert.Equal(t, []string{"skip:nonunique"}, torrents["b"].Tags)
+	assert.Empty(t, torrents["c"].Tags, "torrents not meeting the remove filters are not tagged")
+
+	// a torrent already tagged is not tagged twice
+	torrents = run()
+	assert.Equal(t, []string{"review:active"}, torrents["a"].Tags)
+}
+
+func TestNewSkipTagger(t *testing.T) {
+	filter := &config.FilterConfiguration{}
+
+	s, err := newSkipTagger(newMockClient(t, filter, 0, nil), filter)
+	require.NoError(t, err)
+	assert.Nil(t, s, "disabled by default")
+
+	filter.SkipTags.Enabled = true
+	filter.SkipTags.TrackerDown = "down"
+	s, err = newSkipTagger(newMockClient(t, filter, 0, nil), filter)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		skipNonUnique:     "skip:nonunique",
+		skipTrackerDown:   "down",
+		skipNotPaused:     "skip:not-paused",
+		skipNoReplacement: "skip:no-replacement",
+	}, s.tags)
+}
+
+func TestRemoveEligibleTorrents_SkipTagsTrackerDown(t *testing.T) {
+	removalDelay = 0
+	t.Cleanup(func() { removalDelay = time.Second })
+
+	filter := &config.FilterConfiguration{Remove: []string{`Label == "remove"`}}
+	filter.SkipTags.Enabled = true
+
+	c := newMockClient(t, filter, 0, map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Label: "remove", TrackerStatus: "Connection timed out", Files: []string{"/data/a"}},
+		"b": {Hash: "b", Name: "b", Label: "remove", Files: []string{"/data/b"}},
+	})
+	c.RefuseRemoval = map[string]bool{"a": true}
+
+	working, err := c.GetTorrents(context.Background())
+	require.NoError(t, err)
+	down := working["a"]
+	require.True(t, down.IsTrackerDown())
+
+	err = removeEligibleTorrents(context.Background(), logger.GetLogger("test"), c, working, torrentfilemap.New(working),
+		hardlinkfilemap.NewNoopHardlinkFileMap(), filter, &recordingSender{}, "test", time.Now(), nil)
+	require.NoError(t, err)
+
+	torrents, err := c.GetTorrents(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, c.Removed)
+	assert.Equal(t, []string{"skip:tracker-down"}, torrents["a"].Tags)
+	assert.Equal(t, 1, c.RemoveAttempts["a"])
+}
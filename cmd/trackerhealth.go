@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/notification"
+)
+
+type trackerHealth struct {
+	Tracker string
+	Down    int
+	Total   int
+}
+
+func (h trackerHealth) ratio() float64 {
+	if h.Total == 0 {
+		return 0
+	}
+	return float64(h.Down) / float64(h.Total)
+}
+
+// trackersDown returns the trackers where the share of torrents reporting the tracker down reaches the configured threshold
+func trackersDown(torrents map[string]config.Torrent, cfg config.TrackerDownConfig) []trackerHealth {
+	stats := make(map[string]*trackerHealth)
+	for _, t := range torrents {
+		if t.TrackerName == "" {
+			continue
+		}
+
+		h, ok := stats[t.TrackerName]
+		if !ok {
+			h = &trackerHealth{Tracker: t.TrackerName}
+			stats[t.TrackerName] = h
+		}
+
+		h.Total++
+		if t.IsTrackerDown() {
+			h.Down++
+		}
+	}
+
+	var down []trackerHealth
+	for _, h := range stats {
+		if h.Down == 0 || h.Total < cfg.MinTorrents {
+			continue
+		}
+		if h.ratio() >= cfg.Threshold {
+			down = append(down, *h)
+		}
+	}
+
+	slices.SortFunc(down, func(a, b trackerHealth) int {
+		return cmp.Or(cmp.Compare(b.ratio(), a.ratio()), strings.Compare(a.Tracker, b.Tracker))
+	})
+
+	return down
+}
+
+// checkTrackerHealth sends an early warning notification for trackers that appear to be down
+func checkTrackerHealth(log *logrus.Entry, noti notification.Sender, clientName string, torrents map[string]config.Torrent, startTime time.Time) {
+	cfg := config.Config.Notifications.TrackerDown
+	if !cfg.Enabled() {
+		return
+	}
+
+	down := trackersDown(torrents, cfg)
+	if len(down) == 0 {
+		log.Debug("No trackers appear to be down")
+		return
+	}
+
+	fields := make([]notification.Field, 0, len(down))
+	for _, h := range down {
+		log.Warnf("Tracker %s appears to be down: %d/%d torrents (%.0f%%)", h.Tracker, h.Down, h.Total, h.ratio()*100)
+		fields = append(fields, noti.BuildField(notification.ActionTrackerDown, notification.BuildOptions{
+			Tracker:      h.Tracker,
+			TrackerDown:  h.Down,
+			TrackerTotal: h.Total,
+		}))
+	}
+
+	if !noti.CanSend() {
+		return
+	}
+
+	if err := noti.Send(
+		"Tracker Down",
+		fmt.Sprintf("**%d** tracker(s) appear to be down", len(down)),
+		clientName,
+		time.Since(startTime),
+		fields,
+		flagDryRun,
+	); err != nil {
+		log.WithError(err).Error("Failed sending tracker down notification")
+	}
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/autobrr/tqm/pkg/config"
+)
+
+func TestTrackersDown(t *testing.T) {
+	torrents := map[string]config.Torrent{}
+	add := func(tracker string, status string, n int) {
+		for range n {
+			hash := fmt.Sprintf("%s-%d", tracker, len(torrents))
+			torrents[hash] = config.Torrent{Hash: hash, TrackerName: tracker, TrackerStatus: status}
+		}
+	}
+
+	// outage: most torrents report the tracker down
+	add("down.example", "service unavailable", 9)
+	add("down.example", "working", 1)
+	// a few flaky announces
+	add("flaky.example", "bad gateway", 1)
+	add("flaky.example", "working", 9)
+	// too few torrents to judge
+	add("small.example", "service unavailable", 2)
+	// healthy
+	add("up.example", "working", 5)
+	// no tracker
+	add("", "service unavailable", 5)
+
+	tests := []struct {
+		name string
+		cfg  config.TrackerDownConfig
+		want []trackerHealth
+	}{
+		{
+			name: "threshold",
+			cfg:  config.TrackerDownConfig{Threshold: 0.5, MinTorrents: 5},
+			want: []trackerHealth{{Tracker: "down.example", Down: 9, Total: 10}},
+		},
+		{
+			name: "no_min_torrents",
+			cfg:  config.TrackerDownConfig{Threshold: 0.5},
+			want: []trackerHealth{
+				{Tracker: "small.example", Down: 2, Total: 2},
+				{Tracker: "down.example", Down: 9, Total: 10},
+			},
+		},
+		{
+			name: "low_threshold",
+			cfg:  config.TrackerDownConfig{Threshold: 0.1, MinTorrents: 5},
+			want: []trackerHealth{
+				{Tracker: "down.example", Down: 9, Total: 10},
+				{Tracker: "flaky.example", Down: 1, Total: 10},
+			},
+		},
+		{
+			name: "full_outage_only",
+			cfg:  config.TrackerDownConfig{Threshold: 1},
+			want: []trackerHealth{{Tracker: "small.example", Down: 2, Total: 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, trackersDown(torrents, tt.cfg))
+		})
+	}
+}
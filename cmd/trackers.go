@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/autobrr/tqm/pkg/logger"
+	"github.com/autobrr/tqm/pkg/tracker"
+)
+
+var trackersCmd = &cobra.Command{
+	Use:   "trackers",
+	Short: "Tracker maintenance commands",
+	Long:  `These commands work with the trackers configured under trackers, no torrent client is needed.`,
+}
+
+var trackersTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Check the configured trackers can be reached with their credentials",
+	Long: `This command sends a request that changes nothing to the API of each configured tracker, checking it can
+be reached and accepts the configured credentials, and prints a pass/fail report.`,
+
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+
+		// init core
+		if !initialized {
+			initCore(true)
+			initialized = true
+		}
+
+		// set log
+		log := logger.GetLogger("trackers")
+
+		if tracker.Loaded() == 0 {
+			log.Fatal("No trackers with credentials are configured")
+		}
+
+		results := tracker.ProbeAll(ctx)
+		failed, err := writeProbeReport(os.Stdout, results)
+		if err != nil {
+			log.WithError(err).Fatal("Failed writing report")
+		}
+
+		if failed > 0 {
+			log.Fatalf("%d of %d tracker(s) failed", failed, len(results))
+		}
+
+		log.Infof("All %d tracker(s) passed", len(results))
+	},
+	DisableFlagsInUseLine: true,
+}
+
+func init() {
+	trackersCmd.AddCommand(trackersTestCmd)
+	rootCmd.AddCommand(trackersCmd)
+}
+
+// writeProbeReport writes a line per tracker probed, returning the number of trackers that failed
+func writeProbeReport(w io.Writer, results []tracker.ProbeResult) (int, error) {
+	failed := 0
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TRACKER\tRESULT\tDETAILS")
+	for _, r := range results {
+		switch {
+		case !r.Supported:
+			fmt.Fprintf(tw, "%s\tSKIP\tno probe available\n", r.Tracker)
+		case r.Err != nil:
+			failed++
+			fmt.Fprintf(tw, "%s\tFAIL\t%v%s\n", r.Tracker, r.Err, probeHint(r.Err))
+		default:
+			fmt.Fprintf(tw, "%s\tPASS\tcredentials accepted\n", r.Tracker)
+		}
+	}
+
+	if err := tw.Flush(); err != nil {
+		return failed, fmt.Errorf("write report: %w", err)
+	}
+
+	return failed, nil
+}
+
+// probeHint suggests what to look at for a failed probe, from the class of its error
+func probeHint(err error) string {
+	switch {
+	case errors.Is(err, tracker.ErrAuth):
+		return " (check the credentials)"
+	case errors.Is(err, tracker.ErrRateLimited):
+		return " (rate limited, try again later)"
+	case errors.Is(err, tracker.ErrConnection):
+		return " (tracker unreachable)"
+	}
+
+	return ""
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/tracker"
+)
+
+func TestWriteProbeReport(t *testing.T) {
+	buf := &bytes.Buffer{}
+	failed, err := writeProbeReport(buf, []tracker.ProbeResult{
+		{Tracker: "RED", Supported: true},
+		{Tracker: "BHD", Supported: true, Err: errors.New("unexpected status code: 401")},
+		{Tracker: "OTHER"},
+		{Tracker: "HDB", Supported: true, Err: &tracker.APIError{Kind: tracker.ErrAuth, Err: errors.New("unexpected status code: 403")}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, failed)
+	assert.Equal(t, `TRACKER  RESULT  DETAILS
+RED      PASS    credentials accepted
+BHD      FAIL    unexpected status code: 401
+OTHER    SKIP    no probe available
+HDB      FAIL    unexpected status code: 403 (check the credentials)
+`, buf.String())
+}
@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/autobrr/tqm/pkg/client"
+	"github.com/autobrr/tqm/pkg/config"
+)
+
+// triageDecisions are the decisions of an interactive dry-run, a live run given them with --decisions only removes
+// the torrents under Remove
+type triageDecisions struct {
+	Keep   []string `json:"keep"`
+	Remove []string `json:"remove"`
+}
+
+// decided reports whether a decision was already made for hash
+func (d *triageDecisions) decided(hash string) bool {
+	return slices.Contains(d.Keep, hash) || slices.Contains(d.Remove, hash)
+}
+
+// readTriageDecisions reads the decisions stored in path
+func readTriageDecisions(path string) (*triageDecisions, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read decisions: %w", err)
+	}
+
+	decisions := &triageDecisions{}
+	if err := json.Unmarshal(data, decisions); err != nil {
+		return nil, fmt.Errorf("unmarshal decisions: %w", err)
+	}
+
+	return decisions, nil
+}
+
+// writeTriageDecisions stores the decisions in path
+func writeTriageDecisions(path string, decisions *triageDecisions) error {
+	data, err := json.MarshalIndent(decisions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal decisions: %w", err)
+	}
+
+	return writeFileAtomic(path, append(data, '\n'))
+}
+
+// triageClient triages the torrents of a client, resuming from the decisions already stored in path
+func triageClient(ctx context.Context, c client.Interface, torrents map[string]config.Torrent, path string) (*triageDecisions, error) {
+	decisions, err := readTriageDecisions(path)
+	if errors.Is(err, os.ErrNotExist) {
+		decisions = &triageDecisions{}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := triageTorrents(ctx, c, torrents, decisions, os.Stdin, os.Stdout); err != nil {
+		return nil, err
+	}
+
+	if err := writeTriageDecisions(path, decisions); err != nil {
+		return nil, err
+	}
+
+	return decisions, nil
+}
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// triageTorrents prompts whether to keep, remove or skip each torrent meeting the remove filters, adding the answers
+// to decisions. Torrents decided earlier are not prompted again, quitting stops the triage keeping the decisions made
+func triageTorrents(ctx context.Context, c client.Interface, torrents map[string]config.Torrent, decisions *triageDecisions,
+	in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+
+	for _, h := range slices.Sorted(maps.Keys(torrents)) {
+		t := torrents[h]
+		if decisions.decided(t.Hash) {
+			continue
+		}
+
+		remove, reason, err := triageEvaluate(ctx, c, &t)
+		if err != nil {
+			return err
+		} else if !remove {
+			continue
+		}
+
+		fmt.Fprintln(out, "-----")
+		fmt.Fprintf(out, "Name: %s\n", t.Name)
+		fmt.Fprintf(out, "Hash: %s / Size: %s / Path: %s\n", t.Hash, humanize.IBytes(uint64(t.TotalBytes)), t.Path)
+		fmt.Fprintf(out, "Label: %s / Tags: %s / Tracker: %s (%s)\n", t.Label, strings.Join(t.Tags, ", "), t.TrackerName,
+			t.TrackerStatus)
+		fmt.Fprintf(out, "Ratio: %.3f / Seed days: %.3f / Seeds: %d\n", t.Ratio, t.SeedingDays, t.Seeds)
+		fmt.Fprintf(out, "Removal reason: %s\n", reason)
+
+		for answered := false; !answered; {
+			fmt.Fprint(out, "[k]eep, [r]emove, [s]kip or [q]uit? ")
+			if !scanner.Scan() {
+				// input closed, keep the decisions made so far
+				fmt.Fprintln(out)
+				return scanner.Err()
+			}
+
+			answered = true
+			switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+			case "k", "keep":
+				decisions.Keep = append(decisions.Keep, t.Hash)
+			case "r", "remove":
+				decisions.Remove = append(decisions.Remove, t.Hash)
+			case "s", "skip":
+			case "q", "quit":
+				return nil
+			default:
+				answered = false
+			}
+		}
+	}
+
+	return nil
+}
+
+// triageEvaluate reports whether t meets the remove filters without being ignored, within --timeout-per-torrent
+func triageEvaluate(ctx context.Context, c client.Interface, t *config.Torrent) (bool, string, error) {
+	tctx, done := torrentContext(ctx)
+	defer done(log, t)
+
+	if ignore, err := shouldIgnore(tctx, c, t); err != nil {
+		return false, "", fmt.Errorf("ignore filters: %v: %w", t.Name, err)
+	} else if ignore && (ignoreListed(t) || !t.BypassesIgnore(tctx)) {
+		return false, "", nil
+	}
+
+	remove, reason, err := c.ShouldRemoveWithReason(tctx, t)
+	if err != nil {
+		return false, "", fmt.Errorf("remove filters: %v: %w", t.Name, err)
+	}
+
+	return remove, reason, nil
+}
+
+// applyTriageDecisions drops every torrent that was not decided for removal, returning the number dropped. They stay
+// in the torrent file map, so the torrents that are removed still account for the files they share with them
+func applyTriageDecisions(torrents map[string]config.Torrent, decisions *triageDecisions) int {
+	dropped := 0
+	for h, t := range torrents {
+		if slices.Contains(decisions.Remove, t.Hash) && !slices.Contains(decisions.Keep, t.Hash) {
+			continue
+		}
+
+		delete(torrents, h)
+		dropped++
+	}
+
+	return dropped
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+)
+
+func TestTriageTorrents(t *testing.T) {
+	filter := &config.FilterConfiguration{
+		Ignore: []string{`Label == "ignored"`},
+		Remove: []string{`Label == "remove" || Label == "ignored"`},
+	}
+
+	c := newMockClient(t, filter, 0, map[string]config.Torrent{
+		"a": {Hash: "a", Name: "a", Label: "remove"},
+		"b": {Hash: "b", Name: "b", Label: "remove"},
+		"c": {Hash: "c", Name: "c", Label: "keep"},
+		"d": {Hash: "d", Name: "d", Label: "ignored"},
+		"e": {Hash: "e", Name: "e", Label: "remove"},
+		"f": {Hash: "f", Name: "f", Label: "remove"},
+	})
+
+	torrents, err := c.GetTorrents(context.Background())
+	require.NoError(t, err)
+
+	// b was decided in an earlier triage, an invalid answer is asked again
+	decisions := &triageDecisions{Keep: []string{"b"}}
+	out := &bytes.Buffer{}
+	require.NoError(t, triageTorrents(context.Background(), c, torrents, decisions, strings.NewReader("x\nr\nk\n"), out))
+
+	assert.Equal(t, []string{"b", "e"}, decisions.Keep)
+	assert.Equal(t, []string{"a"}, decisions.Remove)
+	assert.Equal(t, 4, strings.Count(out.String(), "[k]eep, [r]emove, [s]kip or [q]uit? "))
+	assert.NotContains(t, out.String(), "Name: c")
+	assert.NotContains(t, out.String(), "Name: d")
+
+	// skipped torrents are asked again, quitting keeps the decisions made
+	require.NoError(t, triageTorrents(context.Background(), c, torrents, decisions, strings.NewReader("s\nq\n"), &bytes.Buffer{}))
+	assert.Equal(t, []string{"b", "e"}, decisions.Keep)
+	assert.Equal(t, []string{"a"}, decisions.Remove)
+
+	require.NoError(t, triageTorrents(context.Background(), c, torrents, decisions, strings.NewReader("remove\n"), &bytes.Buffer{}))
+	assert.Equal(t, []string{"a", "f"}, decisions.Remove)
+}
+
+func TestApplyTriageDecisions(t *testing.T) {
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a"},
+		"b": {Hash: "b"},
+		"c": {Hash: "c"},
+	}
+
+	dropped := applyTriageDecisions(torrents, &triageDecisions{Keep: []string{"b"}, Remove: []string{"a", "d"}})
+	assert.Equal(t, 2, dropped)
+	assert.Equal(t, map[string]config.Torrent{"a": {Hash: "a"}}, torrents)
+}
+
+func TestTriageDecisionsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "decisions.json")
+
+	_, err := readTriageDecisions(path)
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	decisions := &triageDecisions{Keep: []string{"a"}, Remove: []string{"b", "c"}}
+	require.NoError(t, writeTriageDecisions(path, decisions))
+
+	read, err := readTriageDecisions(path)
+	require.NoError(t, err)
+	assert.Equal(t, decisions, read)
+}
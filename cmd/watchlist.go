@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/notification"
+	"github.com/autobrr/tqm/pkg/regex"
+)
+
+type watchMatch struct {
+	Torrent    config.Torrent
+	Conditions []string
+}
+
+// watchlistMatches returns the torrents of the watchlist that meet one of the conditions of their entry, with the
+// conditions they meet
+func watchlistMatches(ctx context.Context, torrents map[string]config.Torrent, entries []config.WatchlistEntry) ([]watchMatch, error) {
+	names := make([][]*regex.Pattern, len(entries))
+	for i, entry := range entries {
+		for _, name := range entry.Names {
+			p, err := regex.Compile(name)
+			if err != nil {
+				return nil, fmt.Errorf("compile name pattern %q: %w", name, err)
+			}
+			names[i] = append(names[i], p)
+		}
+	}
+
+	var matches []watchMatch
+	for _, t := range torrents {
+		var conditions []string
+		for i, entry := range entries {
+			watched := slices.ContainsFunc(entry.Hashes, func(h string) bool { return strings.EqualFold(h, t.Hash) })
+			if !watched && len(names[i]) > 0 {
+				match, err := regex.CheckAny(t.Name, names[i])
+				if err != nil {
+					return nil, fmt.Errorf("match name pattern: %v: %w", t.Name, err)
+				}
+				watched = match
+			}
+
+			if !watched {
+				continue
+			}
+
+			for _, c := range entry.Conditions {
+				if slices.Contains(conditions, c) {
+					continue
+				}
+
+				switch c {
+				case config.WatchConditionUnregistered:
+					tctx, done := torrentContext(ctx)
+					unregistered := t.IsUnregistered(tctx)
+					done(log, &t)
+					if unregistered {
+						conditions = append(conditions, c)
+					}
+				case config.WatchConditionTrackerDown:
+					if t.IsTrackerDown() {
+						conditions = append(conditions, c)
+					}
+				}
+			}
+		}
+
+		if len(conditions) > 0 {
+			matches = append(matches, watchMatch{Torrent: t, Conditions: conditions})
+		}
+	}
+
+	slices.SortFunc(matches, func(a, b watchMatch) int {
+		return cmp.Or(strings.Compare(a.Torrent.Name, b.Torrent.Name), strings.Compare(a.Torrent.Hash, b.Torrent.Hash))
+	})
+
+	return matches, nil
+}
+
+// checkWatchlist sends a notification for the watched torrents that meet one of their conditions
+func checkWatchlist(ctx context.Context, log *logrus.Entry, noti notification.Sender, clientName string, torrents map[string]config.Torrent, startTime time.Time) {
+	entries := config.Config.Notifications.Watchlist
+	if len(entries) == 0 {
+		return
+	}
+
+	matches, err := watchlistMatches(ctx, torrents, entries)
+	if err != nil {
+		log.WithError(err).Error("Failed checking watchlist")
+		return
+	} else if len(matches) == 0 {
+		log.Debug("No watched torrents meet their conditions")
+		return
+	}
+
+	fields := make([]notification.Field, 0, len(matches))
+	for _, m := range matches {
+		log.Warnf("Watched torrent %q: %s (tracker status: %s)", m.Torrent.Name, strings.Join(m.Conditions, ", "),
+			m.Torrent.TrackerStatus)
+		fields = append(fields, noti.BuildField(notification.ActionWatchlist, notification.BuildOptions{
+			Torrent:         m.Torrent,
+			WatchConditions: m.Conditions,
+		}))
+	}
+
+	if !noti.CanSend() {
+		return
+	}
+
+	if err := noti.Send(
+		"Watchlist",
+		fmt.Sprintf("**%d** watched torrent(s) meet their conditions", len(matches)),
+		clientName,
+		time.Since(startTime),
+		fields,
+		flagDryRun,
+	); err != nil {
+		log.WithError(err).Error("Failed sending watchlist notification")
+	}
+}
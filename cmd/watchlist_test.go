@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+)
+
+func TestWatchlistMatches(t *testing.T) {
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Name: "Rare.Album.FLAC", TrackerStatus: "Unregistered torrent"},
+		"b": {Hash: "b", Name: "Rare.Movie.2020", TrackerStatus: "service unavailable"},
+		"c": {Hash: "c", Name: "Common.Movie.2020", TrackerStatus: "Unregistered torrent"},
+		"d": {Hash: "d", Name: "Rare.Show.S01", TrackerStatus: "working"},
+		"e": {Hash: "e", Name: "Pinned", TrackerStatus: "service unavailable"},
+	}
+
+	entries := []config.WatchlistEntry{
+		{Names: []string{`^Rare\.`}, Conditions: []string{config.WatchConditionUnregistered}},
+		{Names: []string{`Movie`}, Conditions: []string{config.WatchConditionTrackerDown}},
+		{Hashes: []string{"E"}, Conditions: []string{config.WatchConditionUnregistered, config.WatchConditionTrackerDown}},
+	}
+
+	matches, err := watchlistMatches(context.Background(), torrents, entries)
+	require.NoError(t, err)
+
+	got := make(map[string][]string, len(matches))
+	for _, m := range matches {
+		got[m.Torrent.Hash] = m.Conditions
+	}
+
+	assert.Equal(t, map[string][]string{
+		"a": {config.WatchConditionUnregistered},
+		"b": {config.WatchConditionTrackerDown},
+		"e": {config.WatchConditionTrackerDown},
+	}, got)
+	assert.Equal(t, "Pinned", matches[0].Torrent.Name, "matches should be sorted by name")
+}
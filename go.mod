@@ -5,6 +5,7 @@ go 1.24
 toolchain go1.24.2
 
 require (
+	github.com/Masterminds/semver v1.5.0
 	github.com/autobrr/autobrr v1.63.1
 	github.com/autobrr/go-deluge v1.3.0
 	github.com/autobrr/go-qbittorrent v1.14.0
@@ -43,7 +44,6 @@ require (
 )
 
 require (
-	github.com/Masterminds/semver v1.5.0 // indirect
 	github.com/avast/retry-go v3.0.0+incompatible // indirect
 	github.com/benbjohnson/clock v1.3.5 // indirect
 	github.com/fsnotify/fsnotify v1.9.0 // indirect
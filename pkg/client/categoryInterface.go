@@ -0,0 +1,12 @@
+package client
+
+import (
+	"context"
+)
+
+// CategoryInterface is implemented by clients whose labels are categories that have to exist before they are used
+type CategoryInterface interface {
+	Interface
+
+	CreateCategory(ctx context.Context, name string) error
+}
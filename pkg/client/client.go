@@ -1,12 +1,25 @@
 package client
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
+	"github.com/autobrr/tqm/pkg/config"
 	"github.com/autobrr/tqm/pkg/expression"
 )
 
+var (
+	// pausePollInterval is how often the state of a torrent is polled while waiting for it to stop
+	pausePollInterval = 1 * time.Second
+	// pauseTimeout is how long to wait for a paused torrent to stop before aborting its removal
+	pauseTimeout = 30 * time.Second
+	// removalAnnounceDelay is the time given to the tracker between the pause, re-announce and removal of a private
+	// torrent, so the tracker registers the torrent as stopped
+	removalAnnounceDelay = 2 * time.Second
+)
+
 func NewClient(clientType string, clientName string, exp *expression.Expressions) (Interface, error) {
 	switch strings.ToLower(clientType) {
 	case "deluge":
@@ -19,3 +32,31 @@ func NewClient(clientType string, clientName string, exp *expression.Expressions
 
 	return nil, fmt.Errorf("client type not implemented: %q", clientType)
 }
+
+// waitForPaused polls the client state of a torrent until it is reported as paused. A torrent in error, e.g. with
+// missing files, transfers nothing and may never report paused, so it counts as stopped
+func waitForPaused(ctx context.Context, hash string, state func(ctx context.Context) (string, error)) error {
+	ctx, cancel := context.WithTimeout(ctx, pauseTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(pausePollInterval)
+	defer ticker.Stop()
+
+	for {
+		s, err := state(ctx)
+		if err != nil {
+			return fmt.Errorf("get torrent state: %v: %w", hash, err)
+		}
+
+		t := config.Torrent{State: s}
+		if t.NormalizedState() == config.StatePaused || t.IsError() {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("wait for pause: %v (state: %s): %w", hash, s, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
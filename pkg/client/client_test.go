@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForPaused(t *testing.T) {
+	pausePollInterval = time.Millisecond
+	pauseTimeout = 50 * time.Millisecond
+	t.Cleanup(func() {
+		pausePollInterval = 1 * time.Second
+		pauseTimeout = 30 * time.Second
+	})
+
+	// states returns a state source reporting each state in turn, repeating the last one
+	states := func(calls *int, s ...string) func(context.Context) (string, error) {
+		return func(context.Context) (string, error) {
+			*calls++
+			return s[min(*calls, len(s))-1], nil
+		}
+	}
+
+	t.Run("stops_after_polling", func(t *testing.T) {
+		var calls int
+		err := waitForPaused(context.Background(), "a", states(&calls, "uploading", "uploading", "pausedUP"))
+		require.NoError(t, err)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("already_stopped", func(t *testing.T) {
+		var calls int
+		err := waitForPaused(context.Background(), "a", states(&calls, "stoppedUP"))
+		require.NoError(t, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("errored", func(t *testing.T) {
+		for _, s := range []string{"error", "missingFiles", "Error"} {
+			var calls int
+			require.NoError(t, waitForPaused(context.Background(), "a", states(&calls, s)), s)
+			assert.Equal(t, 1, calls)
+		}
+	})
+
+	t.Run("deluge_state", func(t *testing.T) {
+		var calls int
+		require.NoError(t, waitForPaused(context.Background(), "a", states(&calls, "Seeding", "Paused")))
+	})
+
+	t.Run("never_stops", func(t *testing.T) {
+		var calls int
+		err := waitForPaused(context.Background(), "a", states(&calls, "uploading"))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("state_error", func(t *testing.T) {
+		errState := errors.New("connection refused")
+		err := waitForPaused(context.Background(), "a", func(context.Context) (string, error) {
+			return "", errState
+		})
+		require.ErrorIs(t, err, errState)
+	})
+}
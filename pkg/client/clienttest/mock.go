@@ -0,0 +1,411 @@
+package clienttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/autobrr/tqm/pkg/client"
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/expression"
+)
+
+// MockClient is an in-memory client for driving the command flows in tests. It serves a configurable set of
+// torrents, evaluates filters the same way the real clients do and records every mutation.
+type MockClient struct {
+	exp *expression.Expressions
+
+	mu       sync.Mutex
+	torrents map[string]config.Torrent
+
+	// configurable behaviour
+	FreeSpaceGB  float64
+	FreeSpaceSet bool
+	LabelPaths   map[string]string
+	RemoveErrors map[string]error
+	// RemoveFailures is the number of removals of a torrent that fail before one succeeds
+	RemoveFailures map[string]int
+	LabelErrors    map[string]error
+	TagErrors      map[string]error
+	Incomplete     map[string]bool
+	SetTagsErrors  error
+	// RefuseRemoval holds the torrents whose removal is refused without an error, like qBittorrent refuses torrents
+	// whose tracker is down
+	RefuseRemoval map[string]bool
+	// SessionExpired fails removals with ErrAuth until Connect is called
+	SessionExpired bool
+
+	// recorded fetches
+	FullFetches   int
+	SingleFetches int
+	// RemoveAttempts counts the removal attempts of each torrent
+	RemoveAttempts map[string]int
+	Connects       int
+
+	// recorded mutations
+	Removed      []string
+	RemovedData  map[string]bool
+	Labels       map[string]string
+	Hardlinked   map[string]bool
+	Paused       []string
+	Resumed      []string
+	Rechecked    []string
+	UploadLimits map[string]int64
+	CreatedTags  []string
+	DeletedTags  []string
+	// CreatedCategories are added to LabelPaths as well
+	CreatedCategories []string
+}
+
+func NewMockClient(exp *expression.Expressions, torrents map[string]config.Torrent) *MockClient {
+	if exp == nil {
+		exp = &expression.Expressions{}
+	}
+
+	return &MockClient{
+		exp:          exp,
+		torrents:     maps.Clone(torrents),
+		RemovedData:  make(map[string]bool),
+		Labels:       make(map[string]string),
+		Hardlinked:   make(map[string]bool),
+		UploadLimits: make(map[string]int64),
+	}
+}
+
+// Torrent returns the current state of a torrent, reflecting any tags or labels set through the client
+func (c *MockClient) Torrent(hash string) (config.Torrent, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t, ok := c.torrents[hash]
+	return t, ok
+}
+
+func (c *MockClient) Type() string {
+	return "mock"
+}
+
+func (c *MockClient) Connect(_ context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Connects++
+	c.SessionExpired = false
+	return nil
+}
+
+func (c *MockClient) GetTorrents(_ context.Context) (map[string]config.Torrent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.FullFetches++
+
+	torrents := make(map[string]config.Torrent, len(c.torrents))
+	for h, t := range c.torrents {
+		t.Tags = slices.Clone(t.Tags)
+		t.FreeSpaceGB = c.GetFreeSpace
+		t.FreeSpaceSet = c.FreeSpaceSet
+		torrents[h] = t
+	}
+
+	return torrents, nil
+}
+
+func (c *MockClient) GetTorrent(_ context.Context, hash string) (config.Torrent, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.SingleFetches++
+
+	for h, t := range c.torrents {
+		if strings.EqualFold(h, hash) {
+			t.Tags = slices.Clone(t.Tags)
+			t.FreeSpaceGB = c.GetFreeSpace
+			t.FreeSpaceSet = c.FreeSpaceSet
+			return t, nil
+		}
+	}
+
+	return config.Torrent{}, fmt.Errorf("%w: %v", client.ErrTorrentNotFound, hash)
+}
+
+func (c *MockClient) RemoveTorrent(_ context.Context, t *config.Torrent, deleteData bool) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.RemoveAttempts == nil {
+		c.RemoveAttempts = make(map[string]int)
+	}
+	c.RemoveAttempts[t.Hash]++
+
+	if err := c.RemoveErrors[t.Hash]; err != nil {
+		return false, err
+	}
+	if c.RefuseRemoval[t.Hash] {
+		return false, nil
+	}
+	if c.SessionExpired {
+		return false, &client.RequestError{Kind: client.ErrAuth, Err: errors.New("forbidden")}
+	}
+	if c.RemoveFailures[t.Hash] > 0 {
+		c.RemoveFailures[t.Hash]--
+		return false, errors.New("torrent is locked")
+	}
+
+	delete(c.torrents, t.Hash)
+	c.Removed = append(c.Removed, t.Hash)
+	c.RemovedData[t.Hash] = deleteData
+	return true, nil
+}
+
+func (c *MockClient) SetTorrentLabel(_ context.Context, hash string, label string, hardlink bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.LabelErrors[hash]; err != nil {
+		return err
+	}
+
+	if hardlink && c.LabelPaths[label] == "" {
+		return fmt.Errorf("label path not found for label %v", label)
+	}
+
+	c.Labels[hash] = label
+	c.Hardlinked[hash] = hardlink
+
+	if t, ok := c.torrents[hash]; ok {
+		t.Label = label
+		c.torrents[hash] = t
+	}
+
+	return nil
+}
+
+func (c *MockClient) GetCurrentFreeSpace(_ context.Context, _ string) (int64, error) {
+	c.FreeSpaceSet = true
+	return int64(c.FreeSpaceGB * humanize.GiByte), nil
+}
+
+func (c *MockClient) AddFreeSpace(bytes int64) {
+	c.FreeSpaceGB += float64(bytes) / humanize.GiByte
+}
+
+func (c *MockClient) GetFreeSpace() float64 {
+	return c.FreeSpaceGB
+}
+
+func (c *MockClient) LoadLabelPathMap(_ context.Context) error {
+	return nil
+}
+
+func (c *MockClient) LabelPathMap() map[string]string {
+	return c.LabelPaths
+}
+
+func (c *MockClient) CreateCategory(_ context.Context, name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.LabelPaths == nil {
+		c.LabelPaths = make(map[string]string)
+	}
+	c.LabelPaths[name] = "/downloads/" + name
+	c.CreatedCategories = append(c.CreatedCategories, name)
+	return nil
+}
+
+func (c *MockClient) SetUploadLimit(_ context.Context, hash string, limit int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.UploadLimits[hash] = limit
+	return nil
+}
+
+/* Filters */
+
+func (c *MockClient) ShouldIgnore(ctx context.Context, t *config.Torrent) (bool, error) {
+	return expression.CheckTorrentSingleMatch(ctx, t, c.exp.Ignores)
+}
+
+func (c *MockClient) ShouldRemove(ctx context.Context, t *config.Torrent) (bool, error) {
+	return expression.CheckTorrentSingleMatch(ctx, t, c.exp.Removes)
+}
+
+func (c *MockClient) ShouldRemoveWithReason(ctx context.Context, t *config.Torrent) (bool, string, error) {
+	return expression.CheckTorrentSingleMatchWithReason(ctx, t, c.exp.Removes)
+}
+
+func (c *MockClient) CheckTorrentPause(ctx context.Context, t *config.Torrent) (bool, error) {
+	return expression.CheckTorrentSingleMatch(ctx, t, c.exp.Pauses)
+}
+
+func (c *MockClient) ShouldRelabel(ctx context.Context, t *config.Torrent) (string, bool, error) {
+	for _, label := range c.exp.Labels {
+		match, err := expression.CheckTorrentAllMatch(ctx, t, label.Updates)
+		if err != nil {
+			return "", false, err
+		} else if match {
+			return label.Name, true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+func (c *MockClient) ShouldRetag(ctx context.Context, t *config.Torrent) (client.RetagInfo, error) {
+	return client.EvaluateRetag(ctx, c.exp, t)
+}
+
+func (c *MockClient) PauseTorrents(_ context.Context, hashes []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Paused = append(c.Paused, hashes...)
+	for _, h := range hashes {
+		if t, ok := c.torrents[h]; ok {
+			// like qBittorrent, a paused torrent no longer reports a tracker message
+			t.State = config.StatePaused
+			t.TrackerStatus = ""
+			c.torrents[h] = t
+		}
+	}
+
+	return nil
+}
+
+func (c *MockClient) ResumeTorrents(_ context.Context, hashes []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Resumed = append(c.Resumed, hashes...)
+	for _, h := range hashes {
+		if t, ok := c.torrents[h]; ok {
+			t.State = config.StateSeeding
+			c.torrents[h] = t
+		}
+	}
+
+	return nil
+}
+
+func (c *MockClient) RecheckAndWait(_ context.Context, hash string, _ time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Rechecked = append(c.Rechecked, hash)
+	return !c.Incomplete[hash], nil
+}
+
+/* Tags */
+
+func (c *MockClient) AddTags(_ context.Context, hash string, tags []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.TagErrors[hash]; err != nil {
+		return err
+	}
+
+	if t, ok := c.torrents[hash]; ok {
+		for _, tag := range tags {
+			if !slices.Contains(t.Tags, tag) {
+				t.Tags = append(slices.Clone(t.Tags), tag)
+			}
+		}
+		c.torrents[hash] = t
+	}
+
+	return nil
+}
+
+func (c *MockClient) RemoveTags(_ context.Context, hash string, tags []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.TagErrors[hash]; err != nil {
+		return err
+	}
+
+	if t, ok := c.torrents[hash]; ok {
+		t.Tags = slices.DeleteFunc(slices.Clone(t.Tags), func(tag string) bool {
+			return slices.Contains(tags, tag)
+		})
+		c.torrents[hash] = t
+	}
+
+	return nil
+}
+
+func (c *MockClient) SetTags(_ context.Context, hash string, tags []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.SetTagsErrors != nil {
+		return c.SetTagsErrors
+	}
+
+	if err := c.TagErrors[hash]; err != nil {
+		return err
+	}
+
+	if t, ok := c.torrents[hash]; ok {
+		t.Tags = slices.Clone(tags)
+		c.torrents[hash] = t
+	}
+
+	return nil
+}
+
+func (c *MockClient) CreateTags(_ context.Context, tags []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.CreatedTags = append(c.CreatedTags, tags...)
+	return nil
+}
+
+func (c *MockClient) DeleteTags(_ context.Context, tags []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.DeletedTags = append(c.DeletedTags, tags...)
+	return nil
+}
+
+func (c *MockClient) RenameTag(ctx context.Context, oldTag string, newTag string) (int, error) {
+	c.mu.Lock()
+	var hashes []string
+	for h, t := range c.torrents {
+		if slices.Contains(t.Tags, oldTag) {
+			hashes = append(hashes, h)
+		}
+	}
+	c.mu.Unlock()
+
+	if err := c.CreateTags(ctx, []string{newTag}); err != nil {
+		return 0, err
+	}
+
+	for _, h := range hashes {
+		if err := c.AddTags(ctx, h, []string{newTag}); err != nil {
+			return 0, err
+		}
+		if err := c.RemoveTags(ctx, h, []string{oldTag}); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := c.DeleteTags(ctx, []string{oldTag}); err != nil {
+		return len(hashes), err
+	}
+
+	return len(hashes), nil
+}
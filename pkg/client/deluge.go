@@ -2,8 +2,11 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"path"
+	"strings"
+	"sync"
 	"time"
 
 	delugeclient "github.com/autobrr/go-deluge"
@@ -23,6 +26,8 @@ type Deluge struct {
 	Login    *string `validate:"required"`
 	Password *string `validate:"required"`
 	V2       bool
+	// LabelPathMapping maps download location prefixes to the label of torrents without one
+	LabelPathMapping map[string]string `koanf:"label_path_mapping"`
 
 	// internal
 	log        *logrus.Entry
@@ -30,6 +35,8 @@ type Deluge struct {
 	client     *delugeclient.LabelPlugin
 	client1    *delugeclient.Client
 	client2    *delugeclient.ClientV2
+	// rpcMu serializes removals, which can run concurrently, as the rpc connection is not safe for concurrent use
+	rpcMu *sync.Mutex
 
 	// set by cmd handler
 	freeSpaceGB  float64
@@ -45,6 +52,7 @@ func NewDeluge(name string, exp *expression.Expressions) (Interface, error) {
 	tc := Deluge{
 		log:        logger.GetLogger(name),
 		clientType: "Deluge",
+		rpcMu:      &sync.Mutex{},
 		exp:        exp,
 	}
 
@@ -95,7 +103,7 @@ func (c *Deluge) Connect(ctx context.Context) error {
 	}
 
 	if err != nil {
-		return fmt.Errorf("login: %w", err)
+		return fmt.Errorf("login: %w", classifyError(err))
 	}
 
 	// retrieve & set common label client
@@ -108,13 +116,13 @@ func (c *Deluge) Connect(ctx context.Context) error {
 	}
 
 	if err != nil {
-		return fmt.Errorf("get label plugin: %w", err)
+		return fmt.Errorf("get label plugin: %w", classifyError(err))
 	}
 
 	// retrieve daemon version
 	daemonVersion, err := lc.DaemonVersion(ctx)
 	if err != nil {
-		return fmt.Errorf("get daemon version: %w", err)
+		return fmt.Errorf("get daemon version: %w", classifyError(err))
 	}
 	c.log.Debugf("Daemon Version: %v", daemonVersion)
 
@@ -132,18 +140,36 @@ func (c *Deluge) LabelPathMap() map[string]string {
 }
 
 func (c *Deluge) GetTorrents(ctx context.Context) (map[string]config.Torrent, error) {
+	return c.getTorrents(ctx, nil)
+}
+
+func (c *Deluge) GetTorrent(ctx context.Context, hash string) (config.Torrent, error) {
+	torrents, err := c.getTorrents(ctx, []string{strings.ToLower(hash)})
+	if err != nil {
+		return config.Torrent{}, err
+	}
+
+	for _, t := range torrents {
+		return t, nil
+	}
+
+	return config.Torrent{}, fmt.Errorf("%w: %v", ErrTorrentNotFound, hash)
+}
+
+// getTorrents retrieves the torrents with the hashes, or all torrents when hashes is empty
+func (c *Deluge) getTorrents(ctx context.Context, hashes []string) (map[string]config.Torrent, error) {
 	// retrieve torrents from client
 	c.log.Tracef("Retrieving torrents...")
-	ts, err := c.client.TorrentsStatus(ctx, delugeclient.StateUnspecified, nil)
+	ts, err := c.client.TorrentsStatus(ctx, delugeclient.StateUnspecified, hashes)
 	if err != nil {
-		return nil, fmt.Errorf("get torrents: %w", err)
+		return nil, fmt.Errorf("get torrents: %w", classifyError(err))
 	}
 	c.log.Tracef("Retrieved %d torrents", len(ts))
 
 	// retrieve torrent labels
-	labels, err := c.client.GetTorrentsLabels(delugeclient.StateUnspecified, nil)
+	labels, err := c.client.GetTorrentsLabels(delugeclient.StateUnspecified, hashes)
 	if err != nil {
-		return nil, fmt.Errorf("get torrent labels: %w", err)
+		return nil, fmt.Errorf("get torrent labels: %w", classifyError(err))
 	}
 	c.log.Tracef("Retrieved labels for %d torrents", len(labels))
 
@@ -174,7 +200,8 @@ func (c *Deluge) GetTorrents(ctx context.Context) (map[string]config.Torrent, er
 			Files:           files,
 			Downloaded:      t.TotalDone == t.TotalSize,
 			Seeding:         t.IsSeed,
-			Ratio:           t.Ratio,
+			Ratio:           float64(t.Ratio),
+			RatioLimit:      -1,
 			AddedSeconds:    t.ActiveTime,
 			AddedHours:      float32(t.ActiveTime) / 60 / 60,
 			AddedDays:       float32(t.ActiveTime) / 60 / 60 / 24,
@@ -186,6 +213,9 @@ func (c *Deluge) GetTorrents(ctx context.Context) (map[string]config.Torrent, er
 			IsPublic:        !t.Private,
 			Seeds:           t.TotalSeeds,
 			Peers:           t.TotalPeers,
+			Availability:    t.DistributedCopies,
+			// Note: go-deluge does not request max_upload_speed/max_download_speed, so UpLimit and DownLimit
+			// are not populated
 			// free space
 			FreeSpaceGB:  c.GetFreeSpace,
 			FreeSpaceSet: c.freeSpaceSet,
@@ -194,6 +224,12 @@ func (c *Deluge) GetTorrents(ctx context.Context) (map[string]config.Torrent, er
 			TrackerStatus: t.TrackerStatus,
 			// Note: Deluge only uses one tracker at a time, so AllTrackerStatuses is not populated
 			AllTrackerStatuses: nil,
+			TrackerCount:       delugeTrackerCount(t.TrackerHost),
+		}
+
+		// fall back to the label of the download location
+		if torrent.Label == "" {
+			torrent.Label = labelFromPath(&torrent, c.LabelPathMapping)
 		}
 
 		torrents[h] = torrent
@@ -202,33 +238,84 @@ func (c *Deluge) GetTorrents(ctx context.Context) (map[string]config.Torrent, er
 	return torrents, nil
 }
 
-func (c *Deluge) RemoveTorrent(ctx context.Context, torrent *config.Torrent, deleteData bool) (bool, error) {
+// labelFromPath returns the label mapped to the longest prefix of the torrent's path, or "" when none matches
+func labelFromPath(t *config.Torrent, mapping map[string]string) string {
+	label, longest := "", -1
+	for prefix, l := range mapping {
+		if len(prefix) > longest && t.PathHasPrefix(prefix) {
+			label, longest = l, len(prefix)
+		}
+	}
+
+	return label
+}
+
+// reannounce pauses, resumes and re-announces a torrent, so the tracker is told it stopped before it is removed
+func (c *Deluge) reannounce(ctx context.Context, hash string) error {
 	// pause torrent
-	if err := c.client.PauseTorrents(ctx, torrent.Hash); err != nil {
-		return false, fmt.Errorf("pause torrent: %v: %w", torrent.Hash, err)
+	if err := c.client.PauseTorrents(ctx, hash); err != nil {
+		return fmt.Errorf("pause torrent: %v: %w", hash, classifyError(err))
 	}
 
-	time.Sleep(1 * time.Second)
+	time.Sleep(removalAnnounceDelay / 2)
 
 	// resume torrent
-	if err := c.client.ResumeTorrents(ctx, torrent.Hash); err != nil {
-		return false, fmt.Errorf("resume torrent: %v: %w", torrent.Hash, err)
+	if err := c.client.ResumeTorrents(ctx, hash); err != nil {
+		return fmt.Errorf("resume torrent: %v: %w", hash, classifyError(err))
 	}
 
 	// sleep before re-announcing torrent
-	time.Sleep(2 * time.Second)
+	time.Sleep(removalAnnounceDelay)
 
 	// re-announce torrent
-	if err := c.client.ForceReannounce(ctx, []string{torrent.Hash}); err != nil {
-		return false, fmt.Errorf("re-announce torrent: %v: %w", torrent.Hash, err)
+	if err := c.client.ForceReannounce(ctx, []string{hash}); err != nil {
+		return fmt.Errorf("re-announce torrent: %v: %w", hash, classifyError(err))
 	}
 
 	// sleep before removing torrent
-	time.Sleep(2 * time.Second)
+	time.Sleep(removalAnnounceDelay)
+
+	return nil
+}
+
+// pauseAndWait pauses a torrent and waits until deluge reports it stopped, so no data is written while it is deleted
+func (c *Deluge) pauseAndWait(ctx context.Context, hash string) error {
+	if err := c.client.PauseTorrents(ctx, hash); err != nil {
+		return fmt.Errorf("pause torrent: %v: %w", hash, classifyError(err))
+	}
+
+	return waitForPaused(ctx, hash, func(ctx context.Context) (string, error) {
+		ts, err := c.client.TorrentStatus(ctx, hash)
+		if err != nil {
+			return "", err
+		}
+
+		return ts.State, nil
+	})
+}
+
+func (c *Deluge) RemoveTorrent(ctx context.Context, torrent *config.Torrent, deleteData bool) (bool, error) {
+	c.rpcMu.Lock()
+	defer c.rpcMu.Unlock()
+
+	// stop the torrent without re-announcing when its data is deleted and verify_pause is enabled, announcing is
+	// pointless for torrents that are already stopped or unregistered
+	if deleteData && config.Config != nil && config.Config.RemovalAnnounce.VerifyPause {
+		if err := c.pauseAndWait(ctx, torrent.Hash); err != nil {
+			return false, err
+		}
+	} else if torrent.IsPublic {
+		// public trackers have no announce timing to satisfy
+		c.log.Debugf("Skipping re-announce before removal for public torrent %s (%s)", torrent.Name, torrent.Hash)
+	} else if torrent.SkipRemovalAnnounce(ctx) {
+		c.log.Debugf("Skipping re-announce before removal for %s (%s)", torrent.Name, torrent.Hash)
+	} else if err := c.reannounce(ctx, torrent.Hash); err != nil {
+		return false, err
+	}
 
 	// remove
 	if ok, err := c.client.RemoveTorrent(ctx, torrent.Hash, deleteData); err != nil {
-		return false, fmt.Errorf("remove torrent: %v: %w", torrent.Hash, err)
+		return false, fmt.Errorf("remove torrent: %v: %w", torrent.Hash, classifyError(err))
 	} else if !ok {
 		return false, fmt.Errorf("remove torrent: %v", torrent.Hash)
 	}
@@ -244,7 +331,7 @@ func (c *Deluge) SetTorrentLabel(ctx context.Context, hash string, label string,
 
 	// set label
 	if err := c.client.SetTorrentLabel(ctx, hash, label); err != nil {
-		return fmt.Errorf("set torrent label: %v: %w", label, err)
+		return fmt.Errorf("set torrent label: %v: %w", label, classifyError(err))
 	}
 
 	return nil
@@ -258,7 +345,7 @@ func (c *Deluge) GetCurrentFreeSpace(ctx context.Context, path string) (int64, e
 	// get free disk space
 	space, err := c.client.GetFreeSpace(ctx, path)
 	if err != nil {
-		return 0, fmt.Errorf("get free disk space: %v: %w", path, err)
+		return 0, fmt.Errorf("get free disk space: %v: %w", path, classifyError(err))
 	}
 
 	// set internal free size
@@ -323,12 +410,7 @@ func (c *Deluge) ShouldRelabel(ctx context.Context, t *config.Torrent) (string,
 }
 
 func (c *Deluge) SetUploadLimit(ctx context.Context, hash string, limit int64) error {
-	var uploadSpeed int
-	if limit == -1 {
-		uploadSpeed = -1
-	} else {
-		uploadSpeed = int(limit / 1024)
-	}
+	uploadSpeed := delugeSpeedLimit(limit)
 
 	opts := &delugeclient.Options{
 		MaxUploadSpeed: &uploadSpeed,
@@ -342,7 +424,7 @@ func (c *Deluge) SetUploadLimit(ctx context.Context, hash string, limit int64) e
 	}
 
 	if err != nil {
-		return fmt.Errorf("set torrent options for %s: %w", hash, err)
+		return fmt.Errorf("set torrent options for %s: %w", hash, classifyError(err))
 	}
 
 	c.log.Debugf("Set upload limit for torrent %s to %d KiB/s", hash, uploadSpeed)
@@ -367,8 +449,37 @@ func (c *Deluge) PauseTorrents(ctx context.Context, hashes []string) error {
 	}
 
 	if err != nil {
-		return fmt.Errorf("pause torrents: %v: %w", hashes, err)
+		return fmt.Errorf("pause torrents: %v: %w", hashes, classifyError(err))
 	}
 
 	return nil
 }
+
+func (c *Deluge) ResumeTorrents(ctx context.Context, hashes []string) error {
+	if err := c.client.ResumeTorrents(ctx, hashes...); err != nil {
+		return fmt.Errorf("resume torrents: %v: %w", hashes, classifyError(err))
+	}
+	return nil
+}
+
+func (c *Deluge) RecheckAndWait(_ context.Context, _ string, _ time.Duration) (bool, error) {
+	return false, errors.New("recheck is not supported by deluge")
+}
+
+// delugeTrackerCount reports whether the torrent has a tracker, deluge only exposes the current tracker host
+func delugeTrackerCount(trackerHost string) int {
+	if trackerHost == "" {
+		return 0
+	}
+
+	return 1
+}
+
+// delugeSpeedLimit converts a limit in bytes/s (as used by qbittorrent) to the KiB/s deluge expects, -1 is unlimited
+func delugeSpeedLimit(limit int64) int {
+	if limit == -1 {
+		return -1
+	}
+
+	return int(limit / 1024)
+}
@@ -0,0 +1,58 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/autobrr/tqm/pkg/config"
+)
+
+func TestDelugeSpeedLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int64
+		expected int
+	}{
+		{name: "unlimited", limit: -1, expected: -1},
+		{name: "zero", limit: 0, expected: 0},
+		{name: "kib", limit: 100 * 1024, expected: 100},
+		{name: "partial_kib_truncated", limit: 100*1024 + 512, expected: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, delugeSpeedLimit(tt.limit))
+		})
+	}
+}
+
+func TestLabelFromPath(t *testing.T) {
+	mapping := map[string]string{
+		"/downloads/tv":        "tv",
+		"/downloads/tv/anime/": "anime",
+		"/downloads/movies":    "movies",
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+	}{
+		{name: "exact", path: "/downloads/tv", expected: "tv"},
+		{name: "subfolder", path: "/downloads/movies/4k", expected: "movies"},
+		{name: "longest_prefix", path: "/downloads/tv/anime/show", expected: "anime"},
+		{name: "trailing_separator", path: "/downloads/tv/anime", expected: "anime"},
+		{name: "partial_segment", path: "/downloads/tv-4k", expected: ""},
+		{name: "case_insensitive", path: "/Downloads/Movies", expected: "movies"},
+		{name: "no_match", path: "/downloads/music", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, labelFromPath(&config.Torrent{Path: tt.path}, mapping))
+		})
+	}
+
+	assert.Empty(t, labelFromPath(&config.Torrent{Path: "/downloads/tv"}, nil))
+}
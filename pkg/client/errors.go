@@ -0,0 +1,86 @@
+package client
+
+import (
+	"errors"
+	"strings"
+
+	delugeclient "github.com/autobrr/go-deluge"
+	qbit "github.com/autobrr/go-qbittorrent"
+
+	"github.com/autobrr/tqm/pkg/httputils"
+)
+
+// classes of failed client requests, matched with errors.Is
+var (
+	// ErrAuth means the client refused the credentials
+	ErrAuth = errors.New("authentication failed")
+	// ErrConnection means the client could not be reached or dropped the connection
+	ErrConnection = errors.New("connection failed")
+	// ErrNotFound means the client has no such torrent
+	ErrNotFound = errors.New("not found")
+)
+
+// ErrTorrentNotFound is returned by GetTorrent when the client has no torrent with the hash
+var ErrTorrentNotFound = &RequestError{Kind: ErrNotFound, Err: errors.New("torrent not found")}
+
+// RequestError is a failed client request, Kind is its class (ErrAuth, ErrConnection or ErrNotFound). It reads like
+// the error it wraps
+type RequestError struct {
+	Kind error
+	Err  error
+}
+
+func (e *RequestError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RequestError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// errorKind returns the class of a failed client request, nil when it has none
+func errorKind(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var reqErr *RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr.Kind
+	}
+
+	var rpcErr delugeclient.RPCError
+	switch {
+	case errors.Is(err, qbit.ErrBadCredentials) || errors.Is(err, qbit.ErrIPBanned):
+		return ErrAuth
+	case errors.As(err, &rpcErr) && isDelugeAuthError(rpcErr.ExceptionType):
+		return ErrAuth
+	case errors.Is(err, qbit.ErrTorrentNotFound):
+		return ErrNotFound
+	case errors.Is(err, delugeclient.ErrAlreadyClosed) || httputils.IsConnectionError(err):
+		return ErrConnection
+	}
+
+	return nil
+}
+
+// isDelugeAuthError reports whether a deluge daemon exception refuses the login, e.g. BadLoginError
+func isDelugeAuthError(exceptionType string) bool {
+	exceptionType = strings.ToLower(exceptionType)
+	return strings.Contains(exceptionType, "login") || strings.Contains(exceptionType, "auth")
+}
+
+// classifyError wraps err in a RequestError of its class, errors without a class are returned as they are
+func classifyError(err error) error {
+	var reqErr *RequestError
+	if errors.As(err, &reqErr) {
+		return err
+	}
+
+	kind := errorKind(err)
+	if kind == nil {
+		return err
+	}
+
+	return &RequestError{Kind: kind, Err: err}
+}
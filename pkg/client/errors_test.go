@@ -0,0 +1,52 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+
+	delugeclient "github.com/autobrr/go-deluge"
+	qbit "github.com/autobrr/go-qbittorrent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{name: "qbit_bad_credentials", err: qbit.ErrBadCredentials, expected: ErrAuth},
+		{name: "qbit_ip_banned", err: qbit.ErrIPBanned, expected: ErrAuth},
+		{name: "qbit_torrent_not_found", err: qbit.ErrTorrentNotFound, expected: ErrNotFound},
+		{name: "deluge_bad_login", err: delugeclient.RPCError{ExceptionType: "BadLoginError"}, expected: ErrAuth},
+		{name: "deluge_closed", err: delugeclient.ErrAlreadyClosed, expected: ErrConnection},
+		{name: "dial", err: fmt.Errorf("login: %w", &net.OpError{Op: "dial", Err: errors.New("refused")}), expected: ErrConnection},
+		{name: "deluge_other", err: delugeclient.RPCError{ExceptionType: "InvalidTorrentError"}, expected: nil},
+		{name: "other", err: errors.New("unexpected status code"), expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyError(tt.err)
+			require.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.err.Error(), err.Error())
+
+			for _, kind := range []error{ErrAuth, ErrConnection, ErrNotFound} {
+				assert.Equal(t, kind == tt.expected, errors.Is(err, kind), "kind %v", kind)
+			}
+		})
+	}
+}
+
+func TestErrTorrentNotFound(t *testing.T) {
+	err := fmt.Errorf("%w: %v", ErrTorrentNotFound, "abc")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, err, ErrTorrentNotFound)
+	assert.Equal(t, "torrent not found: abc", err.Error())
+
+	// classifying again keeps the error as it is
+	assert.Same(t, ErrTorrentNotFound, classifyError(ErrTorrentNotFound))
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"time"
 
 	"github.com/autobrr/tqm/pkg/config"
 )
@@ -10,6 +11,7 @@ type Interface interface {
 	Type() string
 	Connect(ctx context.Context) error
 	GetTorrents(ctx context.Context) (map[string]config.Torrent, error)
+	GetTorrent(ctx context.Context, hash string) (config.Torrent, error)
 	RemoveTorrent(ctx context.Context, torrent *config.Torrent, deleteData bool) (bool, error)
 	SetTorrentLabel(ctx context.Context, hash string, label string, hardlink bool) error
 	GetCurrentFreeSpace(ctx context.Context, path string) (int64, error)
@@ -27,4 +29,6 @@ type Interface interface {
 	ShouldRelabel(ctx context.Context, t *config.Torrent) (string, bool, error)
 
 	PauseTorrents(ctx context.Context, hashes []string) error
+	ResumeTorrents(ctx context.Context, hashes []string) error
+	RecheckAndWait(ctx context.Context, hash string, timeout time.Duration) (bool, error)
 }
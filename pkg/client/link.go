@@ -0,0 +1,61 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// LinkFiles hardlinks files (relative to sourceRoot) into targetRoot, preserving their directory structure.
+// With preserveTimes the modification times of the created directories are copied from their source directories.
+func LinkFiles(log *logrus.Entry, sourceRoot string, targetRoot string, names []string, preserveTimes bool) error {
+	// target directories created for the hardlinks, mapped to their source directory
+	linkedDirs := make(map[string]string)
+
+	for _, name := range names {
+		source := filepath.Join(sourceRoot, name)
+		target := filepath.Join(targetRoot, name)
+		fi, err := os.Stat(source)
+		if err != nil {
+			return fmt.Errorf("stat file '%v': %w", target, err)
+		}
+
+		// create target directory
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("create target directory: %w", err)
+		}
+
+		// link
+		if err := os.Link(source, target); err != nil {
+			return fmt.Errorf("create hardlink for '%v': %w", name, err)
+		}
+
+		if preserveTimes {
+			if err := os.Chtimes(target, time.Time{}, fi.ModTime()); err != nil {
+				return fmt.Errorf("preserve modification time for '%v': %w", name, err)
+			}
+
+			for dir := filepath.Dir(name); dir != "."; dir = filepath.Dir(dir) {
+				linkedDirs[filepath.Join(targetRoot, dir)] = filepath.Join(sourceRoot, dir)
+			}
+		}
+	}
+
+	// directories are updated last, as linking files into them changes their modification time
+	for target, source := range linkedDirs {
+		fi, err := os.Stat(source)
+		if err != nil {
+			log.WithError(err).Warnf("Failed retrieving modification time of directory: %q", source)
+			continue
+		}
+
+		if err := os.Chtimes(target, time.Time{}, fi.ModTime()); err != nil {
+			log.WithError(err).Warnf("Failed preserving modification time of directory: %q", target)
+		}
+	}
+
+	return nil
+}
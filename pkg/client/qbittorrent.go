@@ -4,11 +4,16 @@ import (
 	"context"
 	"fmt"
 	"io"
-	"os"
+	"net"
+	"net/http"
+	"net/url"
 	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/Masterminds/semver"
 	qbit "github.com/autobrr/go-qbittorrent"
 	"github.com/dustin/go-humanize"
 	"github.com/sirupsen/logrus"
@@ -21,18 +26,34 @@ import (
 
 /* Struct */
 
+// recheckPollInterval is how often the state of a torrent is polled while waiting for a recheck
+var recheckPollInterval = 2 * time.Second
+
+// includeTrackersVersion is the first web api version (qBittorrent 5.1) returning trackers with the torrent list
+var includeTrackersVersion = semver.MustParse("2.11.4")
+
+// defaultTrackerFetchConcurrency is the number of torrents whose trackers are fetched at once on older versions
+const defaultTrackerFetchConcurrency = 8
+
 type QBittorrent struct {
 	Url                       *string `validate:"required"`
 	User                      string
 	Password                  string
 	EnableAutoTmmAfterRelabel bool
 	CreateTagsUpfront         bool `koanf:"create_tags_upfront"`
+	PreserveFileTimes         bool `koanf:"preserve_file_times"`
+	// TrackerFetchConcurrency is the number of torrents whose trackers are fetched at once, only used by versions
+	// older than qBittorrent 5.1 that do not return trackers with the torrent list
+	TrackerFetchConcurrency int `koanf:"tracker_fetch_concurrency"`
 
 	// internal
 	log        *logrus.Entry
 	clientType string
 	client     *qbit.Client
 
+	// set by Connect, whether the torrent list includes the trackers of each torrent
+	includesTrackers bool
+
 	// need to be loaded by LoadLabelPathMap
 	labelPathMap map[string]string
 
@@ -52,6 +73,8 @@ func NewQBittorrent(name string, exp *expression.Expressions) (TagInterface, err
 		clientType:        "qBittorrent",
 		exp:               exp,
 		CreateTagsUpfront: true,
+
+		TrackerFetchConcurrency: defaultTrackerFetchConcurrency,
 	}
 
 	// load config
@@ -68,17 +91,56 @@ func NewQBittorrent(name string, exp *expression.Expressions) (TagInterface, err
 	qbl := logrus.New()
 	qbl.Out = io.Discard
 	//tc.client = qbittorrent.NewClient(strings.TrimSuffix(*tc.Url, "/"), qbl)
-	tc.client = qbit.NewClient(qbit.Config{
-		Host:          *tc.Url,
-		Username:      tc.User,
-		Password:      tc.Password,
+	client, err := newQbitClient(*tc.Url, tc.User, tc.Password)
+	if err != nil {
+		return nil, fmt.Errorf("url: %w", err)
+	}
+	tc.client = client
+
+	return &tc, nil
+}
+
+// newQbitClient creates the api client of a qBittorrent web ui, a unix:// url (e.g. unix:///run/qbittorrent.sock)
+// connects to a unix socket instead of a tcp port
+func newQbitClient(webURL string, user string, password string) (*qbit.Client, error) {
+	cfg := qbit.Config{
+		Host:          webURL,
+		Username:      user,
+		Password:      password,
 		TLSSkipVerify: true,
-		BasicUser:     tc.User,
-		BasicPass:     tc.Password,
+		BasicUser:     user,
+		BasicPass:     password,
 		Log:           nil,
-	})
+	}
 
-	return &tc, nil
+	u, err := url.Parse(webURL)
+	if err != nil || u.Scheme != "unix" {
+		// anything else is left for the client to handle, as before
+		return qbit.NewClient(cfg), nil
+	}
+
+	socket := u.Path
+	if socket == "" {
+		socket = u.Opaque
+	}
+	if socket == "" {
+		return nil, fmt.Errorf("no socket path: %s", webURL)
+	}
+
+	// requests are made to a placeholder host, the transport always dials the socket
+	cfg.Host = "http://localhost"
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+
+	return qbit.NewClient(cfg).WithHTTPClient(&http.Client{
+		Timeout: qbit.DefaultTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return dialer.DialContext(ctx, "unix", socket)
+			},
+			MaxIdleConnsPerHost: 10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}), nil
 }
 
 /* Interface  */
@@ -90,32 +152,39 @@ func (c *QBittorrent) Type() string {
 func (c *QBittorrent) Connect(context.Context) error {
 	// login
 	if err := c.client.Login(); err != nil {
-		return fmt.Errorf("login: %w", err)
+		return fmt.Errorf("login: %w", classifyError(err))
 	}
 
 	// retrieve & validate api version
 	//apiVersion, err := c.client.Application.GetAPIVersion()
 	apiVersion, err := c.client.GetWebAPIVersion()
 	if err != nil {
-		return fmt.Errorf("get api version: %w", err)
+		return fmt.Errorf("get api version: %w", classifyError(err))
 	}
 	//else if stringutils.Atof64(apiVersion[0:3], 0.0) < 2.2 {
 	//	return fmt.Errorf("unsupported webapi version: %v", apiVersion)
 	//}
 
 	c.log.Debugf("API Version: %v", apiVersion)
+
+	if v, err := semver.NewVersion(apiVersion); err != nil {
+		c.log.WithError(err).Warnf("Failed parsing api version, trackers are fetched per torrent: %v", apiVersion)
+	} else {
+		c.includesTrackers = !v.LessThan(includeTrackersVersion)
+	}
+
 	return nil
 }
 
 func (c *QBittorrent) LoadLabelPathMap(ctx context.Context) error {
 	p, err := c.client.GetAppPreferencesCtx(ctx)
 	if err != nil {
-		return fmt.Errorf("get app preferences: %w", err)
+		return fmt.Errorf("get app preferences: %w", classifyError(err))
 	}
 
 	cats, err := c.client.GetCategoriesCtx(ctx)
 	if err != nil {
-		return fmt.Errorf("get categories: %w", err)
+		return fmt.Errorf("get categories: %w", classifyError(err))
 	}
 
 	c.labelPathMap = make(map[string]string)
@@ -140,15 +209,141 @@ func (c *QBittorrent) LabelPathMap() map[string]string {
 	return c.labelPathMap
 }
 
+// CreateCategory creates a category saving to the default path of its name
+func (c *QBittorrent) CreateCategory(ctx context.Context, name string) error {
+	if err := c.client.CreateCategoryCtx(ctx, name, ""); err != nil {
+		return fmt.Errorf("create category: %v: %w", name, classifyError(err))
+	}
+
+	return nil
+}
+
+// parseTrackers returns the primary tracker's name and status, the status of every tracker and the number of
+// trackers, skipping the DHT, LSD and PeX pseudo trackers. The primary tracker is the first working one, as the
+// trackers of a multi-tier torrent are listed across tiers, falling back to the first tracker when none is working
+func parseTrackers(trackers []qbit.TorrentTracker) (string, string, map[string]string, int) {
+	trackerName := ""
+	trackerStatus := ""
+	allTrackerStatuses := make(map[string]string)
+	trackerCount := 0
+	working := false
+
+	for _, tr := range trackers {
+		// skip disabled trackers
+		if strings.Contains(tr.Url, "[DHT]") || strings.Contains(tr.Url, "[LSD]") ||
+			strings.Contains(tr.Url, "[PeX]") {
+			continue
+		}
+
+		// Store all tracker statuses
+		allTrackerStatuses[tr.Url] = tr.Message
+
+		// prefer the first working tracker, keeping the first tracker until one is found
+		if trackerCount == 0 || (!working && tr.Status == qbit.TrackerStatusOK) {
+			trackerName = config.ParseTrackerDomain(tr.Url)
+			trackerStatus = tr.Message
+			working = tr.Status == qbit.TrackerStatusOK
+		}
+		trackerCount++
+	}
+
+	return trackerName, trackerStatus, allTrackerStatuses, trackerCount
+}
+
+func fileNames(files qbit.TorrentFiles) []string {
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+// torrentFilesRoot returns the directory the torrent file names are relative to. this is the save path unless
+// content_path shows the files elsewhere (e.g. in the incomplete download path), falling back to the save path
+// when content_path does not match the file names.
+func torrentFilesRoot(savePath string, contentPath string, names []string) string {
+	if contentPath == "" || len(names) == 0 {
+		return savePath
+	}
+
+	contentPath = filepath.Clean(contentPath)
+
+	// single file: content_path is the file itself
+	if len(names) == 1 {
+		name := filepath.Clean(names[0])
+		if contentPath == name {
+			return savePath
+		}
+		if root, ok := strings.CutSuffix(contentPath, string(filepath.Separator)+name); ok {
+			return root
+		}
+		return savePath
+	}
+
+	// multiple files in a subfolder: content_path is the subfolder
+	folder, _, _ := strings.Cut(filepath.Clean(names[0]), string(filepath.Separator))
+	inFolder := true
+	for _, name := range names {
+		if !strings.HasPrefix(filepath.Clean(name), folder+string(filepath.Separator)) {
+			inFolder = false
+			break
+		}
+	}
+	if inFolder && filepath.Base(contentPath) == folder {
+		return filepath.Dir(contentPath)
+	}
+
+	// multiple files without a subfolder: content_path is the root itself
+	if !inFolder {
+		return contentPath
+	}
+
+	return savePath
+}
+
 func (c *QBittorrent) GetTorrents(ctx context.Context) (map[string]config.Torrent, error) {
+	return c.getTorrents(ctx, nil)
+}
+
+func (c *QBittorrent) GetTorrent(ctx context.Context, hash string) (config.Torrent, error) {
+	torrents, err := c.getTorrents(ctx, []string{strings.ToLower(hash)})
+	if err != nil {
+		return config.Torrent{}, err
+	}
+
+	for _, t := range torrents {
+		return t, nil
+	}
+
+	return config.Torrent{}, fmt.Errorf("%w: %v", ErrTorrentNotFound, hash)
+}
+
+// getTorrents retrieves the torrents with the hashes, or all torrents when hashes is empty
+func (c *QBittorrent) getTorrents(ctx context.Context, hashes []string) (map[string]config.Torrent, error) {
 	// retrieve torrents from client
 	c.log.Tracef("Retrieving torrents...")
-	ts, err := c.client.GetTorrentsCtx(ctx, qbit.TorrentFilterOptions{IncludeTrackers: true})
+	ts, err := c.client.GetTorrentsCtx(ctx, qbit.TorrentFilterOptions{IncludeTrackers: true, Hashes: hashes})
 	if err != nil {
-		return nil, fmt.Errorf("get torrents: %w", err)
+		return nil, fmt.Errorf("get torrents: %w", classifyError(err))
 	}
 	c.log.Tracef("Retrieved %d torrents", len(ts))
 
+	// in qBittorrent v5.1+ includeTrackers populates trackers, older versions need them fetched per torrent
+	var fetchedTrackers map[string][]qbit.TorrentTracker
+	if !c.includesTrackers {
+		var missing []string
+		for _, t := range ts {
+			if len(t.Trackers) == 0 {
+				missing = append(missing, t.Hash)
+			}
+		}
+
+		fetchedTrackers, err = c.fetchTrackers(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// build torrent list
 	torrents := make(map[string]config.Torrent)
 	for _, t := range ts {
@@ -156,73 +351,46 @@ func (c *QBittorrent) GetTorrents(ctx context.Context) (map[string]config.Torren
 		//td, err := c.client.Torrent.GetProperties(t.Hash)
 		td, err := c.client.GetTorrentPropertiesCtx(ctx, t.Hash)
 		if err != nil {
-			return nil, fmt.Errorf("get torrent properties: %v: %w", t.Hash, err)
+			return nil, fmt.Errorf("get torrent properties: %v: %w", t.Hash, classifyError(err))
 		}
 
 		tf, err := c.client.GetFilesInformationCtx(ctx, t.Hash)
 		if err != nil {
-			return nil, fmt.Errorf("get torrent files: %v: %w", t.Hash, err)
+			return nil, fmt.Errorf("get torrent files: %v: %w", t.Hash, classifyError(err))
 		}
 
 		// parse tracker details
-		trackerName := ""
-		trackerStatus := ""
-		allTrackerStatuses := make(map[string]string)
-
-		var trackers []qbit.TorrentTracker
-
-		trackers = t.Trackers
-
-		// in qBittorrent v5.1+ we can use includeTrackers to populate trackers, but in older versions we need to fetch trackers per torrent
-		if len(t.Trackers) == 0 {
-			ts, err := c.client.GetTorrentTrackersCtx(ctx, t.Hash)
-			if err != nil {
-				return nil, fmt.Errorf("get torrent trackers: %v: %w", t.Hash, err)
-			}
-			trackers = ts
+		trackers := t.Trackers
+		if len(trackers) == 0 {
+			trackers = fetchedTrackers[t.Hash]
 		}
 
-		firstTrackerSet := false
-		for _, tr := range trackers {
-			// skip disabled trackers
-			if strings.Contains(tr.Url, "[DHT]") || strings.Contains(tr.Url, "[LSD]") ||
-				strings.Contains(tr.Url, "[PeX]") {
-				continue
-			}
-
-			// Store all tracker statuses
-			allTrackerStatuses[tr.Url] = tr.Message
-
-			// Keep first tracker for backward compatibility
-			if !firstTrackerSet {
-				trackerName = config.ParseTrackerDomain(tr.Url)
-				trackerStatus = tr.Message
-				firstTrackerSet = true
-			}
-		}
+		trackerName, trackerStatus, allTrackerStatuses, trackerCount := parseTrackers(trackers)
 
 		// added time
 		addedTimeSecs := int64(time.Since(time.Unix(int64(td.AdditionDate), 0)).Seconds())
 
 		seedingTime := time.Duration(td.SeedingTime) * time.Second
 
+		// creation date from the torrent metadata, unset when the torrent has none
+		var createdSecs int64
+		if td.CreationDate > 0 {
+			createdSecs = max(int64(time.Since(time.Unix(int64(td.CreationDate), 0)).Seconds()), 0)
+		}
+
 		// last activity time
 		lastActivitySecs := max(
 			int64(time.Since(time.Unix(t.LastActivity, 0)).Seconds()), 0)
 
 		// torrent files
 		var files []string
+		root := torrentFilesRoot(td.SavePath, t.ContentPath, fileNames(*tf))
 		for _, f := range *tf {
-			files = append(files, filepath.Join(td.SavePath, f.Name))
+			files = append(files, filepath.Join(root, f.Name))
 		}
 
 		// create torrent
-		var tags []string
-		if t.Tags == "" {
-			tags = []string{}
-		} else {
-			tags = strings.Split(t.Tags, ", ")
-		}
+		tags := splitTags(t.Tags)
 		torrent := config.Torrent{
 			Hash:            t.Hash,
 			Name:            t.Name,
@@ -243,7 +411,9 @@ func (c *QBittorrent) GetTorrents(ctx context.Context) (map[string]config.Torren
 				"uploading",
 				"stalledUP",
 			}, string(t.State), true),
-			Ratio:               float32(td.ShareRatio),
+			Ratio:               td.ShareRatio,
+			RatioLimit:          t.MaxRatio,
+			SeedingLimitReached: seedingLimitReached(td.ShareRatio, t.MaxRatio, seedingTime, t.MaxSeedingTime),
 			AddedSeconds:        addedTimeSecs,
 			AddedHours:          float32(addedTimeSecs) / 60 / 60,
 			AddedDays:           float32(addedTimeSecs) / 60 / 60 / 24,
@@ -253,10 +423,15 @@ func (c *QBittorrent) GetTorrents(ctx context.Context) (map[string]config.Torren
 			LastActivitySeconds: lastActivitySecs,
 			LastActivityHours:   float32(lastActivitySecs) / 60 / 60,
 			LastActivityDays:    float32(lastActivitySecs) / 60 / 60 / 24,
+			CreatedSeconds:      createdSecs,
+			CreatedDays:         float32(createdSecs) / 60 / 60 / 24,
 			UpLimit:             int64(td.UpLimit),
+			DownLimit:           int64(td.DlLimit),
 			Label:               t.Category,
+			Category:            t.Category,
 			Seeds:               int64(td.SeedsTotal),
 			Peers:               int64(td.PeersTotal),
+			Availability:        float32(t.Availability),
 			IsPrivate:           td.IsPrivate,
 			IsPublic:            !td.IsPrivate,
 			// free space
@@ -266,6 +441,7 @@ func (c *QBittorrent) GetTorrents(ctx context.Context) (map[string]config.Torren
 			TrackerName:        trackerName,
 			TrackerStatus:      trackerStatus,
 			AllTrackerStatuses: allTrackerStatuses,
+			TrackerCount:       trackerCount,
 			Comment:            td.Comment,
 		}
 
@@ -275,6 +451,62 @@ func (c *QBittorrent) GetTorrents(ctx context.Context) (map[string]config.Torren
 	return torrents, nil
 }
 
+// fetchTrackers retrieves the trackers of each hash, TrackerFetchConcurrency at a time
+func (c *QBittorrent) fetchTrackers(ctx context.Context, hashes []string) (map[string][]qbit.TorrentTracker, error) {
+	trackers := make(map[string][]qbit.TorrentTracker, len(hashes))
+	if len(hashes) == 0 {
+		return trackers, nil
+	}
+
+	c.log.Tracef("Retrieving trackers of %d torrents...", len(hashes))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+
+	sem := make(chan struct{}, max(c.TrackerFetchConcurrency, 1))
+	for _, hash := range hashes {
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			ts, err := c.client.GetTorrentTrackersCtx(ctx, hash)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("get torrent trackers: %v: %w", hash, err)
+					cancel()
+				}
+				return
+			}
+			trackers[hash] = ts
+		}()
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return trackers, ctx.Err()
+}
+
 func (c *QBittorrent) RemoveTorrent(ctx context.Context, torrent *config.Torrent, deleteData bool) (bool, error) {
 	// check if the tracker is down before removing
 	if torrent.IsTrackerDown() {
@@ -282,23 +514,55 @@ func (c *QBittorrent) RemoveTorrent(ctx context.Context, torrent *config.Torrent
 		return false, nil
 	}
 
-	// pause torrent
-	if err := c.client.PauseCtx(ctx, []string{torrent.Hash}); err != nil {
-		return false, fmt.Errorf("pause torrent: %v: %w", torrent.Hash, err)
-	}
+	// pause torrent, unless it is already stopped or unregistered
+	if deleteData && config.Config != nil && config.Config.RemovalAnnounce.VerifyPause {
+		if err := c.pauseAndWait(ctx, torrent.Hash); err != nil {
+			return false, err
+		}
+	} else if torrent.IsPublic {
+		// public trackers have no announce timing to satisfy
+		c.log.Debugf("Skipping pause before removal for public torrent %s (%s)", torrent.Name, torrent.Hash)
+	} else if torrent.SkipRemovalAnnounce(ctx) {
+		c.log.Debugf("Skipping pause before removal for %s (%s)", torrent.Name, torrent.Hash)
+	} else {
+		if err := c.client.PauseCtx(ctx, []string{torrent.Hash}); err != nil {
+			return false, fmt.Errorf("pause torrent: %v: %w", torrent.Hash, classifyError(err))
+		}
 
-	// sleep before removing torrent
-	time.Sleep(2 * time.Second)
+		// sleep before removing torrent
+		time.Sleep(removalAnnounceDelay)
+	}
 
 	// remove
 	if err := c.client.DeleteTorrentsCtx(ctx, []string{torrent.Hash}, deleteData); err != nil {
-		return false, fmt.Errorf("delete torrent: %v: %w", torrent.Hash, err)
+		return false, fmt.Errorf("delete torrent: %v: %w", torrent.Hash, classifyError(err))
 	}
 
 	return true, nil
 }
 
+// pauseAndWait pauses a torrent and waits until qbittorrent reports it stopped, so no data is written while it is deleted
+func (c *QBittorrent) pauseAndWait(ctx context.Context, hash string) error {
+	if err := c.client.PauseCtx(ctx, []string{hash}); err != nil {
+		return fmt.Errorf("pause torrent: %v: %w", hash, classifyError(err))
+	}
+
+	return waitForPaused(ctx, hash, func(ctx context.Context) (string, error) {
+		ts, err := c.client.GetTorrentsCtx(ctx, qbit.TorrentFilterOptions{Hashes: []string{hash}})
+		if err != nil {
+			return "", err
+		} else if len(ts) == 0 {
+			return "", fmt.Errorf("%w: %v", ErrTorrentNotFound, hash)
+		}
+
+		return string(ts[0].State), nil
+	})
+}
+
 func (c *QBittorrent) SetTorrentLabel(ctx context.Context, hash string, label string, hardlink bool) error {
+	// tags before relabeling, the recheck after moving a hardlinked torrent can drop them
+	var tags []string
+
 	if hardlink {
 		// get label path
 		lp := c.labelPathMap[label]
@@ -307,34 +571,26 @@ func (c *QBittorrent) SetTorrentLabel(ctx context.Context, hash string, label st
 		}
 
 		// get torrent details
-		td, err := c.client.GetTorrentPropertiesCtx(ctx, hash)
+		ts, err := c.client.GetTorrentsCtx(ctx, qbit.TorrentFilterOptions{Hashes: []string{hash}})
 		if err != nil {
-			return fmt.Errorf("get torrent properties: %w", err)
+			return fmt.Errorf("get torrent: %w", classifyError(err))
 		}
+		if len(ts) == 0 {
+			return fmt.Errorf("%w: %v", ErrTorrentNotFound, hash)
+		}
+		tags = splitTags(ts[0].Tags)
 
-		if filepath.Clean(td.SavePath) != filepath.Clean(lp) {
-			// get torrent files
-			tf, err := c.client.GetFilesInformationCtx(ctx, hash)
-			if err != nil {
-				return fmt.Errorf("get torrent files: %w", err)
-			}
-
-			for _, f := range *tf {
-				source := filepath.Join(td.SavePath, f.Name)
-				target := filepath.Join(lp, f.Name)
-				if _, err := os.Stat(source); err != nil {
-					return fmt.Errorf("stat file '%v': %w", target, err)
-				}
-
-				// create target directory
-				if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
-					return fmt.Errorf("create target directory: %w", err)
-				}
+		// get torrent files
+		tf, err := c.client.GetFilesInformationCtx(ctx, hash)
+		if err != nil {
+			return fmt.Errorf("get torrent files: %w", classifyError(err))
+		}
 
-				// link
-				if err := os.Link(source, target); err != nil {
-					return fmt.Errorf("create hardlink for '%v': %w", f.Name, err)
-				}
+		names := fileNames(*tf)
+		root := torrentFilesRoot(ts[0].SavePath, ts[0].ContentPath, names)
+		if filepath.Clean(root) != filepath.Clean(lp) {
+			if err := LinkFiles(c.log, root, lp, names, c.PreserveFileTimes); err != nil {
+				return err
 			}
 		}
 
@@ -343,32 +599,70 @@ func (c *QBittorrent) SetTorrentLabel(ctx context.Context, hash string, label st
 		// manually settings location, and then setting category works
 		// and causes qbit to recheck instead of move
 		if err := c.client.SetAutoManagementCtx(ctx, []string{hash}, false); err != nil {
-			return fmt.Errorf("set automatic management: %w", err)
+			return fmt.Errorf("set automatic management: %w", classifyError(err))
 		}
 		if err := c.client.SetLocationCtx(ctx, []string{hash}, lp); err != nil {
-			return fmt.Errorf("set location: %w", err)
+			return fmt.Errorf("set location: %w", classifyError(err))
 		}
 	}
 
 	// set label
 	if err := c.client.SetCategoryCtx(ctx, []string{hash}, label); err != nil {
-		return fmt.Errorf("set torrent label: %v: %w", label, err)
+		return fmt.Errorf("set torrent label: %v: %w", label, classifyError(err))
+	}
+
+	if err := c.restoreTags(ctx, hash, tags); err != nil {
+		return fmt.Errorf("restore tags: %w", classifyError(err))
 	}
 
 	// enable autotmm
 	if c.EnableAutoTmmAfterRelabel && !hardlink {
 		if err := c.client.SetAutoManagementCtx(ctx, []string{hash}, true); err != nil {
-			return fmt.Errorf("enable autotmm: %w", err)
+			return fmt.Errorf("enable autotmm: %w", classifyError(err))
+		}
+	}
+
+	return nil
+}
+
+// restoreTags re-adds any of tags the torrent no longer has
+func (c *QBittorrent) restoreTags(ctx context.Context, hash string, tags []string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	ts, err := c.client.GetTorrentsCtx(ctx, qbit.TorrentFilterOptions{Hashes: []string{hash}})
+	if err != nil {
+		return fmt.Errorf("get torrent: %w", classifyError(err))
+	}
+	if len(ts) == 0 {
+		return fmt.Errorf("%w: %v", ErrTorrentNotFound, hash)
+	}
+
+	current := splitTags(ts[0].Tags)
+	var missing []string
+	for _, tag := range tags {
+		if !slices.Contains(current, tag) {
+			missing = append(missing, tag)
 		}
 	}
 
+	if len(missing) == 0 {
+		return nil
+	}
+
+	c.log.Warnf("Torrent %s lost tags %v while relabeling, adding them back", hash, missing)
+	if err := c.client.AddTagsCtx(ctx, []string{hash}, strings.Join(missing, ",")); err != nil {
+		return fmt.Errorf("add tags: %v: %w", missing, classifyError(err))
+	}
+
 	return nil
 }
 
 func (c *QBittorrent) SetUploadLimit(ctx context.Context, hash string, limit int64) error {
 	err := c.client.SetTorrentUploadLimitCtx(ctx, []string{hash}, limit)
 	if err != nil {
-		return fmt.Errorf("set upload limit for %s: %w", hash, err)
+		return fmt.Errorf("set upload limit for %s: %w", hash, classifyError(err))
 	}
 
 	c.log.Debugf("Set upload limit for torrent %s to %d KiB/s", hash, limit)
@@ -379,7 +673,7 @@ func (c *QBittorrent) GetCurrentFreeSpace(ctx context.Context, path string) (int
 	// get current main stats
 	data, err := c.client.SyncMainDataCtx(ctx, 0)
 	if err != nil {
-		return 0, fmt.Errorf("get main data: %w", err)
+		return 0, fmt.Errorf("get main data: %w", classifyError(err))
 	}
 
 	// set internal free size
@@ -397,6 +691,25 @@ func (c *QBittorrent) GetFreeSpace() float64 {
 	return c.freeSpaceGB
 }
 
+// seedingLimitReached reports whether a torrent reached the share limits qbit applies to it. maxRatio and
+// maxSeedingTime (minutes) are the effective limits with the global limits resolved, negative when there is no limit
+func seedingLimitReached(ratio float64, maxRatio float64, seedingTime time.Duration, maxSeedingTime int64) bool {
+	if maxRatio > 0 && ratio >= maxRatio {
+		return true
+	}
+
+	return maxSeedingTime > 0 && seedingTime >= time.Duration(maxSeedingTime)*time.Minute
+}
+
+// splitTags parses the comma separated tags qbit reports for a torrent
+func splitTags(tags string) []string {
+	if tags == "" {
+		return []string{}
+	}
+
+	return strings.Split(tags, ", ")
+}
+
 /* Filters */
 
 func (c *QBittorrent) ShouldIgnore(ctx context.Context, t *config.Torrent) (bool, error) {
@@ -454,19 +767,90 @@ func (c *QBittorrent) CheckTorrentPause(ctx context.Context, t *config.Torrent)
 
 func (c *QBittorrent) PauseTorrents(ctx context.Context, hashes []string) error {
 	if err := c.client.PauseCtx(ctx, hashes); err != nil {
-		return fmt.Errorf("pause torrents: %v: %w", hashes, err)
+		return fmt.Errorf("pause torrents: %v: %w", hashes, classifyError(err))
 	}
 	return nil
 }
 
+func (c *QBittorrent) ResumeTorrents(ctx context.Context, hashes []string) error {
+	if err := c.client.ResumeCtx(ctx, hashes); err != nil {
+		return fmt.Errorf("resume torrents: %v: %w", hashes, classifyError(err))
+	}
+	return nil
+}
+
+// RecheckAndWait force rechecks a torrent and polls until the recheck finishes, returning whether it is complete. The
+// recheck only counts once the torrent was seen checking or its progress changed, until then qBittorrent may still
+// report the state from before the recheck
+func (c *QBittorrent) RecheckAndWait(ctx context.Context, hash string, timeout time.Duration) (bool, error) {
+	before, err := c.client.GetTorrentsCtx(ctx, qbit.TorrentFilterOptions{Hashes: []string{hash}})
+	if err != nil {
+		return false, fmt.Errorf("get torrent: %v: %w", hash, classifyError(err))
+	} else if len(before) == 0 {
+		return false, fmt.Errorf("%w: %v", ErrTorrentNotFound, hash)
+	}
+
+	if err := c.client.RecheckCtx(ctx, []string{hash}); err != nil {
+		return false, fmt.Errorf("recheck torrent: %v: %w", hash, classifyError(err))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(recheckPollInterval)
+	defer ticker.Stop()
+
+	started := false
+	for {
+		select {
+		case <-ctx.Done():
+			if !started {
+				return false, fmt.Errorf("wait for recheck to start: %v: %w", hash, ctx.Err())
+			}
+			return false, fmt.Errorf("wait for recheck: %v: %w", hash, ctx.Err())
+		case <-ticker.C:
+		}
+
+		ts, err := c.client.GetTorrentsCtx(ctx, qbit.TorrentFilterOptions{Hashes: []string{hash}})
+		if err != nil {
+			return false, fmt.Errorf("get torrent: %v: %w", hash, classifyError(err))
+		} else if len(ts) == 0 {
+			return false, fmt.Errorf("%w: %v", ErrTorrentNotFound, hash)
+		}
+
+		t := config.Torrent{State: string(ts[0].State)}
+		if t.NormalizedState() == config.StateChecking {
+			started = true
+			c.log.Tracef("Waiting for recheck of %s (%.2f%%)", hash, ts[0].Progress*100)
+			continue
+		}
+
+		if !started && ts[0].Progress == before[0].Progress {
+			c.log.Tracef("Waiting for recheck of %s to start", hash)
+			continue
+		}
+
+		return ts[0].Progress >= 1, nil
+	}
+}
+
 func (c *QBittorrent) ShouldRetag(ctx context.Context, t *config.Torrent) (RetagInfo, error) {
+	return EvaluateRetag(ctx, c.exp, t)
+}
+
+// EvaluateRetag determines the tags to add and remove and the upload limit to set for a torrent with the tag rules of
+// exp, shared by the clients supporting tags
+func EvaluateRetag(ctx context.Context, exp *expression.Expressions, t *config.Torrent) (RetagInfo, error) {
 	retagInfo := RetagInfo{
 		Add:    make(map[string]struct{}),
 		Remove: make(map[string]struct{}),
 	}
 	var uploadLimitSet = false
 
-	for _, tagRule := range c.exp.Tags {
+	// rules are sorted by priority, the first rule of a tag decides whether it is added or removed
+	decidedTags := make(map[string]struct{})
+
+	for _, tagRule := range exp.Tags {
 		// check update
 		match, err := expression.CheckTorrentAllMatch(ctx, t, tagRule.Updates)
 		if err != nil {
@@ -476,20 +860,44 @@ func (c *QBittorrent) ShouldRetag(ctx context.Context, t *config.Torrent) (Retag
 		var containTag = evaluate.StringSliceContains(t.Tags, tagRule.Name, false)
 		var tagMode = tagRule.Mode
 
-		if containTag && !match && (tagMode == "remove" || tagMode == "full") {
-			retagInfo.Remove[tagRule.Name] = struct{}{}
-		}
-		if !containTag && match && (tagMode == "add" || tagMode == "full") {
-			retagInfo.Add[tagRule.Name] = struct{}{}
+		if _, decided := decidedTags[tagRule.Name]; !decided {
+			decidedTags[tagRule.Name] = struct{}{}
+
+			if containTag && !match && (tagMode == "remove" || tagMode == "full") {
+				retagInfo.Remove[tagRule.Name] = struct{}{}
+			}
+			if !containTag && match && (tagMode == "add" || tagMode == "full") {
+				retagInfo.Add[tagRule.Name] = struct{}{}
+			}
 		}
 
+		// the highest priority matching rule with an upload limit decides the limit
 		if match && tagRule.UploadKb != nil && !uploadLimitSet {
+			uploadLimitSet = true
+
 			limitKiB := int64(*tagRule.UploadKb)
 			currentLimitKiB := t.UpLimit / 1024
 
 			if currentLimitKiB != limitKiB {
 				retagInfo.UploadKb = &limitKiB
-				uploadLimitSet = true
+			}
+		}
+	}
+
+	for _, milestone := range exp.Milestones {
+		// only the highest bucket reached is kept, all other bucket tags are removed
+		bucketTag, err := expression.ResolveMilestone(ctx, t, milestone)
+		if err != nil {
+			return RetagInfo{}, fmt.Errorf("check milestone %s on torrent %v: %w", milestone.Name, t.Hash, classifyError(err))
+		}
+
+		for _, bucket := range milestone.Buckets {
+			containTag := evaluate.StringSliceContains(t.Tags, bucket.Tag, false)
+
+			if bucket.Tag == bucketTag && !containTag {
+				retagInfo.Add[bucket.Tag] = struct{}{}
+			} else if bucket.Tag != bucketTag && containTag {
+				retagInfo.Remove[bucket.Tag] = struct{}{}
 			}
 		}
 	}
@@ -503,7 +911,7 @@ func (c *QBittorrent) AddTags(ctx context.Context, hash string, tags []string) e
 	}
 
 	if err := c.client.AddTagsCtx(ctx, []string{hash}, strings.Join(tags, ",")); err != nil {
-		return fmt.Errorf("add torrent tags: %v: %w", tags, err)
+		return fmt.Errorf("add torrent tags: %v: %w", tags, classifyError(err))
 	}
 
 	return nil
@@ -515,7 +923,7 @@ func (c *QBittorrent) RemoveTags(ctx context.Context, hash string, tags []string
 	}
 
 	if err := c.client.RemoveTagsCtx(ctx, []string{hash}, strings.Join(tags, ",")); err != nil {
-		return fmt.Errorf("remove torrent tags: %v: %w", tags, err)
+		return fmt.Errorf("remove torrent tags: %v: %w", tags, classifyError(err))
 	}
 
 	return nil
@@ -523,7 +931,7 @@ func (c *QBittorrent) RemoveTags(ctx context.Context, hash string, tags []string
 
 func (c *QBittorrent) SetTags(ctx context.Context, hash string, tags []string) error {
 	if err := c.client.SetTags(ctx, []string{hash}, strings.Join(tags, ",")); err != nil {
-		return fmt.Errorf("set torrent tags: %v: %w", tags, err)
+		return fmt.Errorf("set torrent tags: %v: %w", tags, classifyError(err))
 	}
 
 	return nil
@@ -535,7 +943,7 @@ func (c *QBittorrent) CreateTags(ctx context.Context, tags []string) error {
 	}
 
 	if err := c.client.CreateTagsCtx(ctx, tags); err != nil {
-		return fmt.Errorf("create torrent tags: %v: %w", tags, err)
+		return fmt.Errorf("create torrent tags: %v: %w", tags, classifyError(err))
 	}
 
 	return nil
@@ -547,8 +955,42 @@ func (c *QBittorrent) DeleteTags(ctx context.Context, tags []string) error {
 	}
 
 	if err := c.client.DeleteTagsCtx(ctx, tags); err != nil {
-		return fmt.Errorf("delete torrent tags: %v: %w", tags, err)
+		return fmt.Errorf("delete torrent tags: %v: %w", tags, classifyError(err))
 	}
 
 	return nil
 }
+
+// RenameTag moves all torrents from oldTag to newTag, qBittorrent has no native rename so the new tag is
+// created, added to every torrent with the old tag, and the old tag is then removed and deleted
+func (c *QBittorrent) RenameTag(ctx context.Context, oldTag string, newTag string) (int, error) {
+	ts, err := c.client.GetTorrentsCtx(ctx, qbit.TorrentFilterOptions{Tag: oldTag})
+	if err != nil {
+		return 0, fmt.Errorf("get torrents with tag: %v: %w", oldTag, classifyError(err))
+	}
+
+	hashes := make([]string, 0, len(ts))
+	for _, t := range ts {
+		hashes = append(hashes, t.Hash)
+	}
+
+	if err := c.CreateTags(ctx, []string{newTag}); err != nil {
+		return 0, err
+	}
+
+	if len(hashes) > 0 {
+		if err := c.client.AddTagsCtx(ctx, hashes, newTag); err != nil {
+			return 0, fmt.Errorf("add torrent tags: %v: %w", newTag, classifyError(err))
+		}
+
+		if err := c.client.RemoveTagsCtx(ctx, hashes, oldTag); err != nil {
+			return 0, fmt.Errorf("remove torrent tags: %v: %w", oldTag, classifyError(err))
+		}
+	}
+
+	if err := c.DeleteTags(ctx, []string{oldTag}); err != nil {
+		return len(hashes), err
+	}
+
+	return len(hashes), nil
+}
@@ -1,12 +1,25 @@
 package client
 
 import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/autobrr/go-qbittorrent"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/expression"
+	"github.com/autobrr/tqm/pkg/logger"
 )
 
 func TestQBittorrent_ProcessTrackerStatuses(t *testing.T) {
@@ -239,3 +252,744 @@ func TestParseTrackerDomain(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTrackers_TrackerCount(t *testing.T) {
+	tests := []struct {
+		name          string
+		trackers      []qbittorrent.TorrentTracker
+		expectedName  string
+		expectedCount int
+	}{
+		{
+			name:          "no_trackers",
+			trackers:      []qbittorrent.TorrentTracker{},
+			expectedCount: 0,
+		},
+		{
+			name: "only_disabled_trackers",
+			trackers: []qbittorrent.TorrentTracker{
+				{Url: "** [DHT] **", Message: ""},
+				{Url: "** [PeX] **", Message: ""},
+				{Url: "** [LSD] **", Message: ""},
+			},
+			expectedCount: 0,
+		},
+		{
+			name: "disabled_and_real_trackers",
+			trackers: []qbittorrent.TorrentTracker{
+				{Url: "** [DHT] **", Message: ""},
+				{Url: "https://tracker1.com/announce", Message: "Working"},
+				{Url: "https://tracker2.com/announce", Message: "Working"},
+			},
+			expectedName:  "tracker1.com",
+			expectedCount: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, _, all, count := parseTrackers(tt.trackers)
+
+			assert.Equal(t, tt.expectedName, name)
+			assert.Equal(t, tt.expectedCount, count)
+			assert.Len(t, all, tt.expectedCount)
+		})
+	}
+}
+
+func TestParseTrackers_MultiTier(t *testing.T) {
+	tests := []struct {
+		name           string
+		trackers       []qbittorrent.TorrentTracker
+		expectedName   string
+		expectedStatus string
+	}{
+		{
+			name: "second_tier_working",
+			trackers: []qbittorrent.TorrentTracker{
+				{Url: "** [DHT] **", Status: qbittorrent.TrackerStatusOK},
+				{Url: "https://backup.tracker1.com/announce", Status: qbittorrent.TrackerStatusNotWorking, Message: "timeout"},
+				{Url: "https://tracker2.com/announce", Status: qbittorrent.TrackerStatusOK},
+				{Url: "https://tracker3.com/announce", Status: qbittorrent.TrackerStatusOK},
+			},
+			expectedName:   "tracker2.com",
+			expectedStatus: "",
+		},
+		{
+			name: "none_working",
+			trackers: []qbittorrent.TorrentTracker{
+				{Url: "https://tracker1.com/announce", Status: qbittorrent.TrackerStatusNotWorking, Message: "unregistered torrent"},
+				{Url: "https://tracker2.com/announce", Status: qbittorrent.TrackerStatusNotContacted},
+			},
+			expectedName:   "tracker1.com",
+			expectedStatus: "unregistered torrent",
+		},
+		{
+			name: "first_working",
+			trackers: []qbittorrent.TorrentTracker{
+				{Url: "https://tracker1.com/announce", Status: qbittorrent.TrackerStatusOK},
+				{Url: "https://tracker2.com/announce", Status: qbittorrent.TrackerStatusOK, Message: "other"},
+			},
+			expectedName:   "tracker1.com",
+			expectedStatus: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, status, _, _ := parseTrackers(tt.trackers)
+
+			assert.Equal(t, tt.expectedName, name)
+			assert.Equal(t, tt.expectedStatus, status)
+		})
+	}
+}
+
+func TestTorrentFilesRoot(t *testing.T) {
+	tests := []struct {
+		name        string
+		savePath    string
+		contentPath string
+		names       []string
+		want        string
+	}{
+		{
+			name:        "single_file",
+			savePath:    "/data/movies",
+			contentPath: "/data/movies/Movie.2020.mkv",
+			names:       []string{"Movie.2020.mkv"},
+			want:        "/data/movies",
+		},
+		{
+			name:        "single_file_in_incomplete_path",
+			savePath:    "/data/movies",
+			contentPath: "/data/incomplete/Movie.2020.mkv",
+			names:       []string{"Movie.2020.mkv"},
+			want:        "/data/incomplete",
+		},
+		{
+			name:        "single_file_in_subfolder",
+			savePath:    "/data/movies",
+			contentPath: "/data/movies/Movie.2020/Movie.2020.mkv",
+			names:       []string{"Movie.2020/Movie.2020.mkv"},
+			want:        "/data/movies",
+		},
+		{
+			name:        "multi_file",
+			savePath:    "/data/tv/",
+			contentPath: "/data/tv/Show.S01",
+			names:       []string{"Show.S01/E01.mkv", "Show.S01/E02.mkv"},
+			want:        "/data/tv",
+		},
+		{
+			name:        "multi_file_renamed_folder",
+			savePath:    "/data/tv",
+			contentPath: "/data/incomplete/Show.S01",
+			names:       []string{"Show.S01/E01.mkv", "Show.S01/E02.mkv"},
+			want:        "/data/incomplete",
+		},
+		{
+			name:        "multi_file_no_subfolder",
+			savePath:    "/data/tv",
+			contentPath: "/data/incomplete",
+			names:       []string{"E01.mkv", "E02.mkv"},
+			want:        "/data/incomplete",
+		},
+		{
+			name:        "no_content_path",
+			savePath:    "/data/tv",
+			contentPath: "",
+			names:       []string{"Show.S01/E01.mkv"},
+			want:        "/data/tv",
+		},
+		{
+			name:        "content_path_mismatch",
+			savePath:    "/data/movies",
+			contentPath: "/data/movies/Other.mkv",
+			names:       []string{"Movie.2020.mkv"},
+			want:        "/data/movies",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, torrentFilesRoot(tt.savePath, tt.contentPath, tt.names))
+		})
+	}
+}
+
+func TestRelabelLinkTargets(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+		// content path relative to the source root
+		content string
+	}{
+		{name: "single_file", names: []string{"Movie.2020.mkv"}, content: "Movie.2020.mkv"},
+		{name: "multi_file", names: []string{"Show.S01/E01.mkv", "Show.S01/E02.mkv"}, content: "Show.S01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			savePath := filepath.Join(root, "movies")
+			sourceRoot := filepath.Join(root, "incomplete")
+			labelPath := filepath.Join(root, "permaseed")
+
+			for _, name := range tt.names {
+				p := filepath.Join(sourceRoot, name)
+				require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+				require.NoError(t, os.WriteFile(p, []byte(name), 0644))
+			}
+
+			got := torrentFilesRoot(savePath, filepath.Join(sourceRoot, tt.content), tt.names)
+			require.Equal(t, sourceRoot, got)
+			require.NoError(t, LinkFiles(logger.GetLogger("test"), got, labelPath, tt.names, false))
+
+			for _, name := range tt.names {
+				source, err := os.Stat(filepath.Join(sourceRoot, name))
+				require.NoError(t, err)
+				target, err := os.Stat(filepath.Join(labelPath, name))
+				require.NoError(t, err)
+				assert.True(t, os.SameFile(source, target), "target should be a hardlink of the source: %s", name)
+			}
+		})
+	}
+}
+
+func TestEvaluateRetag_Priority(t *testing.T) {
+	low, high := 50, 10
+
+	filter := &config.FilterConfiguration{}
+	filter.Tag = make([]struct {
+		Name     string
+		Mode     string
+		UploadKb *int `mapstructure:"uploadKb"`
+		Priority int
+		Update   []string
+	}, 3)
+	filter.Tag[0].Name = "public"
+	filter.Tag[0].Mode = "full"
+	filter.Tag[0].UploadKb = &low
+	filter.Tag[0].Update = []string{`IsPrivate == false`}
+	filter.Tag[1].Name = "slow"
+	filter.Tag[1].Mode = "full"
+	filter.Tag[1].UploadKb = &high
+	filter.Tag[1].Priority = 10
+	filter.Tag[1].Update = []string{`Ratio > 2`}
+	filter.Tag[2].Name = "public"
+	filter.Tag[2].Mode = "full"
+	filter.Tag[2].Priority = 5
+	filter.Tag[2].Update = []string{`Ratio > 5`}
+
+	exp, err := expression.Compile(filter)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		torrent    config.Torrent
+		wantAdd    []string
+		wantRemove []string
+		wantLimit  *int64
+	}{
+		{
+			name:      "low_priority_limit",
+			torrent:   config.Torrent{Hash: "a", Ratio: 6},
+			wantAdd:   []string{"public", "slow"},
+			wantLimit: func() *int64 { v := int64(high); return &v }(),
+		},
+		{
+			name:      "high_priority_limit_already_set",
+			torrent:   config.Torrent{Hash: "b", Ratio: 6, UpLimit: int64(high) * 1024},
+			wantAdd:   []string{"public", "slow"},
+			wantLimit: nil,
+		},
+		{
+			name:       "high_priority_tag_rule_decides",
+			torrent:    config.Torrent{Hash: "c", Ratio: 1, Tags: []string{"public"}},
+			wantRemove: []string{"public"},
+			wantLimit:  func() *int64 { v := int64(low); return &v }(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := EvaluateRetag(context.Background(), exp, &tt.torrent)
+			require.NoError(t, err)
+
+			assert.ElementsMatch(t, tt.wantAdd, mapKeys(info.Add))
+			assert.ElementsMatch(t, tt.wantRemove, mapKeys(info.Remove))
+			assert.Equal(t, tt.wantLimit, info.UploadKb)
+		})
+	}
+}
+
+func TestQBittorrent_ShouldRelabel_Priority(t *testing.T) {
+	filter := &config.FilterConfiguration{}
+	filter.Label = make([]struct {
+		Name     string
+		Priority int
+		Update   []string
+	}, 3)
+	filter.Label[0].Name = "seeding"
+	filter.Label[0].Update = []string{`Label == "tv"`}
+	filter.Label[1].Name = "archive"
+	filter.Label[1].Priority = 10
+	filter.Label[1].Update = []string{`Label == "tv"`, `Ratio >= 2 || SeedingDays >= 30`}
+	filter.Label[2].Name = "longterm"
+	filter.Label[2].Priority = 10
+	filter.Label[2].Update = []string{`Label == "tv"`, `SeedingDays >= 30`}
+
+	exp, err := expression.Compile(filter)
+	require.NoError(t, err)
+
+	c := &QBittorrent{log: logger.GetLogger("test"), exp: exp}
+
+	tests := []struct {
+		name      string
+		torrent   config.Torrent
+		wantLabel string
+		wantMatch bool
+	}{
+		{
+			name:      "higher_tier_wins_over_config_order",
+			torrent:   config.Torrent{Hash: "a", Label: "tv", Ratio: 3},
+			wantLabel: "archive",
+			wantMatch: true,
+		},
+		{
+			name:      "equal_priority_keeps_config_order",
+			torrent:   config.Torrent{Hash: "b", Label: "tv", SeedingDays: 40},
+			wantLabel: "archive",
+			wantMatch: true,
+		},
+		{
+			name:      "falls_back_to_lower_tier",
+			torrent:   config.Torrent{Hash: "c", Label: "tv", Ratio: 1},
+			wantLabel: "seeding",
+			wantMatch: true,
+		},
+		{
+			name:    "no_tier_matches",
+			torrent: config.Torrent{Hash: "d", Label: "movies", Ratio: 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			label, match, err := c.ShouldRelabel(context.Background(), &tt.torrent)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantMatch, match)
+			assert.Equal(t, tt.wantLabel, label)
+		})
+	}
+}
+
+func mapKeys(m map[string]struct{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func TestQBittorrent_SetTorrentLabel_PreservesTags(t *testing.T) {
+	tests := []struct {
+		name          string
+		dropTags      bool
+		expectedAdded []string
+	}{
+		{name: "tags_kept", dropTags: false, expectedAdded: nil},
+		{name: "tags_dropped", dropTags: true, expectedAdded: []string{"cross-seed,keep"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			savePath := t.TempDir()
+			tags := "cross-seed, keep"
+			var added []string
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/v2/torrents/info", func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewEncoder(w).Encode([]map[string]any{
+					{"hash": "abc", "save_path": savePath, "content_path": filepath.Join(savePath, "file.mkv"), "tags": tags},
+				})
+			})
+			mux.HandleFunc("/api/v2/torrents/files", func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewEncoder(w).Encode([]map[string]any{{"name": "file.mkv"}})
+			})
+			mux.HandleFunc("/api/v2/torrents/setAutoManagement", func(w http.ResponseWriter, r *http.Request) {})
+			mux.HandleFunc("/api/v2/torrents/setLocation", func(w http.ResponseWriter, r *http.Request) {
+				// simulate the recheck after moving dropping the tags
+				if tt.dropTags {
+					tags = ""
+				}
+			})
+			mux.HandleFunc("/api/v2/torrents/setCategory", func(w http.ResponseWriter, r *http.Request) {})
+			mux.HandleFunc("/api/v2/torrents/addTags", func(w http.ResponseWriter, r *http.Request) {
+				require.NoError(t, r.ParseForm())
+				added = append(added, r.PostForm.Get("tags"))
+				tags = strings.ReplaceAll(r.PostForm.Get("tags"), ",", ", ")
+			})
+
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			c := &QBittorrent{
+				log:          logger.GetLogger("test"),
+				client:       qbittorrent.NewClient(qbittorrent.Config{Host: srv.URL}),
+				labelPathMap: map[string]string{"tv-linked": savePath},
+			}
+
+			require.NoError(t, c.SetTorrentLabel(context.Background(), "abc", "tv-linked", true))
+			assert.Equal(t, tt.expectedAdded, added)
+			assert.Equal(t, "cross-seed, keep", tags)
+		})
+	}
+}
+
+func TestSplitTags(t *testing.T) {
+	assert.Equal(t, []string{}, splitTags(""))
+	assert.Equal(t, []string{"a"}, splitTags("a"))
+	assert.Equal(t, []string{"a", "b c"}, splitTags("a, b c"))
+}
+
+func TestSeedingLimitReached(t *testing.T) {
+	tests := []struct {
+		name           string
+		ratio          float64
+		maxRatio       float64
+		seedingTime    time.Duration
+		maxSeedingTime int64
+		expected       bool
+	}{
+		{name: "no_limits", ratio: 10, maxRatio: -1, seedingTime: 1000 * time.Hour, maxSeedingTime: -1, expected: false},
+		{name: "ratio_reached", ratio: 2, maxRatio: 2, maxSeedingTime: -1, expected: true},
+		{name: "ratio_not_reached", ratio: 1.99, maxRatio: 2, maxSeedingTime: -1, expected: false},
+		{name: "seeding_time_reached", maxRatio: -1, seedingTime: 2 * time.Hour, maxSeedingTime: 120, expected: true},
+		{name: "seeding_time_not_reached", maxRatio: -1, seedingTime: 119 * time.Minute, maxSeedingTime: 120, expected: false},
+		{name: "either_limit", ratio: 0.5, maxRatio: 2, seedingTime: 3 * time.Hour, maxSeedingTime: 120, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, seedingLimitReached(tt.ratio, tt.maxRatio, tt.seedingTime, tt.maxSeedingTime))
+		})
+	}
+}
+
+func TestQBittorrent_GetTorrent(t *testing.T) {
+	var requestedHashes []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/torrents/info", func(w http.ResponseWriter, r *http.Request) {
+		hashes := r.URL.Query().Get("hashes")
+		requestedHashes = append(requestedHashes, hashes)
+
+		var ts []map[string]any
+		if hashes == "abc" {
+			ts = append(ts, map[string]any{
+				"hash": "abc", "name": "torrent", "category": "tv", "tags": "one, two", "state": "stalledUP",
+				"max_ratio": 1.0, "max_seeding_time": -1, "availability": 1.5,
+				"trackers": []map[string]any{{"url": "https://tracker.com/announce", "msg": ""}},
+			})
+		}
+		_ = json.NewEncoder(w).Encode(ts)
+	})
+	mux.HandleFunc("/api/v2/torrents/properties", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"save_path": "/data/tv", "share_ratio": 1.5,
+			"creation_date": time.Now().Add(-48 * time.Hour).Unix()})
+	})
+	mux.HandleFunc("/api/v2/torrents/files", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]map[string]any{{"name": "file.mkv"}})
+	})
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := &QBittorrent{
+		log:    logger.GetLogger("test"),
+		client: qbittorrent.NewClient(qbittorrent.Config{Host: srv.URL}),
+	}
+
+	torrent, err := c.GetTorrent(context.Background(), "ABC")
+	require.NoError(t, err)
+	assert.Equal(t, "abc", torrent.Hash)
+	assert.Equal(t, "tv", torrent.Label)
+	assert.Equal(t, []string{"one", "two"}, torrent.Tags)
+	assert.Equal(t, "tracker.com", torrent.TrackerName)
+	assert.Equal(t, []string{filepath.Join("/data/tv", "file.mkv")}, torrent.Files)
+	assert.True(t, torrent.SeedingLimitReached, "share ratio 1.5 reached the max ratio of 1")
+	assert.Equal(t, 1.5, torrent.Ratio)
+	assert.Equal(t, 1.0, torrent.RatioLimit)
+	assert.Equal(t, float32(1.5), torrent.Availability)
+	assert.InDelta(t, 2, torrent.CreatedDays, 0.01)
+
+	_, err = c.GetTorrent(context.Background(), "missing")
+	require.ErrorIs(t, err, ErrTorrentNotFound)
+
+	assert.Equal(t, []string{"abc", "missing"}, requestedHashes, "only the requested torrent is fetched")
+}
+
+func TestQBittorrent_ConnectUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "qbt.sock")
+	listener, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/auth/login", func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "SID", Value: "session"})
+		_, _ = w.Write([]byte("Ok."))
+	})
+	mux.HandleFunc("/api/v2/app/webapiVersion", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("2.11.2"))
+	})
+
+	srv := &http.Server{Handler: mux}
+	go srv.Serve(listener)
+	t.Cleanup(func() { srv.Close() })
+
+	client, err := newQbitClient("unix://"+socket, "user", "pass")
+	require.NoError(t, err)
+
+	c := &QBittorrent{log: logger.GetLogger("test"), client: client}
+	require.NoError(t, c.Connect(context.Background()))
+
+	_, err = newQbitClient("unix://", "user", "pass")
+	require.Error(t, err)
+}
+
+func TestQBittorrent_GetTorrentsTrackers(t *testing.T) {
+	for _, tc := range []struct {
+		version         string
+		wantFetches     int32
+		wantTrackerName string
+	}{
+		{version: "2.11.2", wantFetches: 3, wantTrackerName: "fetched.com"},
+		{version: "2.11.4", wantFetches: 0, wantTrackerName: ""},
+	} {
+		t.Run(tc.version, func(t *testing.T) {
+			var fetches atomic.Int32
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/v2/auth/login", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("Ok."))
+			})
+			mux.HandleFunc("/api/v2/app/webapiVersion", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(tc.version))
+			})
+			mux.HandleFunc("/api/v2/torrents/info", func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewEncoder(w).Encode([]map[string]any{
+					{"hash": "a", "name": "a", "state": "stalledUP"},
+					{"hash": "b", "name": "b", "state": "stalledUP"},
+					{"hash": "c", "name": "c", "state": "stalledUP"},
+				})
+			})
+			mux.HandleFunc("/api/v2/torrents/trackers", func(w http.ResponseWriter, r *http.Request) {
+				fetches.Add(1)
+				_ = json.NewEncoder(w).Encode([]map[string]any{
+					{"url": "https://fetched.com/announce", "status": 2, "msg": ""},
+				})
+			})
+			mux.HandleFunc("/api/v2/torrents/properties", func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewEncoder(w).Encode(map[string]any{"save_path": "/data"})
+			})
+			mux.HandleFunc("/api/v2/torrents/files", func(w http.ResponseWriter, r *http.Request) {
+				_ = json.NewEncoder(w).Encode([]map[string]any{})
+			})
+
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			c := &QBittorrent{
+				log:                     logger.GetLogger("test"),
+				client:                  qbittorrent.NewClient(qbittorrent.Config{Host: srv.URL}),
+				TrackerFetchConcurrency: 2,
+			}
+			require.NoError(t, c.Connect(context.Background()))
+
+			torrents, err := c.GetTorrents(context.Background())
+			require.NoError(t, err)
+			require.Len(t, torrents, 3)
+			assert.Equal(t, tc.wantFetches, fetches.Load())
+			for _, torrent := range torrents {
+				assert.Equal(t, tc.wantTrackerName, torrent.TrackerName)
+				assert.Zero(t, torrent.CreatedSeconds, "torrents without a creation date have no age")
+			}
+		})
+	}
+}
+
+func TestQBittorrent_RemoveTorrent(t *testing.T) {
+	removalAnnounceDelay = 0
+	t.Cleanup(func() { removalAnnounceDelay = 2 * time.Second })
+
+	tests := []struct {
+		name     string
+		torrent  config.Torrent
+		expected []string
+	}{
+		{
+			name:     "private_pauses_first",
+			torrent:  config.Torrent{Hash: "abc", IsPrivate: true},
+			expected: []string{"pause", "delete"},
+		},
+		{
+			name:     "public_skips_pause",
+			torrent:  config.Torrent{Hash: "abc", IsPublic: true},
+			expected: []string{"delete"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/v2/app/webapiVersion", func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("2.8.3"))
+			})
+			for _, endpoint := range []string{"pause", "delete"} {
+				mux.HandleFunc("/api/v2/torrents/"+endpoint, func(w http.ResponseWriter, r *http.Request) {
+					calls = append(calls, endpoint)
+				})
+			}
+
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			c := &QBittorrent{
+				log:    logger.GetLogger("test"),
+				client: qbittorrent.NewClient(qbittorrent.Config{Host: srv.URL}),
+			}
+
+			removed, err := c.RemoveTorrent(context.Background(), &tt.torrent, true)
+			require.NoError(t, err)
+			assert.True(t, removed)
+			assert.Equal(t, tt.expected, calls)
+		})
+	}
+}
+
+func TestQBittorrent_RecheckAndWait(t *testing.T) {
+	recheckPollInterval = time.Millisecond
+	t.Cleanup(func() { recheckPollInterval = 2 * time.Second })
+
+	tests := []struct {
+		name      string
+		states    []map[string]any
+		expected  bool
+		expectErr bool
+	}{
+		{
+			name: "waits_for_recheck_to_start",
+			states: []map[string]any{
+				{"state": "stalledUP", "progress": 1},
+				// the recheck has not started yet
+				{"state": "stalledUP", "progress": 1},
+				{"state": "checkingUP", "progress": 0.5},
+				{"state": "stalledUP", "progress": 0.9},
+			},
+			expected: false,
+		},
+		{
+			name: "complete_after_recheck",
+			states: []map[string]any{
+				{"state": "pausedUP", "progress": 1},
+				{"state": "checkingUP", "progress": 0.2},
+				{"state": "pausedUP", "progress": 1},
+			},
+			expected: true,
+		},
+		{
+			name: "progress_change_without_checking",
+			states: []map[string]any{
+				{"state": "pausedUP", "progress": 1},
+				{"state": "pausedDL", "progress": 0.8},
+			},
+			expected: false,
+		},
+		{
+			name: "recheck_never_started",
+			states: []map[string]any{
+				{"state": "stalledUP", "progress": 1},
+			},
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			polls := 0
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/api/v2/torrents/info", func(w http.ResponseWriter, r *http.Request) {
+				state := tt.states[min(polls, len(tt.states)-1)]
+				polls++
+				_ = json.NewEncoder(w).Encode([]map[string]any{
+					{"hash": "abc", "state": state["state"], "progress": state["progress"]},
+				})
+			})
+			mux.HandleFunc("/api/v2/torrents/recheck", func(w http.ResponseWriter, r *http.Request) {})
+
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			c := &QBittorrent{
+				log:    logger.GetLogger("test"),
+				client: qbittorrent.NewClient(qbittorrent.Config{Host: srv.URL}),
+			}
+
+			complete, err := c.RecheckAndWait(context.Background(), "abc", 100*time.Millisecond)
+			if tt.expectErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, complete)
+			assert.Equal(t, len(tt.states), polls)
+		})
+	}
+}
+
+func TestQBittorrent_RenameTag(t *testing.T) {
+	var calls []string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/torrents/info", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "info "+r.URL.Query().Get("tag"))
+		_ = json.NewEncoder(w).Encode([]map[string]any{
+			{"hash": "abc", "tags": "old"},
+			{"hash": "def", "tags": "old, keep"},
+		})
+	})
+	for _, endpoint := range []string{"createTags", "addTags", "removeTags", "deleteTags"} {
+		mux.HandleFunc("/api/v2/torrents/"+endpoint, func(w http.ResponseWriter, r *http.Request) {
+			require.NoError(t, r.ParseForm())
+			call := endpoint + " " + r.PostForm.Get("tags")
+			if hashes := r.PostForm.Get("hashes"); hashes != "" {
+				call += " " + hashes
+			}
+			calls = append(calls, call)
+		})
+	}
+
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	c := &QBittorrent{
+		log:    logger.GetLogger("test"),
+		client: qbittorrent.NewClient(qbittorrent.Config{Host: srv.URL}),
+	}
+
+	moved, err := c.RenameTag(context.Background(), "old", "new")
+	require.NoError(t, err)
+	assert.Equal(t, 2, moved)
+	assert.Equal(t, []string{
+		"info old",
+		"createTags new",
+		"addTags new abc|def",
+		"removeTags old abc|def",
+		"deleteTags old",
+	}, calls)
+}
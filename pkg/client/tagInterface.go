@@ -21,4 +21,5 @@ type TagInterface interface {
 	SetTags(ctx context.Context, hash string, tags []string) error
 	CreateTags(ctx context.Context, tags []string) error
 	DeleteTags(ctx context.Context, tags []string) error
+	RenameTag(ctx context.Context, oldTag string, newTag string) (int, error)
 }
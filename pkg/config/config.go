@@ -1,8 +1,10 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/knadh/koanf"
 	"github.com/knadh/koanf/parsers/yaml"
@@ -19,21 +21,143 @@ type TrackerErrorsConfig struct {
 	// on a per-tracker basis. The key is the tracker name (case-insensitive),
 	// and the value is a list of status strings (case-insensitive, exact match).
 	PerTrackerUnregisteredStatuses map[string][]string `yaml:"per_tracker_unregistered_statuses" koanf:"per_tracker_unregistered_statuses"`
+	// IntermediateStatuses are added to the built-in statuses that mark a torrent as pending on the tracker
+	// (e.g. under moderation), so it is never treated as unregistered.
+	IntermediateStatuses []string `yaml:"intermediate_statuses" koanf:"intermediate_statuses"`
+	// PerTrackerIntermediateStatuses replaces the intermediate statuses for matching trackers, keyed like
+	// PerTrackerUnregisteredStatuses.
+	PerTrackerIntermediateStatuses map[string][]string `yaml:"per_tracker_intermediate_statuses" koanf:"per_tracker_intermediate_statuses"`
+	// TrackerlessPrivateUnregistered treats private torrents without any trackers as unregistered
+	TrackerlessPrivateUnregistered bool `yaml:"trackerless_private_unregistered" koanf:"trackerless_private_unregistered"`
+	// PreferTrackerAPI requires the tracker API to confirm a torrent is unregistered for trackers with an API
+	// configured, instead of trusting a matching status message.
+	PreferTrackerAPI bool `yaml:"prefer_tracker_api" koanf:"prefer_tracker_api"`
+}
+
+// TrackerRequirement is the seeding requirement of a tracker (e.g. to avoid hit and runs), met by reaching either value
+type TrackerRequirement struct {
+	MinRatio    float64 `yaml:"min_ratio" koanf:"min_ratio"`
+	MinSeedDays float32 `yaml:"min_seed_days" koanf:"min_seed_days"`
+}
+
+// TrackerPolicy overrides how the torrents of a tracker are removed, whatever the filter removing them sets
+type TrackerPolicy struct {
+	// DeleteData set to false keeps the data of removed torrents, true leaves the decision to the filter
+	DeleteData *bool `yaml:"delete_data" koanf:"delete_data"`
+}
+
+// BypassIgnoreExemptions lists the trackers, labels and tags whose unregistered torrents keep being ignored when
+// BypassIgnoreIfUnregistered is enabled
+type BypassIgnoreExemptions struct {
+	Trackers []string `yaml:"trackers" koanf:"trackers"`
+	Labels   []string `yaml:"labels" koanf:"labels"`
+	Tags     []string `yaml:"tags" koanf:"tags"`
+}
+
+// RemovalConfig controls the pace of removals during clean
+type RemovalConfig struct {
+	// Concurrency is the number of torrents removed at once, 1 (default) removes them one after another
+	Concurrency int `yaml:"concurrency" koanf:"concurrency"`
+	// Delay is the pause after each removal, 1s when unset
+	Delay *time.Duration `yaml:"delay" koanf:"delay"`
+}
+
+// TorrentSafetyConfig aborts a run when a client returns fewer torrents than expected, e.g. while it is still loading
+// its torrents after a restart
+type TorrentSafetyConfig struct {
+	// MinTorrents is the fewest torrents a client may return
+	MinTorrents int `yaml:"min_torrents" koanf:"min_torrents"`
+	// MaxDrop is the largest share (0-1) the torrent count of a client may drop by since the last run in last_run_file
+	MaxDrop float64 `yaml:"max_drop" koanf:"max_drop"`
+}
+
+func (c TorrentSafetyConfig) Validate() error {
+	if c.MinTorrents < 0 {
+		return errors.New("min_torrents cannot be negative")
+	}
+	if c.MaxDrop < 0 || c.MaxDrop > 1 {
+		return errors.New("max_drop must be between 0 and 1")
+	}
+
+	return nil
+}
+
+// RemovalAnnounceConfig controls the pause (and for deluge, re-announce) done before a torrent is removed
+type RemovalAnnounceConfig struct {
+	// SkipInactive skips it for torrents that are already stopped or unregistered, where announcing is pointless
+	SkipInactive bool `yaml:"skip_inactive" koanf:"skip_inactive"`
+	// Trackers limits SkipInactive to these trackers, all trackers when empty
+	Trackers []string `yaml:"trackers" koanf:"trackers"`
+	// VerifyPause waits until a torrent whose data is deleted is reported stopped before removing it, instead of
+	// resuming it for a re-announce
+	VerifyPause bool `yaml:"verify_pause" koanf:"verify_pause"`
 }
 
 type Configuration struct {
 	Clients                    map[string]map[string]any
 	Filters                    map[string]FilterConfiguration
+	DefaultFilter              string `yaml:"default_filter" koanf:"default_filter"`
 	Trackers                   tracker.Config
+	SafeMode                   bool   `yaml:"safe_mode" koanf:"safe_mode"`
+	RequireConfirmationToken   string `yaml:"require_confirmation_token" koanf:"require_confirmation_token"`
 	BypassIgnoreIfUnregistered bool
-	TrackerErrors              TrackerErrorsConfig `yaml:"tracker_errors" koanf:"tracker_errors"`
-	Notifications              NotificationsConfig `yaml:"notifications" koanf:"notifications"`
+	Precedence                 string                        `yaml:"precedence" koanf:"precedence"`
+	BypassIgnoreExemptions     BypassIgnoreExemptions        `yaml:"bypass_ignore_exemptions" koanf:"bypass_ignore_exemptions"`
+	RetagPartialFailure        string                        `yaml:"retag_partial_failure" koanf:"retag_partial_failure"`
+	TrackerErrors              TrackerErrorsConfig           `yaml:"tracker_errors" koanf:"tracker_errors"`
+	TrackerRequirements        map[string]TrackerRequirement `yaml:"tracker_requirements" koanf:"tracker_requirements"`
+	TrackerPolicies            map[string]TrackerPolicy      `yaml:"tracker_policies" koanf:"tracker_policies"`
+	Removal                    RemovalConfig                 `yaml:"removal" koanf:"removal"`
+	RelabelDelay               *time.Duration                `yaml:"relabel_delay" koanf:"relabel_delay"`
+	RemovalAnnounce            RemovalAnnounceConfig         `yaml:"removal_announce" koanf:"removal_announce"`
+	Notifications              NotificationsConfig           `yaml:"notifications" koanf:"notifications"`
+	LastRunFile                string                        `yaml:"last_run_file" koanf:"last_run_file"`
+	MetricsFile                string                        `yaml:"metrics_file" koanf:"metrics_file"`
+	IgnoreListFile             string                        `yaml:"ignore_list_file" koanf:"ignore_list_file"`
+	PlanSigningKey             string                        `yaml:"plan_signing_key" koanf:"plan_signing_key"`
+	SkipMoving                 bool                          `yaml:"skip_moving" koanf:"skip_moving"`
+	ExcludeRecentlyActed       bool                          `yaml:"exclude_recently_acted" koanf:"exclude_recently_acted"`
+	RecentlyActedFile          string                        `yaml:"recently_acted_file" koanf:"recently_acted_file"`
+	RecentlyActedTTL           *time.Duration                `yaml:"recently_acted_ttl" koanf:"recently_acted_ttl"`
+	TorrentSafety              TorrentSafetyConfig           `yaml:"torrent_safety" koanf:"torrent_safety"`
+	Schedule                   []ScheduleJob                 `yaml:"schedule" koanf:"schedule"`
+}
+
+const (
+	// RetagPartialFailureRollback reverts added tags when removing tags fails during a retag (default)
+	RetagPartialFailureRollback = "rollback"
+	// RetagPartialFailureKeep leaves added tags in place when removing tags fails during a retag
+	RetagPartialFailureKeep = "keep"
+)
+
+const (
+	// PrecedenceIgnoreWins keeps every torrent matching an ignore filter, whatever the remove filters say
+	PrecedenceIgnoreWins = "ignore_wins"
+	// PrecedenceRemoveWins evaluates the remove filters of ignored torrents too
+	PrecedenceRemoveWins = "remove_wins"
+	// PrecedenceBypassIfUnregistered evaluates the remove filters of ignored torrents that are unregistered
+	PrecedenceBypassIfUnregistered = "bypass_if_unregistered"
+)
+
+// EffectivePrecedence returns how a torrent matching both the ignore and remove filters is handled. When precedence
+// is not set, it is bypass_if_unregistered with BypassIgnoreIfUnregistered and ignore_wins otherwise
+func (c *Configuration) EffectivePrecedence() string {
+	if c.Precedence != "" {
+		return c.Precedence
+	}
+
+	if c.BypassIgnoreIfUnregistered {
+		return PrecedenceBypassIfUnregistered
+	}
+
+	return PrecedenceIgnoreWins
 }
 
 /* Vars */
 
 var (
-	cfgPath = ""
+	cfgPath    = ""
+	cfgProfile = ""
 
 	Delimiter = "."
 	Config    *Configuration
@@ -43,22 +167,55 @@ var (
 	log = logger.GetLogger("cfg")
 )
 
+/* Private */
+
+const envPrefix = "TQM__"
+
+// profilesKey holds the named profiles that can be merged over the base config
+const profilesKey = "profiles"
+
+// envKey maps an environment variable to a config key, nested keys are separated by a double underscore so keys
+// containing an underscore can be reached (TQM__TRACKERS__PTP__API_KEY -> trackers.ptp.api_key). Variables without a
+// double underscore keep the legacy mapping where every underscore separates a key (TQM__CLIENTS_QBT_PASSWORD).
+func envKey(s string) string {
+	key := strings.ToLower(strings.TrimPrefix(s, envPrefix))
+	if strings.Contains(key, "__") {
+		return strings.ReplaceAll(key, "__", Delimiter)
+	}
+
+	return strings.ReplaceAll(key, "_", Delimiter)
+}
+
 /* Public */
 
-func Init(configFilePath string) error {
+// Init loads the config file, merges the named profile over it (when set) and applies the environment variables
+func Init(configFilePath string, profile string) error {
 	// set package variables
 	cfgPath = configFilePath
+	cfgProfile = profile
 
 	// load config
 	if err := K.Load(file.Provider(configFilePath), yaml.Parser()); err != nil {
 		return fmt.Errorf("load file: %w", err)
 	}
 
+	// merge profile over the base config, maps are merged key by key while other values (including lists) replace
+	// those of the base config
+	if profile != "" {
+		path := profilesKey + Delimiter + profile
+		if _, ok := K.Get(path).(map[string]any); !ok {
+			return fmt.Errorf("profile not found: %q", profile)
+		}
+
+		if err := K.Merge(K.Cut(path)); err != nil {
+			return fmt.Errorf("merge profile: %q: %w", profile, err)
+		}
+
+		log.Infof("Using profile: %q", profile)
+	}
+
 	// load environment variables
-	if err := K.Load(env.Provider("TQM__", ".", func(s string) string {
-		return strings.Replace(strings.ToLower(
-			strings.TrimPrefix(s, "TQM__")), "_", ".", -1)
-	}), nil); err != nil {
+	if err := K.Load(env.Provider(envPrefix, Delimiter, envKey), nil); err != nil {
 		return fmt.Errorf("load env: %w", err)
 	}
 
@@ -67,9 +224,69 @@ func Init(configFilePath string) error {
 		return fmt.Errorf("unmarshal: %w", err)
 	}
 
+	if err := Config.Notifications.Service.Discord.Validate(); err != nil {
+		return fmt.Errorf("validate discord notifications: %w", err)
+	}
+
+	if err := Config.Notifications.TrackerDown.Validate(); err != nil {
+		return fmt.Errorf("validate tracker_down notifications: %w", err)
+	}
+
+	for i, entry := range Config.Notifications.Watchlist {
+		if err := entry.Validate(); err != nil {
+			return fmt.Errorf("validate watchlist entry %d: %w", i+1, err)
+		}
+	}
+
+	if err := Config.TorrentSafety.Validate(); err != nil {
+		return fmt.Errorf("validate torrent_safety: %w", err)
+	}
+
+	for i, job := range Config.Schedule {
+		if err := job.Validate(); err != nil {
+			return fmt.Errorf("validate schedule job %d: %w", i+1, err)
+		}
+	}
+
+	Config.Precedence = strings.ToLower(Config.Precedence)
+	switch Config.Precedence {
+	case "", PrecedenceIgnoreWins, PrecedenceRemoveWins, PrecedenceBypassIfUnregistered:
+	default:
+		return fmt.Errorf("validate precedence: unknown value: %q", Config.Precedence)
+	}
+
 	log.Debugf("Parsed TrackerErrors config: %+v", Config.TrackerErrors)
 
-	InitializeTrackerStatuses(Config.TrackerErrors.PerTrackerUnregisteredStatuses)
+	// tracker requirements are looked up by lowercased tracker name
+	requirements := make(map[string]TrackerRequirement, len(Config.TrackerRequirements))
+	for name, req := range Config.TrackerRequirements {
+		requirements[strings.ToLower(name)] = req
+	}
+	Config.TrackerRequirements = requirements
+
+	// tracker policies too
+	policies := make(map[string]TrackerPolicy, len(Config.TrackerPolicies))
+	for name, policy := range Config.TrackerPolicies {
+		policies[strings.ToLower(name)] = policy
+	}
+	Config.TrackerPolicies = policies
+
+	InitializeTrackerStatuses(Config.TrackerErrors)
+
+	return nil
+}
+
+// Reload reads the config file, profile and environment variables given to Init again. The previous config is kept
+// when the new one fails to load or validate
+func Reload() error {
+	previousK, previousConfig := K, Config
+
+	K = koanf.New(Delimiter)
+	Config = nil
+	if err := Init(cfgPath, cfgProfile); err != nil {
+		K, Config = previousK, previousConfig
+		return err
+	}
 
 	return nil
 }
@@ -0,0 +1,187 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/knadh/koanf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvKey(t *testing.T) {
+	tests := []struct {
+		env      string
+		expected string
+	}{
+		{env: "TQM__TRACKERS__PTP__API_KEY", expected: "trackers.ptp.api_key"},
+		{env: "TQM__CLIENTS__QBT__PASSWORD", expected: "clients.qbt.password"},
+		{env: "TQM__TRACKERS__UNIT3D__AITHER__API_KEY", expected: "trackers.unit3d.aither.api_key"},
+		// legacy single underscore mapping
+		{env: "TQM__CLIENTS_QBT_PASSWORD", expected: "clients.qbt.password"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			assert.Equal(t, tt.expected, envKey(tt.env))
+		})
+	}
+}
+
+func TestInit_EnvOverridesSecrets(t *testing.T) {
+	origK, origConfig := K, Config
+	K = koanf.New(Delimiter)
+	t.Cleanup(func() {
+		K, Config = origK, origConfig
+	})
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+clients:
+  qbt:
+    type: qbittorrent
+    password: file-password
+trackers:
+  ptp:
+    api_user: file-user
+    api_key: file-key
+`), 0o600))
+
+	t.Setenv("TQM__TRACKERS__PTP__API_KEY", "env-key")
+	t.Setenv("TQM__CLIENTS__QBT__PASSWORD", "env-password")
+
+	require.NoError(t, Init(configPath, ""))
+
+	assert.Equal(t, "env-key", Config.Trackers.PTP.Key)
+	assert.Equal(t, "file-user", Config.Trackers.PTP.User)
+	assert.Equal(t, "env-password", Config.Clients["qbt"]["password"])
+	assert.Equal(t, "qbittorrent", Config.Clients["qbt"]["type"])
+}
+
+func TestInit_Profile(t *testing.T) {
+	origK, origConfig := K, Config
+	t.Cleanup(func() {
+		K, Config = origK, origConfig
+	})
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configPath, []byte(`
+safe_mode: true
+filters:
+  default:
+    DeleteData: false
+    ignore:
+      - Label == "keep"
+    remove:
+      - Ratio > 5
+profiles:
+  aggressive:
+    safe_mode: false
+    filters:
+      default:
+        archive_path: /mnt/profile-archive
+        remove:
+          - Ratio > 1
+`), 0o600))
+
+	t.Run("base", func(t *testing.T) {
+		K = koanf.New(Delimiter)
+		require.NoError(t, Init(configPath, ""))
+
+		assert.True(t, Config.SafeMode)
+		assert.Equal(t, []string{"Ratio > 5"}, Config.Filters["default"].Remove)
+	})
+
+	t.Run("profile", func(t *testing.T) {
+		K = koanf.New(Delimiter)
+		t.Setenv("TQM__FILTERS__DEFAULT__ARCHIVE_PATH", "/mnt/env-archive")
+		require.NoError(t, Init(configPath, "aggressive"))
+
+		assert.False(t, Config.SafeMode)
+		assert.Equal(t, "/mnt/env-archive", Config.Filters["default"].ArchivePath, "environment variables take precedence over the profile")
+		assert.Equal(t, []string{"Ratio > 1"}, Config.Filters["default"].Remove, "profile lists replace the base lists")
+		assert.Equal(t, []string{`Label == "keep"`}, Config.Filters["default"].Ignore, "keys missing from the profile keep their base value")
+		require.NotNil(t, Config.Filters["default"].DeleteData)
+		assert.False(t, *Config.Filters["default"].DeleteData)
+	})
+
+	t.Run("unknown_profile", func(t *testing.T) {
+		K = koanf.New(Delimiter)
+		assert.ErrorContains(t, Init(configPath, "missing"), "profile not found")
+	})
+}
+
+func TestInit_Precedence(t *testing.T) {
+	origK, origConfig := K, Config
+	t.Cleanup(func() {
+		K, Config = origK, origConfig
+	})
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+
+	require.NoError(t, os.WriteFile(configPath, []byte("precedence: Remove_Wins\n"), 0o600))
+	K = koanf.New(Delimiter)
+	require.NoError(t, Init(configPath, ""))
+	assert.Equal(t, PrecedenceRemoveWins, Config.EffectivePrecedence())
+
+	require.NoError(t, os.WriteFile(configPath, []byte("precedence: remove_first\n"), 0o600))
+	K = koanf.New(Delimiter)
+	assert.Error(t, Init(configPath, ""))
+}
+
+func TestConfiguration_EffectivePrecedence(t *testing.T) {
+	assert.Equal(t, PrecedenceIgnoreWins, (&Configuration{}).EffectivePrecedence())
+	assert.Equal(t, PrecedenceBypassIfUnregistered, (&Configuration{BypassIgnoreIfUnregistered: true}).EffectivePrecedence())
+	assert.Equal(t, PrecedenceIgnoreWins,
+		(&Configuration{BypassIgnoreIfUnregistered: true, Precedence: PrecedenceIgnoreWins}).EffectivePrecedence())
+}
+
+func TestScheduleJob_Validate(t *testing.T) {
+	require.NoError(t, ScheduleJob{Command: "clean", Cron: "0 3 * * *"}.Validate())
+	require.NoError(t, ScheduleJob{Command: "orphan", Cron: "@weekly"}.Validate())
+	require.Error(t, ScheduleJob{Cron: "0 3 * * *"}.Validate())
+	require.Error(t, ScheduleJob{Command: "clean", Cron: "0 25 * * *"}.Validate())
+}
+
+func TestReload(t *testing.T) {
+	origK, origConfig := K, Config
+	t.Cleanup(func() {
+		K, Config = origK, origConfig
+	})
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(content string) {
+		t.Helper()
+		require.NoError(t, os.WriteFile(configPath, []byte(content), 0o600))
+	}
+
+	write(`
+filters:
+  default:
+    remove:
+      - Ratio > 5
+`)
+	K = koanf.New(Delimiter)
+	require.NoError(t, Init(configPath, ""))
+
+	write(`
+filters:
+  default:
+    remove:
+      - Ratio > 2
+`)
+	require.NoError(t, Reload())
+	assert.Equal(t, []string{"Ratio > 2"}, Config.Filters["default"].Remove)
+
+	// an invalid config keeps the previous one
+	write(`
+schedule:
+  - command: clean
+    cron: "not a cron"
+`)
+	require.Error(t, Reload())
+	assert.Equal(t, []string{"Ratio > 2"}, Config.Filters["default"].Remove)
+	assert.Empty(t, Config.Schedule)
+	assert.Equal(t, []string{"Ratio > 2"}, K.Strings("filters.default.remove"))
+}
@@ -2,24 +2,71 @@ package config
 
 import "time"
 
+// FreeSpaceFilter is a tier of free_space_filters, Filter is used instead while free space is below BelowGB
+type FreeSpaceFilter struct {
+	BelowGB float64 `yaml:"below_gb" koanf:"below_gb"`
+	Filter  string  `yaml:"filter" koanf:"filter"`
+}
+
 type FilterConfiguration struct {
-	MapHardlinksFor []string
-	Ignore          []string
-	Remove          []string
-	Pause           []string
-	DeleteData      *bool
-	Orphan          struct {
+	MapHardlinksFor     []string
+	ResolveSymlinks     bool `yaml:"resolve_symlinks" koanf:"resolve_symlinks"`
+	HardlinkFailClosed  bool `yaml:"hardlink_fail_closed" koanf:"hardlink_fail_closed"`
+	ContentCrossSeeds   bool `yaml:"content_cross_seeds" koanf:"content_cross_seeds"`
+	Ignore              []string
+	Remove              []string
+	Pause               []string
+	DeleteData          *bool
+	DeleteDataIfPath    []string `yaml:"delete_data_if_path" koanf:"delete_data_if_path"`
+	RequirePaused       bool     `yaml:"require_paused" koanf:"require_paused"`
+	RequireReplacement  bool     `yaml:"require_replacement" koanf:"require_replacement"`
+	MinCopies           int      `yaml:"min_copies" koanf:"min_copies"`
+	RecheckBeforeRemove bool     `yaml:"recheck_before_remove" koanf:"recheck_before_remove"`
+	ArchivePath         string   `yaml:"archive_path" koanf:"archive_path"`
+	VerifyRemoval       bool     `yaml:"verify_removal" koanf:"verify_removal"`
+	RemoveLeftovers     bool     `yaml:"remove_leftovers" koanf:"remove_leftovers"`
+	Orphan              struct {
 		GracePeriod time.Duration `yaml:"grace_period" koanf:"grace_period"`
 		IgnorePaths []string      `yaml:"ignore_paths" koanf:"ignore_paths"`
+		// IncompleteDirs are folders used for downloads in progress, relative to the download path or absolute
+		IncompleteDirs []string `yaml:"incomplete_dirs" koanf:"incomplete_dirs"`
+		// ProtectedExtensions are extensions of files that are never removed as orphans, e.g. .torrent or .nfo
+		ProtectedExtensions []string `yaml:"protected_extensions" koanf:"protected_extensions"`
 	} `yaml:"orphan" koanf:"orphan"`
+	// FreeSpaceFilters switch clean to another filter while free space is low
+	FreeSpaceFilters []FreeSpaceFilter `yaml:"free_space_filters" koanf:"free_space_filters"`
+	// Quarantine tags and pauses torrents meeting the remove filters, removing them once they carried the tag for Period
+	Quarantine struct {
+		Tag    string        `yaml:"tag" koanf:"tag"`
+		Period time.Duration `yaml:"period" koanf:"period"`
+	} `yaml:"quarantine" koanf:"quarantine"`
+	// SkipTags tags torrents meeting the remove filters that clean skipped with why they were skipped, each tag
+	// defaults to skip:<reason> when empty
+	SkipTags struct {
+		Enabled       bool   `yaml:"enabled" koanf:"enabled"`
+		NonUnique     string `yaml:"non_unique" koanf:"non_unique"`
+		TrackerDown   string `yaml:"tracker_down" koanf:"tracker_down"`
+		NotPaused     string `yaml:"not_paused" koanf:"not_paused"`
+		NoReplacement string `yaml:"no_replacement" koanf:"no_replacement"`
+	} `yaml:"skip_tags" koanf:"skip_tags"`
 	Label []struct {
-		Name   string
-		Update []string
+		Name     string
+		Priority int
+		Update   []string
 	}
 	Tag []struct {
 		Name     string
 		Mode     string
 		UploadKb *int `mapstructure:"uploadKb"`
+		Priority int
 		Update   []string
 	}
+	Milestone []struct {
+		Name    string
+		Value   string
+		Buckets []struct {
+			Tag string
+			Min float64
+		}
+	}
 }
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// media categories returned by MediaCategory
+const (
+	MediaMovies = "movies"
+	MediaTV     = "tv"
+	MediaMusic  = "music"
+	MediaOther  = "other"
+)
+
+var (
+	videoExtensions = map[string]struct{}{
+		".avi": {}, ".m2ts": {}, ".m4v": {}, ".mkv": {}, ".mov": {}, ".mp4": {}, ".mpg": {}, ".ts": {}, ".vob": {},
+		".webm": {}, ".wmv": {},
+	}
+
+	audioExtensions = map[string]struct{}{
+		".aac": {}, ".aiff": {}, ".alac": {}, ".ape": {}, ".dsf": {}, ".flac": {}, ".m4a": {}, ".mp3": {},
+		".ogg": {}, ".opus": {}, ".wav": {}, ".wma": {}, ".wv": {},
+	}
+
+	// episodeRegex matches the season and episode markers of tv releases, e.g. S01E02, S01, 1x02 or Season 1
+	episodeRegex = regexp.MustCompile(`(?i)(?:^|[^a-z0-9])(?:s\d{1,2}(?:e\d{1,3})?|\d{1,2}x\d{2,3}|season[ ._-]?\d{1,2})(?:[^a-z0-9]|$)`)
+
+	// sampleRegex matches the sample videos shipped alongside releases, which say nothing about the category
+	sampleRegex = regexp.MustCompile(`(?i)(?:^|[^a-z0-9])sample(?:[^a-z0-9]|$)`)
+)
+
+// MediaCategory classifies the torrent as movies, tv or music from the extensions of its files, falling back to its
+// name when the client reported no files. Video is tv when the name or a video file has a season or episode marker,
+// and movies otherwise. Torrents with neither video nor audio are other
+func (t *Torrent) MediaCategory() string {
+	files := t.Files
+	if len(files) == 0 {
+		files = []string{t.Name}
+	}
+
+	videos, audios, episodes := 0, 0, 0
+	for _, f := range files {
+		name := path.Base(strings.ReplaceAll(f, `\`, "/"))
+		ext := strings.ToLower(path.Ext(name))
+
+		if _, ok := audioExtensions[ext]; ok {
+			audios++
+			continue
+		}
+
+		if _, ok := videoExtensions[ext]; !ok || sampleRegex.MatchString(name) {
+			continue
+		}
+
+		videos++
+		if episodeRegex.MatchString(name) {
+			episodes++
+		}
+	}
+
+	switch {
+	case videos == 0 && audios == 0:
+		return MediaOther
+	case audios > videos:
+		return MediaMusic
+	case episodes > 0 || episodeRegex.MatchString(t.Name):
+		return MediaTV
+	default:
+		return MediaMovies
+	}
+}
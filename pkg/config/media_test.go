@@ -0,0 +1,80 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTorrent_MediaCategory(t *testing.T) {
+	tests := []struct {
+		name     string
+		torrent  Torrent
+		expected string
+	}{
+		{
+			name: "movie",
+			torrent: Torrent{Name: "Movie.2020.1080p.BluRay.x264", Files: []string{
+				"/data/Movie.2020.1080p.BluRay.x264/Movie.2020.1080p.BluRay.x264.mkv",
+				"/data/Movie.2020.1080p.BluRay.x264/Movie.2020.1080p.BluRay.x264.nfo",
+			}},
+			expected: MediaMovies,
+		},
+		{
+			name: "movie_sample_ignored",
+			torrent: Torrent{Name: "Movie.2020.1080p", Files: []string{
+				"/data/Movie.2020.1080p/Movie.2020.1080p.mkv",
+				"/data/Movie.2020.1080p/Sample/S01-sample.mkv",
+			}},
+			expected: MediaMovies,
+		},
+		{
+			name: "season_pack",
+			torrent: Torrent{Name: "Show.S01.1080p.WEB-DL", Files: []string{
+				"/data/Show.S01.1080p.WEB-DL/Show.S01E01.1080p.WEB-DL.mkv",
+				"/data/Show.S01.1080p.WEB-DL/Show.S01E02.1080p.WEB-DL.mkv",
+			}},
+			expected: MediaTV,
+		},
+		{
+			name:     "episode_by_name",
+			torrent:  Torrent{Name: "Show.2x05.720p", Files: []string{`D:\tv\Show.2x05.720p\episode.mp4`}},
+			expected: MediaTV,
+		},
+		{
+			name:     "season_folder",
+			torrent:  Torrent{Name: "Show Season 3", Files: []string{"/data/Show Season 3/03.mkv"}},
+			expected: MediaTV,
+		},
+		{
+			name: "album",
+			torrent: Torrent{Name: "Artist - Album (2019) [FLAC]", Files: []string{
+				"/data/Artist - Album/01 - Track.flac",
+				"/data/Artist - Album/02 - Track.flac",
+				"/data/Artist - Album/cover.jpg",
+			}},
+			expected: MediaMusic,
+		},
+		{
+			name:     "resolution_is_not_episode",
+			torrent:  Torrent{Name: "Movie 1920x1080", Files: []string{"/data/Movie 1920x1080.mkv"}},
+			expected: MediaMovies,
+		},
+		{
+			name:     "no_files_uses_name",
+			torrent:  Torrent{Name: "Show.S02E03.1080p.mkv"},
+			expected: MediaTV,
+		},
+		{
+			name:     "other",
+			torrent:  Torrent{Name: "Linux ISO", Files: []string{"/data/linux.iso"}},
+			expected: MediaOther,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.torrent.MediaCategory())
+		})
+	}
+}
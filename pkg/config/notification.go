@@ -1,11 +1,82 @@
 package config
 
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/autobrr/tqm/pkg/regex"
+)
+
 type NotificationsConfig struct {
 	Detailed     bool
-	SkipEmptyRun bool `yaml:"skip_empty_run" koanf:"skip_empty_run"`
+	SkipEmptyRun bool              `yaml:"skip_empty_run" koanf:"skip_empty_run"`
+	Batch        bool              `yaml:"batch" koanf:"batch"`
+	BatchDir     string            `yaml:"batch_dir" koanf:"batch_dir"`
+	BatchWindow  time.Duration     `yaml:"batch_window" koanf:"batch_window"`
+	TrackerDown  TrackerDownConfig `yaml:"tracker_down" koanf:"tracker_down"`
+	Watchlist    []WatchlistEntry  `yaml:"watchlist" koanf:"watchlist"`
 	Service      NotificationService
 }
 
+const (
+	WatchConditionUnregistered = "unregistered"
+	WatchConditionTrackerDown  = "tracker_down"
+)
+
+// WatchlistEntry notifies about the torrents matching Hashes or Names (regex) while they meet one of Conditions,
+// whether or not an action is taken on them
+type WatchlistEntry struct {
+	Hashes     []string `yaml:"hashes" koanf:"hashes"`
+	Names      []string `yaml:"names" koanf:"names"`
+	Conditions []string `yaml:"conditions" koanf:"conditions"`
+}
+
+func (e WatchlistEntry) Validate() error {
+	if len(e.Hashes) == 0 && len(e.Names) == 0 {
+		return errors.New("hashes or names must be set")
+	}
+	if len(e.Conditions) == 0 {
+		return errors.New("conditions must be set")
+	}
+
+	for _, c := range e.Conditions {
+		if c != WatchConditionUnregistered && c != WatchConditionTrackerDown {
+			return fmt.Errorf("invalid condition %q, must be %q or %q", c, WatchConditionUnregistered,
+				WatchConditionTrackerDown)
+		}
+	}
+
+	if err := regex.ValidatePatterns(e.Names); err != nil {
+		return fmt.Errorf("invalid name pattern: %w", err)
+	}
+
+	return nil
+}
+
+// TrackerDownConfig alerts when the share of a tracker's torrents reporting the tracker down reaches Threshold
+type TrackerDownConfig struct {
+	Threshold   float64 `yaml:"threshold" koanf:"threshold"`
+	MinTorrents int     `yaml:"min_torrents" koanf:"min_torrents"`
+}
+
+func (c TrackerDownConfig) Enabled() bool {
+	return c.Threshold > 0
+}
+
+func (c TrackerDownConfig) Validate() error {
+	if c.Threshold < 0 || c.Threshold > 1 {
+		return errors.New("threshold must be between 0 and 1")
+	}
+	if c.MinTorrents < 0 {
+		return errors.New("min_torrents cannot be negative")
+	}
+
+	return nil
+}
+
 type NotificationService struct {
 	Discord DiscordConfig `yaml:"discord" koanf:"discord"`
 }
@@ -14,4 +85,34 @@ type DiscordConfig struct {
 	WebhookURL string `yaml:"webhook_url" koanf:"webhook_url"`
 	Username   string `yaml:"username" koanf:"username"`
 	AvatarURL  string `yaml:"avatar_url" koanf:"avatar_url"`
+	// ThreadID posts to an existing thread, ThreadName creates a new forum post per notification
+	ThreadID   string `yaml:"thread_id" koanf:"thread_id"`
+	ThreadName string `yaml:"thread_name" koanf:"thread_name"`
+	// SpoolDir keeps the messages that could not be sent while the webhook is unreachable, they are sent by the next run
+	SpoolDir string `yaml:"spool_dir" koanf:"spool_dir"`
+	// Actions overrides the username and avatar of the notifications of an action, keyed by DiscordActions
+	Actions map[string]DiscordIdentity `yaml:"actions" koanf:"actions"`
+}
+
+// DiscordIdentity is the username and avatar a notification is posted with, empty values keep the global ones
+type DiscordIdentity struct {
+	Username  string `yaml:"username" koanf:"username"`
+	AvatarURL string `yaml:"avatar_url" koanf:"avatar_url"`
+}
+
+// DiscordActions are the actions whose username and avatar can be overridden
+var DiscordActions = []string{"clean", "retag", "relabel", "pause", "recover", "orphan", "tracker_down", "watchlist"}
+
+func (c DiscordConfig) Validate() error {
+	if c.ThreadID != "" && c.ThreadName != "" {
+		return errors.New("thread_id and thread_name cannot both be set")
+	}
+
+	for action := range c.Actions {
+		if !slices.Contains(DiscordActions, action) {
+			return fmt.Errorf("actions: unknown action %q (%s)", action, strings.Join(DiscordActions, ", "))
+		}
+	}
+
+	return nil
 }
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchlistEntry_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		entry   WatchlistEntry
+		wantErr bool
+	}{
+		{name: "hashes", entry: WatchlistEntry{Hashes: []string{"abc"}, Conditions: []string{WatchConditionUnregistered}}},
+		{name: "names", entry: WatchlistEntry{Names: []string{`^Rare\.`}, Conditions: []string{WatchConditionTrackerDown}}},
+		{name: "no_torrents", entry: WatchlistEntry{Conditions: []string{WatchConditionUnregistered}}, wantErr: true},
+		{name: "no_conditions", entry: WatchlistEntry{Hashes: []string{"abc"}}, wantErr: true},
+		{name: "invalid_condition", entry: WatchlistEntry{Hashes: []string{"abc"}, Conditions: []string{"stalled"}}, wantErr: true},
+		{name: "invalid_pattern", entry: WatchlistEntry{Names: []string{"(rare"}, Conditions: []string{WatchConditionUnregistered}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.entry.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDiscordConfig_Validate(t *testing.T) {
+	assert.NoError(t, DiscordConfig{Actions: map[string]DiscordIdentity{"clean": {Username: "clean"}}}.Validate())
+	assert.Error(t, DiscordConfig{Actions: map[string]DiscordIdentity{"cleanup": {Username: "clean"}}}.Validate())
+	assert.Error(t, DiscordConfig{ThreadID: "1", ThreadName: "tqm"}.Validate())
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/autobrr/tqm/pkg/schedule"
+)
+
+// ScheduleJob is a command the daemon runs on a cron schedule
+type ScheduleJob struct {
+	// Name identifies the job in logs, the command and clients when empty
+	Name string `yaml:"name" koanf:"name"`
+	// Cron is a five field cron expression or a descriptor such as @daily, in local time
+	Cron string `yaml:"cron" koanf:"cron"`
+	// Command is the tqm command to run, e.g. clean
+	Command string `yaml:"command" koanf:"command"`
+	// Clients are passed to the command, most commands take one
+	Clients []string `yaml:"clients" koanf:"clients"`
+	// Args are extra flags of the command, e.g. --filter
+	Args []string `yaml:"args" koanf:"args"`
+	// DryRun runs the command with --dry-run
+	DryRun bool `yaml:"dry_run" koanf:"dry_run"`
+}
+
+func (j ScheduleJob) Validate() error {
+	if j.Command == "" {
+		return errors.New("command is required")
+	}
+
+	if _, err := schedule.Parse(j.Cron); err != nil {
+		return fmt.Errorf("cron: %w", err)
+	}
+
+	return nil
+}
@@ -1,12 +1,16 @@
 package config
 
 import (
+	"cmp"
 	"context"
 	"math"
 	"net"
 	"net/url"
 	"os"
+	"path"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/bobesa/go-domain-util/domainutil"
 	"github.com/sirupsen/logrus"
@@ -26,6 +30,18 @@ const (
 	IntermediateState
 )
 
+// normalized torrent states shared across clients
+const (
+	StatePaused      = "paused"
+	StateDownloading = "downloading"
+	StateSeeding     = "seeding"
+	StateQueued      = "queued"
+	StateChecking    = "checking"
+	StateMoving      = "moving"
+	StateError       = "error"
+	StateUnknown     = "unknown"
+)
+
 var (
 	// defaultUnregisteredStatuses holds the default list if none is provided in config.
 	defaultUnregisteredStatuses = []string{
@@ -64,7 +80,7 @@ var (
 	}
 
 	// effectiveUnregisteredStatuses stores per-tracker overrides. Key is lowercased tracker name.
-	effectiveUnregisteredStatuses = map[string]map[string]struct{}{}
+	effectiveUnregisteredStatuses = trackerStatusOverrides{}
 	// defaultUnregisteredStatusesMap is a pre-processed map of the defaults for faster lookups.
 	defaultUnregisteredStatusesMap = map[string]struct{}{}
 
@@ -117,8 +133,29 @@ var (
 		// BHD - torrent is under moderation
 		"torrent has been postponed",
 	}
+
+	// effectiveIntermediateStatuses stores per-tracker overrides. Key is lowercased tracker name.
+	effectiveIntermediateStatuses = trackerStatusOverrides{}
+	// defaultIntermediateStatusesMap holds the built-in and globally configured intermediate statuses.
+	defaultIntermediateStatusesMap = newStatusMap(trackerIntermediateStatuses)
+
+	// trackerGet looks up the tracker API of a host, replaceable in tests
+	trackerGet = tracker.Get
 )
 
+// trackerStatusOverrides maps lowercased tracker names or patterns to their status lists
+type trackerStatusOverrides struct {
+	statuses map[string]map[string]struct{}
+	// patterns holds the "|" alternatives of the keys ordered longest first, used when the exact lookup misses
+	patterns []trackerStatusPattern
+}
+
+// trackerStatusPattern is one alternative of an override key with the statuses of the key
+type trackerStatusPattern struct {
+	pattern  string
+	statuses map[string]struct{}
+}
+
 type Torrent struct {
 	// torrent
 	Hash                string   `json:"Hash"`
@@ -131,7 +168,9 @@ type Torrent struct {
 	Tags                []string `json:"Tags"`
 	Downloaded          bool     `json:"Downloaded"`
 	Seeding             bool     `json:"Seeding"`
-	Ratio               float32  `json:"Ratio"`
+	Ratio               float64  `json:"Ratio"`
+	RatioLimit          float64  `json:"RatioLimit"`
+	SeedingLimitReached bool     `json:"SeedingLimitReached"`
 	AddedSeconds        int64    `json:"AddedSeconds"`
 	AddedHours          float32  `json:"AddedHours"`
 	AddedDays           float32  `json:"AddedDays"`
@@ -142,19 +181,30 @@ type Torrent struct {
 	LastActivityHours   float32  `json:"LastActivityHours"`
 	LastActivityDays    float32  `json:"LastActivityDays"`
 	Label               string   `json:"Label"`
+	Category            string   `json:"Category"`
 	Seeds               int64    `json:"Seeds"`
 	Peers               int64    `json:"Peers"`
+	Availability        float32  `json:"Availability"`
 	IsPrivate           bool     `json:"IsPrivate"`
 	IsPublic            bool     `json:"IsPublic"`
 	UpLimit             int64    `json:"UpLimit,omitempty"`
+	DownLimit           int64    `json:"DownLimit,omitempty"`
+	// CreatedSeconds is the age of the .torrent itself, from the creation date in its metadata. It is 0 when the
+	// client does not report one (e.g. Deluge) or the torrent has none
+	CreatedSeconds int64   `json:"CreatedSeconds"`
+	CreatedDays    float32 `json:"CreatedDays"`
 
 	// set by client on GetCurrentFreeSpace
 	FreeSpaceGB  func() float64 `json:"-"`
 	FreeSpaceSet bool           `json:"-"`
+	// set by clean when the torrent is on another filesystem than the one free space is tracked for
+	FreeSpaceElsewhere bool `json:"-"`
 
 	// tracker
 	TrackerName   string `json:"TrackerName"`
 	TrackerStatus string `json:"TrackerStatus"`
+	// TrackerCount is the number of real trackers, excluding DHT/LSD/PeX
+	TrackerCount int `json:"TrackerCount"`
 	// AllTrackerStatuses stores status messages from all trackers (key: tracker URL, value: status message)
 	AllTrackerStatuses map[string]string `json:"AllTrackerStatuses,omitempty"`
 	Comment            string            `json:"Comment"`
@@ -164,11 +214,22 @@ type Torrent struct {
 	// set by command
 	HardlinkedOutsideClient bool `json:"-"`
 	APIDividerPrinted       bool `json:"-"`
+	// HasReplacement is set when another torrent looks like a newer version of this one, see require_replacement
+	HasReplacement bool `json:"-"`
+	// SharingFiles returns the other torrents sharing files with this one, used by SharesFilesWithRegistered
+	SharingFiles func() []Torrent `json:"-"`
 
 	regexPattern *regex.Pattern
 }
 
 func (t *Torrent) IsTrackerDown() bool {
+	// the tracker api can report the tracker down, e.g. after repeated api failures
+	if tr := trackerGet(t.TrackerName); tr != nil {
+		if _, down := tr.IsTrackerDown(&tracker.Torrent{Hash: t.Hash, Name: t.Name, TrackerName: t.TrackerName}); down {
+			return true
+		}
+	}
+
 	// If we have multiple tracker statuses, check if ALL are down
 	if len(t.AllTrackerStatuses) > 0 {
 		var downCount int
@@ -209,17 +270,15 @@ func (t *Torrent) IsTrackerDown() bool {
 func (t *Torrent) IsIntermediateStatus() bool {
 	// If we have multiple tracker statuses, check if ANY has intermediate status
 	if len(t.AllTrackerStatuses) > 0 {
-		for _, status := range t.AllTrackerStatuses {
+		for trackerURL, status := range t.AllTrackerStatuses {
 			if status == "" {
 				// Empty status means working tracker, not intermediate
 				continue
 			}
 
-			statusLower := strings.ToLower(status)
-			for _, v := range trackerIntermediateStatuses {
-				if strings.Contains(statusLower, v) {
-					return true
-				}
+			trackerLower := strings.ToLower(ParseTrackerDomain(trackerURL))
+			if containsStatus(strings.ToLower(status), intermediateStatusesFor(trackerLower)) {
+				return true
 			}
 		}
 		return false
@@ -230,9 +289,26 @@ func (t *Torrent) IsIntermediateStatus() bool {
 		return false
 	}
 
-	status := strings.ToLower(t.TrackerStatus)
-	for _, v := range trackerIntermediateStatuses {
-		if strings.Contains(status, v) {
+	return containsStatus(strings.ToLower(t.TrackerStatus), intermediateStatusesFor(strings.ToLower(t.TrackerName)))
+}
+
+// upgradeStatuses are the unregistered statuses meaning the torrent was replaced by a better version
+var upgradeStatuses = newStatusMap([]string{"trump", "upgraded"})
+
+// IsUpgraded reports whether a tracker status says the torrent was trumped or upgraded
+func (t *Torrent) IsUpgraded() bool {
+	for _, status := range t.AllTrackerStatuses {
+		if containsStatus(strings.ToLower(status), upgradeStatuses) {
+			return true
+		}
+	}
+
+	return containsStatus(strings.ToLower(t.TrackerStatus), upgradeStatuses)
+}
+
+func containsStatus(statusLower string, statuses map[string]struct{}) bool {
+	for v := range statuses {
+		if strings.Contains(statusLower, v) {
 			return true
 		}
 	}
@@ -240,34 +316,113 @@ func (t *Torrent) IsIntermediateStatus() bool {
 	return false
 }
 
-// InitializeTrackerStatuses prepares the default status map and processes per-tracker overrides.
+func newStatusMap(statuses []string) map[string]struct{} {
+	statusMap := make(map[string]struct{}, len(statuses))
+	for _, status := range statuses {
+		statusMap[strings.ToLower(strings.TrimSpace(status))] = struct{}{}
+	}
+
+	return statusMap
+}
+
+func newTrackerStatusOverrides(perTrackerOverrides map[string][]string) trackerStatusOverrides {
+	overrides := trackerStatusOverrides{
+		statuses: make(map[string]map[string]struct{}, len(perTrackerOverrides)),
+	}
+
+	for tracker, statuses := range perTrackerOverrides {
+		trackerLower := strings.ToLower(strings.TrimSpace(tracker))
+		statusMap := newStatusMap(statuses)
+		overrides.statuses[trackerLower] = statusMap
+
+		for pattern := range strings.SplitSeq(trackerLower, "|") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" {
+				overrides.patterns = append(overrides.patterns, trackerStatusPattern{pattern: pattern, statuses: statusMap})
+			}
+		}
+	}
+
+	// prefer the most specific pattern, falling back to alphabetical order for determinism
+	slices.SortStableFunc(overrides.patterns, func(a, b trackerStatusPattern) int {
+		return cmp.Or(cmp.Compare(len(b.pattern), len(a.pattern)), cmp.Compare(a.pattern, b.pattern))
+	})
+
+	return overrides
+}
+
+// lookup returns the statuses for a lowercased tracker name. Override keys are matched exactly first, then as a
+// domain suffix (tracker.com matches announce.tracker.com) or glob pattern (tracker*), and a key can list several
+// of these separated by "|" (tracker.com|tracker2.net).
+func (o trackerStatusOverrides) lookup(trackerLower string) (map[string]struct{}, bool) {
+	if specificMap, ok := o.statuses[trackerLower]; ok {
+		return specificMap, true
+	}
+
+	if trackerLower == "" {
+		return nil, false
+	}
+
+	// an alternative naming the tracker exactly wins over the suffix and glob matches of the others
+	for _, p := range o.patterns {
+		if p.pattern == trackerLower {
+			return p.statuses, true
+		}
+	}
+
+	for _, p := range o.patterns {
+		if strings.HasSuffix(trackerLower, "."+p.pattern) {
+			return p.statuses, true
+		}
+
+		if matched, err := path.Match(p.pattern, trackerLower); err == nil && matched {
+			return p.statuses, true
+		}
+	}
+
+	return nil, false
+}
+
+// InitializeTrackerStatuses prepares the default status maps and processes the per-tracker overrides.
 // It should be called once after configuration is loaded.
-func InitializeTrackerStatuses(perTrackerOverrides map[string][]string) {
+func InitializeTrackerStatuses(cfg TrackerErrorsConfig) {
 	log := logger.GetLogger("cfg")
 
-	// Prepare the default map (lowercase).
-	defaultUnregisteredStatusesMap = make(map[string]struct{}, len(defaultUnregisteredStatuses))
-	for _, status := range defaultUnregisteredStatuses {
-		defaultUnregisteredStatusesMap[strings.ToLower(strings.TrimSpace(status))] = struct{}{}
-	}
+	// Prepare the default maps (lowercase).
+	defaultUnregisteredStatusesMap = newStatusMap(defaultUnregisteredStatuses)
 	log.Debugf("Initialized default unregistered statuses: %d entries", len(defaultUnregisteredStatusesMap))
 
+	// configured intermediate statuses are added to the built-in ones
+	defaultIntermediateStatusesMap = newStatusMap(append(slices.Clone(trackerIntermediateStatuses), cfg.IntermediateStatuses...))
+	log.Debugf("Initialized default intermediate statuses: %d entries", len(defaultIntermediateStatusesMap))
+
 	// Process per-tracker overrides.
-	effectiveUnregisteredStatuses = make(map[string]map[string]struct{}, len(perTrackerOverrides))
-	if len(perTrackerOverrides) > 0 {
-		log.Debugf("Processing %d per-tracker unregistered status overrides", len(perTrackerOverrides))
-		for tracker, statuses := range perTrackerOverrides {
-			trackerLower := strings.ToLower(strings.TrimSpace(tracker))
-			statusMap := make(map[string]struct{}, len(statuses))
-			for _, status := range statuses {
-				statusMap[strings.ToLower(strings.TrimSpace(status))] = struct{}{}
-			}
-			effectiveUnregisteredStatuses[trackerLower] = statusMap
-			log.Debugf("Set %d custom unregistered statuses for tracker: %s", len(statusMap), tracker)
-		}
-	} else {
-		log.Debug("No per-tracker unregistered status overrides provided, using defaults for all.")
+	effectiveUnregisteredStatuses = newTrackerStatusOverrides(cfg.PerTrackerUnregisteredStatuses)
+	for tracker, statuses := range effectiveUnregisteredStatuses.statuses {
+		log.Debugf("Set %d custom unregistered statuses for tracker: %s", len(statuses), tracker)
+	}
+
+	effectiveIntermediateStatuses = newTrackerStatusOverrides(cfg.PerTrackerIntermediateStatuses)
+	for tracker, statuses := range effectiveIntermediateStatuses.statuses {
+		log.Debugf("Set %d custom intermediate statuses for tracker: %s", len(statuses), tracker)
+	}
+}
+
+// unregisteredStatusesFor returns the unregistered statuses for a lowercased tracker name
+func unregisteredStatusesFor(trackerLower string) map[string]struct{} {
+	if statuses, ok := effectiveUnregisteredStatuses.lookup(trackerLower); ok {
+		return statuses
+	}
+
+	return defaultUnregisteredStatusesMap
+}
+
+// intermediateStatusesFor returns the intermediate statuses for a lowercased tracker name
+func intermediateStatusesFor(trackerLower string) map[string]struct{} {
+	if statuses, ok := effectiveIntermediateStatuses.lookup(trackerLower); ok {
+		return statuses
 	}
+
+	return defaultIntermediateStatusesMap
 }
 
 func (t *Torrent) IsUnregistered(ctx context.Context) bool {
@@ -281,6 +436,12 @@ func (t *Torrent) IsUnregistered(ctx context.Context) bool {
 		return false
 	}
 
+	// trackerless private torrents (often a cross-seed whose tracker was removed) can be treated as unregistered
+	if t.IsPrivate && t.HasNoTrackers() && Config != nil && Config.TrackerErrors.TrackerlessPrivateUnregistered {
+		t.RegistrationState = UnregisteredState
+		return true
+	}
+
 	// If we have multiple tracker statuses, check them
 	if len(t.AllTrackerStatuses) > 0 {
 		if t.IsIntermediateStatus() {
@@ -295,25 +456,15 @@ func (t *Torrent) IsUnregistered(ctx context.Context) bool {
 
 		// Check if ANY tracker reports unregistered status
 		for trackerURL, status := range t.AllTrackerStatuses {
-			if status == "" {
-				continue
-			}
-
-			statusLower := strings.ToLower(status)
-			trackerDomain := ParseTrackerDomain(trackerURL)
-			trackerLower := strings.ToLower(trackerDomain)
-
-			statusMapToCheck := defaultUnregisteredStatusesMap
-			if specificMap, ok := effectiveUnregisteredStatuses[trackerLower]; ok {
-				statusMapToCheck = specificMap
-			}
-
-			for unregStatus := range statusMapToCheck {
-				if strings.Contains(statusLower, unregStatus) {
-					// At least one tracker reports unregistered
-					t.RegistrationState = UnregisteredState
-					return true
+			if isUnregisteredStatus(ParseTrackerDomain(trackerURL), status) {
+				// the tracker api has the final say when it is preferred over the status message
+				if tr := t.authoritativeTracker(); tr != nil {
+					return t.isUnregisteredByAPI(ctx, tr)
 				}
+
+				// At least one tracker reports unregistered
+				t.RegistrationState = UnregisteredState
+				return true
 			}
 		}
 
@@ -338,65 +489,282 @@ func (t *Torrent) IsUnregistered(ctx context.Context) bool {
 		return false
 	}
 
-	// check configured unregistered statuses using exact, case-insensitive match.
+	// the tracker api has the final say when it is preferred over the status message
+	if tr := t.authoritativeTracker(); tr != nil {
+		return t.isUnregisteredByAPI(ctx, tr)
+	}
+
+	// check configured unregistered statuses, case-insensitive.
 	// Use per-tracker list if available, otherwise use defaults.
-	statusLower := strings.ToLower(t.TrackerStatus)
-	trackerLower := strings.ToLower(t.TrackerName)
+	if isUnregisteredStatus(t.TrackerName, t.TrackerStatus) {
+		t.RegistrationState = UnregisteredState
+		return true
+	}
+
+	// check tracker api (if available)
+	if tr := trackerGet(t.TrackerName); tr != nil {
+		return t.isUnregisteredByAPI(ctx, tr)
+	}
 
-	statusMapToCheck := defaultUnregisteredStatusesMap // Default to the global defaults
-	if specificMap, ok := effectiveUnregisteredStatuses[trackerLower]; ok {
-		statusMapToCheck = specificMap // Override with tracker-specific map if it exists
+	t.RegistrationState = RegisteredState
+	return false
+}
+
+// isUnregisteredStatus reports whether status, reported by the tracker with domain, contains one of the unregistered
+// statuses of the tracker
+func isUnregisteredStatus(domain string, status string) bool {
+	if status == "" {
+		return false
 	}
 
-	for status := range statusMapToCheck {
-		if strings.Contains(statusLower, status) {
-			t.RegistrationState = UnregisteredState
+	statusLower := strings.ToLower(status)
+	for unregStatus := range unregisteredStatusesFor(strings.ToLower(domain)) {
+		if strings.Contains(statusLower, unregStatus) {
 			return true
 		}
 	}
 
-	// check tracker api (if available)
-	if tr := tracker.Get(t.TrackerName); tr != nil {
-		tt := &tracker.Torrent{
-			Hash:              t.Hash,
-			Name:              t.Name,
-			TotalBytes:        t.TotalBytes,
-			DownloadedBytes:   t.DownloadedBytes,
-			State:             t.State,
-			Downloaded:        t.Downloaded,
-			Seeding:           t.Seeding,
-			TrackerName:       t.TrackerName,
-			TrackerStatus:     t.TrackerStatus,
-			Comment:           t.Comment,
-			APIDividerPrinted: t.APIDividerPrinted,
+	return false
+}
+
+// UnregisteredTrackerCount returns the number of trackers whose status is an unregistered status, going by the
+// status messages only. Torrents without AllTrackerStatuses count their single tracker status
+func (t *Torrent) UnregisteredTrackerCount() int {
+	if len(t.AllTrackerStatuses) == 0 {
+		if isUnregisteredStatus(t.TrackerName, t.TrackerStatus) {
+			return 1
 		}
+		return 0
+	}
 
-		trackerName := tr.Name()
-		err, ur := tr.IsUnregistered(ctx, tt)
-		if err != nil {
-			log.Errorf("Error checking unregistered tracker status of %s (hash: %s) using %s API: %v", t.Name, t.Hash, trackerName, err)
-			return false
+	count := 0
+	for trackerURL, status := range t.AllTrackerStatuses {
+		if isUnregisteredStatus(ParseTrackerDomain(trackerURL), status) {
+			count++
 		}
+	}
 
-		t.APIDividerPrinted = tt.APIDividerPrinted
+	return count
+}
 
-		if ur {
-			log.Debugf("%s (hash: %s) confirmed as unregistered by %s API", t.Name, t.Hash, trackerName)
-			t.RegistrationState = UnregisteredState
+// UnregisteredOnAllTrackers reports whether every tracker of the torrent reports an unregistered status, a tracker
+// without a status yet does not agree
+func (t *Torrent) UnregisteredOnAllTrackers() bool {
+	trackers := max(len(t.AllTrackerStatuses), 1)
+	return t.UnregisteredTrackerCount() == trackers
+}
 
-			return true
-		}
+// isUnregisteredByAPI checks the registration of the torrent using the tracker API
+func (t *Torrent) isUnregisteredByAPI(ctx context.Context, tr tracker.Interface) bool {
+	tt := &tracker.Torrent{
+		Hash:              t.Hash,
+		Name:              t.Name,
+		TotalBytes:        t.TotalBytes,
+		DownloadedBytes:   t.DownloadedBytes,
+		State:             t.State,
+		Downloaded:        t.Downloaded,
+		Seeding:           t.Seeding,
+		TrackerName:       t.TrackerName,
+		TrackerStatus:     t.TrackerStatus,
+		Comment:           t.Comment,
+		APIDividerPrinted: t.APIDividerPrinted,
+	}
+
+	trackerName := tr.Name()
+	err, ur := tr.IsUnregistered(ctx, tt)
+	if err != nil {
+		log.Errorf("Error checking unregistered tracker status of %s (hash: %s) using %s API: %v", t.Name, t.Hash, trackerName, err)
+		return false
+	}
 
-		log.Debugf("%s (hash: %s) not reported as unregistered by %s API", t.Name, t.Hash, trackerName)
-		t.RegistrationState = RegisteredState
+	t.APIDividerPrinted = tt.APIDividerPrinted
 
-		return false
+	if ur {
+		log.Debugf("%s (hash: %s) confirmed as unregistered by %s API", t.Name, t.Hash, trackerName)
+		t.RegistrationState = UnregisteredState
+
+		return true
 	}
 
+	log.Debugf("%s (hash: %s) not reported as unregistered by %s API", t.Name, t.Hash, trackerName)
 	t.RegistrationState = RegisteredState
+
 	return false
 }
 
+// CheckRegistrationByAPI decides the registration of the torrent with the API of its tracker alone, for torrents whose
+// tracker messages are not current, e.g. paused ones. It returns false when the tracker has no API or the check failed,
+// leaving the registration undecided
+func (t *Torrent) CheckRegistrationByAPI(ctx context.Context) bool {
+	tr := trackerGet(t.TrackerName)
+	if tr == nil {
+		return false
+	}
+
+	t.RegistrationState = NoRegistrationState
+	t.isUnregisteredByAPI(ctx, tr)
+	return t.RegistrationState != NoRegistrationState
+}
+
+// authoritativeTracker returns the tracker API of the torrent when its verdict is required to treat it as unregistered
+func (t *Torrent) authoritativeTracker() tracker.Interface {
+	if Config == nil || !Config.TrackerErrors.PreferTrackerAPI {
+		return nil
+	}
+
+	return trackerGet(t.TrackerName)
+}
+
+// ShiftClock recomputes the time based fields as if they were evaluated d later (or earlier when negative),
+// seeding time only advances for torrents that are currently seeding
+func (t *Torrent) ShiftClock(d time.Duration) {
+	secs := int64(d.Seconds())
+
+	t.AddedSeconds = max(t.AddedSeconds+secs, 0)
+	t.AddedHours = float32(t.AddedSeconds) / 60 / 60
+	t.AddedDays = float32(t.AddedSeconds) / 60 / 60 / 24
+
+	if t.Seeding || secs < 0 {
+		t.SeedingSeconds = max(t.SeedingSeconds+secs, 0)
+	}
+	t.SeedingHours = float32(t.SeedingSeconds) / 60 / 60
+	t.SeedingDays = float32(t.SeedingSeconds) / 60 / 60 / 24
+
+	t.LastActivitySeconds = max(t.LastActivitySeconds+secs, 0)
+	t.LastActivityHours = float32(t.LastActivitySeconds) / 60 / 60
+	t.LastActivityDays = float32(t.LastActivitySeconds) / 60 / 60 / 24
+
+	if t.CreatedSeconds > 0 {
+		t.CreatedSeconds = max(t.CreatedSeconds+secs, 0)
+		t.CreatedDays = float32(t.CreatedSeconds) / 60 / 60 / 24
+	}
+}
+
+// MeetsTrackerRequirement reports whether the torrent has met the seeding requirement configured for its tracker,
+// either the minimum ratio or the minimum seed days. Torrents of trackers without requirements always meet them.
+func (t *Torrent) MeetsTrackerRequirement() bool {
+	if Config == nil {
+		return true
+	}
+
+	req, ok := Config.TrackerRequirements[strings.ToLower(t.TrackerName)]
+	if !ok || (req.MinRatio <= 0 && req.MinSeedDays <= 0) {
+		return true
+	}
+
+	if req.MinRatio > 0 && t.Ratio >= req.MinRatio {
+		return true
+	}
+
+	return req.MinSeedDays > 0 && t.SeedingDays >= req.MinSeedDays
+}
+
+// TrackerKeepsData reports whether the policy of the torrent's tracker keeps the data of the torrent when it is removed
+func (t *Torrent) TrackerKeepsData() bool {
+	if Config == nil {
+		return false
+	}
+
+	policy, ok := Config.TrackerPolicies[strings.ToLower(t.TrackerName)]
+	return ok && policy.DeleteData != nil && !*policy.DeleteData
+}
+
+// BypassesIgnore reports whether an ignored torrent should still be evaluated for removal, which depends on the
+// effective precedence: never with ignore_wins, always with remove_wins and for unregistered torrents with
+// bypass_if_unregistered. Exempted torrents are never evaluated
+func (t *Torrent) BypassesIgnore(ctx context.Context) bool {
+	if Config == nil {
+		return false
+	}
+
+	precedence := Config.EffectivePrecedence()
+	if precedence == PrecedenceIgnoreWins {
+		return false
+	}
+
+	exempt := Config.BypassIgnoreExemptions
+	if evaluate.StringSliceContains(exempt.Trackers, t.TrackerName, true) ||
+		evaluate.StringSliceContains(exempt.Labels, t.Label, true) ||
+		t.HasAnyTag(exempt.Tags...) {
+		return false
+	}
+
+	return precedence == PrecedenceRemoveWins || t.IsUnregistered(ctx)
+}
+
+// SkipRemovalAnnounce reports whether the pause/re-announce before removing the torrent can be skipped, which is
+// the case for stopped or unregistered torrents when removal_announce.skip_inactive is enabled for its tracker
+func (t *Torrent) SkipRemovalAnnounce(ctx context.Context) bool {
+	if Config == nil || !Config.RemovalAnnounce.SkipInactive {
+		return false
+	}
+
+	if trackers := Config.RemovalAnnounce.Trackers; len(trackers) > 0 &&
+		!evaluate.StringSliceContains(trackers, t.TrackerName, true) {
+		return false
+	}
+
+	return t.NormalizedState() == StatePaused || t.IsUnregistered(ctx)
+}
+
+// SharesFilesWithRegistered reports whether a torrent of another tracker sharing files with t (a cross-seed) is
+// registered, false when the command did not map the torrents sharing files
+func (t *Torrent) SharesFilesWithRegistered(ctx context.Context) bool {
+	if t.SharingFiles == nil {
+		return false
+	}
+
+	for _, other := range t.SharingFiles() {
+		if other.TrackerName == "" || strings.EqualFold(other.TrackerName, t.TrackerName) {
+			continue
+		}
+
+		if !other.IsUnregistered(ctx) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsError reports whether the client reports the torrent in an error state, e.g. missing files or a full disk
+func (t *Torrent) IsError() bool {
+	return t.NormalizedState() == StateError
+}
+
+// IsMoving reports whether the client is moving the torrent's files to a new location
+func (t *Torrent) IsMoving() bool {
+	return t.NormalizedState() == StateMoving
+}
+
+// HasNoTrackers reports whether the torrent has no trackers besides DHT/LSD/PeX
+func (t *Torrent) HasNoTrackers() bool {
+	return t.TrackerCount == 0 && t.TrackerName == ""
+}
+
+// NormalizedState maps the client specific state to one of the normalized State constants
+func (t *Torrent) NormalizedState() string {
+	switch strings.ToLower(t.State) {
+	// qbittorrent (stopped* on v5+) / deluge
+	case "pausedup", "pauseddl", "stoppedup", "stoppeddl", "paused":
+		return StatePaused
+	case "downloading", "metadl", "forcedmetadl", "forceddl", "stalleddl", "allocating":
+		return StateDownloading
+	case "uploading", "stalledup", "forcedup", "seeding":
+		return StateSeeding
+	case "queuedup", "queueddl", "queued":
+		return StateQueued
+	case "checkingup", "checkingdl", "checkingresumedata", "checking":
+		return StateChecking
+	case "moving":
+		return StateMoving
+	case "error", "missingfiles":
+		return StateError
+	default:
+		return StateUnknown
+	}
+}
+
 func (t *Torrent) HasAllTags(tags ...string) bool {
 	for _, v := range tags {
 		if !evaluate.StringSliceContains(t.Tags, v, true) {
@@ -417,6 +785,77 @@ func (t *Torrent) HasAnyTag(tags ...string) bool {
 	return false
 }
 
+// RatioPerDay returns the ratio gained per day of seeding, 0 for torrents that have not seeded yet
+func (t *Torrent) RatioPerDay() float64 {
+	if t.SeedingDays <= 0 {
+		return 0
+	}
+
+	return t.Ratio / float64(t.SeedingDays)
+}
+
+// IsWellSeeded reports whether the torrent has at least threshold seeds, as last reported by the client
+func (t *Torrent) IsWellSeeded(threshold int) bool {
+	return t.Seeds >= int64(threshold)
+}
+
+// IsRare reports whether the torrent has fewer than threshold seeds, as last reported by the client
+func (t *Torrent) IsRare(threshold int) bool {
+	return t.Seeds < int64(threshold)
+}
+
+// IsAvailable reports whether the connected peers hold at least one full copy of the torrent, so it can complete
+func (t *Torrent) IsAvailable() bool {
+	return t.Availability >= 1.0
+}
+
+// SizeGB returns the total size of the torrent in GB (10^9 bytes)
+func (t *Torrent) SizeGB() float64 {
+	return float64(t.TotalBytes) / 1e9
+}
+
+// SizeGiB returns the total size of the torrent in GiB (2^30 bytes)
+func (t *Torrent) SizeGiB() float64 {
+	return float64(t.TotalBytes) / (1 << 30)
+}
+
+func (t *Torrent) TagCount() int {
+	return len(t.Tags)
+}
+
+// TagValue returns the rest of the first tag starting with prefix (case-insensitive), e.g. "5" for TagValue("ratio:")
+// with the tag "ratio:5", or an empty string when no tag has the prefix
+func (t *Torrent) TagValue(prefix string) string {
+	for _, tag := range t.Tags {
+		if len(tag) >= len(prefix) && strings.EqualFold(tag[:len(prefix)], prefix) {
+			return tag[len(prefix):]
+		}
+	}
+
+	return ""
+}
+
+// PathHasPrefix reports whether the torrent's save path is prefix or inside it, e.g. "/data/tv" matches "/data/tv/hd"
+// but not "/data/tv-4k". Paths are compared case-insensitively, treating / and \ as the same separator.
+func (t *Torrent) PathHasPrefix(prefix string) bool {
+	path, prefix := normalizePath(t.Path), strings.TrimSuffix(normalizePath(prefix), "/")
+	if prefix == "" {
+		return true
+	}
+
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// PathContains reports whether the torrent's save path contains substr, compared like PathHasPrefix
+func (t *Torrent) PathContains(substr string) bool {
+	return strings.Contains(normalizePath(t.Path), normalizePath(substr))
+}
+
+// normalizePath lowercases a path and uses / as its separator, so unix and windows paths compare equal
+func normalizePath(path string) string {
+	return strings.ToLower(strings.ReplaceAll(path, `\`, "/"))
+}
+
 func (t *Torrent) HasMissingFiles() bool {
 	if !t.Downloaded {
 		return false
@@ -442,6 +881,17 @@ func (t *Torrent) HasMissingFiles() bool {
 	return false
 }
 
+// DataPathExists reports whether the torrent's save path can be read, a single stat to detect a missing mount without
+// checking every file like HasMissingFiles. It is false when the client reported no save path
+func (t *Torrent) DataPathExists() bool {
+	if t.Path == "" {
+		return false
+	}
+
+	_, err := os.Stat(t.Path)
+	return err == nil
+}
+
 func (t *Torrent) Log(n float64) float64 {
 	return math.Log(n)
 }
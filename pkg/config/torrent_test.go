@@ -2,9 +2,14 @@ package config
 
 import (
 	"context"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/tracker"
 )
 
 func TestTorrent_IsTrackerDown(t *testing.T) {
@@ -209,9 +214,64 @@ func TestTorrent_IsIntermediateStatus(t *testing.T) {
 	}
 }
 
+func TestTorrent_IsIntermediateStatus_Configured(t *testing.T) {
+	InitializeTrackerStatuses(TrackerErrorsConfig{
+		IntermediateStatuses: []string{"Pending Approval"},
+		PerTrackerIntermediateStatuses: map[string][]string{
+			"slow.org": {"not registered yet"},
+		},
+	})
+	t.Cleanup(func() { InitializeTrackerStatuses(TrackerErrorsConfig{}) })
+
+	tests := []struct {
+		name                 string
+		torrent              Torrent
+		expectedIntermediate bool
+		expectedUnreg        bool
+	}{
+		{
+			name:                 "global_status",
+			torrent:              Torrent{TrackerName: "tracker.com", TrackerStatus: "pending approval"},
+			expectedIntermediate: true,
+		},
+		{
+			name:                 "builtin_status_kept",
+			torrent:              Torrent{TrackerName: "tracker.com", TrackerStatus: "torrent has been postponed"},
+			expectedIntermediate: true,
+		},
+		{
+			// the per-tracker status would otherwise match the default "not registered" unregistered status
+			name:                 "per_tracker_status",
+			torrent:              Torrent{TrackerName: "slow.org", TrackerStatus: "Torrent not registered yet"},
+			expectedIntermediate: true,
+		},
+		{
+			name:                 "per_tracker_status_subdomain",
+			torrent:              Torrent{AllTrackerStatuses: map[string]string{"https://announce.slow.org/announce": "torrent not registered yet"}},
+			expectedIntermediate: true,
+		},
+		{
+			name:          "per_tracker_status_other_tracker",
+			torrent:       Torrent{TrackerName: "tracker.com", TrackerStatus: "torrent not registered yet"},
+			expectedUnreg: true,
+		},
+		{
+			name:    "per_tracker_replaces_defaults",
+			torrent: Torrent{TrackerName: "slow.org", TrackerStatus: "pending approval"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedIntermediate, tt.torrent.IsIntermediateStatus())
+			assert.Equal(t, tt.expectedUnreg, tt.torrent.IsUnregistered(context.Background()))
+		})
+	}
+}
+
 func TestTorrent_IsUnregistered(t *testing.T) {
 	// Initialize the tracker statuses for tests
-	InitializeTrackerStatuses(nil)
+	InitializeTrackerStatuses(TrackerErrorsConfig{})
 
 	tests := []struct {
 		name          string
@@ -369,7 +429,7 @@ func TestTorrent_IsUnregistered_PerTrackerOverrides(t *testing.T) {
 		"specialtracker.com": {"custom unregistered message", "special removal reason"},
 		"anothertracker.com": {"different error", "unique status"},
 	}
-	InitializeTrackerStatuses(perTrackerOverrides)
+	InitializeTrackerStatuses(TrackerErrorsConfig{PerTrackerUnregisteredStatuses: perTrackerOverrides})
 
 	tests := []struct {
 		name          string
@@ -434,5 +494,687 @@ func TestTorrent_IsUnregistered_PerTrackerOverrides(t *testing.T) {
 	}
 
 	// Reset to default for other tests
-	InitializeTrackerStatuses(nil)
+	InitializeTrackerStatuses(TrackerErrorsConfig{})
+}
+
+func TestTrackerStatusOverrides_Lookup(t *testing.T) {
+	overrides := newTrackerStatusOverrides(map[string][]string{
+		"tracker.com|averylongothertrackername.net": {"broad"},
+		"eu.tracker.com": {"specific"},
+		"tracker*":       {"glob"},
+	})
+
+	tests := []struct {
+		tracker  string
+		expected string
+		found    bool
+	}{
+		// the alternatives are ordered on their own, not by the length of the whole key
+		{tracker: "announce.eu.tracker.com", expected: "specific", found: true},
+		{tracker: "announce.tracker.com", expected: "broad", found: true},
+		{tracker: "averylongothertrackername.net", expected: "broad", found: true},
+		{tracker: "tracker.com", expected: "broad", found: true},
+		{tracker: "trackerx.org", expected: "glob", found: true},
+		{tracker: "other.org", found: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tracker, func(t *testing.T) {
+			statuses, ok := overrides.lookup(tt.tracker)
+			require.Equal(t, tt.found, ok)
+			if tt.found {
+				assert.Contains(t, statuses, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTorrent_NormalizedState(t *testing.T) {
+	tests := []struct {
+		state    string
+		expected string
+	}{
+		{state: "pausedUP", expected: StatePaused},
+		{state: "stoppedDL", expected: StatePaused},
+		{state: "Paused", expected: StatePaused},
+		{state: "stalledUP", expected: StateSeeding},
+		{state: "Seeding", expected: StateSeeding},
+		{state: "metaDL", expected: StateDownloading},
+		{state: "queuedUP", expected: StateQueued},
+		{state: "checkingResumeData", expected: StateChecking},
+		{state: "missingFiles", expected: StateError},
+		{state: "moving", expected: StateMoving},
+		{state: "Moving", expected: StateMoving}, // deluge
+		{state: "", expected: StateUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			torrent := Torrent{State: tt.state}
+			assert.Equal(t, tt.expected, torrent.NormalizedState())
+		})
+	}
+}
+
+func TestTorrent_IsError(t *testing.T) {
+	tests := []struct {
+		state    string
+		expected bool
+	}{
+		{state: "error", expected: true},
+		{state: "missingFiles", expected: true},
+		{state: "Error", expected: true}, // deluge
+		{state: "stalledUP", expected: false},
+		{state: "checkingUP", expected: false},
+		{state: "Paused", expected: false},
+		{state: "", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			torrent := Torrent{State: tt.state}
+			assert.Equal(t, tt.expected, torrent.IsError())
+		})
+	}
+}
+
+func TestTorrent_PathHelpers(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		prefix   string
+		contains string
+		expected bool
+	}{
+		{name: "unix_prefix", path: "/data/torrents/tv/Show", prefix: "/data/torrents/tv", expected: true},
+		{name: "unix_prefix_trailing_separator", path: "/data/torrents/tv", prefix: "/data/torrents/tv/", expected: true},
+		{name: "unix_prefix_case", path: "/Data/Torrents/TV", prefix: "/data/torrents/tv", expected: true},
+		{name: "unix_prefix_partial_segment", path: "/data/torrents/tv-4k", prefix: "/data/torrents/tv", expected: false},
+		{name: "unix_prefix_other", path: "/data/movies", prefix: "/data/torrents", expected: false},
+		{name: "windows_prefix", path: `D:\Torrents\TV\Show`, prefix: `d:\torrents\tv`, expected: true},
+		{name: "windows_path_unix_prefix", path: `D:\Torrents\TV`, prefix: "D:/Torrents", expected: true},
+		{name: "windows_prefix_partial_segment", path: `D:\Torrents\TV-4K`, prefix: `D:\Torrents\TV`, expected: false},
+		{name: "unix_contains", path: "/data/torrents/tv/Show", contains: "/tv/", expected: true},
+		{name: "unix_contains_case", path: "/data/Torrents/TV", contains: "torrents/tv", expected: true},
+		{name: "windows_contains_unix_separator", path: `D:\Torrents\TV\Show`, contains: "torrents/tv", expected: true},
+		{name: "unix_contains_windows_separator", path: "/data/torrents/tv", contains: `torrents\tv`, expected: true},
+		{name: "contains_missing", path: "/data/torrents/tv", contains: "movies", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			torrent := Torrent{Path: tt.path}
+			if tt.prefix != "" {
+				assert.Equal(t, tt.expected, torrent.PathHasPrefix(tt.prefix))
+			} else {
+				assert.Equal(t, tt.expected, torrent.PathContains(tt.contains))
+			}
+		})
+	}
+}
+
+func TestTorrent_TagHelpers(t *testing.T) {
+	torrent := Torrent{Tags: []string{"cross-seed", "Ratio:5", "ratio:10", "seeded:30d"}}
+
+	assert.Equal(t, 4, torrent.TagCount())
+	assert.Equal(t, 0, (&Torrent{}).TagCount())
+
+	tests := []struct {
+		prefix   string
+		expected string
+	}{
+		{prefix: "ratio:", expected: "5"},
+		{prefix: "RATIO:", expected: "5"},
+		{prefix: "seeded:", expected: "30d"},
+		{prefix: "cross-seed", expected: ""},
+		{prefix: "missing:", expected: ""},
+		{prefix: "seeded:30d-and-more", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			assert.Equal(t, tt.expected, torrent.TagValue(tt.prefix))
+		})
+	}
+}
+
+func TestTorrent_RatioPerDay(t *testing.T) {
+	tests := []struct {
+		name        string
+		ratio       float64
+		seedingDays float32
+		expected    float64
+	}{
+		{name: "seeding", ratio: 2, seedingDays: 10, expected: 0.2},
+		{name: "no_upload", ratio: 0, seedingDays: 10, expected: 0},
+		{name: "not_seeded", ratio: 1.5, seedingDays: 0, expected: 0},
+		{name: "negative_seeding_days", ratio: 1.5, seedingDays: -1, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			torrent := Torrent{Ratio: tt.ratio, SeedingDays: tt.seedingDays}
+			assert.InDelta(t, tt.expected, torrent.RatioPerDay(), 0.0001)
+		})
+	}
+}
+
+func TestTorrent_SeedHelpers(t *testing.T) {
+	tests := []struct {
+		name       string
+		seeds      int64
+		wellSeeded bool
+		rare       bool
+	}{
+		{name: "no_seeds", seeds: 0, wellSeeded: false, rare: true},
+		{name: "below", seeds: 4, wellSeeded: false, rare: true},
+		{name: "threshold", seeds: 5, wellSeeded: true, rare: false},
+		{name: "above", seeds: 50, wellSeeded: true, rare: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			torrent := Torrent{Seeds: tt.seeds}
+			assert.Equal(t, tt.wellSeeded, torrent.IsWellSeeded(5))
+			assert.Equal(t, tt.rare, torrent.IsRare(5))
+		})
+	}
+}
+
+func TestTorrent_IsAvailable(t *testing.T) {
+	for availability, expected := range map[float32]bool{-1: false, 0: false, 0.6: false, 1: true, 3.2: true} {
+		torrent := Torrent{Availability: availability}
+		assert.Equal(t, expected, torrent.IsAvailable(), "availability %v", availability)
+	}
+}
+
+func TestTorrent_ShiftClock(t *testing.T) {
+	day := int64(24 * 60 * 60)
+
+	seeding := Torrent{Seeding: true, AddedSeconds: 2 * day, SeedingSeconds: day, LastActivitySeconds: 0, CreatedSeconds: 10 * day}
+	seeding.ShiftClock(24 * time.Hour)
+	assert.Equal(t, float32(11), seeding.CreatedDays)
+	assert.Equal(t, float32(3), seeding.AddedDays)
+	assert.Equal(t, float32(2), seeding.SeedingDays)
+	assert.Equal(t, float32(1), seeding.LastActivityDays)
+
+	// seeding time does not advance for torrents that are not seeding
+	paused := Torrent{AddedSeconds: 2 * day, SeedingSeconds: day}
+	paused.ShiftClock(24 * time.Hour)
+	assert.Equal(t, float32(3), paused.AddedDays)
+	assert.Zero(t, paused.CreatedSeconds, "an unknown creation date stays unknown")
+	assert.Equal(t, float32(1), paused.SeedingDays)
+
+	// shifting into the past never goes negative
+	paused.ShiftClock(-10 * 24 * time.Hour)
+	assert.Equal(t, int64(0), paused.AddedSeconds)
+	assert.Equal(t, int64(0), paused.SeedingSeconds)
+}
+
+func TestTorrent_TrackerlessPrivateUnregistered(t *testing.T) {
+	orig := Config
+	t.Cleanup(func() { Config = orig })
+
+	tests := []struct {
+		name     string
+		enabled  bool
+		torrent  Torrent
+		expected bool
+	}{
+		{name: "disabled", torrent: Torrent{IsPrivate: true}, expected: false},
+		{name: "private_trackerless", enabled: true, torrent: Torrent{IsPrivate: true}, expected: true},
+		{name: "public_trackerless", enabled: true, torrent: Torrent{IsPublic: true}, expected: false},
+		{
+			name:     "private_with_tracker",
+			enabled:  true,
+			torrent:  Torrent{IsPrivate: true, TrackerName: "tracker.com", TrackerCount: 1, TrackerStatus: "Working"},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Config = &Configuration{TrackerErrors: TrackerErrorsConfig{TrackerlessPrivateUnregistered: tt.enabled}}
+
+			assert.Equal(t, tt.expected, tt.torrent.IsUnregistered(context.Background()))
+		})
+	}
+}
+
+func TestTorrent_UnregisteredTrackerCount(t *testing.T) {
+	InitializeTrackerStatuses(TrackerErrorsConfig{})
+
+	tests := []struct {
+		name          string
+		torrent       Torrent
+		expectedCount int
+		expectedAll   bool
+	}{
+		{
+			name: "one_tracker_unregistered",
+			torrent: Torrent{
+				AllTrackerStatuses: map[string]string{
+					"http://tracker1.com/announce": "Working",
+					"http://tracker2.com/announce": "unregistered torrent",
+					"http://tracker3.com/announce": "Active",
+				},
+			},
+			expectedCount: 1,
+			expectedAll:   false,
+		},
+		{
+			name: "all_trackers_unregistered",
+			torrent: Torrent{
+				AllTrackerStatuses: map[string]string{
+					"http://tracker1.com/announce": "torrent not found",
+					"http://tracker2.com/announce": "UNREGISTERED TORRENT",
+				},
+			},
+			expectedCount: 2,
+			expectedAll:   true,
+		},
+		{
+			name: "tracker_without_status",
+			torrent: Torrent{
+				AllTrackerStatuses: map[string]string{
+					"http://tracker1.com/announce": "unregistered",
+					"http://tracker2.com/announce": "",
+				},
+			},
+			expectedCount: 1,
+			expectedAll:   false,
+		},
+		{
+			name: "all_trackers_down",
+			torrent: Torrent{
+				AllTrackerStatuses: map[string]string{
+					"http://tracker1.com/announce": "Connection failed",
+					"http://tracker2.com/announce": "timeout",
+				},
+			},
+			expectedCount: 0,
+			expectedAll:   false,
+		},
+		{
+			name:          "nil_AllTrackerStatuses_unregistered",
+			torrent:       Torrent{TrackerName: "tracker.com", TrackerStatus: "unregistered torrent"},
+			expectedCount: 1,
+			expectedAll:   true,
+		},
+		{
+			name:          "nil_AllTrackerStatuses_working",
+			torrent:       Torrent{TrackerName: "tracker.com", TrackerStatus: "Working"},
+			expectedCount: 0,
+			expectedAll:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedCount, tt.torrent.UnregisteredTrackerCount())
+			assert.Equal(t, tt.expectedAll, tt.torrent.UnregisteredOnAllTrackers())
+		})
+	}
+}
+
+func TestTorrent_BypassesIgnore(t *testing.T) {
+	InitializeTrackerStatuses(TrackerErrorsConfig{})
+
+	orig := Config
+	t.Cleanup(func() { Config = orig })
+
+	exemptions := BypassIgnoreExemptions{
+		Trackers: []string{"keep.tracker"},
+		Labels:   []string{"permaseed"},
+		Tags:     []string{"archive"},
+	}
+
+	unregistered := func(tracker, label string, tags ...string) Torrent {
+		return Torrent{TrackerName: tracker, Label: label, Tags: tags, TrackerStatus: "Unregistered torrent"}
+	}
+
+	tests := []struct {
+		name       string
+		bypass     bool
+		precedence string
+		torrent    Torrent
+		expected   bool
+	}{
+		{name: "disabled", torrent: unregistered("other.tracker", "tv"), expected: false},
+		{
+			name:       "ignore_wins_over_bypass",
+			bypass:     true,
+			precedence: PrecedenceIgnoreWins,
+			torrent:    unregistered("other.tracker", "tv"),
+			expected:   false,
+		},
+		{
+			name:       "remove_wins",
+			precedence: PrecedenceRemoveWins,
+			torrent:    Torrent{TrackerName: "other.tracker", TrackerStatus: "Working"},
+			expected:   true,
+		},
+		{
+			name:       "remove_wins_exempt",
+			precedence: PrecedenceRemoveWins,
+			torrent:    Torrent{TrackerName: "keep.tracker", TrackerStatus: "Working"},
+			expected:   false,
+		},
+		{
+			name:       "bypass_if_unregistered",
+			precedence: PrecedenceBypassIfUnregistered,
+			torrent:    unregistered("other.tracker", "tv"),
+			expected:   true,
+		},
+		{name: "unregistered", bypass: true, torrent: unregistered("other.tracker", "tv"), expected: true},
+		{
+			name:     "registered",
+			bypass:   true,
+			torrent:  Torrent{TrackerName: "other.tracker", TrackerStatus: "Working"},
+			expected: false,
+		},
+		{name: "exempt_tracker", bypass: true, torrent: unregistered("KEEP.tracker", "tv"), expected: false},
+		{name: "exempt_label", bypass: true, torrent: unregistered("other.tracker", "Permaseed"), expected: false},
+		{name: "exempt_tag", bypass: true, torrent: unregistered("other.tracker", "tv", "new", "archive"), expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Config = &Configuration{
+				BypassIgnoreIfUnregistered: tt.bypass,
+				Precedence:                 tt.precedence,
+				BypassIgnoreExemptions:     exemptions,
+			}
+
+			assert.Equal(t, tt.expected, tt.torrent.BypassesIgnore(context.Background()))
+		})
+	}
+}
+
+func TestTorrent_SkipRemovalAnnounce(t *testing.T) {
+	InitializeTrackerStatuses(TrackerErrorsConfig{})
+
+	orig := Config
+	t.Cleanup(func() { Config = orig })
+
+	seeding := Torrent{TrackerName: "tracker.com", State: "uploading", TrackerStatus: "Working"}
+	paused := Torrent{TrackerName: "tracker.com", State: "pausedUP", TrackerStatus: "Working"}
+	unregistered := Torrent{TrackerName: "tracker.com", State: "stalledUP", TrackerStatus: "Unregistered torrent"}
+
+	tests := []struct {
+		name     string
+		cfg      RemovalAnnounceConfig
+		torrent  Torrent
+		expected bool
+	}{
+		{name: "disabled", torrent: paused, expected: false},
+		{name: "seeding", cfg: RemovalAnnounceConfig{SkipInactive: true}, torrent: seeding, expected: false},
+		{name: "paused", cfg: RemovalAnnounceConfig{SkipInactive: true}, torrent: paused, expected: true},
+		{name: "unregistered", cfg: RemovalAnnounceConfig{SkipInactive: true}, torrent: unregistered, expected: true},
+		{
+			name:     "listed_tracker",
+			cfg:      RemovalAnnounceConfig{SkipInactive: true, Trackers: []string{"Tracker.com"}},
+			torrent:  paused,
+			expected: true,
+		},
+		{
+			name:     "unlisted_tracker",
+			cfg:      RemovalAnnounceConfig{SkipInactive: true, Trackers: []string{"other.com"}},
+			torrent:  unregistered,
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Config = &Configuration{RemovalAnnounce: tt.cfg}
+
+			assert.Equal(t, tt.expected, tt.torrent.SkipRemovalAnnounce(context.Background()))
+		})
+	}
+}
+
+func TestTorrent_IsUnregistered_PerTrackerPatterns(t *testing.T) {
+	InitializeTrackerStatuses(TrackerErrorsConfig{PerTrackerUnregisteredStatuses: map[string][]string{
+		"tracker.com|tracker2.net": {"multi domain removed"},
+		"glob*.org":                {"glob removed"},
+		"specific.glob1.org":       {"specific removed"},
+	}})
+	t.Cleanup(func() { InitializeTrackerStatuses(TrackerErrorsConfig{}) })
+
+	tests := []struct {
+		name          string
+		trackerName   string
+		status        string
+		expectedUnreg bool
+	}{
+		{name: "first_domain", trackerName: "tracker.com", status: "multi domain removed", expectedUnreg: true},
+		{name: "second_domain", trackerName: "tracker2.net", status: "multi domain removed", expectedUnreg: true},
+		{name: "subdomain_suffix", trackerName: "announce.tracker2.net", status: "multi domain removed", expectedUnreg: true},
+		{name: "override_replaces_defaults", trackerName: "tracker2.net", status: "unregistered", expectedUnreg: false},
+		{name: "glob", trackerName: "glob1.org", status: "glob removed", expectedUnreg: true},
+		{name: "exact_before_glob", trackerName: "specific.glob1.org", status: "glob removed", expectedUnreg: false},
+		{name: "unrelated_domain_uses_defaults", trackerName: "othertracker.com", status: "unregistered", expectedUnreg: true},
+		{name: "suffix_requires_dot", trackerName: "nottracker.com", status: "multi domain removed", expectedUnreg: false},
+	}
+
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			torrent := Torrent{TrackerName: tt.trackerName, TrackerStatus: tt.status}
+			assert.Equal(t, tt.expectedUnreg, torrent.IsUnregistered(ctx))
+		})
+	}
+}
+
+// fakeTrackerAPI is a tracker API for api.tracker.com returning a fixed verdict
+type fakeTrackerAPI struct {
+	unregistered bool
+	down         bool
+	calls        int
+}
+
+func (f *fakeTrackerAPI) Name() string                                 { return "fake" }
+func (f *fakeTrackerAPI) Check(host string) bool                       { return host == "api.tracker.com" }
+func (f *fakeTrackerAPI) IsTrackerDown(*tracker.Torrent) (error, bool) { return nil, f.down }
+
+func (f *fakeTrackerAPI) IsUnregistered(context.Context, *tracker.Torrent) (error, bool) {
+	f.calls++
+	return nil, f.unregistered
+}
+
+func TestTorrent_IsUnregistered_PreferTrackerAPI(t *testing.T) {
+	InitializeTrackerStatuses(TrackerErrorsConfig{})
+
+	origConfig, origGet := Config, trackerGet
+	t.Cleanup(func() { Config, trackerGet = origConfig, origGet })
+
+	tests := []struct {
+		name          string
+		prefer        bool
+		apiUnreg      bool
+		torrent       Torrent
+		expectedUnreg bool
+		expectedCalls int
+	}{
+		{
+			name:          "status_match_without_prefer",
+			torrent:       Torrent{TrackerName: "api.tracker.com", TrackerStatus: "Unregistered torrent"},
+			expectedUnreg: true,
+		},
+		{
+			name:          "status_match_rejected_by_api",
+			prefer:        true,
+			torrent:       Torrent{TrackerName: "api.tracker.com", TrackerStatus: "Unregistered torrent"},
+			expectedCalls: 1,
+		},
+		{
+			name:          "status_match_confirmed_by_api",
+			prefer:        true,
+			apiUnreg:      true,
+			torrent:       Torrent{TrackerName: "api.tracker.com", TrackerStatus: "Unregistered torrent"},
+			expectedUnreg: true,
+			expectedCalls: 1,
+		},
+		{
+			name:   "multiple_trackers_rejected_by_api",
+			prefer: true,
+			torrent: Torrent{
+				TrackerName: "api.tracker.com",
+				AllTrackerStatuses: map[string]string{
+					"https://api.tracker.com/announce": "Unregistered torrent",
+				},
+			},
+			expectedCalls: 1,
+		},
+		{
+			name:   "multiple_trackers_working_skips_api",
+			prefer: true,
+			torrent: Torrent{
+				TrackerName: "api.tracker.com",
+				AllTrackerStatuses: map[string]string{
+					"https://api.tracker.com/announce": "",
+				},
+			},
+		},
+		{
+			name:          "tracker_without_api",
+			prefer:        true,
+			torrent:       Torrent{TrackerName: "other.com", TrackerStatus: "Unregistered torrent"},
+			expectedUnreg: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := &fakeTrackerAPI{unregistered: tt.apiUnreg}
+			trackerGet = func(host string) tracker.Interface {
+				if api.Check(host) {
+					return api
+				}
+				return nil
+			}
+			Config = &Configuration{TrackerErrors: TrackerErrorsConfig{PreferTrackerAPI: tt.prefer}}
+
+			assert.Equal(t, tt.expectedUnreg, tt.torrent.IsUnregistered(context.Background()))
+			assert.Equal(t, tt.expectedCalls, api.calls)
+		})
+	}
+}
+
+func TestTorrent_IsTrackerDown_API(t *testing.T) {
+	InitializeTrackerStatuses(TrackerErrorsConfig{})
+
+	origConfig, origGet := Config, trackerGet
+	t.Cleanup(func() { Config, trackerGet = origConfig, origGet })
+
+	api := &fakeTrackerAPI{down: true, unregistered: true}
+	trackerGet = func(host string) tracker.Interface {
+		if api.Check(host) {
+			return api
+		}
+		return nil
+	}
+	Config = &Configuration{}
+
+	down := Torrent{TrackerName: "api.tracker.com", TrackerStatus: "Unregistered torrent"}
+	assert.True(t, down.IsTrackerDown())
+
+	other := Torrent{TrackerName: "other.tracker.com"}
+	assert.False(t, other.IsTrackerDown())
+
+	// a torrent on a tracker whose api is down is not queried and not treated as unregistered
+	unknown := Torrent{TrackerName: "api.tracker.com", TrackerStatus: "something else"}
+	assert.False(t, unknown.IsUnregistered(context.Background()))
+	assert.Equal(t, 0, api.calls)
+}
+
+func TestTorrent_SharesFilesWithRegistered(t *testing.T) {
+	sharing := func(others ...Torrent) func() []Torrent {
+		return func() []Torrent { return others }
+	}
+
+	tests := []struct {
+		name     string
+		torrent  Torrent
+		expected bool
+	}{
+		{
+			name:     "not_mapped",
+			torrent:  Torrent{TrackerName: "a.com"},
+			expected: false,
+		},
+		{
+			name: "registered_other_tracker",
+			torrent: Torrent{TrackerName: "a.com", SharingFiles: sharing(
+				Torrent{TrackerName: "b.com", RegistrationState: RegisteredState},
+			)},
+			expected: true,
+		},
+		{
+			name: "unregistered_other_tracker",
+			torrent: Torrent{TrackerName: "a.com", SharingFiles: sharing(
+				Torrent{TrackerName: "b.com", RegistrationState: UnregisteredState},
+			)},
+			expected: false,
+		},
+		{
+			name: "registered_same_tracker",
+			torrent: Torrent{TrackerName: "a.com", SharingFiles: sharing(
+				Torrent{TrackerName: "A.com", RegistrationState: RegisteredState},
+			)},
+			expected: false,
+		},
+		{
+			name: "trackerless_sibling",
+			torrent: Torrent{TrackerName: "a.com", SharingFiles: sharing(
+				Torrent{RegistrationState: RegisteredState},
+			)},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.torrent.SharesFilesWithRegistered(context.Background()))
+		})
+	}
+}
+
+func TestTorrent_IsUpgraded(t *testing.T) {
+	assert.True(t, (&Torrent{TrackerStatus: "Trumped by a better release"}).IsUpgraded())
+	assert.True(t, (&Torrent{AllTrackerStatuses: map[string]string{"https://a.com": "", "https://b.com": "Upgraded: 12345"}}).IsUpgraded())
+	assert.False(t, (&Torrent{TrackerStatus: "unregistered torrent"}).IsUpgraded())
+	assert.False(t, (&Torrent{}).IsUpgraded())
+}
+
+func TestTorrent_Size(t *testing.T) {
+	torrent := Torrent{TotalBytes: 3 << 30}
+	assert.InDelta(t, 3.221225472, torrent.SizeGB(), 0.000001)
+	assert.InDelta(t, 3, torrent.SizeGiB(), 0.000001)
+}
+
+func TestTorrent_TrackerKeepsData(t *testing.T) {
+	origConfig := Config
+	t.Cleanup(func() { Config = origConfig })
+
+	deleteFalse, deleteTrue := false, true
+	Config = &Configuration{TrackerPolicies: map[string]TrackerPolicy{
+		"keep.tracker":  {DeleteData: &deleteFalse},
+		"other.tracker": {DeleteData: &deleteTrue},
+		"empty.tracker": {},
+	}}
+
+	assert.True(t, (&Torrent{TrackerName: "Keep.Tracker"}).TrackerKeepsData())
+	assert.False(t, (&Torrent{TrackerName: "other.tracker"}).TrackerKeepsData())
+	assert.False(t, (&Torrent{TrackerName: "empty.tracker"}).TrackerKeepsData())
+	assert.False(t, (&Torrent{TrackerName: "unknown.tracker"}).TrackerKeepsData())
+}
+
+func TestTorrent_DataPathExists(t *testing.T) {
+	dir := t.TempDir()
+
+	assert.True(t, (&Torrent{Path: dir}).DataPathExists())
+	assert.False(t, (&Torrent{Path: filepath.Join(dir, "unmounted")}).DataPathExists())
+	assert.False(t, (&Torrent{}).DataPathExists())
 }
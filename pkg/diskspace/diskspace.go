@@ -0,0 +1,79 @@
+package diskspace
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/dustin/go-humanize"
+)
+
+var (
+	cache   = make(map[string]uint64)
+	cacheMu sync.Mutex
+
+	devices   = make(map[string]uint64)
+	devicesMu sync.Mutex
+)
+
+// Free returns the bytes available to unprivileged users on the filesystem containing path. Results are cached per
+// path for the lifetime of the process, as filters evaluate it for every torrent.
+func Free(path string) (uint64, error) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+
+	if bytes, ok := cache[path]; ok {
+		return bytes, nil
+	}
+
+	bytes, err := free(path)
+	if err != nil {
+		return 0, fmt.Errorf("free space: %v: %w", path, err)
+	}
+
+	cache[path] = bytes
+	return bytes, nil
+}
+
+// FreeGB returns the free space on the filesystem containing path in GiB
+func FreeGB(path string) (float64, error) {
+	bytes, err := Free(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return float64(bytes) / humanize.GiByte, nil
+}
+
+// Device returns an identifier of the filesystem containing path, equal for paths on the same filesystem. Results are
+// cached per path for the lifetime of the process, like Free.
+func Device(path string) (uint64, error) {
+	devicesMu.Lock()
+	defer devicesMu.Unlock()
+
+	if id, ok := devices[path]; ok {
+		return id, nil
+	}
+
+	id, err := device(path)
+	if err != nil {
+		return 0, fmt.Errorf("filesystem: %v: %w", path, err)
+	}
+
+	devices[path] = id
+	return id, nil
+}
+
+// SameFilesystem reports whether a and b are on the same filesystem
+func SameFilesystem(a, b string) (bool, error) {
+	deviceA, err := Device(a)
+	if err != nil {
+		return false, err
+	}
+
+	deviceB, err := Device(b)
+	if err != nil {
+		return false, err
+	}
+
+	return deviceA == deviceB, nil
+}
@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package diskspace
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func free(_ string) (uint64, error) {
+	return 0, fmt.Errorf("not supported on %s", runtime.GOOS)
+}
+
+func device(_ string) (uint64, error) {
+	return 0, fmt.Errorf("not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,55 @@
+package diskspace
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFree(t *testing.T) {
+	dir := t.TempDir()
+
+	bytes, err := Free(dir)
+	require.NoError(t, err)
+	assert.Positive(t, bytes)
+
+	// later lookups are served from the cache
+	cacheMu.Lock()
+	cache[dir] = 42
+	cacheMu.Unlock()
+
+	bytes, err = Free(dir)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), bytes)
+
+	_, err = Free(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestSameFilesystem(t *testing.T) {
+	dir := t.TempDir()
+
+	same, err := SameFilesystem(dir, filepath.Join(dir, "."))
+	require.NoError(t, err)
+	assert.True(t, same)
+
+	// a path on another filesystem has a different device
+	devicesMu.Lock()
+	devices["/other/mount"] = math.MaxUint64
+	devicesMu.Unlock()
+	t.Cleanup(func() {
+		devicesMu.Lock()
+		delete(devices, "/other/mount")
+		devicesMu.Unlock()
+	})
+
+	same, err = SameFilesystem(dir, "/other/mount")
+	require.NoError(t, err)
+	assert.False(t, same)
+
+	_, err = SameFilesystem(dir, filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,41 @@
+//go:build windows
+
+package diskspace
+
+import (
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var getDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+func free(path string) (uint64, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var available uint64
+	if r, _, err := getDiskFreeSpaceEx.Call(uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(&available)), 0, 0); r == 0 {
+		return 0, err
+	}
+
+	return available, nil
+}
+
+// device identifies the volume of path by its volume name, e.g. "C:", volumes mounted in folders are not told apart
+func device(path string) (uint64, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var id uint64
+	for _, r := range strings.ToUpper(filepath.VolumeName(abs)) {
+		id = id*31 + uint64(r)
+	}
+
+	return id, nil
+}
@@ -67,3 +67,60 @@ func CheckTorrentAllMatchWithReason(ctx context.Context, t *config.Torrent, expr
 
 	return true, nil, nil
 }
+
+// Evaluation is the outcome of a single expression for a torrent
+type Evaluation struct {
+	Expression string `json:"expression"`
+	Matched    bool   `json:"matched"`
+	Error      string `json:"error,omitempty"`
+}
+
+// EvaluateEach runs every expression against the torrent, without stopping at the first match or error
+func EvaluateEach(ctx context.Context, t *config.Torrent, expressions []CompiledExpression) []Evaluation {
+	env := &evalContext{Torrent: t, ctx: ctx}
+	evaluations := make([]Evaluation, 0, len(expressions))
+
+	for _, expression := range expressions {
+		evaluation := Evaluation{Expression: expression.Text}
+
+		result, err := expr.Run(expression.Program, env)
+		if err != nil {
+			evaluation.Error = err.Error()
+		} else if matched, ok := result.(bool); ok {
+			evaluation.Matched = matched
+		} else {
+			evaluation.Error = fmt.Sprintf("expression returned %T, not bool", result)
+		}
+
+		evaluations = append(evaluations, evaluation)
+	}
+
+	return evaluations
+}
+
+// ResolveMilestone evaluates the milestone value for the torrent and returns the tag of the highest bucket reached.
+// An empty tag is returned if the value is below every bucket threshold.
+func ResolveMilestone(ctx context.Context, t *config.Torrent, milestone *MilestoneExpression) (string, error) {
+	env := &evalContext{Torrent: t, ctx: ctx}
+
+	result, err := expr.Run(milestone.Value.Program, env)
+	if err != nil {
+		return "", fmt.Errorf("check expression: %w", err)
+	}
+
+	value, ok := result.(float64)
+	if !ok {
+		return "", fmt.Errorf("type assert expression result: %T", result)
+	}
+
+	tag := ""
+	for _, bucket := range milestone.Buckets {
+		if value < bucket.Min {
+			break
+		}
+
+		tag = bucket.Tag
+	}
+
+	return tag, nil
+}
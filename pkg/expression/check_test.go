@@ -0,0 +1,336 @@
+package expression
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+)
+
+func TestResolveMilestone(t *testing.T) {
+	filter := &config.FilterConfiguration{}
+	filter.Milestone = append(filter.Milestone, struct {
+		Name    string
+		Value   string
+		Buckets []struct {
+			Tag string
+			Min float64
+		}
+	}{
+		Name:  "ratio",
+		Value: "Ratio",
+		Buckets: []struct {
+			Tag string
+			Min float64
+		}{
+			{Tag: "ratio:5+", Min: 5},
+			{Tag: "ratio:1+", Min: 1},
+		},
+	})
+
+	exp, err := Compile(filter)
+	require.NoError(t, err)
+	require.Len(t, exp.Milestones, 1)
+
+	tests := []struct {
+		name     string
+		ratio    float64
+		expected string
+	}{
+		{"below_all_buckets", 0.5, ""},
+		{"exact_threshold", 1, "ratio:1+"},
+		{"between_buckets", 3.2, "ratio:1+"},
+		{"highest_bucket", 7, "ratio:5+"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tag, err := ResolveMilestone(context.Background(), &config.Torrent{Ratio: tt.ratio}, exp.Milestones[0])
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, tag)
+		})
+	}
+}
+
+func TestCheckTorrentPause_TrackerRequirement(t *testing.T) {
+	orig := config.Config
+	config.Config = &config.Configuration{
+		TrackerRequirements: map[string]config.TrackerRequirement{
+			"hnr.tracker": {MinRatio: 1, MinSeedDays: 14},
+		},
+	}
+	t.Cleanup(func() { config.Config = orig })
+
+	// pause torrents past their ratio limit, unless their tracker requirement is not met yet
+	exp, err := Compile(&config.FilterConfiguration{
+		Pause: []string{`RatioLimit > 0 && Ratio >= RatioLimit && MeetsTrackerRequirement()`},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		torrent  config.Torrent
+		expected bool
+	}{
+		{name: "no_requirement", torrent: config.Torrent{TrackerName: "other.tracker", Ratio: 2, RatioLimit: 2}, expected: true},
+		{name: "requirement_not_met", torrent: config.Torrent{TrackerName: "hnr.tracker", Ratio: 0.5, RatioLimit: 0.5, SeedingDays: 3}, expected: false},
+		{name: "requirement_met_by_ratio", torrent: config.Torrent{TrackerName: "hnr.tracker", Ratio: 1, RatioLimit: 1, SeedingDays: 3}, expected: true},
+		{name: "requirement_met_by_seed_days", torrent: config.Torrent{TrackerName: "HNR.tracker", Ratio: 0.5, RatioLimit: 0.5, SeedingDays: 14}, expected: true},
+		{name: "ratio_limit_not_reached", torrent: config.Torrent{TrackerName: "other.tracker", Ratio: 1, RatioLimit: 2}, expected: false},
+		{name: "no_ratio_limit", torrent: config.Torrent{TrackerName: "other.tracker", Ratio: 5, RatioLimit: -1}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := CheckTorrentSingleMatch(context.Background(), &tt.torrent, exp.Pauses)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, match)
+		})
+	}
+}
+
+func TestCheckTorrentSingleMatch_TagHelpers(t *testing.T) {
+	exp, err := Compile(&config.FilterConfiguration{
+		Remove: []string{`TagValue("ratio:") != "" && float(TagValue("ratio:")) >= 5`, `TagCount() == 0`},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		tags     []string
+		expected bool
+	}{
+		{name: "no_tags", tags: []string{}, expected: true},
+		{name: "milestone_reached", tags: []string{"tv", "ratio:5"}, expected: true},
+		{name: "milestone_not_reached", tags: []string{"tv", "ratio:2"}, expected: false},
+		{name: "no_milestone", tags: []string{"tv"}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := CheckTorrentSingleMatch(context.Background(), &config.Torrent{Tags: tt.tags}, exp.Removes)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, match)
+		})
+	}
+}
+
+func TestCheckTorrentSingleMatch_SeedHelpers(t *testing.T) {
+	exp, err := Compile(&config.FilterConfiguration{
+		Ignore: []string{`IsRare(3)`},
+		Remove: []string{`IsPublic && IsWellSeeded(20)`},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		torrent config.Torrent
+		ignore  bool
+		remove  bool
+	}{
+		{name: "rare", torrent: config.Torrent{IsPublic: true, Seeds: 2}, ignore: true, remove: false},
+		{name: "well_seeded_public", torrent: config.Torrent{IsPublic: true, Seeds: 20}, ignore: false, remove: true},
+		{name: "well_seeded_private", torrent: config.Torrent{IsPrivate: true, Seeds: 50}, ignore: false, remove: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ignore, err := CheckTorrentSingleMatch(context.Background(), &tt.torrent, exp.Ignores)
+			require.NoError(t, err)
+			assert.Equal(t, tt.ignore, ignore)
+
+			remove, err := CheckTorrentSingleMatch(context.Background(), &tt.torrent, exp.Removes)
+			require.NoError(t, err)
+			assert.Equal(t, tt.remove, remove)
+		})
+	}
+}
+
+func TestCheckTorrentSingleMatch_IsAvailable(t *testing.T) {
+	exp, err := Compile(&config.FilterConfiguration{
+		Remove: []string{`IsPublic && !Downloaded && !IsAvailable()`},
+	})
+	require.NoError(t, err)
+
+	for availability, expected := range map[float32]bool{0.4: true, 1: false, 2.5: false} {
+		match, err := CheckTorrentSingleMatch(context.Background(), &config.Torrent{IsPublic: true, Availability: availability},
+			exp.Removes)
+		require.NoError(t, err)
+		assert.Equal(t, expected, match, "availability %v", availability)
+	}
+}
+
+func TestCheckTorrentSingleMatch_RatioPerDay(t *testing.T) {
+	exp, err := Compile(&config.FilterConfiguration{
+		Remove: []string{`SeedingDays >= 7 && Ratio < 1.0 && RatioPerDay() < 0.05`},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		torrent  config.Torrent
+		expected bool
+	}{
+		{name: "slow_seeder", torrent: config.Torrent{Ratio: 0.2, SeedingDays: 10}, expected: true},
+		{name: "fast_seeder", torrent: config.Torrent{Ratio: 0.9, SeedingDays: 10}, expected: false},
+		{name: "too_new", torrent: config.Torrent{Ratio: 0, SeedingDays: 0}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := CheckTorrentSingleMatch(context.Background(), &tt.torrent, exp.Removes)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, match)
+		})
+	}
+}
+
+func TestCheckTorrentSingleMatch_RatioPrecision(t *testing.T) {
+	exp, err := Compile(&config.FilterConfiguration{
+		Remove: []string{`Ratio >= 99.9`},
+	})
+	require.NoError(t, err)
+
+	// as a float32 the ratio rounds up to the threshold
+	const ratio = 99.899998
+	require.GreaterOrEqual(t, float64(float32(ratio)), 99.9)
+
+	match, err := CheckTorrentSingleMatch(context.Background(), &config.Torrent{Ratio: ratio}, exp.Removes)
+	require.NoError(t, err)
+	assert.False(t, match)
+
+	match, err = CheckTorrentSingleMatch(context.Background(), &config.Torrent{Ratio: 99.9}, exp.Removes)
+	require.NoError(t, err)
+	assert.True(t, match)
+}
+
+func TestCheckTorrentSingleMatch_FreeSpaceAt(t *testing.T) {
+	dir := t.TempDir()
+
+	exp, err := Compile(&config.FilterConfiguration{
+		Remove: []string{fmt.Sprintf(`FreeSpaceAt(%q) > 0`, dir)},
+		Pause:  []string{fmt.Sprintf(`FreeSpaceAt(%q) > 0`, filepath.Join(dir, "missing"))},
+	})
+	require.NoError(t, err)
+
+	match, err := CheckTorrentSingleMatch(context.Background(), &config.Torrent{}, exp.Removes)
+	require.NoError(t, err)
+	assert.True(t, match)
+
+	_, err = CheckTorrentSingleMatch(context.Background(), &config.Torrent{}, exp.Pauses)
+	assert.Error(t, err, "an unknown free space should fail the evaluation")
+}
+
+func TestCheckTorrentSingleMatch_PathHelpers(t *testing.T) {
+	exp, err := Compile(&config.FilterConfiguration{
+		Remove: []string{`PathHasPrefix("/data/torrents/tv") && !PathContains("permaseed")`},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{path: "/data/torrents/tv/Show", expected: true},
+		{path: `\\nas\data\torrents\tv`, expected: false},
+		{path: "/data/torrents/tv/permaseed", expected: false},
+		{path: "/data/torrents/movies", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			match, err := CheckTorrentSingleMatch(context.Background(), &config.Torrent{Path: tt.path}, exp.Removes)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, match)
+		})
+	}
+}
+
+func TestCheckTorrentSingleMatch_DataPathExists(t *testing.T) {
+	exp, err := Compile(&config.FilterConfiguration{
+		Ignore: []string{`!DataPathExists()`},
+	})
+	require.NoError(t, err)
+
+	match, err := CheckTorrentSingleMatch(context.Background(), &config.Torrent{Path: t.TempDir()}, exp.Ignores)
+	require.NoError(t, err)
+	assert.False(t, match)
+
+	match, err = CheckTorrentSingleMatch(context.Background(), &config.Torrent{Path: "/missing/mount/tv"}, exp.Ignores)
+	require.NoError(t, err)
+	assert.True(t, match)
+}
+
+func TestCheckTorrentSingleMatch_MediaCategory(t *testing.T) {
+	exp, err := Compile(&config.FilterConfiguration{
+		Remove: []string{`MediaCategory() == "tv"`},
+	})
+	require.NoError(t, err)
+
+	match, err := CheckTorrentSingleMatch(context.Background(), &config.Torrent{
+		Name:  "Show.S01.1080p",
+		Files: []string{"/data/Show.S01.1080p/Show.S01E01.1080p.mkv"},
+	}, exp.Removes)
+	require.NoError(t, err)
+	assert.True(t, match)
+
+	match, err = CheckTorrentSingleMatch(context.Background(), &config.Torrent{
+		Name:  "Movie.2020.1080p",
+		Files: []string{"/data/Movie.2020.1080p/Movie.2020.1080p.mkv"},
+	}, exp.Removes)
+	require.NoError(t, err)
+	assert.False(t, match)
+}
+
+func TestCheckTorrentSingleMatch_UnregisteredOnAllTrackers(t *testing.T) {
+	config.InitializeTrackerStatuses(config.TrackerErrorsConfig{})
+
+	exp, err := Compile(&config.FilterConfiguration{
+		Remove: []string{`UnregisteredOnAllTrackers() || UnregisteredTrackerCount() >= 3`},
+	})
+	require.NoError(t, err)
+
+	match, err := CheckTorrentSingleMatch(context.Background(), &config.Torrent{
+		AllTrackerStatuses: map[string]string{
+			"http://tracker1.com/announce": "Working",
+			"http://tracker2.com/announce": "unregistered torrent",
+		},
+	}, exp.Removes)
+	require.NoError(t, err)
+	assert.False(t, match)
+
+	match, err = CheckTorrentSingleMatch(context.Background(), &config.Torrent{
+		AllTrackerStatuses: map[string]string{
+			"http://tracker1.com/announce": "torrent not found",
+			"http://tracker2.com/announce": "unregistered torrent",
+		},
+	}, exp.Removes)
+	require.NoError(t, err)
+	assert.True(t, match)
+}
+
+func TestEvaluateEach(t *testing.T) {
+	exp, err := Compile(&config.FilterConfiguration{
+		Remove: []string{
+			`Ratio > 2`,
+			`Label == "tv"`,
+			`FreeSpaceAt("/nonexistent/tqm") > 10`,
+			`Ratio > 0.5`,
+		},
+	})
+	require.NoError(t, err)
+
+	evaluations := EvaluateEach(context.Background(), &config.Torrent{Ratio: 1, Label: "tv"}, exp.Removes)
+	require.Len(t, evaluations, 4)
+
+	assert.Equal(t, Evaluation{Expression: `Ratio > 2`, Matched: false}, evaluations[0])
+	assert.Equal(t, Evaluation{Expression: `Label == "tv"`, Matched: true}, evaluations[1])
+	assert.False(t, evaluations[2].Matched)
+	assert.NotEmpty(t, evaluations[2].Error)
+	assert.Equal(t, Evaluation{Expression: `Ratio > 0.5`, Matched: true}, evaluations[3], "evaluation continues after a match and an error")
+}
@@ -1,12 +1,15 @@
 package expression
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"slices"
 
 	"github.com/expr-lang/expr"
 
 	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/diskspace"
 	"github.com/autobrr/tqm/pkg/regex"
 )
 
@@ -29,6 +32,41 @@ func (e *evalContext) IsTrackerDown() bool {
 	return e.Torrent.IsTrackerDown()
 }
 
+func (e *evalContext) UnregisteredTrackerCount() int {
+	if e.Torrent == nil {
+		return 0
+	}
+	return e.Torrent.UnregisteredTrackerCount()
+}
+
+func (e *evalContext) UnregisteredOnAllTrackers() bool {
+	if e.Torrent == nil {
+		return false
+	}
+	return e.Torrent.UnregisteredOnAllTrackers()
+}
+
+func (e *evalContext) SharesFilesWithRegistered() bool {
+	if e.Torrent == nil {
+		return false
+	}
+	return e.Torrent.SharesFilesWithRegistered(e.ctx)
+}
+
+func (e *evalContext) IsError() bool {
+	if e.Torrent == nil {
+		return false
+	}
+	return e.Torrent.IsError()
+}
+
+func (e *evalContext) IsMoving() bool {
+	if e.Torrent == nil {
+		return false
+	}
+	return e.Torrent.IsMoving()
+}
+
 func (e *evalContext) HasAllTags(tags ...string) bool {
 	if e.Torrent == nil {
 		return false
@@ -43,6 +81,76 @@ func (e *evalContext) HasAnyTag(tags ...string) bool {
 	return e.Torrent.HasAnyTag(tags...)
 }
 
+func (e *evalContext) IsWellSeeded(threshold int) bool {
+	if e.Torrent == nil {
+		return false
+	}
+	return e.Torrent.IsWellSeeded(threshold)
+}
+
+func (e *evalContext) IsRare(threshold int) bool {
+	if e.Torrent == nil {
+		return false
+	}
+	return e.Torrent.IsRare(threshold)
+}
+
+func (e *evalContext) IsAvailable() bool {
+	if e.Torrent == nil {
+		return false
+	}
+	return e.Torrent.IsAvailable()
+}
+
+func (e *evalContext) SizeGB() float64 {
+	if e.Torrent == nil {
+		return 0
+	}
+	return e.Torrent.SizeGB()
+}
+
+func (e *evalContext) SizeGiB() float64 {
+	if e.Torrent == nil {
+		return 0
+	}
+	return e.Torrent.SizeGiB()
+}
+
+func (e *evalContext) TagCount() int {
+	if e.Torrent == nil {
+		return 0
+	}
+	return e.Torrent.TagCount()
+}
+
+func (e *evalContext) RatioPerDay() float64 {
+	if e.Torrent == nil {
+		return 0
+	}
+	return e.Torrent.RatioPerDay()
+}
+
+func (e *evalContext) TagValue(prefix string) string {
+	if e.Torrent == nil {
+		return ""
+	}
+	return e.Torrent.TagValue(prefix)
+}
+
+func (e *evalContext) PathHasPrefix(prefix string) bool {
+	if e.Torrent == nil {
+		return false
+	}
+	return e.Torrent.PathHasPrefix(prefix)
+}
+
+func (e *evalContext) PathContains(substr string) bool {
+	if e.Torrent == nil {
+		return false
+	}
+	return e.Torrent.PathContains(substr)
+}
+
 func (e *evalContext) HasMissingFiles() bool {
 	if e.Torrent == nil {
 		return false
@@ -50,6 +158,20 @@ func (e *evalContext) HasMissingFiles() bool {
 	return e.Torrent.HasMissingFiles()
 }
 
+func (e *evalContext) DataPathExists() bool {
+	if e.Torrent == nil {
+		return false
+	}
+	return e.Torrent.DataPathExists()
+}
+
+func (e *evalContext) MediaCategory() string {
+	if e.Torrent == nil {
+		return ""
+	}
+	return e.Torrent.MediaCategory()
+}
+
 func (e *evalContext) RegexMatch(pattern string) bool {
 	if e.Torrent == nil {
 		return false
@@ -71,6 +193,12 @@ func (e *evalContext) RegexMatchAll(patternsStr string) bool {
 	return e.Torrent.RegexMatchAll(patternsStr)
 }
 
+// FreeSpaceAt returns the free space in GB of the filesystem containing path, an error fails the evaluation so rules
+// never act on a path whose free space is unknown
+func (e *evalContext) FreeSpaceAt(path string) (float64, error) {
+	return diskspace.FreeGB(path)
+}
+
 func Compile(filter *config.FilterConfiguration) (*Expressions, error) {
 	exprEnv := &evalContext{}
 	exp := new(Expressions)
@@ -87,7 +215,7 @@ func Compile(filter *config.FilterConfiguration) (*Expressions, error) {
 
 	// compile ignores
 	for _, ignoreExpr := range filter.Ignore {
-		program, err := expr.Compile(ignoreExpr, expr.Env(exprEnv), expr.AsBool())
+		program, err := expr.Compile(expandSizeLiterals(ignoreExpr), expr.Env(exprEnv), expr.AsBool())
 		if err != nil {
 			return nil, fmt.Errorf("compile ignore expression: %q: %w", ignoreExpr, err)
 		}
@@ -100,7 +228,7 @@ func Compile(filter *config.FilterConfiguration) (*Expressions, error) {
 
 	// compile removes
 	for _, removeExpr := range filter.Remove {
-		program, err := expr.Compile(removeExpr, expr.Env(exprEnv), expr.AsBool())
+		program, err := expr.Compile(expandSizeLiterals(removeExpr), expr.Env(exprEnv), expr.AsBool())
 		if err != nil {
 			return nil, fmt.Errorf("compile remove expression: %q: %w", removeExpr, err)
 		}
@@ -113,7 +241,7 @@ func Compile(filter *config.FilterConfiguration) (*Expressions, error) {
 
 	// compile pauses
 	for _, pauseExpr := range filter.Pause {
-		program, err := expr.Compile(pauseExpr, expr.Env(exprEnv), expr.AsBool())
+		program, err := expr.Compile(expandSizeLiterals(pauseExpr), expr.Env(exprEnv), expr.AsBool())
 		if err != nil {
 			return nil, fmt.Errorf("compile pause expression: %q: %w", pauseExpr, err)
 		}
@@ -126,11 +254,11 @@ func Compile(filter *config.FilterConfiguration) (*Expressions, error) {
 
 	// compile labels
 	for _, labelExpr := range filter.Label {
-		le := &LabelExpression{Name: labelExpr.Name}
+		le := &LabelExpression{Name: labelExpr.Name, Priority: labelExpr.Priority}
 
 		// compile updates
 		for _, updateExpr := range labelExpr.Update {
-			program, err := expr.Compile(updateExpr, expr.Env(exprEnv), expr.AsBool())
+			program, err := expr.Compile(expandSizeLiterals(updateExpr), expr.Env(exprEnv), expr.AsBool())
 			if err != nil {
 				return nil, fmt.Errorf("compile label update expression: %v: %q: %w", labelExpr.Name, updateExpr, err)
 			}
@@ -144,9 +272,15 @@ func Compile(filter *config.FilterConfiguration) (*Expressions, error) {
 		exp.Labels = append(exp.Labels, le)
 	}
 
+	// the first matching label rule wins, so higher priority rules are evaluated first, rules of equal priority keep
+	// their config order
+	slices.SortStableFunc(exp.Labels, func(a, b *LabelExpression) int {
+		return cmp.Compare(b.Priority, a.Priority)
+	})
+
 	// compile tags
 	for _, tagExpr := range filter.Tag {
-		le := &TagExpression{Name: tagExpr.Name, Mode: tagExpr.Mode, UploadKb: tagExpr.UploadKb}
+		le := &TagExpression{Name: tagExpr.Name, Mode: tagExpr.Mode, UploadKb: tagExpr.UploadKb, Priority: tagExpr.Priority}
 
 		if le.Mode == "" {
 			le.Mode = TagModeFull
@@ -160,7 +294,7 @@ func Compile(filter *config.FilterConfiguration) (*Expressions, error) {
 
 		// compile updates
 		for _, updateExpr := range tagExpr.Update {
-			program, err := expr.Compile(updateExpr, expr.Env(exprEnv), expr.AsBool())
+			program, err := expr.Compile(expandSizeLiterals(updateExpr), expr.Env(exprEnv), expr.AsBool())
 			if err != nil {
 				return nil, fmt.Errorf("compile tag update expression: %v: %q: %w", tagExpr.Name, updateExpr, err)
 			}
@@ -174,5 +308,45 @@ func Compile(filter *config.FilterConfiguration) (*Expressions, error) {
 		exp.Tags = append(exp.Tags, le)
 	}
 
+	// higher priority tag rules are evaluated first, rules of equal priority keep their config order
+	slices.SortStableFunc(exp.Tags, func(a, b *TagExpression) int {
+		return cmp.Compare(b.Priority, a.Priority)
+	})
+
+	// compile milestones
+	for _, milestoneExpr := range filter.Milestone {
+		if len(milestoneExpr.Buckets) == 0 {
+			return nil, fmt.Errorf("milestone '%s' has no buckets", milestoneExpr.Name)
+		}
+
+		program, err := expr.Compile(expandSizeLiterals(milestoneExpr.Value), expr.Env(exprEnv), expr.AsFloat64())
+		if err != nil {
+			return nil, fmt.Errorf("compile milestone value expression: %v: %q: %w", milestoneExpr.Name, milestoneExpr.Value, err)
+		}
+
+		me := &MilestoneExpression{
+			Name: milestoneExpr.Name,
+			Value: CompiledExpression{
+				Program: program,
+				Text:    milestoneExpr.Value,
+			},
+		}
+
+		for _, bucket := range milestoneExpr.Buckets {
+			if bucket.Tag == "" {
+				return nil, fmt.Errorf("milestone '%s' has a bucket without a tag", milestoneExpr.Name)
+			}
+
+			me.Buckets = append(me.Buckets, MilestoneBucket{Tag: bucket.Tag, Min: bucket.Min})
+		}
+
+		// sort buckets by threshold so the highest reached bucket can be resolved
+		slices.SortStableFunc(me.Buckets, func(a, b MilestoneBucket) int {
+			return cmp.Compare(a.Min, b.Min)
+		})
+
+		exp.Milestones = append(exp.Milestones, me)
+	}
+
 	return exp, nil
 }
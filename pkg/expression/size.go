@@ -0,0 +1,57 @@
+package expression
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+// sizeLiteral matches a number directly followed by a size unit, e.g. 10GiB or 1.5TB
+var sizeLiteral = regexp.MustCompile(`(?i)\b\d+(?:\.\d+)?(?:[kmgt]i?b)\b`)
+
+// expandSizeLiterals replaces the size literals of an expression with their number of bytes, e.g.
+// "TotalBytes > 10GiB" becomes "TotalBytes > 10737418240". KB, MB, GB and TB are powers of 1000, KiB, MiB, GiB and
+// TiB powers of 1024. String literals are left as they are
+func expandSizeLiterals(expression string) string {
+	var (
+		b     strings.Builder
+		start int
+	)
+
+	expand := func(code string) {
+		b.WriteString(sizeLiteral.ReplaceAllStringFunc(code, func(literal string) string {
+			bytes, err := humanize.ParseBytes(literal)
+			if err != nil {
+				return literal
+			}
+			return strconv.FormatUint(bytes, 10)
+		}))
+	}
+
+	for i := 0; i < len(expression); i++ {
+		quote := expression[i]
+		if quote != '"' && quote != '\'' && quote != '`' {
+			continue
+		}
+
+		expand(expression[start:i])
+
+		// copy the string literal up to its closing quote, skipping escaped quotes
+		end := i + 1
+		for end < len(expression) && expression[end] != quote {
+			if expression[end] == '\\' && quote != '`' {
+				end++
+			}
+			end++
+		}
+		end = min(end+1, len(expression))
+
+		b.WriteString(expression[i:end])
+		start, i = end, end-1
+	}
+	expand(expression[start:])
+
+	return b.String()
+}
@@ -0,0 +1,60 @@
+package expression
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+)
+
+func TestExpandSizeLiterals(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{expression: `TotalBytes > 10GiB`, expected: `TotalBytes > 10737418240`},
+		{expression: `TotalBytes < 1.5GB && TotalBytes > 500mb`, expected: `TotalBytes < 1500000000 && TotalBytes > 500000000`},
+		{expression: `TotalBytes >= 2TiB`, expected: `TotalBytes >= 2199023255552`},
+		{expression: `Name contains "10GB" && TotalBytes > 1KiB`, expected: `Name contains "10GB" && TotalBytes > 1024`},
+		{expression: `Name matches 'a\'1GB' || TotalBytes > 1KB`, expected: `Name matches 'a\'1GB' || TotalBytes > 1000`},
+		{expression: `Name contains "x264" && Seeds > 10`, expected: `Name contains "x264" && Seeds > 10`},
+		{expression: `Name contains "unterminated 1GB`, expected: `Name contains "unterminated 1GB`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			assert.Equal(t, tt.expected, expandSizeLiterals(tt.expression))
+		})
+	}
+}
+
+func TestCheckTorrentSingleMatch_Size(t *testing.T) {
+	exp, err := Compile(&config.FilterConfiguration{
+		Remove: []string{`TotalBytes > 10GiB`, `SizeGB() < 0.5 && Name != "small"`, `SizeGiB() > 1 && SizeGiB() < 2`},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `TotalBytes > 10GiB`, exp.Removes[0].Text)
+
+	tests := []struct {
+		name     string
+		torrent  config.Torrent
+		expected bool
+	}{
+		{name: "large", torrent: config.Torrent{Name: "large", TotalBytes: 11 << 30}, expected: true},
+		{name: "medium", torrent: config.Torrent{Name: "medium", TotalBytes: 5 << 30}, expected: false},
+		{name: "tiny", torrent: config.Torrent{Name: "tiny", TotalBytes: 100_000_000}, expected: true},
+		{name: "small", torrent: config.Torrent{Name: "small", TotalBytes: 100_000_000}, expected: false},
+		{name: "gib", torrent: config.Torrent{Name: "gib", TotalBytes: 3 << 29}, expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, err := CheckTorrentSingleMatch(context.Background(), &tt.torrent, exp.Removes)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, match)
+		})
+	}
+}
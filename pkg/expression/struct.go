@@ -14,21 +14,35 @@ type CompiledExpression struct {
 }
 
 type Expressions struct {
-	Ignores []CompiledExpression
-	Removes []CompiledExpression
-	Pauses  []CompiledExpression
-	Labels  []*LabelExpression
-	Tags    []*TagExpression
+	Ignores    []CompiledExpression
+	Removes    []CompiledExpression
+	Pauses     []CompiledExpression
+	Labels     []*LabelExpression
+	Tags       []*TagExpression
+	Milestones []*MilestoneExpression
 }
 
 type LabelExpression struct {
-	Name    string
-	Updates []CompiledExpression
+	Name     string
+	Priority int
+	Updates  []CompiledExpression
 }
 
 type TagExpression struct {
 	Name     string
 	Mode     string
 	UploadKb *int
+	Priority int
 	Updates  []CompiledExpression
 }
+
+type MilestoneExpression struct {
+	Name    string
+	Value   CompiledExpression
+	Buckets []MilestoneBucket
+}
+
+type MilestoneBucket struct {
+	Tag string
+	Min float64
+}
@@ -2,48 +2,77 @@ package hardlinkfilemap
 
 import (
 	"os"
-	"strings"
+	"path/filepath"
 
 	"github.com/scylladb/go-set/strset"
 
 	"github.com/autobrr/tqm/pkg/config"
 	"github.com/autobrr/tqm/pkg/logger"
+	"github.com/autobrr/tqm/pkg/pathmapping"
 )
 
-func New(torrents map[string]config.Torrent, torrentPathMapping map[string]string) HardlinkFileMapI {
+// New maps the files of torrents to their underlying file ids. With failClosed, torrents with files that could not be
+// stat'd are never considered safe to delete: they are not unique and count as hardlinked outside the client
+func New(torrents map[string]config.Torrent, torrentPathMapping *pathmapping.Mapping, resolveSymlinks bool,
+	failClosed bool) HardlinkFileMapI {
 	tfm := &HardlinkFileMap{
 		hardlinkFileMap:    make(map[string]*strset.Set),
+		torrentsByID:       make(map[string]map[string]config.Torrent),
 		log:                logger.GetLogger("hardlinkfilemap"),
 		torrentPathMapping: torrentPathMapping,
+		resolveSymlinks:    resolveSymlinks,
+		resolvedPaths:      make(map[string]string),
+		failClosed:         failClosed,
+		statFailures:       strset.New(),
 	}
 
 	for _, torrent := range torrents {
 		tfm.AddByTorrent(torrent)
 	}
 
+	if n := tfm.StatFailures(); n > 0 {
+		if failClosed {
+			tfm.log.Warnf("Failed to stat %d torrent files, torrents with these files are treated as not safe to delete", n)
+		} else {
+			tfm.log.Warnf("Failed to stat %d torrent files, these files were skipped when mapping hardlinks (set hardlink_fail_closed to treat their torrents as not safe to delete)", n)
+		}
+	}
+
 	return tfm
 }
 
 func (t *HardlinkFileMap) considerPathMapping(path string) string {
-	for mapFrom, mapTo := range t.torrentPathMapping {
-		if strings.HasPrefix(path, mapFrom) {
-			return strings.Replace(path, mapFrom, mapTo, 1)
-		}
-	}
-
+	path, _ = t.torrentPathMapping.Map(path)
 	return path
 }
 
 func (t *HardlinkFileMap) linkInfoByPath(path string) (string, uint64, bool) {
+	if t.resolveSymlinks {
+		// remember where symlinks point, so a symlink and its target count as a single file
+		resolved, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			t.log.Warnf("Failed to resolve symlinks: %s - %s", path, err)
+			t.statFailures.Add(path)
+			return "", 0, false
+		}
+
+		if resolved != path {
+			t.resolvedPaths[path] = resolved
+		}
+		path = resolved
+	}
+
 	stat, err1 := os.Stat(path)
 	if err1 != nil {
 		t.log.Warnf("Failed to stat file: %s - %s", path, err1)
+		t.statFailures.Add(path)
 		return "", 0, false
 	}
 
 	id, nlink, err2 := LinkInfo(stat, path)
 	if err2 != nil {
 		t.log.Warnf("Failed to get file identifier: %s - %s", path, err2)
+		t.statFailures.Add(path)
 		return "", 0, false
 	}
 
@@ -64,6 +93,11 @@ func (t *HardlinkFileMap) AddByTorrent(torrent config.Torrent) {
 			continue
 		}
 
+		if _, exists := t.torrentsByID[id]; !exists {
+			t.torrentsByID[id] = make(map[string]config.Torrent)
+		}
+		t.torrentsByID[id][torrent.Hash] = torrent
+
 		if _, exists := t.hardlinkFileMap[id]; exists {
 			// file id already associated with other paths
 			t.hardlinkFileMap[id].Add(f)
@@ -89,9 +123,17 @@ func (t *HardlinkFileMap) RemoveByTorrent(torrent config.Torrent) {
 			continue
 		}
 
+		if torrents, exists := t.torrentsByID[id]; exists {
+			delete(torrents, torrent.Hash)
+			if len(torrents) == 0 {
+				delete(t.torrentsByID, id)
+			}
+		}
+
 		if _, exists := t.hardlinkFileMap[id]; exists {
 			// remove this path from the id entry
 			t.hardlinkFileMap[id].Remove(f)
+			delete(t.resolvedPaths, f)
 
 			// remove id entry if no more paths
 			if t.hardlinkFileMap[id].Size() == 0 {
@@ -103,19 +145,51 @@ func (t *HardlinkFileMap) RemoveByTorrent(torrent config.Torrent) {
 	}
 }
 
-func (t *HardlinkFileMap) countLinks(f string) (inmap uint64, total uint64, ok bool) {
+// countLinks returns the number of torrent paths in the map sharing the file id of f, how many distinct hardlinks
+// those paths resolve to, and the total number of hardlinks of the file
+func (t *HardlinkFileMap) countLinks(f string) (inmap uint64, linked uint64, total uint64, ok bool) {
 	f = t.considerPathMapping(f)
 	id, nlink, ok := t.linkInfoByPath(f)
 
 	if !ok {
-		return 0, 0, false
+		return 0, 0, 0, false
+	}
+
+	paths, exists := t.hardlinkFileMap[id]
+	if !exists {
+		return 0, 0, nlink, true
 	}
 
-	if paths, exists := t.hardlinkFileMap[id]; exists {
-		return uint64(paths.Size()), nlink, true
+	if len(t.resolvedPaths) == 0 {
+		return uint64(paths.Size()), uint64(paths.Size()), nlink, true
 	}
 
-	return 0, nlink, true
+	// symlinks do not add to the link count of their target
+	resolved := strset.NewWithSize(paths.Size())
+	paths.Each(func(p string) bool {
+		if r, ok := t.resolvedPaths[p]; ok {
+			p = r
+		}
+		resolved.Add(p)
+		return true
+	})
+
+	return uint64(paths.Size()), uint64(resolved.Size()), nlink, true
+}
+
+// hasStatFailures reports whether a file of torrent could not be stat'd while failing closed
+func (t *HardlinkFileMap) hasStatFailures(torrent config.Torrent) bool {
+	if !t.failClosed || t.statFailures.IsEmpty() {
+		return false
+	}
+
+	for _, f := range torrent.Files {
+		if t.statFailures.Has(t.considerPathMapping(f)) {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (t *HardlinkFileMap) HardlinkedOutsideClient(torrent config.Torrent) bool {
@@ -123,13 +197,17 @@ func (t *HardlinkFileMap) HardlinkedOutsideClient(torrent config.Torrent) bool {
 		return false
 	}
 
+	if t.hasStatFailures(torrent) {
+		return true
+	}
+
 	for _, f := range torrent.Files {
-		inmap, total, ok := t.countLinks(f)
+		_, linked, total, ok := t.countLinks(f)
 		if !ok {
 			continue
 		}
 
-		if total != inmap {
+		if total != linked {
 			return true
 		}
 	}
@@ -142,8 +220,12 @@ func (t *HardlinkFileMap) IsTorrentUnique(torrent config.Torrent) bool {
 		return true
 	}
 
+	if t.hasStatFailures(torrent) {
+		return false
+	}
+
 	for _, f := range torrent.Files {
-		c, _, ok := t.countLinks(f)
+		c, _, _, ok := t.countLinks(f)
 		if !ok {
 			return false
 		}
@@ -161,8 +243,12 @@ func (t *HardlinkFileMap) NoInstances(torrent config.Torrent) bool {
 		return true
 	}
 
+	if t.hasStatFailures(torrent) {
+		return false
+	}
+
 	for _, f := range torrent.Files {
-		c, _, ok := t.countLinks(f)
+		c, _, _, ok := t.countLinks(f)
 		if !ok {
 			return false
 		}
@@ -175,6 +261,38 @@ func (t *HardlinkFileMap) NoInstances(torrent config.Torrent) bool {
 	return true
 }
 
+// GetTorrentsSharingFiles returns the other torrents with a file sharing a file id with a file of torrent, e.g.
+// cross-seeds hardlinked at different paths
+func (t *HardlinkFileMap) GetTorrentsSharingFiles(torrent config.Torrent) []config.Torrent {
+	if !torrent.Downloaded {
+		return nil
+	}
+
+	seen := map[string]struct{}{torrent.Hash: {}}
+	var sharing []config.Torrent
+	for _, f := range torrent.Files {
+		id, _, ok := t.linkInfoByPath(t.considerPathMapping(f))
+		if !ok {
+			continue
+		}
+
+		for hash, other := range t.torrentsByID[id] {
+			if _, ok := seen[hash]; ok {
+				continue
+			}
+			seen[hash] = struct{}{}
+			sharing = append(sharing, other)
+		}
+	}
+
+	return sharing
+}
+
 func (t *HardlinkFileMap) Length() int {
 	return len(t.hardlinkFileMap)
 }
+
+// StatFailures returns the number of torrent files that could not be stat'd
+func (t *HardlinkFileMap) StatFailures() int {
+	return t.statFailures.Size()
+}
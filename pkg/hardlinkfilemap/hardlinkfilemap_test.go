@@ -0,0 +1,116 @@
+package hardlinkfilemap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+)
+
+func writeFile(t *testing.T, path string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte("data"), 0644))
+}
+
+func TestHardlinkFileMap_Symlinks(t *testing.T) {
+	root := t.TempDir()
+
+	source := filepath.Join(root, "movies", "movie.mkv")
+	symlinked := filepath.Join(root, "cross-seed", "movie.mkv")
+	other := filepath.Join(root, "movies", "other.mkv")
+	writeFile(t, source)
+	writeFile(t, other)
+	require.NoError(t, os.MkdirAll(filepath.Dir(symlinked), 0755))
+	if err := os.Symlink(source, symlinked); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Downloaded: true, Files: []string{source}},
+		"b": {Hash: "b", Downloaded: true, Files: []string{symlinked}},
+		"c": {Hash: "c", Downloaded: true, Files: []string{other}},
+	}
+
+	t.Run("resolve_symlinks", func(t *testing.T) {
+		hfm := New(torrents, nil, true, false)
+
+		assert.False(t, hfm.IsTorrentUnique(torrents["a"]), "symlinked cross-seed should not be unique")
+		assert.False(t, hfm.IsTorrentUnique(torrents["b"]), "symlinked cross-seed should not be unique")
+		assert.True(t, hfm.IsTorrentUnique(torrents["c"]))
+		assert.False(t, hfm.HardlinkedOutsideClient(torrents["a"]), "symlink within the client is not an outside link")
+		assert.False(t, hfm.HardlinkedOutsideClient(torrents["b"]), "symlink within the client is not an outside link")
+
+		hfm.RemoveByTorrent(torrents["b"])
+		assert.True(t, hfm.IsTorrentUnique(torrents["a"]))
+	})
+
+	t.Run("follow_only", func(t *testing.T) {
+		hfm := New(torrents, nil, false, false)
+
+		// the symlink is followed to the same file, but counted as an extra link the file does not have
+		assert.False(t, hfm.IsTorrentUnique(torrents["a"]))
+		assert.True(t, hfm.HardlinkedOutsideClient(torrents["a"]))
+	})
+}
+
+func TestHardlinkFileMap_SymlinkedDirectory(t *testing.T) {
+	root := t.TempDir()
+
+	source := filepath.Join(root, "data", "show", "episode.mkv")
+	writeFile(t, source)
+	if err := os.Symlink(filepath.Join(root, "data"), filepath.Join(root, "linked")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	// hardlinked copy outside the client
+	outside := filepath.Join(root, "library", "episode.mkv")
+	require.NoError(t, os.MkdirAll(filepath.Dir(outside), 0755))
+	require.NoError(t, os.Link(source, outside))
+
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Downloaded: true, Files: []string{source}},
+		"b": {Hash: "b", Downloaded: true, Files: []string{filepath.Join(root, "linked", "show", "episode.mkv")}},
+	}
+
+	hfm := New(torrents, nil, true, false)
+
+	assert.False(t, hfm.IsTorrentUnique(torrents["a"]))
+	assert.True(t, hfm.HardlinkedOutsideClient(torrents["a"]), "hardlinks outside the client should still be detected")
+}
+
+func TestHardlinkFileMap_StatFailures(t *testing.T) {
+	root := t.TempDir()
+
+	present := filepath.Join(root, "movies", "movie.mkv")
+	missing := filepath.Join(root, "movies", "missing.mkv")
+	writeFile(t, present)
+
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Downloaded: true, Files: []string{present, missing}},
+	}
+
+	t.Run("skip", func(t *testing.T) {
+		hfm := New(torrents, nil, false, false)
+
+		assert.Equal(t, 1, hfm.StatFailures())
+		assert.False(t, hfm.HardlinkedOutsideClient(torrents["a"]), "files that failed to stat are skipped")
+	})
+
+	t.Run("fail_closed", func(t *testing.T) {
+		hfm := New(torrents, nil, false, true)
+
+		assert.Equal(t, 1, hfm.StatFailures())
+		assert.False(t, hfm.IsTorrentUnique(torrents["a"]), "torrent with files that failed to stat should not be unique")
+		assert.False(t, hfm.NoInstances(torrents["a"]))
+		assert.True(t, hfm.HardlinkedOutsideClient(torrents["a"]), "torrent with files that failed to stat should not be safe to delete")
+
+		// the file becoming readable later does not make the torrent safe within the same run
+		writeFile(t, missing)
+		assert.False(t, hfm.IsTorrentUnique(torrents["a"]))
+	})
+}
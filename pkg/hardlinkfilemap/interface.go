@@ -8,5 +8,7 @@ type HardlinkFileMapI interface {
 	NoInstances(torrent config.Torrent) bool
 	IsTorrentUnique(torrent config.Torrent) bool
 	HardlinkedOutsideClient(torrent config.Torrent) bool
+	GetTorrentsSharingFiles(torrent config.Torrent) []config.Torrent
 	Length() int
+	StatFailures() int
 }
@@ -27,6 +27,14 @@ func (h *noopHardlinkFileMap) HardlinkedOutsideClient(torrent config.Torrent) bo
 	return false
 }
 
+func (h *noopHardlinkFileMap) GetTorrentsSharingFiles(torrent config.Torrent) []config.Torrent {
+	return nil
+}
+
 func (h *noopHardlinkFileMap) Length() int {
 	return 0
 }
+
+func (h *noopHardlinkFileMap) StatFailures() int {
+	return 0
+}
@@ -3,11 +3,20 @@ package hardlinkfilemap
 import (
 	"github.com/scylladb/go-set/strset"
 	"github.com/sirupsen/logrus"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/pathmapping"
 )
 
 type HardlinkFileMap struct {
 	// hardlinkFileMap map[string]map[string]config.Torrent
-	hardlinkFileMap    map[string]*strset.Set
+	hardlinkFileMap map[string]*strset.Set
+	// torrentsByID holds the torrents with a file of each file id, keyed by hash
+	torrentsByID       map[string]map[string]config.Torrent
 	log                *logrus.Entry
-	torrentPathMapping map[string]string
+	torrentPathMapping *pathmapping.Mapping
+	resolveSymlinks    bool
+	resolvedPaths      map[string]string
+	failClosed         bool
+	statFailures       *strset.Set
 }
@@ -2,12 +2,17 @@ package httputils
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/hashicorp/go-retryablehttp"
@@ -16,7 +21,65 @@ import (
 	"github.com/autobrr/tqm/pkg/runtime"
 )
 
-func NewRetryableHttpClient(timeout time.Duration, rl ratelimit.Limiter) *http.Client {
+// ErrRateLimited is returned by MakeAPIRequest when a request is still rate-limited after retrying
+var ErrRateLimited = errors.New("rate limited")
+
+type rateLimitHookKey struct{}
+
+// WithRateLimitHook returns a context whose MakeAPIRequest calls pass the Retry-After wait of every 429 response to
+// hook before waiting, e.g. to pause other requests to the same API
+func WithRateLimitHook(ctx context.Context, hook func(wait time.Duration)) context.Context {
+	return context.WithValue(ctx, rateLimitHookKey{}, hook)
+}
+
+// StatusError is returned by MakeAPIRequest when the response has a status code other than 200
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+}
+
+// SendError is returned by MakeAPIRequest when no response was received, after retrying, e.g. because the server
+// could not be reached or kept failing with 5xx responses
+type SendError struct {
+	Err error
+}
+
+func (e *SendError) Error() string {
+	return fmt.Sprintf("sending request: %v", e.Err)
+}
+
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
+
+// IsConnectionError reports whether err means the other end could not be reached or dropped the connection, rather
+// than answering the request
+func IsConnectionError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var sendErr *SendError
+	var netErr net.Error
+	return errors.As(err, &sendErr) || errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+var (
+	// rateLimitRetries is how many times a rate-limited (429) request is retried
+	rateLimitRetries = 3
+	// rateLimitDefaultWait is the wait before retrying when the response has no usable Retry-After header
+	rateLimitDefaultWait = 5 * time.Second
+	// rateLimitMaxWait is the longest Retry-After that is waited for, longer waits give up straight away
+	rateLimitMaxWait = 60 * time.Second
+)
+
+// NewRetryableHttpClient returns a client that retries failed requests once, tlsSkipVerify disables certificate
+// verification (e.g. for trackers using self-signed certificates)
+func NewRetryableHttpClient(timeout time.Duration, rl ratelimit.Limiter, tlsSkipVerify bool) *http.Client {
 	retryClient := retryablehttp.NewClient()
 	retryClient.RetryMax = 1
 	retryClient.RetryWaitMin = 1 * time.Second
@@ -32,7 +95,19 @@ func NewRetryableHttpClient(timeout time.Duration, rl ratelimit.Limiter) *http.C
 			rl.Take()
 		}
 	}
+	// rate-limited requests are retried by MakeAPIRequest, which caps the Retry-After wait
+	retryClient.CheckRetry = func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		if err == nil && resp != nil && resp.StatusCode == http.StatusTooManyRequests {
+			return false, nil
+		}
+		return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+	}
 	retryClient.HTTPClient.Timeout = timeout
+	if tlsSkipVerify {
+		if transport, ok := retryClient.HTTPClient.Transport.(*http.Transport); ok {
+			transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+		}
+	}
 	retryClient.Logger = nil
 	return retryClient.StandardClient()
 }
@@ -47,10 +122,60 @@ func URLWithQuery(base string, q url.Values) (string, error) {
 	return u.String(), nil
 }
 
+// MakeAPIRequest sends a request and decodes the JSON response into toType, rate-limited (429) responses are retried
+// after the Retry-After delay
 func MakeAPIRequest(ctx context.Context, client *http.Client, method string, requestURL string, body io.Reader, headers map[string]string, toType any) error {
+	// buffer the body so it can be sent again when rate-limited
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = io.ReadAll(body); err != nil {
+			return fmt.Errorf("reading request body: %w", err)
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		res, err := doAPIRequest(ctx, client, method, requestURL, payload, headers)
+		if err != nil {
+			return err
+		}
+
+		if res.StatusCode == http.StatusTooManyRequests {
+			wait := retryAfter(res.Header.Get("Retry-After"))
+			_ = res.Body.Close()
+
+			if hook, ok := ctx.Value(rateLimitHookKey{}).(func(time.Duration)); ok {
+				hook(wait)
+			}
+
+			if attempt >= rateLimitRetries {
+				return fmt.Errorf("%w: gave up after %d retries", ErrRateLimited, attempt)
+			}
+			if wait > rateLimitMaxWait {
+				return fmt.Errorf("%w: retry after %s exceeds %s", ErrRateLimited, wait, rateLimitMaxWait)
+			}
+
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("%w: %w", ErrRateLimited, ctx.Err())
+			case <-time.After(wait):
+			}
+			continue
+		}
+
+		return decodeAPIResponse(res, toType)
+	}
+}
+
+func doAPIRequest(ctx context.Context, client *http.Client, method string, requestURL string, payload []byte, headers map[string]string) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
 	req, err := http.NewRequestWithContext(ctx, method, requestURL, body)
 	if err != nil {
-		return fmt.Errorf("creating request: %w", err)
+		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
 	for k, v := range headers {
@@ -59,19 +184,41 @@ func MakeAPIRequest(ctx context.Context, client *http.Client, method string, req
 
 	res, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("sending request: %w", err)
+		return nil, &SendError{Err: err}
 	}
+
+	return res, nil
+}
+
+func decodeAPIResponse(res *http.Response, toType any) error {
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", res.StatusCode)
+		return &StatusError{StatusCode: res.StatusCode}
 	}
 
 	buf := bufio.NewReader(res.Body)
 
-	if err = json.NewDecoder(buf).Decode(toType); err != nil {
+	if err := json.NewDecoder(buf).Decode(toType); err != nil {
 		return fmt.Errorf("decoding response: %w", err)
 	}
 
 	return nil
 }
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date, falling back to rateLimitDefaultWait
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return rateLimitDefaultWait
+	}
+
+	if secs, err := strconv.ParseFloat(header, 64); err == nil && secs >= 0 {
+		return time.Duration(secs * float64(time.Second))
+	}
+
+	if t, err := http.ParseTime(header); err == nil {
+		return max(time.Until(t), 0)
+	}
+
+	return rateLimitDefaultWait
+}
@@ -0,0 +1,156 @@
+package httputils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRetryableHttpClient_TLSSkipVerify(t *testing.T) {
+	// self-signed certificate
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	var res struct {
+		OK bool `json:"ok"`
+	}
+
+	t.Run("verify_by_default", func(t *testing.T) {
+		c := NewRetryableHttpClient(5*time.Second, nil, false)
+		err := MakeAPIRequest(context.Background(), c, http.MethodGet, srv.URL, nil, nil, &res)
+		assert.Error(t, err)
+	})
+
+	t.Run("skip_verify", func(t *testing.T) {
+		c := NewRetryableHttpClient(5*time.Second, nil, true)
+		err := MakeAPIRequest(context.Background(), c, http.MethodGet, srv.URL, nil, nil, &res)
+		require.NoError(t, err)
+		assert.True(t, res.OK)
+	})
+}
+
+func TestMakeAPIRequest_RateLimited(t *testing.T) {
+	origDefault, origMax := rateLimitDefaultWait, rateLimitMaxWait
+	rateLimitDefaultWait = 10 * time.Millisecond
+	rateLimitMaxWait = time.Second
+	t.Cleanup(func() { rateLimitDefaultWait, rateLimitMaxWait = origDefault, origMax })
+
+	newServer := func(limited int, retryAfter string) (*httptest.Server, *int) {
+		requests := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			body, _ := io.ReadAll(r.Body)
+			if requests <= limited {
+				if retryAfter != "" {
+					w.Header().Set("Retry-After", retryAfter)
+				}
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			_, _ = w.Write([]byte(`{"ok":true,"body":"` + string(body) + `"}`))
+		}))
+		t.Cleanup(srv.Close)
+		return srv, &requests
+	}
+
+	var res struct {
+		OK   bool   `json:"ok"`
+		Body string `json:"body"`
+	}
+
+	t.Run("retries_after_rate_limit", func(t *testing.T) {
+		srv, requests := newServer(2, "0")
+		c := NewRetryableHttpClient(5*time.Second, nil, false)
+
+		err := MakeAPIRequest(context.Background(), c, http.MethodPost, srv.URL, strings.NewReader("payload"), nil, &res)
+		require.NoError(t, err)
+		assert.True(t, res.OK)
+		assert.Equal(t, "payload", res.Body, "body should be resent on retry")
+		assert.Equal(t, 3, *requests)
+	})
+
+	t.Run("default_wait_without_header", func(t *testing.T) {
+		srv, requests := newServer(1, "")
+		c := NewRetryableHttpClient(5*time.Second, nil, false)
+
+		err := MakeAPIRequest(context.Background(), c, http.MethodGet, srv.URL, nil, nil, &res)
+		require.NoError(t, err)
+		assert.Equal(t, 2, *requests)
+	})
+
+	t.Run("gives_up_after_retries", func(t *testing.T) {
+		srv, requests := newServer(100, "0")
+		c := NewRetryableHttpClient(5*time.Second, nil, false)
+
+		err := MakeAPIRequest(context.Background(), c, http.MethodGet, srv.URL, nil, nil, &res)
+		require.ErrorIs(t, err, ErrRateLimited)
+		assert.Equal(t, rateLimitRetries+1, *requests)
+	})
+
+	t.Run("gives_up_on_long_retry_after", func(t *testing.T) {
+		srv, requests := newServer(100, "3600")
+		c := NewRetryableHttpClient(5*time.Second, nil, false)
+
+		err := MakeAPIRequest(context.Background(), c, http.MethodGet, srv.URL, nil, nil, &res)
+		require.ErrorIs(t, err, ErrRateLimited)
+		assert.Equal(t, 1, *requests)
+	})
+
+	t.Run("hook_sees_every_rate_limit", func(t *testing.T) {
+		srv, _ := newServer(2, "0")
+		c := NewRetryableHttpClient(5*time.Second, nil, false)
+
+		var waits []time.Duration
+		ctx := WithRateLimitHook(context.Background(), func(wait time.Duration) {
+			waits = append(waits, wait)
+		})
+
+		err := MakeAPIRequest(ctx, c, http.MethodGet, srv.URL, nil, nil, &res)
+		require.NoError(t, err)
+		assert.Equal(t, []time.Duration{0, 0}, waits)
+	})
+}
+
+func TestMakeAPIRequest_Unreachable(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := srv.URL
+	srv.Close()
+
+	var resp map[string]any
+	err := MakeAPIRequest(context.Background(), &http.Client{}, http.MethodGet, url, nil, nil, &resp)
+	require.Error(t, err)
+
+	var sendErr *SendError
+	assert.ErrorAs(t, err, &sendErr)
+	assert.True(t, IsConnectionError(err))
+}
+
+func TestIsConnectionError(t *testing.T) {
+	assert.True(t, IsConnectionError(&SendError{Err: errors.New("giving up after 2 attempt(s)")}))
+	assert.True(t, IsConnectionError(fmt.Errorf("reading: %w", io.ErrUnexpectedEOF)))
+	assert.False(t, IsConnectionError(&SendError{Err: context.Canceled}))
+	assert.False(t, IsConnectionError(&StatusError{StatusCode: http.StatusBadGateway}))
+	assert.False(t, IsConnectionError(nil))
+}
+
+func TestRetryAfter(t *testing.T) {
+	assert.Equal(t, rateLimitDefaultWait, retryAfter(""))
+	assert.Equal(t, rateLimitDefaultWait, retryAfter("soon"))
+	assert.Equal(t, 2*time.Second, retryAfter("2"))
+	assert.Equal(t, 1500*time.Millisecond, retryAfter("1.5"))
+	assert.Equal(t, time.Duration(0), retryAfter(time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)))
+
+	wait := retryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	assert.InDelta(t, time.Minute, wait, float64(2*time.Second))
+}
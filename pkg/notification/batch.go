@@ -0,0 +1,158 @@
+package notification
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// batchSender is implemented by senders that can combine several notifications into a single message
+type batchSender interface {
+	SendBatch(messages []Message) error
+}
+
+// Batch buffers every notification sent during a process, so they can be flushed as one combined message. With a
+// spool, the notifications of separate invocations are kept on disk and combined until the window is over
+type Batch struct {
+	sender Sender
+	spool  *spool
+	window time.Duration
+	now    func() time.Time
+
+	mu       sync.Mutex
+	messages []Message
+}
+
+// NewBatch returns a batch flushing to sender, when dir is set the notifications are spooled in dir and only sent once
+// the oldest of them is older than window
+func NewBatch(sender Sender, dir string, window time.Duration) *Batch {
+	b := &Batch{sender: sender, window: window, now: time.Now}
+	if dir != "" {
+		b.spool = newSpool(dir)
+	}
+
+	return b
+}
+
+func (b *Batch) Name() string {
+	return b.sender.Name()
+}
+
+func (b *Batch) CanSend() bool {
+	return b.sender.CanSend()
+}
+
+func (b *Batch) BuildField(action Action, options BuildOptions) Field {
+	return b.sender.BuildField(action, options)
+}
+
+// Send buffers the notification until Flush is called
+func (b *Batch) Send(title string, description string, client string, runTime time.Duration, fields []Field, dryRun bool) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.messages = append(b.messages, Message{
+		Title:       title,
+		Description: description,
+		Client:      client,
+		RunTime:     runTime,
+		Fields:      fields,
+		DryRun:      dryRun,
+	})
+
+	return nil
+}
+
+// Flush sends the buffered notifications, combined when the sender supports it. With a spool they are added to the
+// notifications of earlier invocations instead, which are all sent together once the window is over
+func (b *Batch) Flush() error {
+	b.mu.Lock()
+	messages := b.messages
+	b.messages = nil
+	b.mu.Unlock()
+
+	if b.spool != nil {
+		spooled, err := b.spoolMessages(messages)
+		if err != nil {
+			return err
+		}
+		messages = spooled
+	}
+
+	if len(messages) == 0 {
+		return nil
+	}
+
+	return b.send(messages)
+}
+
+// spoolMessages adds messages to the spool, it returns every spooled message once the oldest is older than the window
+// and removes them from the spool, and none before that
+func (b *Batch) spoolMessages(messages []Message) ([]Message, error) {
+	for _, msg := range messages {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return nil, fmt.Errorf("marshal batched notification: %w", err)
+		}
+
+		if err := b.spool.add(spooledMessage{Payload: data, Queued: b.now()}); err != nil {
+			return nil, err
+		}
+	}
+
+	paths, err := b.spool.pending()
+	if err != nil || len(paths) == 0 {
+		return nil, err
+	}
+
+	spooled := make([]Message, 0, len(paths))
+	for i, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read batched notification: %w", err)
+		}
+
+		var sm spooledMessage
+		var msg Message
+		if err := json.Unmarshal(data, &sm); err != nil || json.Unmarshal(sm.Payload, &msg) != nil {
+			// a corrupt message would be kept forever
+			os.Remove(path)
+			continue
+		}
+
+		// the window starts with the oldest message
+		if i == 0 && b.now().Sub(sm.Queued) < b.window {
+			return nil, nil
+		}
+
+		spooled = append(spooled, msg)
+	}
+
+	// the messages are removed before sending, retrying a failed send is left to the sender (e.g. spool_dir)
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("remove batched notification: %w", err)
+		}
+	}
+
+	return spooled, nil
+}
+
+// send sends messages, combined when the sender supports it
+func (b *Batch) send(messages []Message) error {
+	if bs, ok := b.sender.(batchSender); ok {
+		return bs.SendBatch(messages)
+	}
+
+	var errs []error
+	for _, msg := range messages {
+		if err := b.sender.Send(msg.Title, msg.Description, msg.Client, msg.RunTime, msg.Fields, msg.DryRun); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,112 @@
+package notification
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/logger"
+)
+
+type countingSender struct {
+	captureSender
+	sent int
+}
+
+func (s *countingSender) Send(title string, description string, client string, runTime time.Duration, fields []Field, dryRun bool) error {
+	s.sent++
+	return s.captureSender.Send(title, description, client, runTime, fields, dryRun)
+}
+
+func TestBatch_FlushFallsBackToSend(t *testing.T) {
+	sender := &countingSender{}
+	batch := NewBatch(sender, "", 0)
+
+	require.NoError(t, batch.Send("Torrent Retag", "Retagged **1** torrent(s)", "qbt", time.Second, nil, false))
+	require.NoError(t, batch.Send("Torrent Cleanup", "Removed **2** torrent(s)", "qbt", time.Second, nil, false))
+	assert.Zero(t, sender.sent, "notifications should be buffered until flushed")
+
+	require.NoError(t, batch.Flush())
+	assert.Equal(t, 2, sender.sent)
+	assert.Equal(t, "Removed **2** torrent(s)", sender.description)
+
+	// flushing again sends nothing
+	require.NoError(t, batch.Flush())
+	assert.Equal(t, 2, sender.sent)
+}
+
+func TestBatch_FlushCombinesDiscordMessages(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		messages []DiscordMessage
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg DiscordMessage
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+
+		mu.Lock()
+		messages = append(messages, msg)
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.NotificationsConfig{}
+	cfg.Service.Discord.WebhookURL = server.URL
+
+	batch := NewBatch(NewDiscordSender(logger.GetLogger("test"), cfg), "", 0)
+	require.NoError(t, batch.Send("Torrent Retag", "Retagged **1** torrent(s)", "qbt", time.Second, nil, false))
+	require.NoError(t, batch.Send("Torrent Relabel", "Relabeled **0** torrent(s)", "qbt", time.Second, nil, false))
+	require.NoError(t, batch.Send("Torrent Cleanup", "Removed **2** torrent(s)", "qbt", time.Second, nil, true))
+	require.NoError(t, batch.Flush())
+
+	require.Len(t, messages, 1, "batched notifications should be sent as a single message")
+	require.Len(t, messages[0].Embeds, 3)
+	assert.Equal(t, "Torrent Retag", messages[0].Embeds[0].Title)
+	assert.Equal(t, "Torrent Relabel", messages[0].Embeds[1].Title)
+	assert.Equal(t, "Torrent Cleanup [Dry Run]", messages[0].Embeds[2].Title)
+}
+
+func TestBatch_SpooledAcrossInvocations(t *testing.T) {
+	dir := t.TempDir()
+	sender := &countingSender{}
+
+	current := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	invocation := func() *Batch {
+		b := NewBatch(sender, dir, 30*time.Minute)
+		b.now = func() time.Time { return current }
+		return b
+	}
+
+	// retag and relabel run as separate invocations within the window
+	retag := invocation()
+	require.NoError(t, retag.Send("Torrent Retag", "Retagged **1** torrent(s)", "qbt", time.Second, nil, false))
+	require.NoError(t, retag.Flush())
+
+	current = current.Add(time.Minute)
+	relabel := invocation()
+	require.NoError(t, relabel.Send("Torrent Relabel", "Relabeled **1** torrent(s)", "qbt", time.Second, nil, false))
+	require.NoError(t, relabel.Flush())
+	assert.Zero(t, sender.sent, "notifications should be kept until the window is over")
+
+	// the first invocation after the window sends every spooled notification
+	current = current.Add(30 * time.Minute)
+	clean := invocation()
+	require.NoError(t, clean.Send("Torrent Cleanup", "Removed **2** torrent(s)", "qbt", time.Second, nil, false))
+	require.NoError(t, clean.Flush())
+	assert.Equal(t, 3, sender.sent)
+	assert.Equal(t, "Removed **2** torrent(s)", sender.description)
+
+	paths, err := newSpool(dir).pending()
+	require.NoError(t, err)
+	assert.Empty(t, paths)
+}
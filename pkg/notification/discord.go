@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/autobrr/autobrr/pkg/errors"
@@ -29,10 +31,11 @@ const (
 )
 
 type DiscordMessage struct {
-	Content   any            `json:"content"`
-	Username  string         `json:"username,omitempty"`
-	AvatarURL string         `json:"avatar_url,omitempty"`
-	Embeds    []DiscordEmbed `json:"embeds,omitempty"`
+	Content    any            `json:"content"`
+	Username   string         `json:"username,omitempty"`
+	AvatarURL  string         `json:"avatar_url,omitempty"`
+	ThreadName string         `json:"thread_name,omitempty"`
+	Embeds     []DiscordEmbed `json:"embeds,omitempty"`
 }
 
 type DiscordEmbed struct {
@@ -215,6 +218,11 @@ type discordSender struct {
 
 	httpClient  *http.Client
 	rateLimiter *RateLimiter
+
+	// spool keeps the messages that could not be sent while the webhook is unreachable, once a message is spooled
+	// the following ones are spooled behind it to keep their order
+	spool   *spool
+	spooled atomic.Bool
 }
 
 func (d *discordSender) Name() string {
@@ -233,6 +241,11 @@ func NewDiscordSender(log *logrus.Entry, config config.NotificationsConfig) Send
 
 	sender.rateLimiter = NewRateLimiter(sender.log)
 
+	if dir := config.Service.Discord.SpoolDir; dir != "" && sender.CanSend() {
+		sender.spool = newSpool(dir)
+		sender.drainSpool()
+	}
+
 	// Start cleanup routine
 	go func() {
 		ticker := time.NewTicker(5 * time.Minute)
@@ -255,29 +268,121 @@ func (d *discordSender) calculateEmbedSize(embed DiscordEmbed) (int, error) {
 	return len(jsonData), nil
 }
 
+// discordActionTitles maps the titles of the notifications sent by each command to their action
+var discordActionTitles = map[string]string{
+	"Torrent Cleanup": "clean",
+	"Torrent Retag":   "retag",
+	"Torrent Relabel": "relabel",
+	"Torrent Pause":   "pause",
+	"Torrent Recover": "recover",
+	"Orphans":         "orphan",
+	"Tracker Down":    "tracker_down",
+	"Watchlist":       "watchlist",
+}
+
+// identity returns the username and avatar to post the notifications titled title with, the action's override
+// falling back to the global values
+func (d *discordSender) identity(titles ...string) config.DiscordIdentity {
+	cfg := d.config.Service.Discord
+	identity := config.DiscordIdentity{Username: cfg.Username, AvatarURL: cfg.AvatarURL}
+
+	// a combined message only takes the override when all its notifications are of the same action
+	action := ""
+	for i, title := range titles {
+		if a := discordActionTitles[title]; i == 0 {
+			action = a
+		} else if a != action {
+			return identity
+		}
+	}
+
+	override, ok := cfg.Actions[action]
+	if !ok {
+		return identity
+	}
+
+	if override.Username != "" {
+		identity.Username = override.Username
+	}
+	if override.AvatarURL != "" {
+		identity.AvatarURL = override.AvatarURL
+	}
+
+	return identity
+}
+
 func (d *discordSender) Send(title string, description string, client string, runTime time.Duration, fields []Field, dryRun bool) error {
+	identity := d.identity(title)
+	title, embeds := d.buildEmbeds(Message{
+		Title:       title,
+		Description: description,
+		Client:      client,
+		RunTime:     runTime,
+		Fields:      fields,
+		DryRun:      dryRun,
+	})
+	if len(embeds) == 0 {
+		return nil
+	}
+
+	return d.sendEmbeds(title, embeds, identity)
+}
+
+// SendBatch sends several notifications as one combined message, split only where discord's limits require it
+func (d *discordSender) SendBatch(messages []Message) error {
+	var (
+		allEmbeds []DiscordEmbed
+		titles    []string
+		rawTitles []string
+	)
+
+	for _, msg := range messages {
+		title, embeds := d.buildEmbeds(msg)
+		if len(embeds) == 0 {
+			continue
+		}
+		rawTitles = append(rawTitles, msg.Title)
+
+		// keep each notification recognizable within the combined message
+		if embeds[0].Title == "" {
+			embeds[0].Title = escapeDiscordMarkdown(title)
+		}
+
+		allEmbeds = append(allEmbeds, embeds...)
+		titles = append(titles, title)
+	}
+
+	if len(allEmbeds) == 0 {
+		return nil
+	}
+
+	return d.sendEmbeds(strings.Join(titles, " | "), allEmbeds, d.identity(rawTitles...))
+}
+
+// buildEmbeds returns the (dry run adjusted) title and the embeds of a notification, no embeds means it is skipped
+func (d *discordSender) buildEmbeds(msg Message) (string, []DiscordEmbed) {
 	var (
 		allEmbeds   []DiscordEmbed
+		title       = msg.Title
+		description = msg.Description
+		client      = msg.Client
+		fields      = msg.Fields
 		totalFields = len(fields)
 		timestamp   = time.Now()
-
-		batches      [][]DiscordEmbed
-		currentBatch []DiscordEmbed
-		currentChars int
 	)
 
 	// Add (Dry Run) to title if enabled
-	if dryRun {
+	if msg.DryRun {
 		title = title + " [Dry Run]"
 	}
 
 	// if the config setting "skip_empty_run" is set to true, and there are no fields,
 	// skip sending the message entirely.
 	if totalFields == 0 && d.config.SkipEmptyRun {
-		return nil
+		return title, nil
 	}
 
-	rt := runTime.Truncate(time.Millisecond).String()
+	rt := msg.RunTime.Truncate(time.Millisecond).String()
 
 	// only send a summary embed if no fields are present, there are more fields than allowed,
 	// or the config setting "detailed" is set to false
@@ -325,6 +430,17 @@ func (d *discordSender) Send(title string, description string, client string, ru
 		}
 	}
 
+	return title, allEmbeds
+}
+
+// sendEmbeds posts embeds in as few messages as discord's limits allow
+func (d *discordSender) sendEmbeds(title string, allEmbeds []DiscordEmbed, identity config.DiscordIdentity) error {
+	var (
+		batches      [][]DiscordEmbed
+		currentBatch []DiscordEmbed
+		currentChars int
+	)
+
 	// Batch embeds for messages (max 10 embeds per message)
 	flush := func() {
 		if len(currentBatch) == 0 {
@@ -352,6 +468,7 @@ func (d *discordSender) Send(title string, description string, client string, ru
 	flush()
 
 	totalMsgs := len(batches)
+	threadID := d.config.Service.Discord.ThreadID
 
 	for i, batch := range batches {
 		// Only set the title if it's the first embed in the batch and doesn't already have a title
@@ -366,20 +483,44 @@ func (d *discordSender) Send(title string, description string, client string, ru
 
 		msg := DiscordMessage{
 			Content:   nil,
-			Username:  d.config.Service.Discord.Username,
-			AvatarURL: d.config.Service.Discord.AvatarURL,
+			Username:  identity.Username,
+			AvatarURL: identity.AvatarURL,
 			Embeds:    batch,
 		}
 
+		// the first message creates the thread, the rest are posted into it
+		if threadID == "" {
+			msg.ThreadName = d.config.Service.Discord.ThreadName
+		}
+
 		jsonData, err := json.Marshal(msg)
 		if err != nil {
 			return errors.Wrap(err, "could not marshal json request for a message chunk")
 		}
 
-		if sendErr := d.sendRequest(jsonData); sendErr != nil {
+		if d.spooled.Load() {
+			if err := d.spoolMessage(jsonData, threadID, msg.ThreadName != ""); err != nil {
+				return err
+			}
+			continue
+		}
+
+		createdThreadID, sendErr := d.sendRequest(jsonData, threadID, msg.ThreadName != "")
+		if sendErr != nil && d.spool != nil && isWebhookUnreachable(sendErr) {
+			d.log.WithError(sendErr).Warn("Discord webhook unreachable, spooling notifications for the next run")
+			d.spooled.Store(true)
+			if err := d.spoolMessage(jsonData, threadID, msg.ThreadName != ""); err != nil {
+				return err
+			}
+			continue
+		} else if sendErr != nil {
 			return errors.Wrap(sendErr, "failed to send a message chunk to Discord")
 		}
 
+		if createdThreadID != "" {
+			threadID = createdThreadID
+		}
+
 		d.log.Debugf("Sent Discord message %d/%d (%d embeds, %d chars).",
 			i+1, totalMsgs, len(batch), len(jsonData))
 	}
@@ -388,11 +529,62 @@ func (d *discordSender) Send(title string, description string, client string, ru
 	return nil
 }
 
+// spoolMessage stores a message that could not be sent, to be sent by the next run
+func (d *discordSender) spoolMessage(jsonData []byte, threadID string, createThread bool) error {
+	if err := d.spool.add(spooledMessage{Payload: jsonData, ThreadID: threadID, CreateThread: createThread}); err != nil {
+		return errors.Wrap(err, "failed to spool a message chunk")
+	}
+
+	d.log.Debug("Spooled Discord message")
+	return nil
+}
+
+// drainSpool sends the messages spooled by earlier runs, when the webhook is still unreachable new messages are
+// spooled behind them
+func (d *discordSender) drainSpool() {
+	sent, err := d.spool.drain(func(msg spooledMessage) error {
+		_, err := d.sendRequest(msg.Payload, msg.ThreadID, msg.CreateThread)
+		return err
+	})
+	if sent > 0 {
+		d.log.Infof("Sent %d spooled Discord message(s)", sent)
+	}
+	if err != nil {
+		d.log.WithError(err).Warn("Failed sending spooled Discord messages, spooling new notifications behind them")
+		d.spooled.Store(true)
+	}
+}
+
 func (d *discordSender) CanSend() bool {
 	return d.config.Service.Discord.WebhookURL != ""
 }
 
-func (d *discordSender) sendRequest(jsonData []byte) error {
+// webhookURL returns the configured webhook url with the thread query params applied
+func (d *discordSender) webhookURL(threadID string, wait bool) (string, error) {
+	if threadID == "" && !wait {
+		return d.config.Service.Discord.WebhookURL, nil
+	}
+
+	u, err := url.Parse(d.config.Service.Discord.WebhookURL)
+	if err != nil {
+		return "", errors.Wrap(err, "could not parse webhook url")
+	}
+
+	q := u.Query()
+	if threadID != "" {
+		q.Set("thread_id", threadID)
+	}
+	if wait {
+		// wait for the created message so the id of the new thread can be read from the response
+		q.Set("wait", "true")
+	}
+	u.RawQuery = q.Encode()
+
+	return u.String(), nil
+}
+
+// sendRequest posts a message to the webhook, returning the id of the thread when one was created
+func (d *discordSender) sendRequest(jsonData []byte, threadID string, createThread bool) (string, error) {
 	// Extract bucket identifier from webhook URL for rate limiting
 	// Discord webhooks use a per-webhook bucket system
 	bucket := d.getBucketFromURL(d.config.Service.Discord.WebhookURL)
@@ -400,16 +592,21 @@ func (d *discordSender) sendRequest(jsonData []byte) error {
 	// Wait for rate limit clearance
 	d.rateLimiter.Wait(bucket)
 
-	req, err := http.NewRequest(http.MethodPost, d.config.Service.Discord.WebhookURL, bytes.NewBuffer(jsonData))
+	webhookURL, err := d.webhookURL(threadID, createThread)
 	if err != nil {
-		return errors.Wrap(err, "could not create request")
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, webhookURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", errors.Wrap(err, "could not create request")
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 
 	res, err := d.httpClient.Do(req)
 	if err != nil {
-		return errors.Wrap(err, "client request error")
+		return "", errors.Wrap(err, "client request error")
 	}
 	defer res.Body.Close()
 
@@ -422,27 +619,40 @@ func (d *discordSender) sendRequest(jsonData []byte) error {
 	if res.StatusCode == http.StatusTooManyRequests {
 		body, readErr := io.ReadAll(bufio.NewReader(res.Body))
 		if readErr != nil {
-			return errors.Wrap(readErr, "could not read rate limit response body")
+			return "", errors.Wrap(readErr, "could not read rate limit response body")
 		}
 
 		d.log.Warnf("Discord rate limit hit (429): %s", string(body))
 
 		// The rate limiter has already been updated with retry-after info
 		// Return error to indicate the request failed due to rate limiting
-		return errors.New("discord rate limit exceeded, request will be retried")
+		return "", errors.Wrap(&webhookStatusError{StatusCode: res.StatusCode, Body: string(body)}, "discord rate limit exceeded")
 	}
 
 	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent {
 		body, readErr := io.ReadAll(bufio.NewReader(res.Body))
 		if readErr != nil {
-			return errors.Wrap(readErr, "could not read body")
+			return "", errors.Wrap(readErr, "could not read body")
 		}
 
-		return errors.New("unexpected status: %v body: %v", res.StatusCode, string(body))
+		return "", &webhookStatusError{StatusCode: res.StatusCode, Body: string(body)}
 	}
 
 	d.log.Debug("Notification successfully sent to discord")
-	return nil
+
+	if !createThread {
+		return "", nil
+	}
+
+	// with wait=true the created message is returned, its channel is the new thread
+	var created struct {
+		ChannelID string `json:"channel_id"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&created); err != nil {
+		return "", errors.Wrap(err, "could not decode created message")
+	}
+
+	return created.ChannelID, nil
 }
 
 // getBucketFromURL extracts a bucket identifier from the webhook URL
@@ -468,10 +678,14 @@ func (d *discordSender) BuildField(action Action, opt BuildOptions) Field {
 		return d.buildRelabelField(opt.Torrent, opt.NewLabel)
 	case ActionClean:
 		return d.buildGenericField(opt.Torrent, opt.RemovalReason)
-	case ActionPause:
+	case ActionPause, ActionRecover:
 		return d.buildGenericField(opt.Torrent, "")
 	case ActionOrphan:
 		return d.buildOrphanField(opt.Orphan, opt.OrphanSize, opt.IsFile)
+	case ActionTrackerDown:
+		return d.buildTrackerDownField(opt.Tracker, opt.TrackerDown, opt.TrackerTotal)
+	case ActionWatchlist:
+		return d.buildGenericField(opt.Torrent, strings.Join(opt.WatchConditions, ", "))
 	}
 
 	return Field{}
@@ -649,6 +863,34 @@ func (d *discordSender) buildOrphanField(orphan string, orphanSize int64, isFile
 	}
 }
 
+func (d *discordSender) buildTrackerDownField(tracker string, down int, total int) Field {
+	var percent float64
+	if total > 0 {
+		percent = float64(down) / float64(total) * 100
+	}
+
+	inlineFields := []DiscordEmbedsField{
+		{
+			Name:   "Down",
+			Value:  fmt.Sprintf("%d/%d torrents", down, total),
+			Inline: true,
+		},
+		{
+			Name:   "Ratio",
+			Value:  fmt.Sprintf("%.0f%%", percent),
+			Inline: true,
+		},
+	}
+
+	// Serialize to JSON to store in the field value
+	jsonData, _ := json.Marshal(inlineFields)
+
+	return Field{
+		Name:  tracker,
+		Value: string(jsonData),
+	}
+}
+
 func (d *discordSender) buildFooter(progress int, totalFields int, client string, runTime string) string {
 	if totalFields == 0 {
 		return fmt.Sprintf("Client: %s | Started: %s ago", client, runTime)
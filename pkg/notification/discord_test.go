@@ -0,0 +1,65 @@
+package notification
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/logger"
+)
+
+func TestDiscordSender_ActionIdentity(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		messages []DiscordMessage
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var msg DiscordMessage
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&msg))
+
+		mu.Lock()
+		messages = append(messages, msg)
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := config.NotificationsConfig{}
+	cfg.Service.Discord.WebhookURL = server.URL
+	cfg.Service.Discord.Username = "tqm"
+	cfg.Service.Discord.AvatarURL = "https://example.com/tqm.png"
+	cfg.Service.Discord.Actions = map[string]config.DiscordIdentity{
+		"clean": {Username: "tqm clean", AvatarURL: "https://example.com/trash.png"},
+		"retag": {AvatarURL: "https://example.com/tag.png"},
+	}
+
+	sender := NewDiscordSender(logger.GetLogger("test"), cfg)
+	require.NoError(t, sender.Send("Torrent Cleanup", "Removed **1** torrent(s)", "qbt", time.Second, nil, true))
+	require.NoError(t, sender.Send("Torrent Retag", "Retagged **1** torrent(s)", "qbt", time.Second, nil, false))
+	require.NoError(t, sender.Send("Torrent Pause", "Paused **1** torrent(s)", "qbt", time.Second, nil, false))
+
+	// a combined message of different actions keeps the global identity
+	batch := NewBatch(sender, "", 0)
+	require.NoError(t, batch.Send("Torrent Retag", "Retagged **1** torrent(s)", "qbt", time.Second, nil, false))
+	require.NoError(t, batch.Send("Torrent Cleanup", "Removed **1** torrent(s)", "qbt", time.Second, nil, false))
+	require.NoError(t, batch.Flush())
+
+	require.Len(t, messages, 4)
+	assert.Equal(t, "tqm clean", messages[0].Username)
+	assert.Equal(t, "https://example.com/trash.png", messages[0].AvatarURL)
+	assert.Equal(t, "tqm", messages[1].Username, "an unset override keeps the global username")
+	assert.Equal(t, "https://example.com/tag.png", messages[1].AvatarURL)
+	assert.Equal(t, "tqm", messages[2].Username)
+	assert.Equal(t, "https://example.com/tqm.png", messages[2].AvatarURL)
+	assert.Equal(t, "tqm", messages[3].Username)
+	assert.Equal(t, "https://example.com/tqm.png", messages[3].AvatarURL)
+}
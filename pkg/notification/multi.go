@@ -0,0 +1,154 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/autobrr/tqm/pkg/config"
+)
+
+// services are the constructors of the notification services in NotificationsConfig.Service
+var services = []func(log *logrus.Entry, config config.NotificationsConfig) Sender{
+	NewDiscordSender,
+}
+
+// NewSender returns the sender of the configured notification services, each notification is sent to every service
+// that is configured
+func NewSender(log *logrus.Entry, config config.NotificationsConfig) Sender {
+	all := make([]Sender, 0, len(services))
+	configured := make([]Sender, 0, len(services))
+	for _, newService := range services {
+		s := newService(log, config)
+		all = append(all, s)
+		if s.CanSend() {
+			configured = append(configured, s)
+		}
+	}
+
+	if len(configured) == 0 {
+		return newMultiSender(all...)
+	}
+
+	return newMultiSender(configured...)
+}
+
+// multiSender fans out every notification to the senders that can send
+type multiSender struct {
+	senders []Sender
+}
+
+func newMultiSender(senders ...Sender) Sender {
+	if len(senders) == 1 {
+		return senders[0]
+	}
+
+	return &multiSender{senders: senders}
+}
+
+func (m *multiSender) Name() string {
+	names := make([]string, 0, len(m.senders))
+	for _, s := range m.senders {
+		names = append(names, s.Name())
+	}
+
+	return strings.Join(names, "+")
+}
+
+// CanSend reports whether any of the senders can send
+func (m *multiSender) CanSend() bool {
+	for _, s := range m.senders {
+		if s.CanSend() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// BuildField builds the field with every sender, each of them is sent its own. The name and value are the ones of the
+// first sender that can send
+func (m *multiSender) BuildField(action Action, options BuildOptions) Field {
+	perSender := make([]Field, len(m.senders))
+	first := -1
+	for i, s := range m.senders {
+		perSender[i] = s.BuildField(action, options)
+		if first == -1 && s.CanSend() {
+			first = i
+		}
+	}
+
+	field := perSender[max(first, 0)]
+	field.PerSender = perSender
+	return field
+}
+
+// senderFields returns the fields built by the sender at index i
+func (m *multiSender) senderFields(i int, fields []Field) []Field {
+	built := make([]Field, len(fields))
+	for j, f := range fields {
+		if len(f.PerSender) == len(m.senders) {
+			built[j] = f.PerSender[i]
+		} else {
+			built[j] = f
+		}
+	}
+
+	return built
+}
+
+// senderMessages returns messages with the fields built by the sender at index i
+func (m *multiSender) senderMessages(i int, messages []Message) []Message {
+	built := make([]Message, len(messages))
+	for j, msg := range messages {
+		msg.Fields = m.senderFields(i, msg.Fields)
+		built[j] = msg
+	}
+
+	return built
+}
+
+// Send sends the notification with every sender that can send, a failing sender does not stop the others
+func (m *multiSender) Send(title string, description string, client string, runTime time.Duration, fields []Field, dryRun bool) error {
+	var errs []error
+	for i, s := range m.senders {
+		if !s.CanSend() {
+			continue
+		}
+
+		if err := s.Send(title, description, client, runTime, m.senderFields(i, fields), dryRun); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// SendBatch sends the messages with every sender that can send, combined when the sender supports it
+func (m *multiSender) SendBatch(messages []Message) error {
+	var errs []error
+	for i, s := range m.senders {
+		if !s.CanSend() {
+			continue
+		}
+
+		built := m.senderMessages(i, messages)
+		if bs, ok := s.(batchSender); ok {
+			if err := bs.SendBatch(built); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.Name(), err))
+			}
+			continue
+		}
+
+		for _, msg := range built {
+			if err := s.Send(msg.Title, msg.Description, msg.Client, msg.RunTime, msg.Fields, msg.DryRun); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", s.Name(), err))
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,93 @@
+package notification
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/logger"
+)
+
+// stubSender records the notifications it sends, failing them with err
+type stubSender struct {
+	name    string
+	canSend bool
+	err     error
+	titles  []string
+	fields  []Field
+}
+
+func (s *stubSender) CanSend() bool { return s.canSend }
+func (s *stubSender) Name() string  { return s.name }
+
+func (s *stubSender) BuildField(Action, BuildOptions) Field {
+	return Field{Name: s.name}
+}
+
+func (s *stubSender) Send(title string, _ string, _ string, _ time.Duration, fields []Field, _ bool) error {
+	s.titles = append(s.titles, title)
+	s.fields = append(s.fields, fields...)
+	return s.err
+}
+
+func TestMultiSender(t *testing.T) {
+	disabled := &stubSender{name: "disabled"}
+	failing := &stubSender{name: "failing", canSend: true, err: errors.New("unreachable")}
+	working := &stubSender{name: "working", canSend: true}
+
+	sender := newMultiSender(disabled, failing, working)
+	assert.True(t, sender.CanSend())
+	assert.Equal(t, "disabled+failing+working", sender.Name())
+
+	field := sender.BuildField(ActionClean, BuildOptions{})
+	assert.Equal(t, "failing", field.Name)
+
+	// a failing sender does not stop the others, and each sender is sent the fields it built
+	err := sender.Send("Torrent Cleanup", "", "qbt", time.Second, []Field{field, {Name: "summary"}}, false)
+	require.ErrorContains(t, err, "failing: unreachable")
+	assert.Empty(t, disabled.titles)
+	assert.Equal(t, []string{"Torrent Cleanup"}, failing.titles)
+	assert.Equal(t, []string{"Torrent Cleanup"}, working.titles)
+	assert.Equal(t, []Field{{Name: "failing"}, {Name: "summary"}}, failing.fields)
+	assert.Equal(t, []Field{{Name: "working"}, {Name: "summary"}}, working.fields)
+
+	// batches are fanned out too
+	batch := NewBatch(newMultiSender(disabled, working), "", 0)
+	require.NoError(t, batch.Send("Torrent Retag", "", "qbt", time.Second, nil, false))
+	require.NoError(t, batch.Send("Torrent Pause", "", "qbt", time.Second, nil, false))
+	require.NoError(t, batch.Flush())
+	assert.Equal(t, []string{"Torrent Cleanup", "Torrent Retag", "Torrent Pause"}, working.titles)
+
+	assert.False(t, newMultiSender(disabled, &stubSender{name: "other"}).CanSend())
+	assert.Same(t, working, newMultiSender(working))
+}
+
+func TestNewSender_ConfiguredServices(t *testing.T) {
+	old := services
+	t.Cleanup(func() { services = old })
+
+	working := &stubSender{name: "working", canSend: true}
+	other := &stubSender{name: "other", canSend: true}
+	disabled := &stubSender{name: "disabled"}
+	services = []func(*logrus.Entry, config.NotificationsConfig) Sender{
+		func(*logrus.Entry, config.NotificationsConfig) Sender { return working },
+		func(*logrus.Entry, config.NotificationsConfig) Sender { return disabled },
+		func(*logrus.Entry, config.NotificationsConfig) Sender { return other },
+	}
+
+	sender := NewSender(logger.GetLogger("test"), config.NotificationsConfig{})
+	assert.Equal(t, "working+other", sender.Name())
+
+	require.NoError(t, sender.Send("Torrent Cleanup", "", "qbt", time.Second, nil, false))
+	assert.Equal(t, []string{"Torrent Cleanup"}, working.titles)
+	assert.Equal(t, []string{"Torrent Cleanup"}, other.titles)
+
+	// without a configured service nothing can send
+	services = services[1:2]
+	assert.False(t, NewSender(logger.GetLogger("test"), config.NotificationsConfig{}).CanSend())
+}
@@ -14,6 +14,9 @@ const (
 	ActionClean
 	ActionPause
 	ActionOrphan
+	ActionTrackerDown
+	ActionRecover
+	ActionWatchlist
 )
 
 type Sender interface {
@@ -23,9 +26,21 @@ type Sender interface {
 	Name() string
 }
 
+// Message is a single notification, as passed to Sender.Send
+type Message struct {
+	Title       string
+	Description string
+	Client      string
+	RunTime     time.Duration
+	Fields      []Field
+	DryRun      bool
+}
+
 type Field struct {
 	Name  string
 	Value string
+	// PerSender holds the field as built by each sender of a multi sender, in the order of the senders
+	PerSender []Field `json:",omitempty"`
 }
 
 type BuildOptions struct {
@@ -41,4 +56,10 @@ type BuildOptions struct {
 	Orphan     string
 	OrphanSize int64
 	IsFile     bool
+
+	Tracker      string
+	TrackerDown  int
+	TrackerTotal int
+
+	WatchConditions []string
 }
@@ -0,0 +1,137 @@
+package notification
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/autobrr/autobrr/pkg/errors"
+)
+
+// spooledMessage is a webhook payload that could not be sent, kept on disk until a later run sends it
+type spooledMessage struct {
+	Payload      json.RawMessage `json:"payload"`
+	ThreadID     string          `json:"thread_id,omitempty"`
+	CreateThread bool            `json:"create_thread,omitempty"`
+	// Queued is when a batched notification was spooled
+	Queued time.Time `json:"queued,omitempty"`
+}
+
+// spool persists the messages that could not be sent while the webhook was unreachable, in the order they were sent
+type spool struct {
+	dir string
+	seq atomic.Uint64
+}
+
+func newSpool(dir string) *spool {
+	return &spool{dir: dir}
+}
+
+// add stores msg after every message already in the spool
+func (s *spool) add(msg spooledMessage) error {
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("create spool dir: %w", err)
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("marshal spooled message: %w", err)
+	}
+
+	// names sort in the order the messages were spooled
+	name := fmt.Sprintf("%020d-%06d.json", time.Now().UnixNano(), s.seq.Add(1))
+	tmp := filepath.Join(s.dir, "."+name)
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write spooled message: %w", err)
+	}
+
+	if err := os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("rename spooled message: %w", err)
+	}
+
+	return nil
+}
+
+// pending returns the paths of the spooled messages, oldest first
+func (s *spool) pending() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read spool dir: %w", err)
+	}
+
+	var paths []string
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		paths = append(paths, filepath.Join(s.dir, e.Name()))
+	}
+	slices.Sort(paths)
+
+	return paths, nil
+}
+
+// drain sends the spooled messages oldest first, stopping at the first one that fails so the order is kept, it
+// returns the number of messages sent
+func (s *spool) drain(send func(msg spooledMessage) error) (int, error) {
+	paths, err := s.pending()
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return sent, fmt.Errorf("read spooled message: %w", err)
+		}
+
+		var msg spooledMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			// a corrupt message would block the spool forever
+			os.Remove(path)
+			continue
+		}
+
+		if err := send(msg); err != nil {
+			return sent, err
+		}
+
+		if err := os.Remove(path); err != nil {
+			return sent, fmt.Errorf("remove spooled message: %w", err)
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// webhookStatusError is a response of the webhook other than a success
+type webhookStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *webhookStatusError) Error() string {
+	return fmt.Sprintf("unexpected status: %v body: %v", e.StatusCode, e.Body)
+}
+
+// isWebhookUnreachable reports whether a failed send should be retried later, the webhook could not be reached, was
+// rate limited or failed on its side. Other responses mean the message itself was rejected
+func isWebhookUnreachable(err error) bool {
+	var statusErr *webhookStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	return err != nil
+}
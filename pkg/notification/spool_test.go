@@ -0,0 +1,104 @@
+package notification
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/logger"
+)
+
+// fakeWebhook records the titles of the messages it accepts, responding with status
+type fakeWebhook struct {
+	mu     sync.Mutex
+	status int
+	titles []string
+}
+
+func (f *fakeWebhook) setStatus(status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.status = status
+}
+
+func (f *fakeWebhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.status != http.StatusNoContent {
+		w.WriteHeader(f.status)
+		return
+	}
+
+	var msg DiscordMessage
+	if err := json.NewDecoder(r.Body).Decode(&msg); err == nil && len(msg.Embeds) > 0 {
+		f.titles = append(f.titles, msg.Embeds[0].Title)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestDiscordSender_Spool(t *testing.T) {
+	webhook := &fakeWebhook{status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(webhook)
+	t.Cleanup(server.Close)
+
+	cfg := config.NotificationsConfig{}
+	cfg.Service.Discord.WebhookURL = server.URL
+	cfg.Service.Discord.SpoolDir = t.TempDir()
+
+	// the webhook is down, the messages are spooled instead of failing the run
+	sender := NewDiscordSender(logger.GetLogger("test"), cfg)
+	require.NoError(t, sender.Send("First", "first", "qbt", time.Second, nil, false))
+
+	webhook.setStatus(http.StatusNoContent)
+	require.NoError(t, sender.Send("Second", "second", "qbt", time.Second, nil, false))
+	assert.Empty(t, webhook.titles, "messages should stay behind the spooled ones")
+
+	pending, err := newSpool(cfg.Service.Discord.SpoolDir).pending()
+	require.NoError(t, err)
+	assert.Len(t, pending, 2)
+
+	// the next run sends the spooled messages in order before new ones
+	sender = NewDiscordSender(logger.GetLogger("test"), cfg)
+	require.NoError(t, sender.Send("Third", "third", "qbt", time.Second, nil, false))
+	assert.Equal(t, []string{"First", "Second", "Third"}, webhook.titles)
+
+	pending, err = newSpool(cfg.Service.Discord.SpoolDir).pending()
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestDiscordSender_SpoolRejectedMessage(t *testing.T) {
+	webhook := &fakeWebhook{status: http.StatusBadRequest}
+	server := httptest.NewServer(webhook)
+	t.Cleanup(server.Close)
+
+	cfg := config.NotificationsConfig{}
+	cfg.Service.Discord.WebhookURL = server.URL
+	cfg.Service.Discord.SpoolDir = t.TempDir()
+
+	// a rejected message would be rejected again, it is not spooled
+	sender := NewDiscordSender(logger.GetLogger("test"), cfg)
+	require.Error(t, sender.Send("First", "first", "qbt", time.Second, nil, false))
+
+	pending, err := newSpool(cfg.Service.Discord.SpoolDir).pending()
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestIsWebhookUnreachable(t *testing.T) {
+	assert.False(t, isWebhookUnreachable(nil))
+	assert.True(t, isWebhookUnreachable(errors.New("connection refused")))
+	assert.True(t, isWebhookUnreachable(&webhookStatusError{StatusCode: http.StatusTooManyRequests}))
+	assert.True(t, isWebhookUnreachable(&webhookStatusError{StatusCode: http.StatusBadGateway}))
+	assert.False(t, isWebhookUnreachable(&webhookStatusError{StatusCode: http.StatusBadRequest}))
+	assert.False(t, isWebhookUnreachable(&webhookStatusError{StatusCode: http.StatusNotFound}))
+}
@@ -0,0 +1,127 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// Summary accumulates the results of each client in a multi-client run, so a single rollup notification can be sent
+type Summary struct {
+	mu      sync.Mutex
+	clients []ClientSummary
+}
+
+type ClientSummary struct {
+	Client         string
+	Count          int
+	ReclaimedBytes int64
+	// Err is why the client failed, nil when it was cleaned
+	Err error
+}
+
+func NewSummary() *Summary {
+	return &Summary{}
+}
+
+func (s *Summary) Add(client string, count int, reclaimedBytes int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clients = append(s.clients, ClientSummary{
+		Client:         client,
+		Count:          count,
+		ReclaimedBytes: reclaimedBytes,
+	})
+}
+
+// Fail records a client that could not be cleaned, the run continues with the next client
+func (s *Summary) Fail(client string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clients = append(s.clients, ClientSummary{
+		Client: client,
+		Err:    err,
+	})
+}
+
+// Failed returns the number of clients that could not be cleaned
+func (s *Summary) Failed() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	failed := 0
+	for _, c := range s.clients {
+		if c.Err != nil {
+			failed++
+		}
+	}
+
+	return failed
+}
+
+func (s *Summary) Clients() []ClientSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clients := make([]ClientSummary, len(s.clients))
+	copy(clients, s.clients)
+	return clients
+}
+
+// Totals returns the count and reclaimed bytes summed across all clients
+func (s *Summary) Totals() (int, int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		count     int
+		reclaimed int64
+	)
+	for _, c := range s.clients {
+		count += c.Count
+		reclaimed += c.ReclaimedBytes
+	}
+
+	return count, reclaimed
+}
+
+// Send sends a single notification with a field per client and the grand total in the description
+func (s *Summary) Send(noti Sender, title string, runTime time.Duration, dryRun bool) error {
+	clients := s.Clients()
+	count, reclaimed := s.Totals()
+
+	fields := make([]Field, 0, len(clients))
+	names := make([]string, 0, len(clients))
+	for _, c := range clients {
+		value := fmt.Sprintf("Removed: %d | Reclaimed: %s", c.Count, humanize.IBytes(uint64(c.ReclaimedBytes)))
+		if c.Err != nil {
+			value = fmt.Sprintf("Failed: %v", c.Err)
+		}
+
+		fields = append(fields, Field{
+			Name:  c.Client,
+			Value: value,
+		})
+		names = append(names, c.Client)
+	}
+
+	description := fmt.Sprintf("Removed **%d** torrent(s) across **%d** clients | Total reclaimed **%s**",
+		count, len(clients), humanize.IBytes(uint64(reclaimed)))
+	if failed := s.Failed(); failed > 0 {
+		description += fmt.Sprintf(" | **%d** failed", failed)
+	}
+
+	return noti.Send(
+		title,
+		description,
+		strings.Join(names, ", "),
+		runTime,
+		fields,
+		dryRun,
+	)
+}
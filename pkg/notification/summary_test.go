@@ -0,0 +1,66 @@
+package notification
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type captureSender struct {
+	description string
+	client      string
+	fields      []Field
+}
+
+func (s *captureSender) CanSend() bool                         { return true }
+func (s *captureSender) Name() string                          { return "capture" }
+func (s *captureSender) BuildField(Action, BuildOptions) Field { return Field{} }
+
+func (s *captureSender) Send(_ string, description string, client string, _ time.Duration, fields []Field, _ bool) error {
+	s.description = description
+	s.client = client
+	s.fields = fields
+	return nil
+}
+
+func TestSummary_Send(t *testing.T) {
+	summary := NewSummary()
+	summary.Add("qbt", 2, 3*1024*1024*1024)
+	summary.Add("deluge", 1, 1024*1024*1024)
+
+	count, reclaimed := summary.Totals()
+	assert.Equal(t, 3, count)
+	assert.Equal(t, int64(4*1024*1024*1024), reclaimed)
+
+	noti := &captureSender{}
+	require.NoError(t, summary.Send(noti, "Torrent Cleanup", time.Second, false))
+
+	assert.Equal(t, "Removed **3** torrent(s) across **2** clients | Total reclaimed **4.0 GiB**", noti.description)
+	assert.Equal(t, "qbt, deluge", noti.client)
+	assert.Equal(t, []Field{
+		{Name: "qbt", Value: "Removed: 2 | Reclaimed: 3.0 GiB"},
+		{Name: "deluge", Value: "Removed: 1 | Reclaimed: 1.0 GiB"},
+	}, noti.fields)
+}
+
+func TestSummary_Failed(t *testing.T) {
+	summary := NewSummary()
+	summary.Add("qbt", 2, 1024*1024*1024)
+	summary.Fail("deluge", errors.New("connect: connection refused"))
+
+	count, _ := summary.Totals()
+	assert.Equal(t, 2, count)
+	assert.Equal(t, 1, summary.Failed())
+
+	noti := &captureSender{}
+	require.NoError(t, summary.Send(noti, "Torrent Cleanup", time.Second, false))
+
+	assert.Equal(t, "Removed **2** torrent(s) across **2** clients | Total reclaimed **1.0 GiB** | **1** failed", noti.description)
+	assert.Equal(t, []Field{
+		{Name: "qbt", Value: "Removed: 2 | Reclaimed: 1.0 GiB"},
+		{Name: "deluge", Value: "Failed: connect: connection refused"},
+	}, noti.fields)
+}
@@ -0,0 +1,109 @@
+package pathmapping
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// RegexPrefix selects a regex rewrite instead of a prefix rewrite, e.g. "re:^/mnt/user/(.*)" mapped to "/data/$1"
+const RegexPrefix = "re:"
+
+type rule struct {
+	from string
+	re   *regexp.Regexp
+	to   string
+}
+
+// Mapping rewrites paths as the client reports them to paths as tqm sees them. Prefix rules are tried first, longest
+// prefix first, then regex rules in order of their pattern. The first matching rule is applied.
+type Mapping struct {
+	rules []rule
+}
+
+// New compiles the mappings of a download_path_mapping, keys starting with RegexPrefix are regular expressions and
+// their value may reference capture groups ($1, ${name})
+func New(mappings map[string]string) (*Mapping, error) {
+	m := &Mapping{}
+	for from, to := range mappings {
+		r := rule{from: from, to: to}
+		if pattern, ok := strings.CutPrefix(from, RegexPrefix); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("compile mapping %q: %w", from, err)
+			}
+			r.re = re
+		}
+
+		m.rules = append(m.rules, r)
+	}
+
+	slices.SortFunc(m.rules, func(a, b rule) int {
+		if (a.re == nil) != (b.re == nil) {
+			if a.re == nil {
+				return -1
+			}
+			return 1
+		}
+
+		if a.re == nil {
+			if c := cmp.Compare(len(b.from), len(a.from)); c != 0 {
+				return c
+			}
+		}
+
+		return cmp.Compare(a.from, b.from)
+	})
+
+	return m, nil
+}
+
+// Map returns path rewritten by the first matching rule, or path unchanged when no rule matches
+func (m *Mapping) Map(path string) (string, bool) {
+	if m == nil {
+		return path, false
+	}
+
+	for _, r := range m.rules {
+		if r.re == nil {
+			if strings.HasPrefix(path, r.from) {
+				return r.to + path[len(r.from):], true
+			}
+			continue
+		}
+
+		// only the matched part of the path is rewritten
+		loc := r.re.FindStringSubmatchIndex(path)
+		if loc == nil {
+			continue
+		}
+
+		return path[:loc[0]] + string(r.re.ExpandString(nil, r.to, path, loc)) + path[loc[1]:], true
+	}
+
+	return path, false
+}
+
+// Len returns the number of rules
+func (m *Mapping) Len() int {
+	if m == nil {
+		return 0
+	}
+
+	return len(m.rules)
+}
+
+func (m *Mapping) String() string {
+	if m == nil {
+		return "[]"
+	}
+
+	rules := make([]string, 0, len(m.rules))
+	for _, r := range m.rules {
+		rules = append(rules, fmt.Sprintf("%s -> %s", r.from, r.to))
+	}
+
+	return "[" + strings.Join(rules, ", ") + "]"
+}
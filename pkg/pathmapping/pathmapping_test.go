@@ -0,0 +1,56 @@
+package pathmapping
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapping_Map(t *testing.T) {
+	m, err := New(map[string]string{
+		"/downloads":                  "/data",
+		"/downloads/tv":               "/tv",
+		`re:^/mnt/user/(.*)`:          "/data/$1",
+		`re:^/mnt/disk\d+/(?P<p>.*)$`: "/data/${p}",
+		`re:/cache/(movies|tv)/`:      "/data/$1/",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 5, m.Len())
+
+	tests := []struct {
+		name     string
+		path     string
+		expected string
+		mapped   bool
+	}{
+		{name: "prefix", path: "/downloads/movies/a.mkv", expected: "/data/movies/a.mkv", mapped: true},
+		{name: "longest_prefix", path: "/downloads/tv/a.mkv", expected: "/tv/a.mkv", mapped: true},
+		{name: "regex", path: "/mnt/user/torrents/a.mkv", expected: "/data/torrents/a.mkv", mapped: true},
+		{name: "regex_named_group", path: "/mnt/disk12/torrents/a.mkv", expected: "/data/torrents/a.mkv", mapped: true},
+		{name: "regex_partial_match", path: "/pool/cache/tv/a.mkv", expected: "/pool/data/tv/a.mkv", mapped: true},
+		{name: "no_match", path: "/other/a.mkv", expected: "/other/a.mkv", mapped: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, mapped := m.Map(tt.path)
+			assert.Equal(t, tt.expected, got)
+			assert.Equal(t, tt.mapped, mapped)
+		})
+	}
+}
+
+func TestMapping_Nil(t *testing.T) {
+	var m *Mapping
+
+	got, mapped := m.Map("/downloads/a.mkv")
+	assert.Equal(t, "/downloads/a.mkv", got)
+	assert.False(t, mapped)
+	assert.Equal(t, 0, m.Len())
+}
+
+func TestNew_InvalidRegex(t *testing.T) {
+	_, err := New(map[string]string{"re:/mnt/(user": "/data"})
+	require.Error(t, err)
+}
@@ -0,0 +1,213 @@
+// Package schedule parses cron expressions and computes when they next run.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression, each field is a bitset of the values it matches
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// a restricted day of month or day of week, when both are restricted a day matching either runs
+	domRestricted, dowRestricted bool
+}
+
+// field is the range of a cron field
+type field struct {
+	name     string
+	min, max int
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12}
+	// 7 is accepted for sunday and folded onto 0
+	dowField = field{name: "day of week", min: 0, max: 7}
+)
+
+// descriptors are the shorthands accepted in place of the five fields
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// maxSearch bounds the search of Next, an expression that never matches (e.g. 30 February) has no next run
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// Parse parses a standard five field cron expression (minute, hour, day of month, month, day of week) or one of the
+// descriptors such as @daily. Fields accept *, values, ranges (1-5), steps (*/15, 1-10/2) and lists of these
+func Parse(spec string) (*Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if expanded, ok := descriptors[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d: %q", len(fields), spec)
+	}
+
+	s := &Schedule{}
+	var err error
+	if s.minute, err = parseField(fields[0], minuteField); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], hourField); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], domField); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], monthField); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], dowField); err != nil {
+		return nil, err
+	}
+
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+
+	s.domRestricted = fields[2] != "*"
+	s.dowRestricted = fields[4] != "*"
+	return s, nil
+}
+
+// parseField parses a comma separated cron field into a bitset
+func parseField(expr string, f field) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(expr, ",") {
+		bits, err := parsePart(part, f)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", f.name, err)
+		}
+		set |= bits
+	}
+
+	return set, nil
+}
+
+// parsePart parses a single value, range or step of a cron field
+func parsePart(part string, f field) (uint64, error) {
+	rng, stepExpr, hasStep := strings.Cut(part, "/")
+
+	step := 1
+	if hasStep {
+		var err error
+		if step, err = strconv.Atoi(stepExpr); err != nil || step <= 0 {
+			return 0, fmt.Errorf("invalid step: %q", part)
+		}
+	}
+
+	start, end := f.min, f.max
+	switch {
+	case rng == "*":
+	case strings.Contains(rng, "-"):
+		lo, hi, _ := strings.Cut(rng, "-")
+		var err error
+		if start, err = parseValue(lo, f); err != nil {
+			return 0, err
+		}
+		if end, err = parseValue(hi, f); err != nil {
+			return 0, err
+		}
+		if start > end {
+			return 0, fmt.Errorf("invalid range: %q", part)
+		}
+	default:
+		value, err := parseValue(rng, f)
+		if err != nil {
+			return 0, err
+		}
+		start = value
+		if !hasStep {
+			end = value
+		}
+	}
+
+	var set uint64
+	for v := start; v <= end; v += step {
+		set |= 1 << v
+	}
+
+	return set, nil
+}
+
+// parseValue parses a number within the range of a field
+func parseValue(value string, f field) (int, error) {
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value: %q", value)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %d out of range %d-%d", v, f.min, f.max)
+	}
+
+	return v, nil
+}
+
+// ErrNoNextRun is returned by Next for expressions that never match
+var ErrNoNextRun = errors.New("schedule never runs")
+
+// Next returns the first time after t the schedule runs, in the location of t
+func (s *Schedule) Next(t time.Time) (time.Time, error) {
+	// cron runs on whole minutes
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxSearch)
+
+	for next.Before(limit) {
+		if !has(s.month, int(next.Month())) {
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+
+		if !s.dayMatches(next) {
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+
+		if !has(s.hour, next.Hour()) {
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+			continue
+		}
+
+		if !has(s.minute, next.Minute()) {
+			next = next.Add(time.Minute)
+			continue
+		}
+
+		return next, nil
+	}
+
+	return time.Time{}, ErrNoNextRun
+}
+
+// dayMatches reports whether the day of t matches the day of month and day of week fields
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := has(s.dom, t.Day())
+	dow := has(s.dow, int(t.Weekday()))
+
+	if s.domRestricted && s.dowRestricted {
+		return dom || dow
+	}
+
+	return dom && dow
+}
+
+// has reports whether value is in set
+func has(set uint64, value int) bool {
+	return set&(1<<value) != 0
+}
@@ -0,0 +1,71 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@sometimes",
+	} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	// a wednesday
+	from := time.Date(2024, 1, 3, 10, 17, 30, 0, time.UTC)
+
+	tests := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{spec: "* * * * *", expected: time.Date(2024, 1, 3, 10, 18, 0, 0, time.UTC)},
+		{spec: "*/15 * * * *", expected: time.Date(2024, 1, 3, 10, 30, 0, 0, time.UTC)},
+		{spec: "0 3 * * *", expected: time.Date(2024, 1, 4, 3, 0, 0, 0, time.UTC)},
+		{spec: "@daily", expected: time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC)},
+		{spec: "@hourly", expected: time.Date(2024, 1, 3, 11, 0, 0, 0, time.UTC)},
+		{spec: "30 2 * * 0", expected: time.Date(2024, 1, 7, 2, 30, 0, 0, time.UTC)},
+		{spec: "30 2 * * 7", expected: time.Date(2024, 1, 7, 2, 30, 0, 0, time.UTC)},
+		{spec: "0 0 1 * *", expected: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 22-23 * * 1-5", expected: time.Date(2024, 1, 3, 22, 0, 0, 0, time.UTC)},
+		{spec: "0 12 29 2 *", expected: time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)},
+		{spec: "0,45 10 * * *", expected: time.Date(2024, 1, 3, 10, 45, 0, 0, time.UTC)},
+		// a restricted day of month and day of week run on either
+		{spec: "0 0 15 * 5", expected: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			require.NoError(t, err)
+
+			next, err := s.Next(from)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, next)
+		})
+	}
+}
+
+func TestSchedule_NextNever(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	require.NoError(t, err)
+
+	_, err = s.Next(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	require.ErrorIs(t, err, ErrNoNextRun)
+}
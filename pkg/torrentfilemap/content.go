@@ -0,0 +1,93 @@
+package torrentfilemap
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/pathmapping"
+)
+
+// contentSampleSize is the size of each sample read from the start, middle and end of a file
+const contentSampleSize = 64 * 1024
+
+// NewByContent builds a map keyed on file content instead of path, so identical files stored at different paths
+// are detected as shared. Files are identified by their size and a hash of samples of their content, paths are
+// mapped with torrentPathMapping before reading and files that cannot be read fall back to their path.
+// The map is only meant for cross-seed detection, HasPath still expects a path keyed map.
+func NewByContent(torrents map[string]config.Torrent, torrentPathMapping *pathmapping.Mapping) *TorrentFileMap {
+	tfm := &TorrentFileMap{
+		torrentFileMap: make(map[string]map[string]struct{}),
+		torrents:       make(map[string]config.Torrent, len(torrents)),
+		pathCache:      sync.Map{},
+		byContent:      true,
+		pathMapping:    torrentPathMapping,
+	}
+
+	tfm.mu.Lock()
+	for _, torrent := range torrents {
+		tfm.addInternal(torrent)
+	}
+	tfm.mu.Unlock()
+
+	return tfm
+}
+
+// key returns the map key of a torrent file, its path unless the map is keyed on content
+func (t *TorrentFileMap) key(path string) string {
+	if !t.byContent {
+		return path
+	}
+
+	if k, ok := t.contentKeys.Load(path); ok {
+		return k.(string)
+	}
+
+	mapped, _ := t.pathMapping.Map(path)
+	k, err := contentKey(mapped)
+	if err != nil {
+		k = path
+	}
+
+	t.contentKeys.Store(path, k)
+	return k
+}
+
+// contentKey identifies a file by its size and a hash of samples from its start, middle and end
+func contentKey(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("stat file: %w", err)
+	}
+
+	size := stat.Size()
+	if size == 0 || !stat.Mode().IsRegular() {
+		// empty files all share the same content, keep them keyed on their path
+		return "", fmt.Errorf("not a regular non-empty file: %s", path)
+	}
+
+	h := sha256.New()
+	buf := make([]byte, contentSampleSize)
+	for _, offset := range []int64{0, size/2 - contentSampleSize/2, size - contentSampleSize} {
+		offset = max(offset, 0)
+
+		n, err := f.ReadAt(buf, offset)
+		if err != nil && err != io.EOF {
+			return "", fmt.Errorf("read file: %w", err)
+		}
+		h.Write(buf[:n])
+	}
+
+	return "content:" + strconv.FormatInt(size, 10) + "|" + hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,85 @@
+package torrentfilemap
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/pathmapping"
+)
+
+func writeFile(t *testing.T, path string, data []byte) string {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, data, 0644))
+	return path
+}
+
+func TestNewByContent(t *testing.T) {
+	root := t.TempDir()
+
+	content := bytes.Repeat([]byte("movie"), 100_000)
+	// same size, only differs in the middle
+	other := bytes.Clone(content)
+	other[len(other)/2] = 'x'
+
+	original := writeFile(t, filepath.Join(root, "movies", "Movie.2020.mkv"), content)
+	copied := writeFile(t, filepath.Join(root, "cross-seed", "Movie (2020).mkv"), content)
+	different := writeFile(t, filepath.Join(root, "movies", "Other.2020.mkv"), other)
+	emptyA := writeFile(t, filepath.Join(root, "a", "empty.txt"), nil)
+	emptyB := writeFile(t, filepath.Join(root, "b", "empty.txt"), nil)
+
+	torrents := map[string]config.Torrent{
+		"original":  {Hash: "original", Files: []string{original}},
+		"copied":    {Hash: "copied", Files: []string{copied}},
+		"different": {Hash: "different", Files: []string{different}},
+		"emptyA":    {Hash: "emptyA", Files: []string{emptyA}},
+		"emptyB":    {Hash: "emptyB", Files: []string{emptyB}},
+		"missing":   {Hash: "missing", Files: []string{filepath.Join(root, "missing.mkv")}},
+	}
+
+	// path keyed map does not detect the copy
+	assert.True(t, New(torrents).IsUnique(torrents["original"]))
+
+	tfm := NewByContent(torrents, nil)
+
+	assert.False(t, tfm.IsUnique(torrents["original"]), "copy at a different path should share the content")
+	assert.False(t, tfm.IsUnique(torrents["copied"]), "copy at a different path should share the content")
+	assert.True(t, tfm.IsUnique(torrents["different"]))
+	assert.True(t, tfm.IsUnique(torrents["emptyA"]), "empty files should be keyed on their path")
+	assert.True(t, tfm.IsUnique(torrents["emptyB"]), "empty files should be keyed on their path")
+	assert.True(t, tfm.IsUnique(torrents["missing"]), "unreadable files should be keyed on their path")
+
+	tfm.Remove(torrents["copied"])
+	assert.True(t, tfm.IsUnique(torrents["original"]))
+	assert.False(t, tfm.NoInstances(torrents["copied"]), "original still holds the content")
+
+	tfm.Remove(torrents["original"])
+	assert.True(t, tfm.NoInstances(torrents["copied"]))
+}
+
+func TestNewByContent_PathMapping(t *testing.T) {
+	root := t.TempDir()
+	content := bytes.Repeat([]byte("episode"), 50_000)
+
+	writeFile(t, filepath.Join(root, "tv", "Show.S01E01.mkv"), content)
+	writeFile(t, filepath.Join(root, "cross-seed", "Show S01E01.mkv"), content)
+
+	// the client reports paths under /downloads, which are mounted at root locally
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Files: []string{"/downloads/tv/Show.S01E01.mkv"}},
+		"b": {Hash: "b", Files: []string{"/downloads/cross-seed/Show S01E01.mkv"}},
+	}
+
+	mapping, err := pathmapping.New(map[string]string{"/downloads": root})
+	require.NoError(t, err)
+
+	tfm := NewByContent(torrents, mapping)
+	assert.False(t, tfm.IsUnique(torrents["a"]))
+	assert.False(t, tfm.IsUnique(torrents["b"]))
+}
@@ -4,10 +4,18 @@ import (
 	"sync"
 
 	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/pathmapping"
 )
 
 type TorrentFileMap struct {
-	torrentFileMap map[string]map[string]config.Torrent
+	// torrentFileMap holds the hashes of the torrents of each file, looked up in torrents
+	torrentFileMap map[string]map[string]struct{}
+	torrents       map[string]config.Torrent
 	pathCache      sync.Map
 	mu             sync.RWMutex
+
+	// set when keyed on file content, see NewByContent
+	byContent   bool
+	pathMapping *pathmapping.Mapping
+	contentKeys sync.Map
 }
@@ -5,11 +5,13 @@ import (
 	"sync"
 
 	"github.com/autobrr/tqm/pkg/config"
+	"github.com/autobrr/tqm/pkg/pathmapping"
 )
 
 func New(torrents map[string]config.Torrent) *TorrentFileMap {
 	tfm := &TorrentFileMap{
-		torrentFileMap: make(map[string]map[string]config.Torrent),
+		torrentFileMap: make(map[string]map[string]struct{}),
+		torrents:       make(map[string]config.Torrent, len(torrents)),
 		pathCache:      sync.Map{},
 	}
 
@@ -24,14 +26,19 @@ func New(torrents map[string]config.Torrent) *TorrentFileMap {
 
 // addInternal is the non-locking version of Add for use within New
 func (t *TorrentFileMap) addInternal(torrent config.Torrent) {
+	t.torrents[torrent.Hash] = torrent
+
 	for _, f := range torrent.Files {
+		f = t.key(f)
 		if _, exists := t.torrentFileMap[f]; exists {
-			t.torrentFileMap[f][torrent.Hash] = torrent
+			// filepath already associated with other torrents
+			t.torrentFileMap[f][torrent.Hash] = struct{}{}
 			continue
 		}
 
-		t.torrentFileMap[f] = map[string]config.Torrent{
-			torrent.Hash: torrent,
+		// filepath has not been seen before, create file entry
+		t.torrentFileMap[f] = map[string]struct{}{
+			torrent.Hash: {},
 		}
 	}
 }
@@ -40,25 +47,17 @@ func (t *TorrentFileMap) Add(torrent config.Torrent) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	for _, f := range torrent.Files {
-		if _, exists := t.torrentFileMap[f]; exists {
-			// filepath already associated with other torrents
-			t.torrentFileMap[f][torrent.Hash] = torrent
-			continue
-		}
-
-		// filepath has not been seen before, create file entry
-		t.torrentFileMap[f] = map[string]config.Torrent{
-			torrent.Hash: torrent,
-		}
-	}
+	t.addInternal(torrent)
 }
 
 func (t *TorrentFileMap) Remove(torrent config.Torrent) {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
+	delete(t.torrents, torrent.Hash)
+
 	for _, f := range torrent.Files {
+		f = t.key(f)
 		if _, exists := t.torrentFileMap[f]; exists {
 			// remove this hash from the file entry
 			delete(t.torrentFileMap[f], torrent.Hash)
@@ -78,7 +77,7 @@ func (t *TorrentFileMap) IsUnique(torrent config.Torrent) bool {
 	defer t.mu.RUnlock()
 
 	for _, f := range torrent.Files {
-		if torrents, exists := t.torrentFileMap[f]; exists && len(torrents) > 1 {
+		if torrents, exists := t.torrentFileMap[t.key(f)]; exists && len(torrents) > 1 {
 			return false
 		}
 	}
@@ -91,7 +90,7 @@ func (t *TorrentFileMap) NoInstances(torrent config.Torrent) bool {
 	defer t.mu.RUnlock()
 
 	for _, f := range torrent.Files {
-		if torrents, exists := t.torrentFileMap[f]; exists && len(torrents) >= 1 {
+		if torrents, exists := t.torrentFileMap[t.key(f)]; exists && len(torrents) >= 1 {
 			return false
 		}
 	}
@@ -99,14 +98,37 @@ func (t *TorrentFileMap) NoInstances(torrent config.Torrent) bool {
 	return true
 }
 
-func (t *TorrentFileMap) HasPath(path string, torrentPathMapping map[string]string) bool {
+// GetTorrentsSharingFiles returns the other torrents sharing at least one file with torrent
+func (t *TorrentFileMap) GetTorrentsSharingFiles(torrent config.Torrent) []config.Torrent {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	seen := map[string]struct{}{torrent.Hash: {}}
+	var sharing []config.Torrent
+	for _, f := range torrent.Files {
+		for hash := range t.torrentFileMap[t.key(f)] {
+			if _, ok := seen[hash]; ok {
+				continue
+			}
+			seen[hash] = struct{}{}
+
+			if other, ok := t.torrents[hash]; ok {
+				sharing = append(sharing, other)
+			}
+		}
+	}
+
+	return sharing
+}
+
+func (t *TorrentFileMap) HasPath(path string, torrentPathMapping *pathmapping.Mapping) bool {
 	if val, found := t.pathCache.Load(path); found {
 		return val.(bool)
 	}
 
 	t.mu.RLock()
 	var found bool
-	if len(torrentPathMapping) == 0 {
+	if torrentPathMapping.Len() == 0 {
 		found = t.hasPathDirect(path)
 	} else {
 		found = t.hasPathWithMapping(path, torrentPathMapping)
@@ -129,12 +151,10 @@ func (t *TorrentFileMap) hasPathDirect(path string) bool {
 }
 
 // hasPathWithMapping checks if a path exists using torrent path mappings
-func (t *TorrentFileMap) hasPathWithMapping(path string, torrentPathMapping map[string]string) bool {
+func (t *TorrentFileMap) hasPathWithMapping(path string, torrentPathMapping *pathmapping.Mapping) bool {
 	for torrentPath := range t.torrentFileMap {
-		for mapFrom, mapTo := range torrentPathMapping {
-			if strings.Contains(strings.Replace(torrentPath, mapFrom, mapTo, 1), path) {
-				return true
-			}
+		if mapped, _ := torrentPathMapping.Map(torrentPath); strings.Contains(mapped, path) {
+			return true
 		}
 	}
 	return false
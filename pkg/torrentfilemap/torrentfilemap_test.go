@@ -0,0 +1,66 @@
+package torrentfilemap
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/autobrr/tqm/pkg/config"
+)
+
+// largeTorrents returns n torrents of files files each, every other torrent cross-seeding the one before it
+func largeTorrents(n, files int) map[string]config.Torrent {
+	torrents := make(map[string]config.Torrent, n)
+	for i := range n {
+		paths := make([]string, files)
+		for j := range files {
+			paths[j] = fmt.Sprintf("/data/torrent-%d/file-%d.flac", i/2, j)
+		}
+
+		hash := fmt.Sprintf("%040d", i)
+		torrents[hash] = config.Torrent{
+			Hash:        hash,
+			Name:        fmt.Sprintf("torrent-%d", i),
+			Files:       paths,
+			TrackerName: "tracker.example",
+		}
+	}
+
+	return torrents
+}
+
+func TestTorrentFileMap(t *testing.T) {
+	torrents := map[string]config.Torrent{
+		"a": {Hash: "a", Files: []string{"/data/movie/a.mkv", "/data/movie/a.nfo"}},
+		"b": {Hash: "b", Files: []string{"/data/movie/a.mkv"}},
+		"c": {Hash: "c", Files: []string{"/data/other/c.mkv"}},
+	}
+
+	tfm := New(torrents)
+	assert.False(t, tfm.IsUnique(torrents["a"]))
+	assert.True(t, tfm.IsUnique(torrents["c"]))
+	assert.Equal(t, []config.Torrent{torrents["b"]}, tfm.GetTorrentsSharingFiles(torrents["a"]))
+	assert.Empty(t, tfm.GetTorrentsSharingFiles(torrents["c"]))
+
+	tfm.Remove(torrents["b"])
+	assert.True(t, tfm.IsUnique(torrents["a"]))
+	assert.Empty(t, tfm.GetTorrentsSharingFiles(torrents["a"]))
+	assert.False(t, tfm.NoInstances(torrents["b"]), "a still holds the file")
+
+	tfm.Remove(torrents["a"])
+	assert.True(t, tfm.NoInstances(torrents["b"]))
+	assert.Equal(t, 1, tfm.Length())
+
+	tfm.Add(torrents["b"])
+	assert.Equal(t, 2, tfm.Length())
+}
+
+func BenchmarkNew(b *testing.B) {
+	torrents := largeTorrents(200, 500)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		New(torrents)
+	}
+}
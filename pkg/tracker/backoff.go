@@ -0,0 +1,104 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/autobrr/tqm/pkg/httputils"
+)
+
+// backoffMaxWait is the longest pause that a request waits out, requests to a tracker paused for longer fail straight
+// away as rate-limited
+const backoffMaxWait = 60 * time.Second
+
+// apiBackoff holds the trackers paused by a rate-limited (429) response
+var apiBackoff = newBackoff()
+
+// backoff pauses every request to a tracker until the Retry-After of its last rate-limited response has passed, so
+// concurrent checks stop hammering a tracker that asked them to slow down
+type backoff struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+func newBackoff() *backoff {
+	return &backoff{
+		until: make(map[string]time.Time),
+	}
+}
+
+// lock pauses the requests to the tracker for wait, an earlier pause is only ever extended
+func (b *backoff) lock(log *logrus.Entry, key string, wait time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	until := time.Now().Add(wait)
+	if until.After(b.until[key]) {
+		b.until[key] = until
+		log.Warnf("%s API rate-limited the request, pausing its requests for %s", key, wait)
+	}
+}
+
+// remaining returns how long the requests to the tracker are still paused for
+func (b *backoff) remaining(key string) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return max(time.Until(b.until[key]), 0)
+}
+
+// wait blocks until the requests to the tracker are no longer paused, failing as rate-limited when the pause is
+// longer than backoffMaxWait
+func (b *backoff) wait(ctx context.Context, key string) error {
+	wait := b.remaining(key)
+	if wait == 0 {
+		return nil
+	}
+	if wait > backoffMaxWait {
+		return withKind(ErrRateLimited, fmt.Errorf("%s API is paused for another %s", key, wait.Round(time.Second)))
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+// skipAPIRequest reports whether the per-torrent request to the API of the tracker keyed by key is skipped. Once the
+// API keeps failing the request is not made and the tracker is reported down instead, and once the request budget ran
+// out the torrent is left unchecked and counted as skipped
+func skipAPIRequest(log *logrus.Entry, key string, torrent *Torrent) bool {
+	if apiFailures.down(key) {
+		log.Tracef("Skipping %s API request for torrent: %s, the API is down", key, torrent.Name)
+		return true
+	}
+
+	if !requestBudget.take(torrent.Hash) {
+		log.Tracef("Skipping %s API request for torrent: %s, the request budget is exhausted", key, torrent.Name)
+		return true
+	}
+
+	return false
+}
+
+// makeAPIRequest makes a request to the API of the tracker once it is no longer paused, a rate-limited response
+// pauses every other request to it
+func makeAPIRequest(ctx context.Context, log *logrus.Entry, key string, client *http.Client, method string, requestURL string, body io.Reader, headers map[string]string, toType any) error {
+	if err := apiBackoff.wait(ctx, key); err != nil {
+		return err
+	}
+
+	ctx = httputils.WithRateLimitHook(ctx, func(wait time.Duration) {
+		apiBackoff.lock(log, key, wait)
+	})
+
+	return httputils.MakeAPIRequest(ctx, client, method, requestURL, body, headers, toType)
+}
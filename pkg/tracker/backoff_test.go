@@ -0,0 +1,86 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/logger"
+)
+
+func TestMakeAPIRequest_RetryAfterPausesTracker(t *testing.T) {
+	apiBackoff = newBackoff()
+	t.Cleanup(func() { apiBackoff = newBackoff() })
+
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path == "/limited" {
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	log := logger.GetLogger("test")
+	ctx := context.Background()
+
+	var resp map[string]any
+	err := makeAPIRequest(ctx, log, "BTN", server.Client(), http.MethodGet, server.URL+"/limited", nil, nil, &resp)
+	require.Error(t, err)
+	assert.True(t, errors.Is(classifyError(err), ErrRateLimited))
+	assert.EqualValues(t, 1, hits.Load())
+
+	// every request to the rate-limited tracker is refused without reaching it
+	err = makeAPIRequest(ctx, log, "BTN", server.Client(), http.MethodGet, server.URL+"/ok", nil, nil, &resp)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrRateLimited))
+	assert.EqualValues(t, 1, hits.Load())
+
+	// other trackers are not paused
+	err = makeAPIRequest(ctx, log, "HDB", server.Client(), http.MethodGet, server.URL+"/ok", nil, nil, &resp)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, hits.Load())
+}
+
+func TestBackoff_Wait(t *testing.T) {
+	log := logger.GetLogger("test")
+
+	t.Run("waits_out_short_pause", func(t *testing.T) {
+		b := newBackoff()
+		b.lock(log, "BTN", 50*time.Millisecond)
+
+		start := time.Now()
+		require.NoError(t, b.wait(context.Background(), "BTN"))
+		assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+		assert.Zero(t, b.remaining("BTN"))
+	})
+
+	t.Run("pause_only_extended", func(t *testing.T) {
+		b := newBackoff()
+		b.lock(log, "BTN", time.Hour)
+		b.lock(log, "BTN", time.Second)
+		assert.Greater(t, b.remaining("BTN"), backoffMaxWait)
+
+		err := b.wait(context.Background(), "BTN")
+		assert.True(t, errors.Is(err, ErrRateLimited))
+	})
+
+	t.Run("canceled_context", func(t *testing.T) {
+		b := newBackoff()
+		b.lock(log, "BTN", 10*time.Second)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.ErrorIs(t, b.wait(ctx, "BTN"), context.Canceled)
+	})
+}
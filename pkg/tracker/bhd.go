@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -18,7 +19,8 @@ import (
 )
 
 type BHDConfig struct {
-	Key string `koanf:"api_key"`
+	Key           string `koanf:"api_key"`
+	TLSSkipVerify bool   `koanf:"tls_skip_verify"`
 }
 
 type BHD struct {
@@ -32,7 +34,7 @@ func NewBHD(c BHDConfig) *BHD {
 	l := logger.GetLogger("bhd-api")
 	return &BHD{
 		cfg:  c,
-		http: httputils.NewRetryableHttpClient(15*time.Second, ratelimit.New(1, ratelimit.WithoutSlack)),
+		http: httputils.NewRetryableHttpClient(15*time.Second, ratelimit.New(1, ratelimit.WithoutSlack), c.TLSSkipVerify),
 		headers: map[string]string{
 			"Content-Type": "application/json",
 			"Accept":       "application/json",
@@ -105,10 +107,15 @@ func (c *BHD) IsUnregistered(ctx context.Context, torrent *Torrent) (error, bool
 		return fmt.Errorf("marshalling request: %w", sanitizeError(err)), false
 	}
 
+	if skipAPIRequest(c.log, c.Name(), torrent) {
+		return nil, false
+	}
+
 	var resp *response
-	err = httputils.MakeAPIRequest(ctx, c.http, http.MethodPost, requestURL, bytes.NewReader(body), c.headers, &resp)
+	err = makeAPIRequest(ctx, c.log, c.Name(), c.http, http.MethodPost, requestURL, bytes.NewReader(body), c.headers, &resp)
+	apiFailures.record(c.log, c.Name(), err)
 	if err != nil {
-		return fmt.Errorf("making api request: %w", sanitizeError(err)), false
+		return fmt.Errorf("making api request: %w", withKind(errorKind(err), sanitizeError(err))), false
 	}
 
 	// verify API response structure
@@ -120,5 +127,41 @@ func (c *BHD) IsUnregistered(ctx context.Context, torrent *Torrent) (error, bool
 }
 
 func (c *BHD) IsTrackerDown(_ *Torrent) (error, bool) {
-	return nil, false
+	return nil, apiFailures.down(c.Name())
+}
+
+// Probe checks the API key by searching for a hash no torrent has
+func (c *BHD) Probe(ctx context.Context) error {
+	type request struct {
+		Hash   string `json:"info_hash"`
+		Action string `json:"action"`
+	}
+
+	type response struct {
+		StatusCode int  `json:"status_code"`
+		Success    bool `json:"success"`
+	}
+
+	requestURL, err := url.JoinPath("https://beyond-hd.me/api/torrents", c.cfg.Key)
+	if err != nil {
+		return errors.New("creating request URL: invalid api key")
+	}
+
+	body, err := json.Marshal(&request{Hash: probeHash, Action: "search"})
+	if err != nil {
+		return fmt.Errorf("marshalling request: %w", err)
+	}
+
+	var resp *response
+	if err := makeAPIRequest(ctx, c.log, c.Name(), c.http, http.MethodPost, requestURL, bytes.NewReader(body), c.headers, &resp); err != nil {
+		// the api key is part of the URL, keep it out of the error
+		return fmt.Errorf("making api request: %w", withKind(errorKind(err),
+			errors.New(strings.ReplaceAll(err.Error(), c.cfg.Key, "[API_KEY_REDACTED]"))))
+	}
+
+	if !resp.Success {
+		return fmt.Errorf("API error (status code: %d)", resp.StatusCode)
+	}
+
+	return nil
 }
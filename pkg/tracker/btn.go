@@ -20,27 +20,36 @@ import (
 var torrentIDRegex = regexp.MustCompile(`https?://[^/]*broadcasthe\.net/torrents\.php\?action=reqlink&id=(\d+)`)
 
 type BTNConfig struct {
-	Key string `koanf:"api_key"`
+	Key            string `koanf:"api_key"`
+	TLSSkipVerify  bool   `koanf:"tls_skip_verify"`
+	CommentIDRegex string `koanf:"comment_id_regex"`
 }
 
 type BTN struct {
-	cfg     BTNConfig
-	http    *http.Client
-	headers map[string]string
-	log     *logrus.Entry
+	cfg            BTNConfig
+	http           *http.Client
+	headers        map[string]string
+	log            *logrus.Entry
+	commentIDRegex *regexp.Regexp
 }
 
-func NewBTN(c BTNConfig) *BTN {
+func NewBTN(c BTNConfig) (*BTN, error) {
+	commentIDRegex, err := compileCommentIDRegex(c.CommentIDRegex)
+	if err != nil {
+		return nil, err
+	}
+
 	l := logger.GetLogger("btn-api")
 	return &BTN{
-		cfg:  c,
-		http: httputils.NewRetryableHttpClient(15*time.Second, ratelimit.New(1, ratelimit.WithoutSlack)),
+		cfg:            c,
+		commentIDRegex: commentIDRegex,
+		http:           httputils.NewRetryableHttpClient(15*time.Second, ratelimit.New(1, ratelimit.WithoutSlack), c.TLSSkipVerify),
 		headers: map[string]string{
 			"Content-Type": "application/json",
 			"Accept":       "application/json",
 		},
 		log: l,
-	}
+	}, nil
 }
 
 func (c *BTN) Name() string {
@@ -57,6 +66,13 @@ func (c *BTN) extractTorrentID(comment string) (string, error) {
 		return "", fmt.Errorf("empty comment field")
 	}
 
+	// the configured pattern takes precedence, falling back to the built-in one
+	if c.commentIDRegex != nil {
+		if id, ok := matchCommentID(c.commentIDRegex, comment); ok {
+			return id, nil
+		}
+	}
+
 	matches := torrentIDRegex.FindStringSubmatch(comment)
 
 	if len(matches) < 2 {
@@ -99,7 +115,9 @@ func (c *BTN) IsUnregistered(ctx context.Context, torrent *Torrent) (error, bool
 
 	c.log.Tracef("Querying BTN API for torrent: %s (hash: %s)", torrent.Name, torrent.Hash)
 
-	torrentID, err := c.extractTorrentID(torrent.Comment)
+	torrentID, err := torrentIDs.torrentID(c.Name(), c.cfg.CommentIDRegex, torrent.Hash, func() (string, error) {
+		return c.extractTorrentID(torrent.Comment)
+	})
 	if err != nil {
 		return fmt.Errorf("extracting torrent ID: %w", err), false
 	}
@@ -116,10 +134,15 @@ func (c *BTN) IsUnregistered(ctx context.Context, torrent *Torrent) (error, bool
 		return fmt.Errorf("marshalling request: %w", err), false
 	}
 
+	if skipAPIRequest(c.log, c.Name(), torrent) {
+		return nil, false
+	}
+
 	var resp *response
-	err = httputils.MakeAPIRequest(ctx, c.http, http.MethodPost, "https://api.broadcasthe.net", bytes.NewReader(body), c.headers, &resp)
+	err = makeAPIRequest(ctx, c.log, c.Name(), c.http, http.MethodPost, "https://api.broadcasthe.net", bytes.NewReader(body), c.headers, &resp)
+	apiFailures.record(c.log, c.Name(), err)
 	if err != nil {
-		return fmt.Errorf("making api request: %w", err), false
+		return fmt.Errorf("making api request: %w", classifyError(err)), false
 	}
 
 	if resp.Error != nil {
@@ -143,5 +166,41 @@ func (c *BTN) IsUnregistered(ctx context.Context, torrent *Torrent) (error, bool
 }
 
 func (c *BTN) IsTrackerDown(_ *Torrent) (error, bool) {
-	return nil, false
+	return nil, apiFailures.down(c.Name())
+}
+
+// Probe checks the API key with a user info request
+func (c *BTN) Probe(ctx context.Context) error {
+	type request struct {
+		JsonRPC string `json:"jsonrpc"`
+		Method  string `json:"method"`
+		Params  any    `json:"params"`
+		ID      int    `json:"id"`
+	}
+
+	type rpcError struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+
+	type response struct {
+		Result any       `json:"result,omitempty"`
+		Error  *rpcError `json:"error,omitempty"`
+	}
+
+	body, err := json.Marshal(&request{ID: 1, JsonRPC: "2.0", Method: "userInfo", Params: [1]string{c.cfg.Key}})
+	if err != nil {
+		return fmt.Errorf("marshalling request: %w", err)
+	}
+
+	var resp *response
+	if err := makeAPIRequest(ctx, c.log, c.Name(), c.http, http.MethodPost, "https://api.broadcasthe.net", bytes.NewReader(body), c.headers, &resp); err != nil {
+		return fmt.Errorf("making api request: %w", classifyError(err))
+	}
+
+	if resp.Error != nil {
+		return fmt.Errorf("API error: %s (code: %d)", resp.Error.Message, resp.Error.Code)
+	}
+
+	return nil
 }
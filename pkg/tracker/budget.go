@@ -0,0 +1,68 @@
+package tracker
+
+import (
+	"errors"
+	"sync"
+)
+
+var errBudgetExhausted = errors.New("tracker request budget exhausted")
+
+// requestBudget caps the tracker API requests of a run, shared by every tracker
+var requestBudget = newBudget(0)
+
+// budget counts the API requests made against a limit, the torrents whose check was skipped once it ran out are
+// remembered so they can be reported
+type budget struct {
+	mu      sync.Mutex
+	limit   int
+	used    int
+	skipped map[string]struct{}
+}
+
+func newBudget(limit int) *budget {
+	return &budget{
+		limit:   limit,
+		skipped: make(map[string]struct{}),
+	}
+}
+
+// take uses one request of the budget, returning false when none is left. hash is the torrent the request is for,
+// recorded as skipped when the budget ran out, or empty for a request not made for a single torrent
+func (b *budget) take(hash string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.limit <= 0 || b.used < b.limit {
+		b.used++
+		return true
+	}
+
+	if hash != "" {
+		b.skipped[hash] = struct{}{}
+	}
+	return false
+}
+
+// skip records hash as skipped, for a torrent left unchecked because a request made for many torrents was refused
+func (b *budget) skip(hash string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.skipped[hash] = struct{}{}
+}
+
+// BudgetLimit returns the max_requests_per_run budget, 0 when requests are not capped
+func BudgetLimit() int {
+	requestBudget.mu.Lock()
+	defer requestBudget.mu.Unlock()
+
+	return max(requestBudget.limit, 0)
+}
+
+// BudgetSkipped returns the number of torrents whose API check was skipped because the request budget ran out
+func BudgetSkipped() int {
+	requestBudget.mu.Lock()
+	defer requestBudget.mu.Unlock()
+
+	return len(requestBudget.skipped)
+}
@@ -0,0 +1,98 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/logger"
+)
+
+func TestBudget(t *testing.T) {
+	t.Run("runs_out_after_limit", func(t *testing.T) {
+		b := newBudget(2)
+		assert.True(t, b.take("a"))
+		assert.True(t, b.take("b"))
+		assert.False(t, b.take("c"))
+		assert.False(t, b.take("c"))
+		assert.False(t, b.take(""))
+		assert.Len(t, b.skipped, 1)
+	})
+
+	t.Run("zero_is_unlimited", func(t *testing.T) {
+		b := newBudget(0)
+		for i := range 100 {
+			assert.True(t, b.take(fmt.Sprint(i)))
+		}
+		assert.Empty(t, b.skipped)
+	})
+}
+
+func TestUNIT3D_BudgetExhausted(t *testing.T) {
+	orig := requestBudget
+	requestBudget = newBudget(1)
+	t.Cleanup(func() { requestBudget = orig })
+
+	requests := 0
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"data":{"attributes":{"info_hash":"other"}}}`)
+	}))
+	defer srv.Close()
+
+	domain := strings.TrimPrefix(srv.URL, "https://")
+	tr, err := NewUNIT3D("test", UNIT3DConfig{APIKey: "key", Domain: domain, TLSSkipVerify: true})
+	require.NoError(t, err)
+
+	first := &Torrent{Name: "first", Hash: "abc", Comment: fmt.Sprintf("https://%s/torrents/1", domain)}
+	_, unregistered := tr.IsUnregistered(context.Background(), first)
+	assert.True(t, unregistered)
+
+	// the budget is spent, the next torrent is not checked and not reported as unregistered
+	second := &Torrent{Name: "second", Hash: "def", Comment: fmt.Sprintf("https://%s/torrents/2", domain)}
+	err, unregistered = tr.IsUnregistered(context.Background(), second)
+	assert.NoError(t, err)
+	assert.False(t, unregistered)
+	assert.Equal(t, 1, requests)
+	assert.Equal(t, 1, BudgetSkipped())
+	assert.Equal(t, 1, BudgetLimit())
+}
+
+func TestPTP_BudgetExhausted(t *testing.T) {
+	orig := requestBudget
+	requestBudget = newBudget(1)
+	t.Cleanup(func() { requestBudget = orig })
+
+	// another tracker spent the budget
+	require.True(t, requestBudget.take("other"))
+
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer srv.Close()
+
+	ptp := &PTP{
+		cfg:               PTPConfig{User: "test", Key: "test"},
+		http:              &http.Client{Transport: &redirectTransport{server: srv}},
+		headers:           map[string]string{"ApiUser": "test", "ApiKey": "test"},
+		log:               logger.GetLogger("test"),
+		unregisteredCache: make(map[string]bool),
+	}
+
+	// the bulk fetch is refused, every torrent looked up is reported as skipped
+	for _, hash := range []string{"abc", "def"} {
+		err, unregistered := ptp.IsUnregistered(context.Background(), &Torrent{Name: hash, Hash: hash})
+		assert.NoError(t, err)
+		assert.False(t, unregistered)
+	}
+	assert.Equal(t, 0, requests)
+	assert.Equal(t, 2, BudgetSkipped())
+}
@@ -0,0 +1,87 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/autobrr/tqm/pkg/httputils"
+)
+
+// classes of failed API requests, matched with errors.Is
+var (
+	// ErrAuth means the tracker refused the credentials
+	ErrAuth = errors.New("authentication failed")
+	// ErrConnection means the tracker could not be reached or failed to answer
+	ErrConnection = errors.New("connection failed")
+	// ErrRateLimited means the tracker kept rate-limiting the request
+	ErrRateLimited = errors.New("rate limited")
+	// ErrNotFound means the tracker has no such resource
+	ErrNotFound = errors.New("not found")
+)
+
+// APIError is a failed tracker API request, Kind is its class (ErrAuth, ErrConnection, ErrRateLimited or ErrNotFound).
+// It reads like the error it wraps
+type APIError struct {
+	Kind error
+	Err  error
+}
+
+func (e *APIError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *APIError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// errorKind returns the class of a failed API request, nil when it has none (e.g. a response that can't be decoded)
+func errorKind(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return nil
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Kind
+	}
+
+	if errors.Is(err, httputils.ErrRateLimited) {
+		return ErrRateLimited
+	}
+
+	var statusErr *httputils.StatusError
+	if errors.As(err, &statusErr) {
+		switch code := statusErr.StatusCode; {
+		case code == http.StatusUnauthorized || code == http.StatusForbidden:
+			return ErrAuth
+		case code == http.StatusNotFound:
+			return ErrNotFound
+		case code == http.StatusTooManyRequests:
+			return ErrRateLimited
+		case code >= http.StatusInternalServerError:
+			return ErrConnection
+		}
+		return nil
+	}
+
+	if httputils.IsConnectionError(err) || errors.Is(err, context.DeadlineExceeded) {
+		return ErrConnection
+	}
+
+	return nil
+}
+
+// classifyError wraps err in an APIError of its class, errors without a class are returned as they are
+func classifyError(err error) error {
+	return withKind(errorKind(err), err)
+}
+
+// withKind wraps err in an APIError of kind, used when err lost the chain it was classified from
+func withKind(kind error, err error) error {
+	if kind == nil || err == nil {
+		return err
+	}
+
+	return &APIError{Kind: kind, Err: err}
+}
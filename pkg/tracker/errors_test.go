@@ -0,0 +1,73 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/httputils"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected error
+	}{
+		{name: "unauthorized", err: &httputils.StatusError{StatusCode: http.StatusUnauthorized}, expected: ErrAuth},
+		{name: "forbidden", err: &httputils.StatusError{StatusCode: http.StatusForbidden}, expected: ErrAuth},
+		{name: "not_found", err: &httputils.StatusError{StatusCode: http.StatusNotFound}, expected: ErrNotFound},
+		{name: "too_many_requests", err: &httputils.StatusError{StatusCode: http.StatusTooManyRequests}, expected: ErrRateLimited},
+		{name: "rate_limited", err: fmt.Errorf("%w: gave up", httputils.ErrRateLimited), expected: ErrRateLimited},
+		{name: "server_error", err: &httputils.StatusError{StatusCode: http.StatusBadGateway}, expected: ErrConnection},
+		{name: "send_failed", err: &httputils.SendError{Err: errors.New("giving up after 2 attempt(s)")}, expected: ErrConnection},
+		{name: "dial", err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, expected: ErrConnection},
+		{name: "timeout", err: fmt.Errorf("wrapped: %w", context.DeadlineExceeded), expected: ErrConnection},
+		{name: "canceled", err: &httputils.SendError{Err: context.Canceled}, expected: nil},
+		{name: "bad_request", err: &httputils.StatusError{StatusCode: http.StatusBadRequest}, expected: nil},
+		{name: "decode", err: errors.New("decoding response: unexpected EOF"), expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyError(tt.err)
+			require.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.err.Error(), err.Error())
+
+			for _, kind := range []error{ErrAuth, ErrConnection, ErrRateLimited, ErrNotFound} {
+				assert.Equal(t, kind == tt.expected, errors.Is(err, kind), "kind %v", kind)
+			}
+		})
+	}
+}
+
+func TestUNIT3D_ClassifiesErrors(t *testing.T) {
+	orig := apiFailures
+	apiFailures = newFailureCounter(-1)
+	t.Cleanup(func() { apiFailures = orig })
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	domain := strings.TrimPrefix(srv.URL, "https://")
+	tr, err := NewUNIT3D("test", UNIT3DConfig{APIKey: "key", Domain: domain, TLSSkipVerify: true})
+	require.NoError(t, err)
+
+	err, _ = tr.IsUnregistered(context.Background(), &Torrent{Name: "torrent", Hash: "abc",
+		Comment: fmt.Sprintf("https://%s/torrents/1", domain)})
+	require.ErrorIs(t, err, ErrAuth)
+
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, ErrAuth, apiErr.Kind)
+}
@@ -0,0 +1,84 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/autobrr/tqm/pkg/httputils"
+)
+
+// defaultAPIFailureThreshold is the number of consecutive API failures after which a tracker is treated as down
+const defaultAPIFailureThreshold = 5
+
+// apiFailures counts the consecutive API failures of the trackers checked per torrent
+var apiFailures = newFailureCounter(0)
+
+// failureCounter counts consecutive API failures per tracker, a tracker reaching the threshold is skipped for the
+// rest of the run instead of failing a request for every torrent
+type failureCounter struct {
+	mu        sync.Mutex
+	threshold int
+	failures  map[string]int
+}
+
+func newFailureCounter(threshold int) *failureCounter {
+	if threshold == 0 {
+		threshold = defaultAPIFailureThreshold
+	}
+
+	return &failureCounter{
+		threshold: threshold,
+		failures:  make(map[string]int),
+	}
+}
+
+// down reports whether the API of the tracker reached the failure threshold
+func (f *failureCounter) down(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.threshold > 0 && f.failures[key] >= f.threshold
+}
+
+// record counts the outcome of an API request, a successful request resets the count
+func (f *failureCounter) record(log *logrus.Entry, key string, err error) {
+	apiStats.count(key, err)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err == nil {
+		f.failures[key] = 0
+		return
+	}
+
+	if !isAPIFailure(err) {
+		return
+	}
+
+	f.failures[key]++
+	if f.threshold > 0 && f.failures[key] == f.threshold {
+		log.Warnf("%s API failed %d times in a row, treating it as down for the rest of the run", key, f.threshold)
+	}
+}
+
+// isAPIFailure reports whether err means the tracker API is unavailable, rather than rejecting the request
+func isAPIFailure(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	switch errorKind(err) {
+	case ErrConnection, ErrRateLimited:
+		return true
+	case ErrAuth, ErrNotFound:
+		return false
+	}
+
+	// other 4xx responses reject the request
+	var statusErr *httputils.StatusError
+	return !errors.As(err, &statusErr)
+}
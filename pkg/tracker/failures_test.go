@@ -0,0 +1,103 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/httputils"
+	"github.com/autobrr/tqm/pkg/logger"
+)
+
+func TestFailureCounter(t *testing.T) {
+	log := logger.GetLogger("test")
+	failure := &httputils.StatusError{StatusCode: http.StatusBadGateway}
+
+	t.Run("trips_after_consecutive_failures", func(t *testing.T) {
+		f := newFailureCounter(3)
+		f.record(log, "BTN", failure)
+		f.record(log, "BTN", failure)
+		assert.False(t, f.down("BTN"))
+
+		f.record(log, "BTN", failure)
+		assert.True(t, f.down("BTN"))
+		assert.False(t, f.down("HDB"))
+	})
+
+	t.Run("success_resets_count", func(t *testing.T) {
+		f := newFailureCounter(2)
+		f.record(log, "BTN", failure)
+		f.record(log, "BTN", nil)
+		f.record(log, "BTN", failure)
+		assert.False(t, f.down("BTN"))
+	})
+
+	t.Run("client_errors_not_counted", func(t *testing.T) {
+		f := newFailureCounter(1)
+		f.record(log, "BTN", &httputils.StatusError{StatusCode: http.StatusNotFound})
+		f.record(log, "BTN", fmt.Errorf("wrapped: %w", context.Canceled))
+		assert.False(t, f.down("BTN"))
+
+		f.record(log, "BTN", fmt.Errorf("%w: gave up", httputils.ErrRateLimited))
+		assert.True(t, f.down("BTN"))
+	})
+
+	t.Run("default_threshold", func(t *testing.T) {
+		f := newFailureCounter(0)
+		for range defaultAPIFailureThreshold - 1 {
+			f.record(log, "BTN", errors.New("connection refused"))
+		}
+		assert.False(t, f.down("BTN"))
+
+		f.record(log, "BTN", errors.New("connection refused"))
+		assert.True(t, f.down("BTN"))
+	})
+
+	t.Run("negative_threshold_never_trips", func(t *testing.T) {
+		f := newFailureCounter(-1)
+		for range 10 {
+			f.record(log, "BTN", failure)
+		}
+		assert.False(t, f.down("BTN"))
+	})
+}
+
+func TestUNIT3D_APIDown(t *testing.T) {
+	orig := apiFailures
+	apiFailures = newFailureCounter(2)
+	t.Cleanup(func() { apiFailures = orig })
+
+	requests := 0
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	domain := strings.TrimPrefix(srv.URL, "https://")
+	tr, err := NewUNIT3D("test", UNIT3DConfig{APIKey: "key", Domain: domain, TLSSkipVerify: true})
+	require.NoError(t, err)
+
+	torrent := &Torrent{Name: "torrent", Hash: "abc", Comment: fmt.Sprintf("https://%s/torrents/1", domain)}
+
+	for range 2 {
+		err, _ := tr.IsUnregistered(context.Background(), torrent)
+		assert.Error(t, err)
+	}
+
+	_, down := tr.IsTrackerDown(torrent)
+	assert.True(t, down)
+
+	// further checks are skipped without a request
+	err, unregistered := tr.IsUnregistered(context.Background(), torrent)
+	assert.NoError(t, err)
+	assert.False(t, unregistered)
+	assert.Equal(t, 2*2, requests, "each failed request is retried once by the http client")
+}
@@ -0,0 +1,219 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.uber.org/ratelimit"
+
+	"github.com/autobrr/tqm/pkg/httputils"
+	"github.com/autobrr/tqm/pkg/logger"
+)
+
+// GazelleFailure is an API response meaning the torrent is not registered, an empty status matches any status
+type GazelleFailure struct {
+	Status string `koanf:"status"`
+	Error  string `koanf:"error"`
+}
+
+type GazelleConfig struct {
+	// Preset fills in the settings of a well-known tracker, settings given explicitly take precedence
+	Preset string `koanf:"preset"`
+	APIKey string `koanf:"api_key"`
+	// Domain is matched against the tracker host of torrents
+	Domain string `koanf:"domain"`
+	// APIURL is the torrent endpoint, the info hash is added as the hash query parameter
+	APIURL        string           `koanf:"api_url"`
+	AuthHeader    string           `koanf:"auth_header"`
+	AuthPrefix    string           `koanf:"auth_prefix"`
+	NotFound      []GazelleFailure `koanf:"not_found"`
+	TLSSkipVerify bool             `koanf:"tls_skip_verify"`
+}
+
+// gazellePresets holds the settings of well-known Gazelle trackers
+var gazellePresets = map[string]GazelleConfig{
+	"red": {
+		Domain:     "flacsfor.me",
+		APIURL:     "https://redacted.sh/ajax.php?action=torrent",
+		AuthPrefix: "token ",
+		NotFound:   []GazelleFailure{{Status: "failure", Error: "bad hash parameter"}},
+	},
+	"ops": {
+		Domain:     "opsfet.ch",
+		APIURL:     "https://orpheus.network/ajax.php?action=torrent",
+		AuthPrefix: "token ",
+		NotFound:   []GazelleFailure{{Status: "failure", Error: "bad parameters"}},
+	},
+	"ggn": {
+		Domain:     "gazellegames.net",
+		APIURL:     "https://gazellegames.net/api.php?request=torrent",
+		AuthHeader: "X-API-Key",
+		NotFound: []GazelleFailure{
+			{Status: "failure", Error: "bad id"},
+			{Status: "failure", Error: "bad hash parameter"},
+		},
+	},
+}
+
+type Gazelle struct {
+	name    string
+	cfg     GazelleConfig
+	http    *http.Client
+	headers map[string]string
+	log     *logrus.Entry
+}
+
+// applyGazellePreset returns the config with the unset settings taken from its preset and the defaults
+func applyGazellePreset(c GazelleConfig) (GazelleConfig, error) {
+	if c.Preset != "" {
+		preset, ok := gazellePresets[strings.ToLower(c.Preset)]
+		if !ok {
+			return c, fmt.Errorf("unknown preset: %q", c.Preset)
+		}
+
+		if c.Domain == "" {
+			c.Domain = preset.Domain
+		}
+		if c.APIURL == "" {
+			c.APIURL = preset.APIURL
+		}
+		if c.AuthHeader == "" {
+			c.AuthHeader = preset.AuthHeader
+		}
+		if c.AuthPrefix == "" {
+			c.AuthPrefix = preset.AuthPrefix
+		}
+		if len(c.NotFound) == 0 {
+			c.NotFound = preset.NotFound
+		}
+	}
+
+	if c.Domain == "" {
+		return c, fmt.Errorf("domain is required")
+	}
+	if c.APIURL == "" {
+		c.APIURL = fmt.Sprintf("https://%s/ajax.php?action=torrent", c.Domain)
+	}
+	if c.AuthHeader == "" {
+		c.AuthHeader = "Authorization"
+	}
+	if len(c.NotFound) == 0 {
+		c.NotFound = []GazelleFailure{{Status: "failure", Error: "bad hash parameter"}}
+	}
+
+	return c, nil
+}
+
+func NewGazelle(name string, c GazelleConfig) (Interface, error) {
+	c, err := applyGazellePreset(c)
+	if err != nil {
+		return nil, err
+	}
+
+	l := logger.GetLogger(fmt.Sprintf("%s-api", strings.ToLower(name)))
+	return &Gazelle{
+		name: name,
+		cfg:  c,
+		http: httputils.NewRetryableHttpClient(15*time.Second, ratelimit.New(1, ratelimit.WithoutSlack), c.TLSSkipVerify),
+		headers: map[string]string{
+			"Accept":     "application/json",
+			c.AuthHeader: c.AuthPrefix + c.APIKey,
+		},
+		log: l,
+	}, nil
+}
+
+func (c *Gazelle) Name() string {
+	return c.name
+}
+
+func (c *Gazelle) Check(host string) bool {
+	return strings.Contains(host, c.cfg.Domain)
+}
+
+// notFound reports whether the response is one of the configured failures for unregistered torrents
+func (c *Gazelle) notFound(status string, errMsg string) bool {
+	for _, f := range c.cfg.NotFound {
+		if (f.Status == "" || strings.EqualFold(f.Status, status)) && strings.EqualFold(f.Error, errMsg) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Gazelle) IsUnregistered(ctx context.Context, torrent *Torrent) (error, bool) {
+	type response struct {
+		Status   string `json:"status"`
+		Error    string `json:"error"`
+		Response any    `json:"response"`
+	}
+
+	if c.log.Logger.IsLevelEnabled(logrus.DebugLevel) {
+		c.log.Info("-----")
+		torrent.APIDividerPrinted = true
+	}
+
+	c.log.Tracef("Querying %s API for torrent: %s (hash: %s)", c.name, torrent.Name, torrent.Hash)
+
+	u, err := url.Parse(c.cfg.APIURL)
+	if err != nil {
+		return fmt.Errorf("parsing api url: %w", err), false
+	}
+
+	q := u.Query()
+	q.Set("hash", torrent.Hash)
+	requestURL, err := httputils.URLWithQuery(c.cfg.APIURL, q)
+	if err != nil {
+		return fmt.Errorf("creating request URL: %w", err), false
+	}
+
+	if skipAPIRequest(c.log, c.name, torrent) {
+		return nil, false
+	}
+
+	var resp *response
+	err = makeAPIRequest(ctx, c.log, c.name, c.http, http.MethodGet, requestURL, nil, c.headers, &resp)
+	apiFailures.record(c.log, c.name, err)
+	if err != nil {
+		return fmt.Errorf("making api request: %w", classifyError(err)), false
+	}
+
+	return nil, c.notFound(resp.Status, resp.Error)
+}
+
+func (c *Gazelle) IsTrackerDown(_ *Torrent) (error, bool) {
+	return nil, apiFailures.down(c.name)
+}
+
+// Probe checks the API key by looking up a hash no torrent has, which the API answers as not found
+func (c *Gazelle) Probe(ctx context.Context) error {
+	type response struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	}
+
+	u, err := url.Parse(c.cfg.APIURL)
+	if err != nil {
+		return fmt.Errorf("parsing api url: %w", err)
+	}
+
+	q := u.Query()
+	q.Set("hash", probeHash)
+	requestURL, err := httputils.URLWithQuery(c.cfg.APIURL, q)
+	if err != nil {
+		return fmt.Errorf("creating request URL: %w", err)
+	}
+
+	var resp *response
+	if err := makeAPIRequest(ctx, c.log, c.name, c.http, http.MethodGet, requestURL, nil, c.headers, &resp); err != nil {
+		return fmt.Errorf("making api request: %w", classifyError(err))
+	}
+
+	return gazelleProbeError(resp.Status, resp.Error, c.notFound)
+}
@@ -0,0 +1,128 @@
+package tracker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyGazellePreset(t *testing.T) {
+	t.Run("preset", func(t *testing.T) {
+		c, err := applyGazellePreset(GazelleConfig{Preset: "GGN", APIKey: "key"})
+		require.NoError(t, err)
+		assert.Equal(t, "gazellegames.net", c.Domain)
+		assert.Equal(t, "https://gazellegames.net/api.php?request=torrent", c.APIURL)
+		assert.Equal(t, "X-API-Key", c.AuthHeader)
+		assert.Empty(t, c.AuthPrefix)
+	})
+
+	t.Run("explicit_settings_override_preset", func(t *testing.T) {
+		c, err := applyGazellePreset(GazelleConfig{
+			Preset:   "red",
+			APIURL:   "https://mirror.example/ajax.php?action=torrent",
+			NotFound: []GazelleFailure{{Error: "gone"}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "flacsfor.me", c.Domain)
+		assert.Equal(t, "https://mirror.example/ajax.php?action=torrent", c.APIURL)
+		assert.Equal(t, "token ", c.AuthPrefix)
+		assert.Equal(t, []GazelleFailure{{Error: "gone"}}, c.NotFound)
+	})
+
+	t.Run("defaults", func(t *testing.T) {
+		c, err := applyGazellePreset(GazelleConfig{Domain: "tracker.example"})
+		require.NoError(t, err)
+		assert.Equal(t, "https://tracker.example/ajax.php?action=torrent", c.APIURL)
+		assert.Equal(t, "Authorization", c.AuthHeader)
+		assert.Equal(t, []GazelleFailure{{Status: "failure", Error: "bad hash parameter"}}, c.NotFound)
+	})
+
+	t.Run("unknown_preset", func(t *testing.T) {
+		_, err := applyGazellePreset(GazelleConfig{Preset: "nope"})
+		assert.Error(t, err)
+	})
+
+	t.Run("missing_domain", func(t *testing.T) {
+		_, err := applyGazellePreset(GazelleConfig{APIKey: "key"})
+		assert.Error(t, err)
+	})
+}
+
+func TestGazelle_IsUnregistered(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "torrent", r.URL.Query().Get("request"))
+		assert.Equal(t, "key", r.Header.Get("X-API-Key"))
+
+		switch r.URL.Query().Get("hash") {
+		case "gone":
+			_, _ = w.Write([]byte(`{"status":"failure","error":"bad id"}`))
+		case "other":
+			_, _ = w.Write([]byte(`{"status":"failure","error":"rate limit exceeded"}`))
+		default:
+			_, _ = w.Write([]byte(`{"status":"success","response":{}}`))
+		}
+	}))
+	defer srv.Close()
+
+	tr, err := NewGazelle("ggn", GazelleConfig{
+		Preset:        "ggn",
+		APIKey:        "key",
+		APIURL:        srv.URL + "/api.php?request=torrent",
+		TLSSkipVerify: true,
+	})
+	require.NoError(t, err)
+	assert.True(t, tr.Check("tracker.gazellegames.net"))
+	assert.False(t, tr.Check("tracker.example"))
+
+	tests := []struct {
+		hash     string
+		expected bool
+	}{
+		{hash: "gone", expected: true},
+		{hash: "other", expected: false},
+		{hash: "exists", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hash, func(t *testing.T) {
+			err, unregistered := tr.IsUnregistered(context.Background(), &Torrent{Hash: tt.hash})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, unregistered)
+		})
+	}
+}
+
+func TestGazelle_Probe(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, probeHash, r.URL.Query().Get("hash"))
+
+		switch r.Header.Get("X-API-Key") {
+		case "key":
+			_, _ = w.Write([]byte(`{"status":"failure","error":"bad hash parameter"}`))
+		case "revoked":
+			_, _ = w.Write([]byte(`{"status":"failure","error":"invalid api key"}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer srv.Close()
+
+	probe := func(key string) error {
+		tr, err := NewGazelle("ggn", GazelleConfig{
+			Preset:        "ggn",
+			APIKey:        key,
+			APIURL:        srv.URL + "/api.php?request=torrent",
+			TLSSkipVerify: true,
+		})
+		require.NoError(t, err)
+		return tr.(Prober).Probe(context.Background())
+	}
+
+	assert.NoError(t, probe("key"))
+	assert.Error(t, probe("revoked"))
+	assert.Error(t, probe("unknown"))
+}
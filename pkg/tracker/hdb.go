@@ -17,8 +17,9 @@ import (
 )
 
 type HDBConfig struct {
-	Username string `koanf:"username"`
-	Passkey  string `koanf:"passkey"`
+	Username      string `koanf:"username"`
+	Passkey       string `koanf:"passkey"`
+	TLSSkipVerify bool   `koanf:"tls_skip_verify"`
 }
 
 type HDB struct {
@@ -32,7 +33,7 @@ func NewHDB(c HDBConfig) *HDB {
 	l := logger.GetLogger("hdb-api")
 	return &HDB{
 		cfg:  c,
-		http: httputils.NewRetryableHttpClient(15*time.Second, ratelimit.New(1, ratelimit.WithoutSlack)),
+		http: httputils.NewRetryableHttpClient(15*time.Second, ratelimit.New(1, ratelimit.WithoutSlack), c.TLSSkipVerify),
 		headers: map[string]string{
 			"Content-Type": "application/json",
 			"Accept":       "application/json",
@@ -86,10 +87,15 @@ func (c *HDB) IsUnregistered(ctx context.Context, torrent *Torrent) (error, bool
 		return fmt.Errorf("marshalling request: %w", err), false
 	}
 
+	if skipAPIRequest(c.log, c.Name(), torrent) {
+		return nil, false
+	}
+
 	var resp *response
-	err = httputils.MakeAPIRequest(ctx, c.http, http.MethodPost, "https://hdbits.org/api/torrents", bytes.NewReader(body), c.headers, &resp)
+	err = makeAPIRequest(ctx, c.log, c.Name(), c.http, http.MethodPost, "https://hdbits.org/api/torrents", bytes.NewReader(body), c.headers, &resp)
+	apiFailures.record(c.log, c.Name(), err)
 	if err != nil {
-		return fmt.Errorf("making api request: %w", err), false
+		return fmt.Errorf("making api request: %w", classifyError(err)), false
 	}
 
 	// HDB returns status 0 for success, anything else is an error
@@ -98,5 +104,35 @@ func (c *HDB) IsUnregistered(ctx context.Context, torrent *Torrent) (error, bool
 }
 
 func (c *HDB) IsTrackerDown(_ *Torrent) (error, bool) {
-	return nil, false
+	return nil, apiFailures.down(c.Name())
+}
+
+// Probe checks the username and passkey with the API test endpoint
+func (c *HDB) Probe(ctx context.Context) error {
+	type request struct {
+		Username string `json:"username"`
+		Passkey  string `json:"passkey"`
+	}
+
+	type response struct {
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	}
+
+	body, err := json.Marshal(&request{Username: c.cfg.Username, Passkey: c.cfg.Passkey})
+	if err != nil {
+		return fmt.Errorf("marshalling request: %w", err)
+	}
+
+	var resp *response
+	if err := makeAPIRequest(ctx, c.log, c.Name(), c.http, http.MethodPost, "https://hdbits.org/api/test", bytes.NewReader(body), c.headers, &resp); err != nil {
+		return fmt.Errorf("making api request: %w", classifyError(err))
+	}
+
+	// HDB returns status 0 for success, anything else is an error
+	if resp.Status != 0 {
+		return fmt.Errorf("API error: %s (status: %d)", resp.Message, resp.Status)
+	}
+
+	return nil
 }
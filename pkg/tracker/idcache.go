@@ -0,0 +1,174 @@
+package tracker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errNoTorrentID = errors.New("no torrent ID found in comment")
+
+// idCacheMaxAgeDays is how long the ID of a torrent is kept without being looked up, so the IDs of torrents that left
+// the client are pruned
+const idCacheMaxAgeDays = 30
+
+// torrentIDs caches the torrent IDs extracted from the comments of torrents, persisted to id_cache_file when set
+var torrentIDs = newIDCache("")
+
+type idCacheTracker struct {
+	// Pattern is the comment_id_regex the IDs were extracted with, the IDs are dropped once it changes
+	Pattern string `json:"pattern"`
+	// IDs holds the torrent ID per hash, empty when the comment has none
+	IDs map[string]string `json:"ids"`
+	// Seen holds the day (since the unix epoch) each hash was last looked up on
+	Seen map[string]int64 `json:"seen"`
+}
+
+// idCache maps the hashes of torrents to the torrent IDs in their comments per tracker, so the comments are not
+// parsed again every run. The comment of a torrent never changes, but the IDs of torrents not looked up for
+// idCacheMaxAgeDays are pruned when the cache is saved
+type idCache struct {
+	mu       sync.Mutex
+	path     string
+	trackers map[string]*idCacheTracker
+	dirty    bool
+	now      func() time.Time
+}
+
+func newIDCache(path string) *idCache {
+	return &idCache{
+		path:     path,
+		trackers: make(map[string]*idCacheTracker),
+		now:      time.Now,
+	}
+}
+
+// today returns the current day since the unix epoch
+func (c *idCache) today() int64 {
+	return c.now().Unix() / 86400
+}
+
+// loadIDCache reads the cache stored in path, a missing file is an empty cache
+func loadIDCache(path string) (*idCache, error) {
+	c := newIDCache(path)
+	if path == "" {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read id cache: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &c.trackers); err != nil {
+		return nil, fmt.Errorf("unmarshal id cache: %w", err)
+	}
+
+	// IDs cached before the days were recorded start their age now
+	today := c.today()
+	for _, t := range c.trackers {
+		if t.Seen == nil {
+			t.Seen = make(map[string]int64, len(t.IDs))
+		}
+		for hash := range t.IDs {
+			if _, ok := t.Seen[hash]; !ok {
+				t.Seen[hash] = today
+			}
+		}
+	}
+
+	return c, nil
+}
+
+// torrentID returns the torrent ID of the torrent with hash on tracker, calling extract on a cache miss. A comment
+// without an ID is cached too, returning errNoTorrentID on later calls
+func (c *idCache) torrentID(tracker string, pattern string, hash string, extract func() (string, error)) (string, error) {
+	if hash == "" {
+		return extract()
+	}
+
+	key := strings.ToLower(tracker)
+	hash = strings.ToUpper(hash)
+
+	c.mu.Lock()
+	t, ok := c.trackers[key]
+	if !ok || t.Pattern != pattern || t.IDs == nil {
+		t = &idCacheTracker{Pattern: pattern, IDs: make(map[string]string), Seen: make(map[string]int64)}
+		c.trackers[key] = t
+	}
+
+	id, ok := t.IDs[hash]
+	if today := c.today(); ok && t.Seen[hash] != today {
+		t.Seen[hash] = today
+		c.dirty = true
+	}
+	c.mu.Unlock()
+
+	if ok {
+		if id == "" {
+			return "", errNoTorrentID
+		}
+		return id, nil
+	}
+
+	id, err := extract()
+
+	c.mu.Lock()
+	t.IDs[hash] = id
+	t.Seen[hash] = c.today()
+	c.dirty = true
+	c.mu.Unlock()
+
+	return id, err
+}
+
+// save writes the cache to its file, when it has one and changed since it was loaded
+func (c *idCache) save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.path == "" || !c.dirty {
+		return nil
+	}
+
+	// the torrents not looked up for a while left the client
+	cutoff := c.today() - idCacheMaxAgeDays
+	for _, t := range c.trackers {
+		for hash, day := range t.Seen {
+			if day < cutoff {
+				delete(t.IDs, hash)
+				delete(t.Seen, hash)
+			}
+		}
+	}
+
+	data, err := json.Marshal(c.trackers)
+	if err != nil {
+		return fmt.Errorf("marshal id cache: %w", err)
+	}
+
+	tmp := filepath.Join(filepath.Dir(c.path), "."+filepath.Base(c.path))
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write id cache: %w", err)
+	}
+
+	if err := os.Rename(tmp, c.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("rename id cache: %w", err)
+	}
+
+	c.dirty = false
+	return nil
+}
+
+// SaveIDCache persists the torrent IDs extracted during the run to id_cache_file, when it is set
+func SaveIDCache() error {
+	return torrentIDs.save()
+}
@@ -0,0 +1,126 @@
+package tracker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIDCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ids.json")
+
+	calls := 0
+	extract := func(id string, err error) func() (string, error) {
+		return func() (string, error) {
+			calls++
+			return id, err
+		}
+	}
+
+	c, err := loadIDCache(path)
+	require.NoError(t, err)
+
+	// misses call extract, hits do not
+	id, err := c.torrentID("BTN", "", "abc", extract("123", nil))
+	require.NoError(t, err)
+	assert.Equal(t, "123", id)
+
+	id, err = c.torrentID("BTN", "", "ABC", extract("456", nil))
+	require.NoError(t, err)
+	assert.Equal(t, "123", id)
+	assert.Equal(t, 1, calls)
+
+	// a comment without an id is cached too
+	_, err = c.torrentID("BTN", "", "def", extract("", errNoTorrentID))
+	require.Error(t, err)
+	_, err = c.torrentID("BTN", "", "def", extract("789", nil))
+	require.ErrorIs(t, err, errNoTorrentID)
+	assert.Equal(t, 2, calls)
+
+	// trackers are cached apart
+	id, err = c.torrentID("aither.cc", "", "abc", extract("999", nil))
+	require.NoError(t, err)
+	assert.Equal(t, "999", id)
+	assert.Equal(t, 3, calls)
+
+	require.NoError(t, c.save())
+
+	// the ids are read back on the next run
+	c, err = loadIDCache(path)
+	require.NoError(t, err)
+
+	id, err = c.torrentID("BTN", "", "abc", extract("456", nil))
+	require.NoError(t, err)
+	assert.Equal(t, "123", id)
+	assert.Equal(t, 3, calls)
+
+	// changing the pattern drops the ids of the tracker
+	id, err = c.torrentID("BTN", `id=(\d+)`, "abc", extract("456", nil))
+	require.NoError(t, err)
+	assert.Equal(t, "456", id)
+	assert.Equal(t, 4, calls)
+}
+
+func TestIDCache_WithoutFile(t *testing.T) {
+	c, err := loadIDCache("")
+	require.NoError(t, err)
+
+	_, err = c.torrentID("BTN", "", "abc", func() (string, error) { return "123", nil })
+	require.NoError(t, err)
+	require.NoError(t, c.save())
+}
+
+func TestLoadIDCache_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ids.json")
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0o644))
+
+	_, err := loadIDCache(path)
+	assert.Error(t, err)
+}
+
+func TestIDCache_Prune(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ids.json")
+	current := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return current }
+
+	extract := func(id string) func() (string, error) {
+		return func() (string, error) { return id, nil }
+	}
+
+	c, err := loadIDCache(path)
+	require.NoError(t, err)
+	c.now = clock
+
+	_, err = c.torrentID("BTN", "", "gone", extract("1"))
+	require.NoError(t, err)
+	_, err = c.torrentID("BTN", "", "kept", extract("2"))
+	require.NoError(t, err)
+	require.NoError(t, c.save())
+
+	// only one of the torrents is still looked up
+	current = current.AddDate(0, 0, 20)
+	c, err = loadIDCache(path)
+	require.NoError(t, err)
+	c.now = clock
+
+	_, err = c.torrentID("BTN", "", "kept", extract("2"))
+	require.NoError(t, err)
+	require.NoError(t, c.save())
+
+	current = current.AddDate(0, 0, 20)
+	c, err = loadIDCache(path)
+	require.NoError(t, err)
+	c.now = clock
+
+	_, err = c.torrentID("BTN", "", "kept", extract("2"))
+	require.NoError(t, err)
+	require.NoError(t, c.save())
+
+	c, err = loadIDCache(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"KEPT": "2"}, c.trackers["btn"].IDs)
+}
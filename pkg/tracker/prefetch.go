@@ -0,0 +1,77 @@
+package tracker
+
+import (
+	"context"
+	"sync"
+)
+
+// defaultPrefetchConcurrency is the number of trackers prefetched at once when prefetch_concurrency is not set
+const defaultPrefetchConcurrency = 4
+
+// prefetchConcurrency is the number of trackers PrefetchAll warms at once
+var prefetchConcurrency = defaultPrefetchConcurrency
+
+// Prefetcher is implemented by trackers that fetch data in bulk, e.g. PTP's list of unregistered torrents, so the
+// fetch can happen before the torrents are evaluated instead of on the first lookup. Trackers without bulk data do
+// not implement it
+type Prefetcher interface {
+	Prefetch(ctx context.Context) error
+}
+
+// PrefetchResult is the outcome of prefetching a loaded tracker
+type PrefetchResult struct {
+	Tracker string
+	Err     error
+}
+
+// Prefetchers returns the number of loaded trackers that prefetch
+func Prefetchers() int {
+	n := 0
+	for _, t := range trackers {
+		if _, ok := t.(Prefetcher); ok {
+			n++
+		}
+	}
+
+	return n
+}
+
+// PrefetchAll prefetches every loaded tracker that supports it, prefetch_concurrency at a time. progress is called
+// as each tracker finishes with the number finished so far
+func PrefetchAll(ctx context.Context, progress func(r PrefetchResult, done, total int)) {
+	var prefetchers []Interface
+	for _, t := range trackers {
+		if _, ok := t.(Prefetcher); ok {
+			prefetchers = append(prefetchers, t)
+		}
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		done int
+	)
+
+	sem := make(chan struct{}, prefetchConcurrency)
+	for _, t := range prefetchers {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			r := PrefetchResult{Tracker: probeName(t), Err: t.(Prefetcher).Prefetch(ctx)}
+
+			mu.Lock()
+			defer mu.Unlock()
+			done++
+			if progress != nil {
+				progress(r, done, len(prefetchers))
+			}
+		}()
+	}
+
+	wg.Wait()
+}
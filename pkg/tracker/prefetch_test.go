@@ -0,0 +1,119 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/autobrr/tqm/pkg/logger"
+)
+
+// fakePrefetcher records the highest number of prefetches running at once
+type fakePrefetcher struct {
+	fakeTracker
+	err      error
+	running  *atomic.Int32
+	maxSeen  *atomic.Int32
+	prefetch atomic.Int32
+}
+
+func (f *fakePrefetcher) Prefetch(context.Context) error {
+	f.prefetch.Add(1)
+
+	n := f.running.Add(1)
+	defer f.running.Add(-1)
+	for {
+		seen := f.maxSeen.Load()
+		if n <= seen || f.maxSeen.CompareAndSwap(seen, n) {
+			break
+		}
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	return f.err
+}
+
+func TestPrefetchAll(t *testing.T) {
+	loaded, concurrency := trackers, prefetchConcurrency
+	t.Cleanup(func() {
+		trackers = loaded
+		prefetchConcurrency = concurrency
+	})
+
+	var running, maxSeen atomic.Int32
+	failed := errors.New("api down")
+
+	var prefetchers []*fakePrefetcher
+	trackers = []Interface{&fakeTracker{name: "NOPREFETCH"}}
+	for i := range 5 {
+		p := &fakePrefetcher{fakeTracker: fakeTracker{name: "P"}, running: &running, maxSeen: &maxSeen}
+		if i == 0 {
+			p.err = failed
+		}
+		prefetchers = append(prefetchers, p)
+		trackers = append(trackers, p)
+	}
+	prefetchConcurrency = 2
+
+	assert.Equal(t, 5, Prefetchers())
+
+	var (
+		mu     sync.Mutex
+		dones  []int
+		errs   int
+		totals = map[int]struct{}{}
+	)
+	PrefetchAll(context.Background(), func(r PrefetchResult, done, total int) {
+		mu.Lock()
+		defer mu.Unlock()
+		dones = append(dones, done)
+		totals[total] = struct{}{}
+		if r.Err != nil {
+			errs++
+		}
+	})
+
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, dones)
+	assert.Equal(t, map[int]struct{}{5: {}}, totals)
+	assert.Equal(t, 1, errs)
+	assert.LessOrEqual(t, maxSeen.Load(), int32(2))
+	for _, p := range prefetchers {
+		assert.Equal(t, int32(1), p.prefetch.Load())
+	}
+}
+
+func TestPTP_Prefetch(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"Total":1,"Page":1,"Pages":1,"Unregistered":[{"InfoHash":"aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"}]}`))
+	}))
+	defer server.Close()
+
+	ptp := &PTP{
+		cfg:               PTPConfig{User: "test", Key: "test"},
+		http:              &http.Client{Transport: &redirectTransport{server: server}},
+		headers:           map[string]string{"ApiUser": "test", "ApiKey": "test"},
+		log:               logger.GetLogger("test"),
+		unregisteredCache: make(map[string]bool),
+	}
+
+	ctx := context.Background()
+	require.NoError(t, ptp.Prefetch(ctx))
+	require.NoError(t, ptp.Prefetch(ctx))
+
+	// the lookup uses the prefetched list
+	err, unregistered := ptp.IsUnregistered(ctx, &Torrent{Hash: "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"})
+	require.NoError(t, err)
+	assert.True(t, unregistered)
+	assert.Equal(t, int32(1), requests.Load())
+}
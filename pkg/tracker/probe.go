@@ -0,0 +1,58 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// probeHash is an info hash no torrent has, used to check the credentials of APIs that are queried by hash
+const probeHash = "0000000000000000000000000000000000000000"
+
+// Prober is implemented by trackers that can check their API is reachable and accepts the credentials with a request
+// that changes nothing
+type Prober interface {
+	Probe(ctx context.Context) error
+}
+
+// ProbeResult is the outcome of probing a loaded tracker, Supported is false for trackers without a probe
+type ProbeResult struct {
+	Tracker   string
+	Supported bool
+	Err       error
+}
+
+// ProbeAll probes every loaded tracker in turn
+func ProbeAll(ctx context.Context) []ProbeResult {
+	results := make([]ProbeResult, 0, len(trackers))
+	for _, t := range trackers {
+		result := ProbeResult{Tracker: probeName(t)}
+		if p, ok := t.(Prober); ok {
+			result.Supported = true
+			result.Err = p.Probe(ctx)
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// probeName names a tracker in the probe report, UNIT3D trackers share a name so their domain is added
+func probeName(t Interface) string {
+	if u, ok := t.(*UNIT3D); ok {
+		return fmt.Sprintf("%s (%s)", u.Name(), u.cfg.Domain)
+	}
+
+	return t.Name()
+}
+
+// gazelleProbeError checks the response of a Gazelle ajax request, failures other than notFound mean the request
+// was refused
+func gazelleProbeError(status string, errMsg string, notFound func(status string, errMsg string) bool) error {
+	if strings.EqualFold(status, "success") || (notFound != nil && notFound(status, errMsg)) {
+		return nil
+	}
+
+	return fmt.Errorf("api error: %s: %s", status, errMsg)
+}
@@ -0,0 +1,45 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeTracker struct {
+	name string
+}
+
+func (f *fakeTracker) Name() string                                           { return f.name }
+func (f *fakeTracker) Check(string) bool                                      { return false }
+func (f *fakeTracker) IsUnregistered(context.Context, *Torrent) (error, bool) { return nil, false }
+func (f *fakeTracker) IsTrackerDown(*Torrent) (error, bool)                   { return nil, false }
+
+type fakeProber struct {
+	fakeTracker
+	err error
+}
+
+func (f *fakeProber) Probe(context.Context) error { return f.err }
+
+func TestProbeAll(t *testing.T) {
+	loaded := trackers
+	t.Cleanup(func() { trackers = loaded })
+
+	refused := errors.New("invalid api key")
+	trackers = []Interface{
+		&fakeProber{fakeTracker: fakeTracker{name: "OK"}},
+		&fakeProber{fakeTracker: fakeTracker{name: "BAD"}, err: refused},
+		&fakeTracker{name: "NOPROBE"},
+	}
+
+	assert.Equal(t, []ProbeResult{
+		{Tracker: "OK", Supported: true},
+		{Tracker: "BAD", Supported: true, Err: refused},
+		{Tracker: "NOPROBE"},
+	}, ProbeAll(context.Background()))
+
+	assert.Equal(t, "UNIT3D (aither.cc)", probeName(&UNIT3D{cfg: UNIT3DConfig{Domain: "aither.cc"}}))
+}
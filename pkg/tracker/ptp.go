@@ -2,6 +2,7 @@ package tracker
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -24,8 +25,9 @@ const (
 )
 
 type PTPConfig struct {
-	User string `koanf:"api_user"`
-	Key  string `koanf:"api_key"`
+	User          string `koanf:"api_user"`
+	Key           string `koanf:"api_key"`
+	TLSSkipVerify bool   `koanf:"tls_skip_verify"`
 }
 
 type PTP struct {
@@ -36,14 +38,17 @@ type PTP struct {
 	unregisteredCache    map[string]bool
 	unregisteredFetched  bool
 	unregisteredCacheMux sync.RWMutex
-	apiError             bool
+	// budgetExhausted is set when the request budget ran out before the unregistered torrents were fetched, every
+	// torrent looked up afterwards is recorded as skipped
+	budgetExhausted bool
+	apiError        bool
 }
 
 func NewPTP(c PTPConfig) *PTP {
 	l := logger.GetLogger("ptp-api")
 	return &PTP{
 		cfg:  c,
-		http: httputils.NewRetryableHttpClient(15*time.Second, ratelimit.New(1, ratelimit.WithoutSlack)),
+		http: httputils.NewRetryableHttpClient(15*time.Second, ratelimit.New(1, ratelimit.WithoutSlack), c.TLSSkipVerify),
 		headers: map[string]string{
 			"Accept":  "application/json",
 			"ApiUser": c.User,
@@ -82,11 +87,16 @@ func (c *PTP) fetchUnregisteredTorrents(ctx context.Context) error {
 		return fmt.Errorf("creating request URL: %w", err)
 	}
 
+	if !requestBudget.take("") {
+		return errBudgetExhausted
+	}
+
 	var resp *unregisteredResponse
-	err = httputils.MakeAPIRequest(ctx, c.http, http.MethodGet, requestURL, nil, c.headers, &resp)
+	err = makeAPIRequest(ctx, c.log, c.Name(), c.http, http.MethodGet, requestURL, nil, c.headers, &resp)
+	apiStats.count(c.Name(), err)
 	if err != nil {
 		c.apiError = true
-		return fmt.Errorf("making api request: %w", err)
+		return fmt.Errorf("making api request: %w", classifyError(err))
 	}
 
 	// validate response structure
@@ -115,14 +125,20 @@ func (c *PTP) IsUnregistered(ctx context.Context, torrent *Torrent) (error, bool
 		if err := c.fetchUnregisteredTorrents(ctx); err != nil {
 			// mark as fetched to prevent retrying on every torrent
 			c.unregisteredFetched = true
-			c.unregisteredCacheMux.Unlock()
+			c.budgetExhausted = errors.Is(err, errBudgetExhausted)
 			c.log.Errorf("Failed to fetch unregistered torrents from PTP API: %v", err)
-			return nil, false
+		} else {
+			c.unregisteredFetched = true
 		}
-		c.unregisteredFetched = true
 	}
+	budgetExhausted := c.budgetExhausted
 	c.unregisteredCacheMux.Unlock()
 
+	if budgetExhausted {
+		requestBudget.skip(torrent.Hash)
+		return nil, false
+	}
+
 	c.unregisteredCacheMux.RLock()
 	isUnregistered := c.unregisteredCache[strings.ToUpper(torrent.Hash)]
 	c.unregisteredCacheMux.RUnlock()
@@ -136,6 +152,40 @@ func (c *PTP) IsUnregistered(ctx context.Context, torrent *Torrent) (error, bool
 	return nil, isUnregistered
 }
 
+// Prefetch fetches the list of unregistered torrents ahead of the first lookup, a failed fetch is not retried
+func (c *PTP) Prefetch(ctx context.Context) error {
+	c.unregisteredCacheMux.Lock()
+	defer c.unregisteredCacheMux.Unlock()
+
+	if c.unregisteredFetched {
+		return nil
+	}
+
+	// mark as fetched to prevent retrying on every torrent
+	c.unregisteredFetched = true
+	err := c.fetchUnregisteredTorrents(ctx)
+	c.budgetExhausted = errors.Is(err, errBudgetExhausted)
+	return err
+}
+
 func (c *PTP) IsTrackerDown(_ *Torrent) (error, bool) {
 	return nil, c.apiError
 }
+
+// Probe checks the API user and key by requesting the unregistered torrents
+func (c *PTP) Probe(ctx context.Context) error {
+	requestURL, err := httputils.URLWithQuery("https://"+ptpDomain+ptpUserHistoryEndpoint, url.Values{
+		"action": []string{ptpActionUnregistered},
+		"type":   []string{ptpResponseTypeJSON},
+	})
+	if err != nil {
+		return fmt.Errorf("creating request URL: %w", err)
+	}
+
+	var resp map[string]any
+	if err := makeAPIRequest(ctx, c.log, c.Name(), c.http, http.MethodGet, requestURL, nil, c.headers, &resp); err != nil {
+		return fmt.Errorf("making api request: %w", classifyError(err))
+	}
+
+	return nil
+}
@@ -49,7 +49,7 @@ func TestPTP_RealAPI_SingleCall(t *testing.T) {
 	// Create PTP instance with real credentials
 	ptp := &PTP{
 		cfg:  PTPConfig{User: apiUser, Key: apiKey},
-		http: httputils.NewRetryableHttpClient(15*time.Second, ratelimit.New(1, ratelimit.WithoutSlack)),
+		http: httputils.NewRetryableHttpClient(15*time.Second, ratelimit.New(1, ratelimit.WithoutSlack), false),
 		headers: map[string]string{
 			"Accept":  "application/json",
 			"ApiUser": apiUser,
@@ -16,7 +16,8 @@ import (
 )
 
 type REDConfig struct {
-	Key string `koanf:"api_key"`
+	Key           string `koanf:"api_key"`
+	TLSSkipVerify bool   `koanf:"tls_skip_verify"`
 }
 
 type RED struct {
@@ -30,7 +31,7 @@ func NewRED(c REDConfig) *RED {
 	l := logger.GetLogger("red-api")
 	return &RED{
 		cfg:  c,
-		http: httputils.NewRetryableHttpClient(15*time.Second, ratelimit.New(1, ratelimit.WithoutSlack)),
+		http: httputils.NewRetryableHttpClient(15*time.Second, ratelimit.New(1, ratelimit.WithoutSlack), c.TLSSkipVerify),
 		headers: map[string]string{
 			"Accept":        "application/json",
 			"Authorization": "token " + c.Key,
@@ -69,15 +70,42 @@ func (c *RED) IsUnregistered(ctx context.Context, torrent *Torrent) (error, bool
 		return fmt.Errorf("creating request URL: %w", err), false
 	}
 
+	if skipAPIRequest(c.log, c.Name(), torrent) {
+		return nil, false
+	}
+
 	var resp *response
-	err = httputils.MakeAPIRequest(ctx, c.http, http.MethodGet, requestURL, nil, c.headers, &resp)
+	err = makeAPIRequest(ctx, c.log, c.Name(), c.http, http.MethodGet, requestURL, nil, c.headers, &resp)
+	apiFailures.record(c.log, c.Name(), err)
 	if err != nil {
-		return fmt.Errorf("making api request: %w", err), false
+		return fmt.Errorf("making api request: %w", classifyError(err)), false
 	}
 
 	return nil, resp.Status == "failure" && resp.Error == "bad hash parameter"
 }
 
 func (c *RED) IsTrackerDown(_ *Torrent) (error, bool) {
-	return nil, false
+	return nil, apiFailures.down(c.Name())
+}
+
+// Probe checks the API key with an index request
+func (c *RED) Probe(ctx context.Context) error {
+	type response struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	}
+
+	requestURL, err := httputils.URLWithQuery("https://redacted.sh/ajax.php", url.Values{
+		"action": []string{"index"},
+	})
+	if err != nil {
+		return fmt.Errorf("creating request URL: %w", err)
+	}
+
+	var resp *response
+	if err := makeAPIRequest(ctx, c.log, c.Name(), c.http, http.MethodGet, requestURL, nil, c.headers, &resp); err != nil {
+		return fmt.Errorf("making api request: %w", classifyError(err))
+	}
+
+	return gazelleProbeError(resp.Status, resp.Error, nil)
 }
@@ -0,0 +1,61 @@
+package tracker
+
+import (
+	"maps"
+	"sync"
+)
+
+// apiStats counts the API requests of the run per tracker
+var apiStats = newRequestStats()
+
+// APIStat is the number of API requests made to a tracker during the run, and how many of them failed
+type APIStat struct {
+	Requests int
+	Errors   int
+}
+
+type requestStats struct {
+	mu    sync.Mutex
+	stats map[string]APIStat
+}
+
+func newRequestStats() *requestStats {
+	return &requestStats{stats: make(map[string]APIStat)}
+}
+
+// count records a request made to the tracker with key, err is its outcome
+func (s *requestStats) count(key string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stat := s.stats[key]
+	stat.Requests++
+	if err != nil {
+		stat.Errors++
+	}
+	s.stats[key] = stat
+}
+
+// StatsKey returns the key the API requests for the torrents of host are counted under in APIStats, host itself when
+// no tracker API is configured for it
+func StatsKey(host string) string {
+	tr := Get(host)
+	if tr == nil {
+		return host
+	}
+
+	if u, ok := tr.(*UNIT3D); ok {
+		return u.cfg.Domain
+	}
+
+	return tr.Name()
+}
+
+// APIStats returns the API requests made during the run per tracker, keyed by tracker name (the domain for UNIT3D
+// trackers)
+func APIStats() map[string]APIStat {
+	apiStats.mu.Lock()
+	defer apiStats.mu.Unlock()
+
+	return maps.Clone(apiStats.stats)
+}
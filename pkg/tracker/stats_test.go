@@ -0,0 +1,33 @@
+package tracker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestStats(t *testing.T) {
+	s := newRequestStats()
+	s.count("BTN", nil)
+	s.count("BTN", errors.New("boom"))
+	s.count("HDB", nil)
+
+	assert.Equal(t, APIStat{Requests: 2, Errors: 1}, s.stats["BTN"])
+	assert.Equal(t, APIStat{Requests: 1}, s.stats["HDB"])
+}
+
+func TestStatsKey(t *testing.T) {
+	btn, err := NewBTN(BTNConfig{Key: "key"})
+	require.NoError(t, err)
+	aither, err := NewUNIT3D("aither", UNIT3DConfig{APIKey: "key", Domain: "aither.cc"})
+	require.NoError(t, err)
+
+	trackers = []Interface{btn, aither}
+	t.Cleanup(func() { trackers = nil })
+
+	assert.Equal(t, "BTN", StatsKey("landof.tv"))
+	assert.Equal(t, "aither.cc", StatsKey("aither.cc"))
+	assert.Equal(t, "tracker.example", StatsKey("tracker.example"))
+}
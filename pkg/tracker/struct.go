@@ -8,6 +8,21 @@ type Config struct {
 	RED    REDConfig
 	OPS    OPSConfig
 	UNIT3D map[string]UNIT3DConfig
+	// Gazelle holds trackers running Gazelle (or a variant with the same API), keyed by name
+	Gazelle map[string]GazelleConfig
+
+	// APIFailureThreshold is the number of consecutive API failures after which a tracker is treated as down for the
+	// rest of the run, 0 uses the default and a negative value never gives up
+	APIFailureThreshold int `koanf:"api_failure_threshold"`
+	// PrefetchConcurrency is the number of trackers whose bulk data is fetched at once before clean evaluates the
+	// torrents, 0 uses the default
+	PrefetchConcurrency int `koanf:"prefetch_concurrency"`
+	// MaxRequestsPerRun caps the tracker API requests of a run, torrents left to check once it is reached are treated
+	// as not unregistered. 0 does not cap them
+	MaxRequestsPerRun int `koanf:"max_requests_per_run"`
+	// IDCacheFile persists the torrent IDs extracted from the comments of torrents, so they are not parsed again every
+	// run. Empty keeps them for the run only
+	IDCacheFile string `koanf:"id_cache_file"`
 }
 
 type Torrent struct {
@@ -1,18 +1,42 @@
 package tracker
 
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
 var (
 	trackers []Interface
 )
 
 func Init(cfg Config) error {
 	trackers = make([]Interface, 0)
+	apiFailures = newFailureCounter(cfg.APIFailureThreshold)
+	requestBudget = newBudget(cfg.MaxRequestsPerRun)
+	apiStats = newRequestStats()
+	apiBackoff = newBackoff()
+
+	ids, err := loadIDCache(cfg.IDCacheFile)
+	if err != nil {
+		return err
+	}
+	torrentIDs = ids
+	prefetchConcurrency = defaultPrefetchConcurrency
+	if cfg.PrefetchConcurrency > 0 {
+		prefetchConcurrency = cfg.PrefetchConcurrency
+	}
 
 	// load trackers
 	if cfg.BHD.Key != "" {
 		trackers = append(trackers, NewBHD(cfg.BHD))
 	}
 	if cfg.BTN.Key != "" {
-		trackers = append(trackers, NewBTN(cfg.BTN))
+		btn, err := NewBTN(cfg.BTN)
+		if err != nil {
+			return fmt.Errorf("btn: %w", err)
+		}
+		trackers = append(trackers, btn)
 	}
 	if cfg.PTP.User != "" && cfg.PTP.Key != "" {
 		trackers = append(trackers, NewPTP(cfg.PTP))
@@ -28,7 +52,20 @@ func Init(cfg Config) error {
 	}
 	for name, unit3dCfg := range cfg.UNIT3D {
 		if unit3dCfg.APIKey != "" && unit3dCfg.Domain != "" {
-			trackers = append(trackers, NewUNIT3D(name, unit3dCfg))
+			unit3d, err := NewUNIT3D(name, unit3dCfg)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			trackers = append(trackers, unit3d)
+		}
+	}
+	for name, gazelleCfg := range cfg.Gazelle {
+		if gazelleCfg.APIKey != "" {
+			gazelle, err := NewGazelle(name, gazelleCfg)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			trackers = append(trackers, gazelle)
 		}
 	}
 	return nil
@@ -49,3 +86,37 @@ func Get(host string) Interface {
 func Loaded() int {
 	return len(trackers)
 }
+
+// compileCommentIDRegex compiles a user provided pattern for extracting the torrent ID from a torrent comment, the ID
+// is taken from the capture group named "id", or the first capture group
+func compileCommentIDRegex(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("comment_id_regex: %w", err)
+	}
+
+	if re.NumSubexp() == 0 {
+		return nil, errors.New("comment_id_regex: must contain a capture group for the torrent ID")
+	}
+
+	return re, nil
+}
+
+// matchCommentID returns the torrent ID captured by re from comment
+func matchCommentID(re *regexp.Regexp, comment string) (string, bool) {
+	matches := re.FindStringSubmatch(comment)
+	if matches == nil {
+		return "", false
+	}
+
+	group := 1
+	if i := re.SubexpIndex("id"); i > 0 {
+		group = i
+	}
+
+	return matches[group], matches[group] != ""
+}
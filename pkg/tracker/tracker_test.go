@@ -0,0 +1,106 @@
+package tracker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractTorrentID_CommentIDRegex(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  string
+		comment  string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "built_in_pattern",
+			comment:  "https://broadcasthe.net/torrents.php?action=reqlink&id=123",
+			expected: "123",
+		},
+		{
+			name:     "custom_pattern",
+			pattern:  `BTN torrent #(\d+)`,
+			comment:  "BTN torrent #456",
+			expected: "456",
+		},
+		{
+			name:     "named_group",
+			pattern:  `(?P<site>\w+) id=(?P<id>\d+)`,
+			comment:  "btn id=789",
+			expected: "789",
+		},
+		{
+			name:     "falls_back_to_built_in_pattern",
+			pattern:  `BTN torrent #(\d+)`,
+			comment:  "https://broadcasthe.net/torrents.php?action=reqlink&id=123",
+			expected: "123",
+		},
+		{
+			name:    "no_match",
+			pattern: `BTN torrent #(\d+)`,
+			comment: "no id here",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			btn, err := NewBTN(BTNConfig{Key: "key", CommentIDRegex: tt.pattern})
+			require.NoError(t, err)
+
+			id, err := btn.extractTorrentID(tt.comment)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, id)
+		})
+	}
+}
+
+func TestExtractTorrentID_UNIT3DCommentIDRegex(t *testing.T) {
+	u, err := NewUNIT3D("aither", UNIT3DConfig{APIKey: "key", Domain: "aither.cc", CommentIDRegex: `aither\.cc/t/(\d+)`})
+	require.NoError(t, err)
+	unit3d := u.(*UNIT3D)
+
+	id, err := unit3d.extractTorrentID("https://aither.cc/t/42")
+	require.NoError(t, err)
+	assert.Equal(t, "42", id)
+
+	id, err = unit3d.extractTorrentID("This torrent was downloaded from aither.cc. https://aither.cc/torrents/123456")
+	require.NoError(t, err)
+	assert.Equal(t, "123456", id, "the built-in pattern should still apply")
+}
+
+func TestInit_InvalidCommentIDRegex(t *testing.T) {
+	t.Cleanup(func() { trackers = nil })
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{
+			name:    "invalid_pattern",
+			cfg:     Config{BTN: BTNConfig{Key: "key", CommentIDRegex: `id=(\d+`}},
+			wantErr: "btn: comment_id_regex",
+		},
+		{
+			name: "no_capture_group",
+			cfg: Config{UNIT3D: map[string]UNIT3DConfig{
+				"aither": {APIKey: "key", Domain: "aither.cc", CommentIDRegex: `id=\d+`},
+			}},
+			wantErr: "aither: comment_id_regex: must contain a capture group",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorContains(t, Init(tt.cfg), tt.wantErr)
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
 	"time"
@@ -16,30 +17,39 @@ import (
 )
 
 type UNIT3DConfig struct {
-	APIKey string `koanf:"api_key"`
-	Domain string `koanf:"domain"`
+	APIKey         string `koanf:"api_key"`
+	Domain         string `koanf:"domain"`
+	TLSSkipVerify  bool   `koanf:"tls_skip_verify"`
+	CommentIDRegex string `koanf:"comment_id_regex"`
 }
 
 type UNIT3D struct {
-	cfg     UNIT3DConfig
-	http    *http.Client
-	headers map[string]string
-	log     *logrus.Entry
+	cfg            UNIT3DConfig
+	http           *http.Client
+	headers        map[string]string
+	log            *logrus.Entry
+	commentIDRegex *regexp.Regexp
 }
 
 // API docs: https://hdinnovations.github.io/UNIT3D/torrent_api.html
-func NewUNIT3D(name string, c UNIT3DConfig) Interface {
+func NewUNIT3D(name string, c UNIT3DConfig) (Interface, error) {
+	commentIDRegex, err := compileCommentIDRegex(c.CommentIDRegex)
+	if err != nil {
+		return nil, err
+	}
+
 	l := logger.GetLogger(fmt.Sprintf("%s-api", strings.ToLower(name)))
 
 	return &UNIT3D{
-		cfg:  c,
-		http: httputils.NewRetryableHttpClient(15*time.Second, ratelimit.New(1, ratelimit.WithoutSlack)),
+		cfg:            c,
+		commentIDRegex: commentIDRegex,
+		http:           httputils.NewRetryableHttpClient(15*time.Second, ratelimit.New(1, ratelimit.WithoutSlack), c.TLSSkipVerify),
 		headers: map[string]string{
 			"Authorization": fmt.Sprintf("Bearer %s", c.APIKey),
 			"Accept":        "application/json",
 		},
 		log: l,
-	}
+	}, nil
 }
 
 func (c *UNIT3D) Name() string {
@@ -57,6 +67,13 @@ func (c *UNIT3D) extractTorrentID(comment string) (string, error) {
 		return "", fmt.Errorf("empty comment field")
 	}
 
+	// the configured pattern takes precedence, falling back to the built-in one
+	if c.commentIDRegex != nil {
+		if id, ok := matchCommentID(c.commentIDRegex, comment); ok {
+			return id, nil
+		}
+	}
+
 	// extract torrent ID from any URL in the comment that matches our domain
 	re := regexp.MustCompile(fmt.Sprintf(`https?://[^/]*%s/(?:torrents|details)/(\d+)`, regexp.QuoteMeta(c.cfg.Domain)))
 	matches := re.FindStringSubmatch(comment)
@@ -88,17 +105,24 @@ func (c *UNIT3D) IsUnregistered(ctx context.Context, torrent *Torrent) (error, b
 
 	c.log.Tracef("Querying UNIT3D API for torrent: %s (hash: %s)", torrent.Name, torrent.Hash)
 
-	torrentID, err := c.extractTorrentID(torrent.Comment)
+	torrentID, err := torrentIDs.torrentID(c.cfg.Domain, c.cfg.CommentIDRegex, torrent.Hash, func() (string, error) {
+		return c.extractTorrentID(torrent.Comment)
+	})
 	if err != nil {
 		return nil, false
 	}
 
 	requestURL := fmt.Sprintf("https://%s/api/torrents/%s", c.cfg.Domain, torrentID)
 
+	if skipAPIRequest(c.log, c.cfg.Domain, torrent) {
+		return nil, false
+	}
+
 	var resp *response
-	err = httputils.MakeAPIRequest(ctx, c.http, http.MethodGet, requestURL, nil, c.headers, &resp)
+	err = makeAPIRequest(ctx, c.log, c.cfg.Domain, c.http, http.MethodGet, requestURL, nil, c.headers, &resp)
+	apiFailures.record(c.log, c.cfg.Domain, err)
 	if err != nil {
-		return fmt.Errorf("making api request: %w", err), false
+		return fmt.Errorf("making api request: %w", classifyError(err)), false
 	}
 
 	// compare hash
@@ -114,5 +138,22 @@ func (c *UNIT3D) IsUnregistered(ctx context.Context, torrent *Torrent) (error, b
 }
 
 func (c *UNIT3D) IsTrackerDown(_ *Torrent) (error, bool) {
-	return nil, false
+	return nil, apiFailures.down(c.cfg.Domain)
+}
+
+// Probe checks the API key by listing a single torrent
+func (c *UNIT3D) Probe(ctx context.Context) error {
+	requestURL, err := httputils.URLWithQuery(fmt.Sprintf("https://%s/api/torrents", c.cfg.Domain), url.Values{
+		"perPage": []string{"1"},
+	})
+	if err != nil {
+		return fmt.Errorf("creating request URL: %w", err)
+	}
+
+	var resp map[string]any
+	if err := makeAPIRequest(ctx, c.log, c.cfg.Domain, c.http, http.MethodGet, requestURL, nil, c.headers, &resp); err != nil {
+		return fmt.Errorf("making api request: %w", classifyError(err))
+	}
+
+	return nil
 }